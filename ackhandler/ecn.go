@@ -0,0 +1,147 @@
+package ackhandler
+
+import (
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+)
+
+// numECNTestingPackets is the number of packets sent with ECT(0) to find out if a path supports ECN
+const numECNTestingPackets = 10
+
+// ECNState is the state of the ECN validation of a path, as in RFC 9000, section 13.4.2
+type ECNState uint8
+
+const (
+	// ECNStateTesting means that the first packets are sent with ECT(0), to test the path
+	ECNStateTesting ECNState = iota
+	// ECNStateUnknown means that the testing packets were sent, but none of them was acknowledged yet.
+	// Packets are not marked until the ECN counts of the peer confirm that ECN works.
+	ECNStateUnknown
+	// ECNStateCapable means that the path passed the validation, all packets are marked
+	ECNStateCapable
+	// ECNStateFailed means that the path or the peer doesn't support ECN, or mangles the marks
+	ECNStateFailed
+)
+
+func (s ECNState) String() string {
+	switch s {
+	case ECNStateTesting:
+		return "testing"
+	case ECNStateUnknown:
+		return "unknown"
+	case ECNStateCapable:
+		return "capable"
+	case ECNStateFailed:
+		return "failed"
+	}
+	return "invalid"
+}
+
+// The ecnTracker decides which packets are marked, and validates the ECN counts reported in the ACK frames.
+// A path that drops ECN marked packets (black hole), clears the marks or reports wrong counts fails the validation.
+type ecnTracker struct {
+	pathID protocol.PathID
+	state  ECNState
+
+	numSentTesting uint64
+	numLostTesting uint64
+	numSentECT0    uint64
+
+	// The ECN counts of the last ACK frame
+	ect0, ect1, ce uint64
+}
+
+// Mode returns the ECN codepoint of the next retransmittable packet
+func (e *ecnTracker) Mode() protocol.ECN {
+	if e.state == ECNStateTesting || e.state == ECNStateCapable {
+		return protocol.ECT0
+	}
+	return protocol.ECNNon
+}
+
+// SentPacket is called for every retransmittable packet, with the codepoint it was marked with
+func (e *ecnTracker) SentPacket(ecn protocol.ECN) {
+	if ecn != protocol.ECT0 {
+		return
+	}
+	e.numSentECT0++
+	if e.state == ECNStateTesting {
+		e.numSentTesting++
+		if e.numSentTesting >= numECNTestingPackets {
+			e.state = ECNStateUnknown
+		}
+	}
+}
+
+// LostPacket is called for every packet declared lost.
+// If all the testing packets are lost, the path probably drops ECN marked packets.
+func (e *ecnTracker) LostPacket(ecn protocol.ECN) {
+	if (e.state != ECNStateTesting && e.state != ECNStateUnknown) || ecn != protocol.ECT0 {
+		return
+	}
+	// Only the testing packets are marked before the path is validated
+	e.numLostTesting++
+	if e.numLostTesting >= numECNTestingPackets {
+		e.fail("all testing packets were lost")
+	}
+}
+
+// HandleAck validates the ECN counts of an ACK frame acknowledging the ackedPackets.
+// It returns true if the number of CE marks increased, i.e. if a router on the path experienced congestion.
+func (e *ecnTracker) HandleAck(ackedPackets []*PacketElement, counts *wire.ECNCounts) bool {
+	if e.state == ECNStateFailed {
+		return false
+	}
+	var newlyAckedECT0 uint64
+	for _, p := range ackedPackets {
+		if p.Value.ECN == protocol.ECT0 {
+			newlyAckedECT0++
+		}
+	}
+	if counts == nil {
+		if newlyAckedECT0 > 0 {
+			e.fail("ACK frame without ECN counts acknowledges ECT(0) packets")
+		}
+		return false
+	}
+	if counts.ECT0 < e.ect0 || counts.ECT1 < e.ect1 || counts.ECNCE < e.ce {
+		e.fail("ECN counts decreased")
+		return false
+	}
+	// We never send ECT(1), the marks were changed on the path
+	if counts.ECT1 > e.ect1 {
+		e.fail("ECT(1) count increased")
+		return false
+	}
+	if counts.ECT0+counts.ECNCE > e.numSentECT0 {
+		e.fail("more ECT(0) and CE marks than ECT(0) packets sent")
+		return false
+	}
+	if (counts.ECT0-e.ect0)+(counts.ECNCE-e.ce) < newlyAckedECT0 {
+		e.fail("ECN counts don't cover the acknowledged ECT(0) packets")
+		return false
+	}
+	congestionExperienced := counts.ECNCE > e.ce
+	e.ect0, e.ect1, e.ce = counts.ECT0, counts.ECT1, counts.ECNCE
+	if newlyAckedECT0 > 0 && (e.state == ECNStateTesting || e.state == ECNStateUnknown) {
+		e.state = ECNStateCapable
+		utils.Debugf("Path %d: ECN validation succeeded", e.pathID)
+	}
+	return congestionExperienced
+}
+
+// State returns the state of the validation
+func (e *ecnTracker) State() ECNState {
+	return e.state
+}
+
+// CEMarks returns the number of CE marks reported by the peer
+func (e *ecnTracker) CEMarks() uint64 {
+	return e.ce
+}
+
+func (e *ecnTracker) fail(reason string) {
+	e.state = ECNStateFailed
+	utils.Infof("Path %d: ECN validation failed, %s", e.pathID, reason)
+}
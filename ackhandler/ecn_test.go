@@ -0,0 +1,114 @@
+package ackhandler
+
+import (
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ECN tracker", func() {
+	var ecn *ecnTracker
+
+	ackedPackets := func(marks ...protocol.ECN) []*PacketElement {
+		var els []*PacketElement
+		for i, m := range marks {
+			els = append(els, &PacketElement{Value: Packet{PacketNumber: protocol.PacketNumber(i + 1), ECN: m}})
+		}
+		return els
+	}
+
+	sendTestingPackets := func() {
+		for i := 0; i < numECNTestingPackets; i++ {
+			Expect(ecn.Mode()).To(Equal(protocol.ECT0))
+			ecn.SentPacket(ecn.Mode())
+		}
+	}
+
+	BeforeEach(func() {
+		ecn = &ecnTracker{}
+	})
+
+	It("has a string representation of the states", func() {
+		Expect(ECNStateTesting.String()).To(Equal("testing"))
+		Expect(ECNStateUnknown.String()).To(Equal("unknown"))
+		Expect(ECNStateCapable.String()).To(Equal("capable"))
+		Expect(ECNStateFailed.String()).To(Equal("failed"))
+	})
+
+	It("marks the testing packets, and then waits for the validation", func() {
+		sendTestingPackets()
+		Expect(ecn.State()).To(Equal(ECNStateUnknown))
+		Expect(ecn.Mode()).To(Equal(protocol.ECNNon))
+	})
+
+	It("validates the path if an ECT(0) packet is acknowledged with the correct counts", func() {
+		sendTestingPackets()
+		Expect(ecn.HandleAck(ackedPackets(protocol.ECT0, protocol.ECT0), &wire.ECNCounts{ECT0: 2})).To(BeFalse())
+		Expect(ecn.State()).To(Equal(ECNStateCapable))
+		Expect(ecn.Mode()).To(Equal(protocol.ECT0))
+	})
+
+	It("reports CE marks", func() {
+		sendTestingPackets()
+		Expect(ecn.HandleAck(ackedPackets(protocol.ECT0, protocol.ECT0), &wire.ECNCounts{ECT0: 1, ECNCE: 1})).To(BeTrue())
+		Expect(ecn.CEMarks()).To(BeEquivalentTo(1))
+		Expect(ecn.HandleAck(ackedPackets(protocol.ECT0), &wire.ECNCounts{ECT0: 2, ECNCE: 1})).To(BeFalse())
+		Expect(ecn.State()).To(Equal(ECNStateCapable))
+	})
+
+	It("fails if all testing packets are lost", func() {
+		sendTestingPackets()
+		for i := 0; i < numECNTestingPackets-1; i++ {
+			ecn.LostPacket(protocol.ECT0)
+		}
+		Expect(ecn.State()).To(Equal(ECNStateUnknown))
+		ecn.LostPacket(protocol.ECT0)
+		Expect(ecn.State()).To(Equal(ECNStateFailed))
+		Expect(ecn.Mode()).To(Equal(protocol.ECNNon))
+	})
+
+	It("fails if ECT(0) packets are acknowledged without ECN counts", func() {
+		sendTestingPackets()
+		Expect(ecn.HandleAck(ackedPackets(protocol.ECT0), nil)).To(BeFalse())
+		Expect(ecn.State()).To(Equal(ECNStateFailed))
+	})
+
+	It("doesn't fail if only unmarked packets are acknowledged without ECN counts", func() {
+		Expect(ecn.HandleAck(ackedPackets(protocol.ECNNon), nil)).To(BeFalse())
+		Expect(ecn.State()).To(Equal(ECNStateTesting))
+	})
+
+	It("fails if the counts decrease", func() {
+		sendTestingPackets()
+		ecn.HandleAck(ackedPackets(protocol.ECT0, protocol.ECT0), &wire.ECNCounts{ECT0: 2})
+		Expect(ecn.HandleAck(nil, &wire.ECNCounts{ECT0: 1})).To(BeFalse())
+		Expect(ecn.State()).To(Equal(ECNStateFailed))
+	})
+
+	It("fails if the ECT(1) count increases", func() {
+		sendTestingPackets()
+		ecn.HandleAck(ackedPackets(protocol.ECT0), &wire.ECNCounts{ECT0: 1, ECT1: 1})
+		Expect(ecn.State()).To(Equal(ECNStateFailed))
+	})
+
+	It("fails if more packets are reported than were sent", func() {
+		ecn.SentPacket(protocol.ECT0)
+		ecn.HandleAck(ackedPackets(protocol.ECT0), &wire.ECNCounts{ECT0: 2})
+		Expect(ecn.State()).To(Equal(ECNStateFailed))
+	})
+
+	It("fails if the marks were cleared", func() {
+		sendTestingPackets()
+		ecn.HandleAck(ackedPackets(protocol.ECT0, protocol.ECT0), &wire.ECNCounts{ECT0: 1})
+		Expect(ecn.State()).To(Equal(ECNStateFailed))
+	})
+
+	It("ignores the counts after the validation failed", func() {
+		sendTestingPackets()
+		ecn.HandleAck(ackedPackets(protocol.ECT0), nil)
+		Expect(ecn.HandleAck(ackedPackets(protocol.ECT0), &wire.ECNCounts{ECNCE: 1})).To(BeFalse())
+		Expect(ecn.State()).To(Equal(ECNStateFailed))
+	})
+})
@@ -3,13 +3,14 @@ package ackhandler
 import (
 	"time"
 
+	"github.com/lucas-clemente/quic-go/congestion"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 )
 
 // SentPacketHandler handles ACKs received for outgoing packets
 type SentPacketHandler interface {
-	// SentPacket may modify the packet
+	// SentPacket may modify the packet, it sets the ECN codepoint the packet must be sent with
 	SentPacket(packet *Packet) error
 	//******
 	ReceivedAck(ackFrame *wire.AckFrame, withPacketNumber protocol.PacketNumber, recvTime time.Time,count int) error
@@ -19,6 +20,8 @@ type SentPacketHandler interface {
 	SetInflightAsLost()
 
 	SendingAllowed() bool
+	// TimeUntilSend returns the pacing delay before the next packet can be sent, 0 if it can be sent now
+	TimeUntilSend() time.Duration
 	GetStopWaitingFrame(force bool) *wire.StopWaitingFrame
 	ShouldSendRetransmittablePacket() bool
 	DequeuePacketForRetransmission() (packet *Packet)
@@ -30,11 +33,38 @@ type SentPacketHandler interface {
 	DuplicatePacket(packet *Packet)
 
 	GetStatistics() (uint64, uint64, uint64)
+	// GetBytesSent returns the number of bytes sent, including retransmissions
+	GetBytesSent() protocol.ByteCount
+	GetBytesInFlight() protocol.ByteCount
+	GetCongestionWindow() protocol.ByteCount
+	// GetCongestionState returns a snapshot of the congestion state
+	GetCongestionState() CongestionState
+	// SetAppLimited is called when the window allows sending but there is no data to send
+	SetAppLimited()
+}
+
+// CongestionState is a snapshot of the congestion state of a SentPacketHandler
+type CongestionState struct {
+	CongestionWindow   protocol.ByteCount
+	SlowStartThreshold protocol.ByteCount
+	BytesInFlight      protocol.ByteCount
+	InRecovery         bool
+	InSlowStart        bool
+	BandwidthEstimate  congestion.Bandwidth
+	// Number of TLPs and RTOs sent since the last ACK
+	TLPCount uint32
+	RTOCount uint32
+	// Olia is only set if the congestion controller is OLIA
+	Olia *congestion.OliaState
+	// ECN is the state of the ECN validation, ECNCEMarks the number of CE marks reported by the peer
+	ECN        ECNState
+	ECNCEMarks uint64
 }
 
 // ReceivedPacketHandler handles ACKs needed to send for incoming packets
 type ReceivedPacketHandler interface {
-	ReceivedPacket(packetNumber protocol.PacketNumber, shouldInstigateAck bool) error
+	// ReceivedPacket is called for every received packet, ecn is the ECN codepoint of its IP header
+	ReceivedPacket(packetNumber protocol.PacketNumber, ecn protocol.ECN, shouldInstigateAck bool) error
 	SetLowerLimit(protocol.PacketNumber)
 
 	GetAlarmTimeout() time.Time
@@ -8,14 +8,21 @@ import (
 )
 
 // A Packet is a packet
-// +gen linkedlist
 type Packet struct {
 	PacketNumber    protocol.PacketNumber
 	Frames          []wire.Frame
 	Length          protocol.ByteCount
 	EncryptionLevel protocol.EncryptionLevel
+	// ECN is the codepoint the packet is marked with, it's set by the SentPacketHandler
+	ECN protocol.ECN
 
 	SendTime time.Time
+
+	// Delivery state of the path when the packet was sent, used to sample the delivery rate
+	Delivered     protocol.ByteCount
+	DeliveredTime time.Time
+	FirstSentTime time.Time
+	IsAppLimited  bool
 }
 
 // GetFramesForRetransmission gets all the frames for retransmission
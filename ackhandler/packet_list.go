@@ -0,0 +1,202 @@
+package ackhandler
+
+import (
+	"math/bits"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// initialPacketListSize is the initial capacity of a PacketList, it must be a power of 2 and a multiple of 64
+const initialPacketListSize = 64
+
+// PacketElement is a packet in a PacketList.
+// It's only valid until the next call to PushBack, which may move the packets.
+type PacketElement struct {
+	// The list to which this element belongs, nil if the packet was removed (or skipped).
+	list *PacketList
+
+	// The value stored with this element.
+	Value Packet
+}
+
+// Next returns the next packet of the list or nil.
+func (e *PacketElement) Next() *PacketElement {
+	l := e.list
+	if l == nil {
+		return nil
+	}
+	if off := l.nextOffset(int(e.Value.PacketNumber-l.first) + 1); off >= 0 {
+		return l.at(off)
+	}
+	return nil
+}
+
+// Prev returns the previous packet of the list or nil.
+func (e *PacketElement) Prev() *PacketElement {
+	l := e.list
+	if l == nil {
+		return nil
+	}
+	if off := l.prevOffset(int(e.Value.PacketNumber-l.first) - 1); off >= 0 {
+		return l.at(off)
+	}
+	return nil
+}
+
+// PacketList holds the sent packets, sorted by packet number.
+// It is a ring buffer indexed by packet number: looking a packet up is O(1), and the elements are not
+// allocated one by one. Packets must be pushed with increasing packet numbers, the skipped packet
+// numbers and the packets removed from the middle of the list leave holes in the ring.
+type PacketList struct {
+	ring []PacketElement // its length is a power of 2
+	// A bit per slot of the ring, set if the slot holds a packet, to skip the holes quickly
+	present []uint64
+
+	head  int                   // index in the ring of the first packet
+	first protocol.PacketNumber // packet number of the first packet
+	span  int                   // number of slots from the first to the last packet, holes included
+	len   int                   // number of packets
+}
+
+// NewPacketList returns an empty list.
+// The zero value for PacketList is an empty list ready to use, the ring is allocated by the first PushBack.
+func NewPacketList() *PacketList { return &PacketList{} }
+
+// Len returns the number of packets of the list.
+func (l *PacketList) Len() int { return l.len }
+
+// Front returns the packet with the lowest packet number, or nil if the list is empty.
+func (l *PacketList) Front() *PacketElement {
+	if l.len == 0 {
+		return nil
+	}
+	return l.at(0)
+}
+
+// Back returns the packet with the highest packet number, or nil if the list is empty.
+func (l *PacketList) Back() *PacketElement {
+	if l.len == 0 {
+		return nil
+	}
+	return l.at(l.span - 1)
+}
+
+// Get returns the packet with packet number p, or nil if it isn't in the list.
+func (l *PacketList) Get(p protocol.PacketNumber) *PacketElement {
+	if l.len == 0 || p < l.first || p-l.first >= protocol.PacketNumber(l.span) {
+		return nil
+	}
+	if e := l.at(int(p - l.first)); e.list != nil {
+		return e
+	}
+	return nil
+}
+
+// PushBack inserts a new packet at the back of the list and returns it.
+// Its packet number must be higher than the one of the last packet.
+func (l *PacketList) PushBack(v Packet) *PacketElement {
+	if l.len == 0 {
+		l.first = v.PacketNumber
+		l.span = 0
+	} else if v.PacketNumber < l.first+protocol.PacketNumber(l.span) {
+		panic("PacketList: packet numbers must be increasing")
+	}
+	off := int(v.PacketNumber - l.first)
+	if off >= len(l.ring) {
+		l.grow(off + 1)
+	}
+	l.span = off + 1
+	i := l.index(off)
+	l.ring[i] = PacketElement{list: l, Value: v}
+	l.present[i/64] |= 1 << uint(i%64)
+	l.len++
+	return &l.ring[i]
+}
+
+// Remove removes e from l if e is an element of list l, and returns its packet.
+// The frames of the removed packet are released, but the rest of e.Value can still be read.
+func (l *PacketList) Remove(e *PacketElement) Packet {
+	v := e.Value
+	if e.list != l {
+		return v
+	}
+	i := l.index(int(v.PacketNumber - l.first))
+	l.present[i/64] &^= 1 << uint(i%64)
+	e.list = nil
+	e.Value.Frames = nil
+	l.len--
+	if l.len == 0 {
+		l.span = 0
+		return v
+	}
+	// Skip the holes, so that the first and last slots always hold a packet
+	if off := l.nextOffset(0); off > 0 {
+		l.first += protocol.PacketNumber(off)
+		l.head = l.index(off)
+		l.span -= off
+	}
+	l.span = l.prevOffset(l.span-1) + 1
+	return v
+}
+
+func (l *PacketList) index(off int) int {
+	return (l.head + off) & (len(l.ring) - 1)
+}
+
+func (l *PacketList) at(off int) *PacketElement {
+	return &l.ring[l.index(off)]
+}
+
+// nextOffset returns the offset of the first packet at or after off, or -1
+func (l *PacketList) nextOffset(off int) int {
+	for off < l.span {
+		i := l.index(off)
+		if word := l.present[i/64] >> uint(i%64); word != 0 {
+			// If the ring wraps around within the word, the bit may belong to one of the first packets
+			if off += bits.TrailingZeros64(word); off < l.span {
+				return off
+			}
+			return -1
+		}
+		off += 64 - i%64
+	}
+	return -1
+}
+
+// prevOffset returns the offset of the last packet at or before off, or -1
+func (l *PacketList) prevOffset(off int) int {
+	for off >= 0 {
+		i := l.index(off)
+		if word := l.present[i/64] << uint(63-i%64); word != 0 {
+			// If the ring wraps around within the word, the bit may belong to one of the last packets
+			if off -= bits.LeadingZeros64(word); off >= 0 {
+				return off
+			}
+			return -1
+		}
+		off -= i%64 + 1
+	}
+	return -1
+}
+
+// grow reallocates the ring so that it holds at least span slots
+func (l *PacketList) grow(span int) {
+	size := len(l.ring)
+	if size == 0 {
+		size = initialPacketListSize
+	}
+	for size < span {
+		size *= 2
+	}
+	ring := make([]PacketElement, size)
+	present := make([]uint64, size/64)
+	for off := 0; off < l.span; off++ {
+		if e := l.at(off); e.list != nil {
+			ring[off] = *e
+			present[off/64] |= 1 << uint(off%64)
+		}
+	}
+	l.ring = ring
+	l.present = present
+	l.head = 0
+}
@@ -0,0 +1,190 @@
+package ackhandler
+
+import (
+	"math/rand"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PacketList", func() {
+	var list *PacketList
+
+	pushPackets := func(pns ...protocol.PacketNumber) {
+		for _, p := range pns {
+			list.PushBack(Packet{PacketNumber: p})
+		}
+	}
+
+	packetNumbers := func() []protocol.PacketNumber {
+		var pns []protocol.PacketNumber
+		for el := list.Front(); el != nil; el = el.Next() {
+			pns = append(pns, el.Value.PacketNumber)
+		}
+		return pns
+	}
+
+	BeforeEach(func() {
+		list = NewPacketList()
+	})
+
+	It("is empty", func() {
+		Expect(list.Len()).To(BeZero())
+		Expect(list.Front()).To(BeNil())
+		Expect(list.Back()).To(BeNil())
+		Expect(list.Get(1)).To(BeNil())
+	})
+
+	It("pushes packets", func() {
+		pushPackets(1, 2, 3)
+		Expect(list.Len()).To(Equal(3))
+		Expect(list.Front().Value.PacketNumber).To(Equal(protocol.PacketNumber(1)))
+		Expect(list.Back().Value.PacketNumber).To(Equal(protocol.PacketNumber(3)))
+		Expect(packetNumbers()).To(Equal([]protocol.PacketNumber{1, 2, 3}))
+	})
+
+	It("skips the packet numbers that were not pushed", func() {
+		pushPackets(1, 4, 5, 8)
+		Expect(list.Len()).To(Equal(4))
+		Expect(packetNumbers()).To(Equal([]protocol.PacketNumber{1, 4, 5, 8}))
+		Expect(list.Back().Prev().Value.PacketNumber).To(Equal(protocol.PacketNumber(5)))
+		Expect(list.Get(4).Prev().Value.PacketNumber).To(Equal(protocol.PacketNumber(1)))
+		Expect(list.Front().Prev()).To(BeNil())
+		Expect(list.Get(3)).To(BeNil())
+		Expect(list.Get(9)).To(BeNil())
+	})
+
+	It("refuses packet numbers that are not increasing", func() {
+		pushPackets(3)
+		Expect(func() { pushPackets(3) }).To(Panic())
+		Expect(func() { pushPackets(2) }).To(Panic())
+	})
+
+	It("gets packets by packet number", func() {
+		pushPackets(10, 11, 12)
+		Expect(list.Get(11).Value.PacketNumber).To(Equal(protocol.PacketNumber(11)))
+		Expect(list.Get(9)).To(BeNil())
+		Expect(list.Get(13)).To(BeNil())
+	})
+
+	It("removes packets", func() {
+		pushPackets(1, 2, 3, 4)
+		list.Remove(list.Get(2))
+		Expect(list.Len()).To(Equal(3))
+		Expect(list.Get(2)).To(BeNil())
+		Expect(packetNumbers()).To(Equal([]protocol.PacketNumber{1, 3, 4}))
+		list.Remove(list.Front())
+		Expect(list.Front().Value.PacketNumber).To(Equal(protocol.PacketNumber(3)))
+		list.Remove(list.Back())
+		Expect(list.Back().Value.PacketNumber).To(Equal(protocol.PacketNumber(3)))
+		Expect(packetNumbers()).To(Equal([]protocol.PacketNumber{3}))
+	})
+
+	It("skips the holes at the front and the back after a removal", func() {
+		pushPackets(1, 2, 3, 4, 5)
+		list.Remove(list.Get(2))
+		list.Remove(list.Get(4))
+		list.Remove(list.Front())
+		Expect(list.Front().Value.PacketNumber).To(Equal(protocol.PacketNumber(3)))
+		list.Remove(list.Back())
+		Expect(list.Back().Value.PacketNumber).To(Equal(protocol.PacketNumber(3)))
+		Expect(list.Len()).To(Equal(1))
+	})
+
+	It("returns the removed packet, and releases its frames", func() {
+		frames := []wire.Frame{&wire.PingFrame{}}
+		list.PushBack(Packet{PacketNumber: 1, Frames: frames, Length: 42})
+		el := list.Front()
+		p := list.Remove(el)
+		Expect(p.Frames).To(Equal(frames))
+		Expect(p.Length).To(Equal(protocol.ByteCount(42)))
+		Expect(el.Value.Frames).To(BeNil())
+		Expect(el.Value.Length).To(Equal(protocol.ByteCount(42)))
+		Expect(el.Next()).To(BeNil())
+		Expect(list.Len()).To(BeZero())
+		// removing it twice has no effect
+		list.Remove(el)
+		Expect(list.Len()).To(BeZero())
+	})
+
+	It("restarts at any packet number once empty", func() {
+		pushPackets(1, 2)
+		list.Remove(list.Front())
+		list.Remove(list.Front())
+		pushPackets(1000)
+		Expect(list.Len()).To(Equal(1))
+		Expect(packetNumbers()).To(Equal([]protocol.PacketNumber{1000}))
+	})
+
+	It("wraps around the ring", func() {
+		var expected []protocol.PacketNumber
+		for p := protocol.PacketNumber(1); p <= initialPacketListSize*3; p++ {
+			pushPackets(p)
+			if p > initialPacketListSize/2 {
+				list.Remove(list.Front())
+			}
+		}
+		for p := protocol.PacketNumber(initialPacketListSize*3 - initialPacketListSize/2 + 1); p <= initialPacketListSize*3; p++ {
+			expected = append(expected, p)
+		}
+		Expect(list.ring).To(HaveLen(initialPacketListSize))
+		Expect(packetNumbers()).To(Equal(expected))
+	})
+
+	It("grows, keeping the packets in order", func() {
+		var expected []protocol.PacketNumber
+		// start with an offset, so that the ring wraps when it grows
+		for p := protocol.PacketNumber(1); p <= 10; p++ {
+			pushPackets(p)
+			list.Remove(list.Front())
+		}
+		for p := protocol.PacketNumber(11); p <= 10+initialPacketListSize*4; p += 2 {
+			pushPackets(p)
+			expected = append(expected, p)
+		}
+		Expect(len(list.ring)).To(BeNumerically(">", initialPacketListSize))
+		Expect(list.Len()).To(Equal(len(expected)))
+		Expect(packetNumbers()).To(Equal(expected))
+		for _, p := range expected {
+			Expect(list.Get(p).Value.PacketNumber).To(Equal(p))
+		}
+	})
+
+	It("behaves like a sorted slice", func() {
+		r := rand.New(rand.NewSource(42))
+		var expected []protocol.PacketNumber
+		var p protocol.PacketNumber
+		for i := 0; i < 20000; i++ {
+			if len(expected) == 0 || r.Intn(2) == 0 {
+				p += protocol.PacketNumber(1 + r.Intn(3))
+				pushPackets(p)
+				expected = append(expected, p)
+			} else {
+				// mostly remove from the front, as the ACKs do
+				j := r.Intn(len(expected))
+				if r.Intn(4) != 0 {
+					j = r.Intn(utils.Min(len(expected), 3))
+				}
+				list.Remove(list.Get(expected[j]))
+				expected = append(expected[:j], expected[j+1:]...)
+			}
+			Expect(list.Len()).To(Equal(len(expected)))
+			if len(expected) > 0 {
+				Expect(list.Front().Value.PacketNumber).To(Equal(expected[0]))
+				Expect(list.Back().Value.PacketNumber).To(Equal(expected[len(expected)-1]))
+			}
+			if i%100 == 0 {
+				Expect(packetNumbers()).To(Equal(expected))
+				var reversed []protocol.PacketNumber
+				for el := list.Back(); el != nil; el = el.Prev() {
+					reversed = append([]protocol.PacketNumber{el.Value.PacketNumber}, reversed...)
+				}
+				Expect(reversed).To(Equal(expected))
+			}
+		}
+	})
+})
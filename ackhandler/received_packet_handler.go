@@ -26,13 +26,15 @@ type receivedPacketHandler struct {
 	lastAck                                    *wire.AckFrame
 
 	version protocol.VersionNumber
-  //******
+	//******
 
-	owdtimestamp map[protocol.PacketNumber]time.Time
-	lastowdtimestamp map[protocol.PacketNumber]time.Time
+	// The receive times of the retransmittable packets, to compute one-way delays
+	timestamps receivedTimestamps
 
-  //******
+	//******
 	packets uint64
+	// ecnCounts counts the ECN codepoints of the received packets, they're reported in the ACK frames
+	ecnCounts wire.ECNCounts
 }
 
 // NewReceivedPacketHandler creates a new receivedPacketHandler
@@ -48,24 +50,25 @@ func (h *receivedPacketHandler) GetStatistics() uint64 {
 	return h.packets
 }
 
-func (h *receivedPacketHandler) ReceivedPacket(packetNumber protocol.PacketNumber, shouldInstigateAck bool) error {
+func (h *receivedPacketHandler) ReceivedPacket(packetNumber protocol.PacketNumber, ecn protocol.ECN, shouldInstigateAck bool) error {
 	if packetNumber == 0 {
 		return errInvalidPacketNumber
 	}
-	if shouldInstigateAck{
-		//******
-		if h.owdtimestamp==nil{
-			h.owdtimestamp = make(map[protocol.PacketNumber]time.Time)
-		}
-		h.owdtimestamp[packetNumber] = time.Now()
-		//******
-	}
+	rcvTime := time.Now()
 	// A new packet was received on that path and passes checks, so count it for stats
 	h.packets++
+	switch ecn {
+	case protocol.ECT0:
+		h.ecnCounts.ECT0++
+	case protocol.ECT1:
+		h.ecnCounts.ECT1++
+	case protocol.ECNCE:
+		h.ecnCounts.ECNCE++
+	}
 
 	if packetNumber > h.largestObserved {
 		h.largestObserved = packetNumber
-		h.largestObservedReceivedTime = time.Now()
+		h.largestObservedReceivedTime = rcvTime
 	}
 
 	if packetNumber <= h.lowerLimit {
@@ -75,7 +78,12 @@ func (h *receivedPacketHandler) ReceivedPacket(packetNumber protocol.PacketNumbe
 	if err := h.packetHistory.ReceivedPacket(packetNumber); err != nil {
 		return err
 	}
-	h.maybeQueueAck(packetNumber, shouldInstigateAck)
+	//******
+	if shouldInstigateAck {
+		h.timestamps.Add(packetNumber, rcvTime)
+	}
+	//******
+	h.maybeQueueAck(packetNumber, ecn, shouldInstigateAck)
 
 	return nil
 }
@@ -85,9 +93,10 @@ func (h *receivedPacketHandler) ReceivedPacket(packetNumber protocol.PacketNumbe
 func (h *receivedPacketHandler) SetLowerLimit(p protocol.PacketNumber) {
 	h.lowerLimit = p
 	h.packetHistory.DeleteUpTo(p)
+	h.timestamps.DeleteUpTo(p)
 }
 
-func (h *receivedPacketHandler) maybeQueueAck(packetNumber protocol.PacketNumber, shouldInstigateAck bool) {
+func (h *receivedPacketHandler) maybeQueueAck(packetNumber protocol.PacketNumber, ecn protocol.ECN, shouldInstigateAck bool) {
 	h.packetsReceivedSinceLastAck++
 
 	if shouldInstigateAck {
@@ -114,6 +123,11 @@ func (h *receivedPacketHandler) maybeQueueAck(packetNumber protocol.PacketNumber
 		h.ackQueued = true
 	}
 
+	// report congestion as soon as possible
+	if ecn == protocol.ECNCE {
+		h.ackQueued = true
+	}
+
 	// check if a new missing range above the previously was created
 	if h.lastAck != nil && h.packetHistory.GetHighestAckRange().First > h.lastAck.LargestAcked {
 		h.ackQueued = true
@@ -143,30 +157,23 @@ func (h *receivedPacketHandler) GetAckFrame() *wire.AckFrame {
 
 	ackRanges := h.packetHistory.GetAckRanges()
 
-	var o map[protocol.PacketNumber]time.Time
-	o = make(map[protocol.PacketNumber]time.Time)
-	for number,stamp :=range h.lastowdtimestamp{
-		o[number] = stamp
-	}
-	for number,stamp :=range h.owdtimestamp{
-		o[number] = stamp
-	}
 	ack := &wire.AckFrame{
 		LargestAcked:       h.largestObserved,
 		LowestAcked:        ackRanges[len(ackRanges)-1].First,
-		Owdtimestamp:       o,
+		Timestamps:         h.timestamps.Drain(),
 		PacketReceivedTime: h.largestObservedReceivedTime,
 	}
-  //h.owdtimestamp = []time.Time{}
 	if len(ackRanges) > 1 {
 		ack.AckRanges = ackRanges
 	}
+	if h.ecnCounts != (wire.ECNCounts{}) {
+		ecnCounts := h.ecnCounts
+		ack.ECN = &ecnCounts
+	}
 
 	h.lastAck = ack
 	h.ackAlarm = time.Time{}
 	h.ackQueued = false
-  h.lastowdtimestamp = h.owdtimestamp
-	h.owdtimestamp = make(map[protocol.PacketNumber]time.Time)
 	h.packetsReceivedSinceLastAck = 0
 	h.retransmittablePacketsReceivedSinceLastAck = 0
 
@@ -21,21 +21,21 @@ var _ = Describe("receivedPacketHandler", func() {
 
 	Context("accepting packets", func() {
 		It("handles a packet that arrives late", func() {
-			err := handler.ReceivedPacket(protocol.PacketNumber(1), true)
+			err := handler.ReceivedPacket(protocol.PacketNumber(1), protocol.ECNNon, true)
 			Expect(err).ToNot(HaveOccurred())
-			err = handler.ReceivedPacket(protocol.PacketNumber(3), true)
+			err = handler.ReceivedPacket(protocol.PacketNumber(3), protocol.ECNNon, true)
 			Expect(err).ToNot(HaveOccurred())
-			err = handler.ReceivedPacket(protocol.PacketNumber(2), true)
+			err = handler.ReceivedPacket(protocol.PacketNumber(2), protocol.ECNNon, true)
 			Expect(err).ToNot(HaveOccurred())
 		})
 
 		It("rejects packets with packet number 0", func() {
-			err := handler.ReceivedPacket(protocol.PacketNumber(0), true)
+			err := handler.ReceivedPacket(protocol.PacketNumber(0), protocol.ECNNon, true)
 			Expect(err).To(MatchError(errInvalidPacketNumber))
 		})
 
 		It("saves the time when each packet arrived", func() {
-			err := handler.ReceivedPacket(protocol.PacketNumber(3), true)
+			err := handler.ReceivedPacket(protocol.PacketNumber(3), protocol.ECNNon, true)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(handler.largestObservedReceivedTime).To(BeTemporally("~", time.Now(), 10*time.Millisecond))
 		})
@@ -43,7 +43,7 @@ var _ = Describe("receivedPacketHandler", func() {
 		It("updates the largestObserved and the largestObservedReceivedTime", func() {
 			handler.largestObserved = 3
 			handler.largestObservedReceivedTime = time.Now().Add(-1 * time.Second)
-			err := handler.ReceivedPacket(5, true)
+			err := handler.ReceivedPacket(5, protocol.ECNNon, true)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(handler.largestObserved).To(Equal(protocol.PacketNumber(5)))
 			Expect(handler.largestObservedReceivedTime).To(BeTemporally("~", time.Now(), 10*time.Millisecond))
@@ -53,7 +53,7 @@ var _ = Describe("receivedPacketHandler", func() {
 			timestamp := time.Now().Add(-1 * time.Second)
 			handler.largestObserved = 5
 			handler.largestObservedReceivedTime = timestamp
-			err := handler.ReceivedPacket(4, true)
+			err := handler.ReceivedPacket(4, protocol.ECNNon, true)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(handler.largestObserved).To(Equal(protocol.PacketNumber(5)))
 			Expect(handler.largestObservedReceivedTime).To(Equal(timestamp))
@@ -62,7 +62,7 @@ var _ = Describe("receivedPacketHandler", func() {
 		It("passes on errors from receivedPacketHistory", func() {
 			var err error
 			for i := protocol.PacketNumber(0); i < 5*protocol.MaxTrackedReceivedAckRanges; i++ {
-				err = handler.ReceivedPacket(2*i+1, true)
+				err = handler.ReceivedPacket(2*i+1, protocol.ECNNon, true)
 				// this will eventually return an error
 				// details about when exactly the receivedPacketHistory errors are tested there
 				if err != nil {
@@ -77,7 +77,7 @@ var _ = Describe("receivedPacketHandler", func() {
 		Context("queueing ACKs", func() {
 			receiveAndAck10Packets := func() {
 				for i := 1; i <= 10; i++ {
-					err := handler.ReceivedPacket(protocol.PacketNumber(i), true)
+					err := handler.ReceivedPacket(protocol.PacketNumber(i), protocol.ECNNon, true)
 					Expect(err).ToNot(HaveOccurred())
 				}
 				Expect(handler.GetAckFrame()).ToNot(BeNil())
@@ -85,7 +85,7 @@ var _ = Describe("receivedPacketHandler", func() {
 			}
 
 			It("always queues an ACK for the first packet", func() {
-				err := handler.ReceivedPacket(1, false)
+				err := handler.ReceivedPacket(1, protocol.ECNNon, false)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(handler.ackQueued).To(BeTrue())
 				Expect(handler.GetAlarmTimeout()).To(BeZero())
@@ -94,11 +94,11 @@ var _ = Describe("receivedPacketHandler", func() {
 			It("only queues one ACK for many non-retransmittable packets", func() {
 				receiveAndAck10Packets()
 				for i := 11; i < 10+protocol.MaxPacketsReceivedBeforeAckSend; i++ {
-					err := handler.ReceivedPacket(protocol.PacketNumber(i), false)
+					err := handler.ReceivedPacket(protocol.PacketNumber(i), protocol.ECNNon, false)
 					Expect(err).ToNot(HaveOccurred())
 					Expect(handler.ackQueued).To(BeFalse())
 				}
-				err := handler.ReceivedPacket(10+protocol.MaxPacketsReceivedBeforeAckSend, false)
+				err := handler.ReceivedPacket(10+protocol.MaxPacketsReceivedBeforeAckSend, protocol.ECNNon, false)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(handler.ackQueued).To(BeTrue())
 				Expect(handler.GetAlarmTimeout()).To(BeZero())
@@ -108,7 +108,7 @@ var _ = Describe("receivedPacketHandler", func() {
 				receiveAndAck10Packets()
 				handler.version = protocol.Version39
 				for i := 11; i < 10+10*protocol.MaxPacketsReceivedBeforeAckSend; i++ {
-					err := handler.ReceivedPacket(protocol.PacketNumber(i), false)
+					err := handler.ReceivedPacket(protocol.PacketNumber(i), protocol.ECNNon, false)
 					Expect(err).ToNot(HaveOccurred())
 					Expect(handler.ackQueued).To(BeFalse())
 				}
@@ -116,11 +116,11 @@ var _ = Describe("receivedPacketHandler", func() {
 
 			It("queues an ACK for every second retransmittable packet, if they are arriving fast", func() {
 				receiveAndAck10Packets()
-				err := handler.ReceivedPacket(11, true)
+				err := handler.ReceivedPacket(11, protocol.ECNNon, true)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(handler.ackQueued).To(BeFalse())
 				Expect(handler.GetAlarmTimeout()).NotTo(BeZero())
-				err = handler.ReceivedPacket(12, true)
+				err = handler.ReceivedPacket(12, protocol.ECNNon, true)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(handler.ackQueued).To(BeTrue())
 				Expect(handler.GetAlarmTimeout()).To(BeZero())
@@ -128,28 +128,39 @@ var _ = Describe("receivedPacketHandler", func() {
 
 			It("only sets the timer when receiving a retransmittable packets", func() {
 				receiveAndAck10Packets()
-				err := handler.ReceivedPacket(11, false)
+				err := handler.ReceivedPacket(11, protocol.ECNNon, false)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(handler.ackQueued).To(BeFalse())
 				Expect(handler.ackAlarm).To(BeZero())
-				err = handler.ReceivedPacket(12, true)
+				err = handler.ReceivedPacket(12, protocol.ECNNon, true)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(handler.ackQueued).To(BeFalse())
 				Expect(handler.ackAlarm).ToNot(BeZero())
 				Expect(handler.GetAlarmTimeout()).NotTo(BeZero())
 			})
 
+			It("queues an ACK for a CE marked packet", func() {
+				receiveAndAck10Packets()
+				err := handler.ReceivedPacket(11, protocol.ECT0, true)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(handler.ackQueued).To(BeFalse())
+				err = handler.ReceivedPacket(12, protocol.ECNCE, false)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(handler.ackQueued).To(BeTrue())
+				Expect(handler.GetAlarmTimeout()).To(BeZero())
+			})
+
 			It("queues an ACK if it was reported missing before", func() {
 				receiveAndAck10Packets()
-				err := handler.ReceivedPacket(11, true)
+				err := handler.ReceivedPacket(11, protocol.ECNNon, true)
 				Expect(err).ToNot(HaveOccurred())
-				err = handler.ReceivedPacket(13, true)
+				err = handler.ReceivedPacket(13, protocol.ECNNon, true)
 				Expect(err).ToNot(HaveOccurred())
 				ack := handler.GetAckFrame() // ACK: 1 and 3, missing: 2
 				Expect(ack).ToNot(BeNil())
 				Expect(ack.HasMissingRanges()).To(BeTrue())
 				Expect(handler.ackQueued).To(BeFalse())
-				err = handler.ReceivedPacket(12, false)
+				err = handler.ReceivedPacket(12, protocol.ECNNon, false)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(handler.ackQueued).To(BeTrue())
 			})
@@ -157,10 +168,10 @@ var _ = Describe("receivedPacketHandler", func() {
 			It("queues an ACK if it creates a new missing range", func() {
 				receiveAndAck10Packets()
 				for i := 11; i < 16; i++ {
-					err := handler.ReceivedPacket(protocol.PacketNumber(i), true)
+					err := handler.ReceivedPacket(protocol.PacketNumber(i), protocol.ECNNon, true)
 					Expect(err).ToNot(HaveOccurred())
 				}
-				err := handler.ReceivedPacket(20, true) // we now know that packets 16 to 19 are missing
+				err := handler.ReceivedPacket(20, protocol.ECNNon, true) // we now know that packets 16 to 19 are missing
 				Expect(err).ToNot(HaveOccurred())
 				Expect(handler.ackQueued).To(BeTrue())
 				ack := handler.GetAckFrame()
@@ -175,9 +186,9 @@ var _ = Describe("receivedPacketHandler", func() {
 			})
 
 			It("generates a simple ACK frame", func() {
-				err := handler.ReceivedPacket(1, true)
+				err := handler.ReceivedPacket(1, protocol.ECNNon, true)
 				Expect(err).ToNot(HaveOccurred())
-				err = handler.ReceivedPacket(2, true)
+				err = handler.ReceivedPacket(2, protocol.ECNNon, true)
 				Expect(err).ToNot(HaveOccurred())
 				ack := handler.GetAckFrame()
 				Expect(ack).ToNot(BeNil())
@@ -187,12 +198,12 @@ var _ = Describe("receivedPacketHandler", func() {
 			})
 
 			It("saves the last sent ACK", func() {
-				err := handler.ReceivedPacket(1, true)
+				err := handler.ReceivedPacket(1, protocol.ECNNon, true)
 				Expect(err).ToNot(HaveOccurred())
 				ack := handler.GetAckFrame()
 				Expect(ack).ToNot(BeNil())
 				Expect(handler.lastAck).To(Equal(ack))
-				err = handler.ReceivedPacket(2, true)
+				err = handler.ReceivedPacket(2, protocol.ECNNon, true)
 				Expect(err).ToNot(HaveOccurred())
 				handler.ackQueued = true
 				ack = handler.GetAckFrame()
@@ -201,9 +212,9 @@ var _ = Describe("receivedPacketHandler", func() {
 			})
 
 			It("generates an ACK frame with missing packets", func() {
-				err := handler.ReceivedPacket(1, true)
+				err := handler.ReceivedPacket(1, protocol.ECNNon, true)
 				Expect(err).ToNot(HaveOccurred())
-				err = handler.ReceivedPacket(4, true)
+				err = handler.ReceivedPacket(4, protocol.ECNNon, true)
 				Expect(err).ToNot(HaveOccurred())
 				ack := handler.GetAckFrame()
 				Expect(ack).ToNot(BeNil())
@@ -214,17 +225,80 @@ var _ = Describe("receivedPacketHandler", func() {
 				Expect(ack.AckRanges[1]).To(Equal(wire.AckRange{First: 1, Last: 1}))
 			})
 
+			It("reports the ECN counts", func() {
+				for i, ecn := range []protocol.ECN{protocol.ECT0, protocol.ECNNon, protocol.ECT0, protocol.ECNCE, protocol.ECT1} {
+					err := handler.ReceivedPacket(protocol.PacketNumber(i+1), ecn, true)
+					Expect(err).ToNot(HaveOccurred())
+				}
+				ack := handler.GetAckFrame()
+				Expect(ack).ToNot(BeNil())
+				Expect(ack.ECN).To(Equal(&wire.ECNCounts{ECT0: 2, ECT1: 1, ECNCE: 1}))
+				// the counts are cumulative
+				err := handler.ReceivedPacket(6, protocol.ECNCE, true)
+				Expect(err).ToNot(HaveOccurred())
+				ack = handler.GetAckFrame()
+				Expect(ack).ToNot(BeNil())
+				Expect(ack.ECN).To(Equal(&wire.ECNCounts{ECT0: 2, ECT1: 1, ECNCE: 2}))
+			})
+
+			It("doesn't report ECN counts if no packet was ECN marked", func() {
+				err := handler.ReceivedPacket(1, protocol.ECNNon, true)
+				Expect(err).ToNot(HaveOccurred())
+				ack := handler.GetAckFrame()
+				Expect(ack).ToNot(BeNil())
+				Expect(ack.ECN).To(BeNil())
+			})
+
+			It("reports the receive timestamps of the retransmittable packets", func() {
+				err := handler.ReceivedPacket(1, protocol.ECNNon, true)
+				Expect(err).ToNot(HaveOccurred())
+				err = handler.ReceivedPacket(2, protocol.ECNNon, false)
+				Expect(err).ToNot(HaveOccurred())
+				ack := handler.GetAckFrame()
+				Expect(ack).ToNot(BeNil())
+				Expect(ack.Timestamps).To(HaveLen(1))
+				Expect(ack.Timestamps[0].PacketNumber).To(Equal(protocol.PacketNumber(1)))
+				Expect(ack.Timestamps[0].ReceiveTime).To(BeTemporally("~", time.Now(), 10*time.Millisecond))
+				// the timestamp is reported again, in case the first ACK is lost
+				err = handler.ReceivedPacket(3, protocol.ECNNon, true)
+				Expect(err).ToNot(HaveOccurred())
+				handler.ackQueued = true
+				ack = handler.GetAckFrame()
+				Expect(ack).ToNot(BeNil())
+				Expect(ack.Timestamps).To(HaveLen(2))
+				Expect(ack.Timestamps[0].PacketNumber).To(Equal(protocol.PacketNumber(1)))
+				Expect(ack.Timestamps[1].PacketNumber).To(Equal(protocol.PacketNumber(3)))
+				handler.ackQueued = true
+				ack = handler.GetAckFrame()
+				Expect(ack).ToNot(BeNil())
+				Expect(ack.Timestamps).To(HaveLen(1))
+				Expect(ack.Timestamps[0].PacketNumber).To(Equal(protocol.PacketNumber(3)))
+			})
+
+			It("deletes the timestamps when a lower limit is set", func() {
+				for i := 1; i <= 6; i++ {
+					err := handler.ReceivedPacket(protocol.PacketNumber(i), protocol.ECNNon, true)
+					Expect(err).ToNot(HaveOccurred())
+				}
+				handler.SetLowerLimit(4)
+				ack := handler.GetAckFrame()
+				Expect(ack).ToNot(BeNil())
+				Expect(ack.Timestamps).To(HaveLen(2))
+				Expect(ack.Timestamps[0].PacketNumber).To(Equal(protocol.PacketNumber(5)))
+				Expect(ack.Timestamps[1].PacketNumber).To(Equal(protocol.PacketNumber(6)))
+			})
+
 			It("accepts packets below the lower limit", func() {
 				handler.SetLowerLimit(5)
-				err := handler.ReceivedPacket(2, true)
+				err := handler.ReceivedPacket(2, protocol.ECNNon, true)
 				Expect(err).ToNot(HaveOccurred())
 			})
 
 			It("doesn't add delayed packets to the packetHistory", func() {
 				handler.SetLowerLimit(6)
-				err := handler.ReceivedPacket(4, true)
+				err := handler.ReceivedPacket(4, protocol.ECNNon, true)
 				Expect(err).ToNot(HaveOccurred())
-				err = handler.ReceivedPacket(10, true)
+				err = handler.ReceivedPacket(10, protocol.ECNNon, true)
 				Expect(err).ToNot(HaveOccurred())
 				ack := handler.GetAckFrame()
 				Expect(ack).ToNot(BeNil())
@@ -234,7 +308,7 @@ var _ = Describe("receivedPacketHandler", func() {
 
 			It("deletes packets from the packetHistory when a lower limit is set", func() {
 				for i := 1; i <= 12; i++ {
-					err := handler.ReceivedPacket(protocol.PacketNumber(i), true)
+					err := handler.ReceivedPacket(protocol.PacketNumber(i), protocol.ECNNon, true)
 					Expect(err).ToNot(HaveOccurred())
 				}
 				handler.SetLowerLimit(6)
@@ -249,7 +323,7 @@ var _ = Describe("receivedPacketHandler", func() {
 			// TODO: remove this test when dropping support for STOP_WAITINGs
 			It("handles a lower limit of 0", func() {
 				handler.SetLowerLimit(0)
-				err := handler.ReceivedPacket(1337, true)
+				err := handler.ReceivedPacket(1337, protocol.ECNNon, true)
 				Expect(err).ToNot(HaveOccurred())
 				ack := handler.GetAckFrame()
 				Expect(ack).ToNot(BeNil())
@@ -257,7 +331,7 @@ var _ = Describe("receivedPacketHandler", func() {
 			})
 
 			It("resets all counters needed for the ACK queueing decision when sending an ACK", func() {
-				err := handler.ReceivedPacket(1, true)
+				err := handler.ReceivedPacket(1, protocol.ECNNon, true)
 				Expect(err).ToNot(HaveOccurred())
 				handler.ackAlarm = time.Now().Add(-time.Minute)
 				Expect(handler.GetAckFrame()).ToNot(BeNil())
@@ -268,7 +342,7 @@ var _ = Describe("receivedPacketHandler", func() {
 			})
 
 			It("doesn't generate an ACK when none is queued and the timer is not set", func() {
-				err := handler.ReceivedPacket(1, true)
+				err := handler.ReceivedPacket(1, protocol.ECNNon, true)
 				Expect(err).ToNot(HaveOccurred())
 				handler.ackQueued = false
 				handler.ackAlarm = time.Time{}
@@ -276,7 +350,7 @@ var _ = Describe("receivedPacketHandler", func() {
 			})
 
 			It("doesn't generate an ACK when none is queued and the timer has not yet expired", func() {
-				err := handler.ReceivedPacket(1, true)
+				err := handler.ReceivedPacket(1, protocol.ECNNon, true)
 				Expect(err).ToNot(HaveOccurred())
 				handler.ackQueued = false
 				handler.ackAlarm = time.Now().Add(time.Minute)
@@ -284,7 +358,7 @@ var _ = Describe("receivedPacketHandler", func() {
 			})
 
 			It("generates an ACK when the timer has expired", func() {
-				err := handler.ReceivedPacket(1, true)
+				err := handler.ReceivedPacket(1, protocol.ECNNon, true)
 				Expect(err).ToNot(HaveOccurred())
 				handler.ackQueued = false
 				handler.ackAlarm = time.Now().Add(-time.Minute)
@@ -294,9 +368,9 @@ var _ = Describe("receivedPacketHandler", func() {
 
 		Context("ClosePath generation", func() {
 			It("generates a simple ClosePath frame", func() {
-				err := handler.ReceivedPacket(1, true)
+				err := handler.ReceivedPacket(1, protocol.ECNNon, true)
 				Expect(err).ToNot(HaveOccurred())
-				err = handler.ReceivedPacket(2, true)
+				err = handler.ReceivedPacket(2, protocol.ECNNon, true)
 				Expect(err).ToNot(HaveOccurred())
 				frame := handler.GetClosePathFrame()
 				Expect(frame).ToNot(BeNil())
@@ -306,9 +380,9 @@ var _ = Describe("receivedPacketHandler", func() {
 			})
 
 			It("generates an ClosePath frame with missing packets", func() {
-				err := handler.ReceivedPacket(1, true)
+				err := handler.ReceivedPacket(1, protocol.ECNNon, true)
 				Expect(err).ToNot(HaveOccurred())
-				err = handler.ReceivedPacket(4, true)
+				err = handler.ReceivedPacket(4, protocol.ECNNon, true)
 				Expect(err).ToNot(HaveOccurred())
 				frame := handler.GetClosePathFrame()
 				Expect(frame).ToNot(BeNil())
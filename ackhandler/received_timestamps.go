@@ -0,0 +1,74 @@
+package ackhandler
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+)
+
+// receivedTimestamps is a ring of the receive times of the last retransmittable packets, reported in the ACK frames.
+// Every timestamp is reported in two ACK frames, in case the first one is lost, unless the peer stops waiting
+// for the packet before. If the ring is full, the oldest timestamp is dropped.
+type receivedTimestamps struct {
+	entries    [protocol.MaxTrackedReceivedTimestamps]receivedTimestamp
+	start, len int
+
+	// The timestamps of the last ACK frame
+	ackTimestamps [protocol.MaxTrackedReceivedTimestamps]wire.PacketTimestamp
+}
+
+type receivedTimestamp struct {
+	wire.PacketTimestamp
+	numReported int
+}
+
+// Add adds the receive time of a packet
+func (t *receivedTimestamps) Add(p protocol.PacketNumber, rcvTime time.Time) {
+	if t.len == len(t.entries) {
+		t.start = (t.start + 1) % len(t.entries)
+		t.len--
+	}
+	t.entries[(t.start+t.len)%len(t.entries)] = receivedTimestamp{
+		PacketTimestamp: wire.PacketTimestamp{PacketNumber: p, ReceiveTime: rcvTime},
+	}
+	t.len++
+}
+
+// Drain returns the timestamps to report in an ACK frame, nil if there are none.
+// The returned slice is reused by the next call.
+func (t *receivedTimestamps) Drain() []wire.PacketTimestamp {
+	if t.len == 0 {
+		return nil
+	}
+	timestamps := t.ackTimestamps[:0]
+	for i := 0; i < t.len; i++ {
+		e := &t.entries[(t.start+i)%len(t.entries)]
+		timestamps = append(timestamps, e.PacketTimestamp)
+		e.numReported++
+	}
+	// The oldest timestamps come first, they were reported at least as often as the newer ones
+	for t.len > 0 && t.entries[t.start].numReported >= 2 {
+		t.start = (t.start + 1) % len(t.entries)
+		t.len--
+	}
+	return timestamps
+}
+
+// DeleteUpTo deletes the timestamps of the packets up to p, since the peer stopped waiting for their ACK
+func (t *receivedTimestamps) DeleteUpTo(p protocol.PacketNumber) {
+	n := 0
+	for i := 0; i < t.len; i++ {
+		e := t.entries[(t.start+i)%len(t.entries)]
+		if e.PacketNumber > p {
+			t.entries[(t.start+n)%len(t.entries)] = e
+			n++
+		}
+	}
+	t.len = n
+}
+
+// Len returns the number of timestamps that will be reported
+func (t *receivedTimestamps) Len() int {
+	return t.len
+}
@@ -0,0 +1,91 @@
+package ackhandler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Received timestamps", func() {
+	var timestamps *receivedTimestamps
+
+	packetNumbers := func(ts []wire.PacketTimestamp) []protocol.PacketNumber {
+		var pns []protocol.PacketNumber
+		for _, t := range ts {
+			pns = append(pns, t.PacketNumber)
+		}
+		return pns
+	}
+
+	BeforeEach(func() {
+		timestamps = &receivedTimestamps{}
+	})
+
+	It("has no timestamps to report at first", func() {
+		Expect(timestamps.Drain()).To(BeNil())
+	})
+
+	It("reports every timestamp in two ACK frames", func() {
+		now := time.Now()
+		timestamps.Add(1, now)
+		timestamps.Add(3, now.Add(time.Millisecond))
+		timestamps.Add(2, now.Add(2*time.Millisecond))
+		ts := timestamps.Drain()
+		Expect(ts).To(Equal([]wire.PacketTimestamp{
+			{PacketNumber: 1, ReceiveTime: now},
+			{PacketNumber: 3, ReceiveTime: now.Add(time.Millisecond)},
+			{PacketNumber: 2, ReceiveTime: now.Add(2 * time.Millisecond)},
+		}))
+		timestamps.Add(4, now)
+		Expect(packetNumbers(timestamps.Drain())).To(Equal([]protocol.PacketNumber{1, 3, 2, 4}))
+		Expect(packetNumbers(timestamps.Drain())).To(Equal([]protocol.PacketNumber{4}))
+		Expect(timestamps.Drain()).To(BeNil())
+	})
+
+	It("drops the oldest timestamps when it's full", func() {
+		for i := 1; i <= protocol.MaxTrackedReceivedTimestamps+5; i++ {
+			timestamps.Add(protocol.PacketNumber(i), time.Now())
+		}
+		ts := timestamps.Drain()
+		Expect(ts).To(HaveLen(protocol.MaxTrackedReceivedTimestamps))
+		Expect(ts[0].PacketNumber).To(Equal(protocol.PacketNumber(6)))
+		Expect(ts[len(ts)-1].PacketNumber).To(Equal(protocol.PacketNumber(protocol.MaxTrackedReceivedTimestamps + 5)))
+	})
+
+	It("deletes the timestamps of the packets the peer doesn't wait for anymore", func() {
+		for _, p := range []protocol.PacketNumber{5, 2, 7, 3, 8} {
+			timestamps.Add(p, time.Now())
+		}
+		timestamps.DeleteUpTo(5)
+		Expect(timestamps.Len()).To(Equal(2))
+		Expect(packetNumbers(timestamps.Drain())).To(Equal([]protocol.PacketNumber{7, 8}))
+	})
+
+	It("wraps around", func() {
+		for i := 1; i <= 3*protocol.MaxTrackedReceivedTimestamps; i++ {
+			timestamps.Add(protocol.PacketNumber(i), time.Now())
+			timestamps.Drain()
+		}
+		Expect(packetNumbers(timestamps.Drain())).To(Equal([]protocol.PacketNumber{3 * protocol.MaxTrackedReceivedTimestamps}))
+		Expect(timestamps.Len()).To(BeZero())
+	})
+
+	It("doesn't allocate", func() {
+		now := time.Now()
+		var p protocol.PacketNumber
+		allocs := testing.AllocsPerRun(100, func() {
+			for i := 0; i < 10; i++ {
+				p++
+				timestamps.Add(p, now)
+			}
+			timestamps.DeleteUpTo(p - 5)
+			timestamps.Drain()
+		})
+		Expect(allocs).To(BeZero())
+	})
+})
@@ -2,13 +2,14 @@ package ackhandler
 
 import (
 	"errors"
-	"fmt"
+	"sort"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/congestion"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/logging"
 	"github.com/lucas-clemente/quic-go/qerr"
 )
 
@@ -58,15 +59,25 @@ type sentPacketHandler struct {
 	packetHistory      *PacketList
 	stopWaitingManager stopWaitingManager
 
+	// Reused by every ACK, to avoid allocating
+	ackedPackets []*PacketElement
+	receiveTimes []time.Time
+	lostPackets  []*PacketElement
+
 	retransmissionQueue []*Packet
 
 	bytesInFlight protocol.ByteCount
 
 	congestion congestion.SendAlgorithm
 	rttStats   *congestion.RTTStats
+	pacer      *congestion.Pacer
 
 	onRTOCallback func(time.Time) bool
 
+	// The tracer of the session, nil if not tracing
+	tracer logging.Tracer
+	pathID protocol.PathID
+
 	// The number of times an RTO has been sent without receiving an ack.
 	rtoCount uint32
 
@@ -85,10 +96,21 @@ type sentPacketHandler struct {
 	packets         uint64
 	retransmissions uint64
 	losses          uint64
+	bytesSent       protocol.ByteCount
+
+	// Delivery rate estimation, as in draft-cheng-iccrg-delivery-rate-estimation
+	delivered     protocol.ByteCount
+	deliveredTime time.Time
+	firstSentTime time.Time
+	// Bytes delivered when the application limited phase ends, 0 if not application limited
+	appLimitedUntil protocol.ByteCount
+
+	ecn ecnTracker
 }
 
 // NewSentPacketHandler creates a new sentPacketHandler
-func NewSentPacketHandler(rttStats *congestion.RTTStats, cong congestion.SendAlgorithm, onRTOCallback func(time.Time) bool) SentPacketHandler {
+// The tracer may be nil, otherwise the events are traced for pathID
+func NewSentPacketHandler(pathID protocol.PathID, rttStats *congestion.RTTStats, cong congestion.SendAlgorithm, onRTOCallback func(time.Time) bool, tracer logging.Tracer) SentPacketHandler {
 	var congestionControl congestion.SendAlgorithm
 
 	if cong != nil {
@@ -108,7 +130,11 @@ func NewSentPacketHandler(rttStats *congestion.RTTStats, cong congestion.SendAlg
 		stopWaitingManager: stopWaitingManager{},
 		rttStats:           rttStats,
 		congestion:         congestionControl,
+		pacer:              congestion.NewPacer(congestionControl, rttStats),
 		onRTOCallback:      onRTOCallback,
+		tracer:             tracer,
+		pathID:             pathID,
+		ecn:                ecnTracker{pathID: pathID},
 	}
 }
 
@@ -116,6 +142,40 @@ func (h *sentPacketHandler) GetStatistics() (uint64, uint64, uint64) {
 	return h.packets, h.retransmissions, h.losses
 }
 
+func (h *sentPacketHandler) GetBytesSent() protocol.ByteCount {
+	return h.bytesSent
+}
+
+func (h *sentPacketHandler) GetBytesInFlight() protocol.ByteCount {
+	return h.bytesInFlight
+}
+
+func (h *sentPacketHandler) GetCongestionWindow() protocol.ByteCount {
+	return h.congestion.GetCongestionWindow()
+}
+
+func (h *sentPacketHandler) GetCongestionState() CongestionState {
+	state := CongestionState{
+		CongestionWindow: h.congestion.GetCongestionWindow(),
+		BytesInFlight:    h.bytesInFlight,
+		TLPCount:         h.tlpCount,
+		RTOCount:         h.rtoCount,
+		ECN:              h.ecn.State(),
+		ECNCEMarks:       h.ecn.CEMarks(),
+	}
+	if cong, ok := h.congestion.(congestion.SendAlgorithmWithDebugInfo); ok {
+		state.SlowStartThreshold = protocol.ByteCount(cong.SlowstartThreshold()) * protocol.DefaultTCPMSS
+		state.InRecovery = cong.InRecovery()
+		state.InSlowStart = cong.InSlowStart()
+		state.BandwidthEstimate = cong.BandwidthEstimate()
+	}
+	if cong, ok := h.congestion.(*congestion.OliaSender); ok {
+		olia := cong.Olia.State()
+		state.Olia = &olia
+	}
+	return state
+}
+
 func (h *sentPacketHandler) largestInOrderAcked() protocol.PacketNumber {
 	if f := h.packetHistory.Front(); f != nil {
 		return f.Value.PacketNumber - 1
@@ -149,6 +209,7 @@ func (h *sentPacketHandler) SentPacket(packet *Packet) error {
 
 	// Update some statistics
 	h.packets++
+	h.bytesSent += packet.Length
 
 	// XXX RTO and TLP are recomputed based on the possible last sent retransmission. Is it ok like this?
 	h.lastSentTime = now
@@ -157,7 +218,20 @@ func (h *sentPacketHandler) SentPacket(packet *Packet) error {
 	isRetransmittable := len(packet.Frames) != 0
 
 	if isRetransmittable {
+		// ACK-only packets are not paced, they don't use the pacing budget either
+		h.pacer.OnPacketSent(now, packet.Length)
 		packet.SendTime = now
+		packet.ECN = h.ecn.Mode()
+		h.ecn.SentPacket(packet.ECN)
+		if h.bytesInFlight == 0 {
+			// Start a new sampling interval
+			h.firstSentTime = now
+			h.deliveredTime = now
+		}
+		packet.Delivered = h.delivered
+		packet.DeliveredTime = h.deliveredTime
+		packet.FirstSentTime = h.firstSentTime
+		packet.IsAppLimited = h.appLimitedUntil != 0
 		h.bytesInFlight += packet.Length
 		h.packetHistory.PushBack(*packet)
 		h.numNonRetransmittablePackets = 0
@@ -205,21 +279,48 @@ func (h *sentPacketHandler) ReceivedAck(ackFrame *wire.AckFrame, withPacketNumbe
 		h.congestion.MaybeExitSlowStart()
 	}
 
-	ackedPackets, OWD,err := h.determineNewlyAckedPackets(ackFrame)
-	if err != nil {
-		return err
-	}
+	ackedPackets, receiveTimes := h.determineNewlyAckedPackets(ackFrame)
 
+	var rttSample time.Duration
+	if rttUpdated {
+		rttSample = h.rttStats.LatestRTT()
+	}
 
+	var lastAcked Packet
+	var ackedBytes protocol.ByteCount
 	if len(ackedPackets) > 0 {
-		for m, p := range ackedPackets {
-			h.onPacketAcked(p)
-			h.congestion.OnPacketAcked(p.Value.PacketNumber, p.Value.Length, h.bytesInFlight,OWD[m],count,h.losses)
+		for i, p := range ackedPackets {
+			lastAcked = p.Value
+			ackedBytes += p.Value.Length
+			h.onPacketAcked(p, rcvTime)
+			h.congestion.OnPacketAcked(&congestion.AckEvent{
+				PacketNumber:  p.Value.PacketNumber,
+				Bytes:         p.Value.Length,
+				BytesInFlight: h.bytesInFlight,
+				SendTime:      p.Value.SendTime,
+				ReceiveTime:   receiveTimes[i],
+				OWD:           oneWayDelay(p.Value.SendTime, receiveTimes[i]),
+				RTT:           rttSample,
+				SBDInterval:   count,
+			})
 		}
 	}
 
+	if h.ecn.HandleAck(ackedPackets, ackFrame.ECN) {
+		h.onCongestionExperienced(ackFrame.LargestAcked, lastAcked.SendTime)
+	}
+
+	if h.tracer != nil && len(ackedPackets) > 0 {
+		h.tracer.ProcessedAck(h.pathID, ackFrame.LargestAcked, owdSamples(ackedPackets, receiveTimes))
+	}
+
 	h.detectLostPackets()
+
+	if cong, ok := h.congestion.(congestion.SendAlgorithmWithRateSample); ok && ackedBytes > 0 {
+		cong.OnRateSample(h.getRateSample(&lastAcked, ackedBytes, rcvTime))
+	}
 	h.updateLossDetectionAlarm()
+	h.traceMetrics()
 
 	h.garbageCollectSkippedPackets()
 	h.stopWaitingManager.ReceivedAck(ackFrame)
@@ -246,15 +347,18 @@ func (h *sentPacketHandler) ReceivedClosePath(f *wire.ClosePathFrame, withPacket
 
 	// No need for RTT estimation
 
-	ackedPackets, err := h.determineNewlyAckedPacketsClosePath(f)
-	if err != nil {
-		return err
-	}
+	ackedPackets := h.determineNewlyAckedPacketsClosePath(f)
 
 	if len(ackedPackets) > 0 {
 		for _, p := range ackedPackets {
-			h.onPacketAcked(p)
-			h.congestion.OnPacketAcked(p.Value.PacketNumber, p.Value.Length, h.bytesInFlight,time.Duration(0),0,h.losses)
+			h.onPacketAcked(p, rcvTime)
+			h.congestion.OnPacketAcked(&congestion.AckEvent{
+				PacketNumber:  p.Value.PacketNumber,
+				Bytes:         p.Value.Length,
+				BytesInFlight: h.bytesInFlight,
+				SendTime:      p.Value.SendTime,
+				OWD:           congestion.UnknownOWD,
+			})
 		}
 	}
 
@@ -266,129 +370,93 @@ func (h *sentPacketHandler) ReceivedClosePath(f *wire.ClosePathFrame, withPacket
 	return nil
 }
 
-func (h *sentPacketHandler) determineNewlyAckedPackets(ackFrame *wire.AckFrame) ([]*PacketElement, []time.Duration,error) {
-	var ackedPackets []*PacketElement
-	var owd []time.Duration
-
-	ackRangeIndex := 0
-
-	for el := h.packetHistory.Front(); el != nil; el = el.Next() {
-		packet := el.Value
-		packetNumber := packet.PacketNumber
-
-		// Ignore packets below the LowestAcked
-		if packetNumber < ackFrame.LowestAcked {
-			continue
+// determineNewlyAckedPackets returns the packets acked by the frame, and the time the peer received each of them.
+// The returned slices are reused by the next call.
+func (h *sentPacketHandler) determineNewlyAckedPackets(ackFrame *wire.AckFrame) ([]*PacketElement, []time.Time) {
+	h.ackedPackets = h.ackedPackets[:0]
+	h.receiveTimes = h.receiveTimes[:0]
+	h.appendAckedPackets(ackFrame.LowestAcked, ackFrame.LargestAcked, ackFrame.AckRanges)
+	for range h.ackedPackets {
+		h.receiveTimes = append(h.receiveTimes, time.Time{})
+	}
+	// The acked packets are sorted by packet number
+	for _, t := range ackFrame.Timestamps {
+		i := sort.Search(len(h.ackedPackets), func(i int) bool { return h.ackedPackets[i].Value.PacketNumber >= t.PacketNumber })
+		if i < len(h.ackedPackets) && h.ackedPackets[i].Value.PacketNumber == t.PacketNumber {
+			h.receiveTimes[i] = t.ReceiveTime
 		}
-		// Break after LargestAcked is reached
-		if packetNumber > ackFrame.LargestAcked {
-			break
-		}
-
-		if ackFrame.HasMissingRanges() {
-			ackRange := ackFrame.AckRanges[len(ackFrame.AckRanges)-1-ackRangeIndex]
-
-			for packetNumber > ackRange.Last && ackRangeIndex < len(ackFrame.AckRanges)-1 {
-				ackRangeIndex++
-				ackRange = ackFrame.AckRanges[len(ackFrame.AckRanges)-1-ackRangeIndex]
-			}
-
-			if packetNumber >= ackRange.First { // packet i contained in ACK range
-				if packetNumber > ackRange.Last {
-					return nil, nil,fmt.Errorf("BUG: ackhandler would have acked wrong packet 0x%x, while evaluating range 0x%x -> 0x%x", packetNumber, ackRange.First, ackRange.Last)
-				}
-
-				ackedPackets = append(ackedPackets, el)
-				//******
-				if ackFrame.Owdtimestamp[packetNumber].Sub(el.Value.SendTime)>0*time.Millisecond{
-					owd = append(owd,ackFrame.Owdtimestamp[packetNumber].Sub(el.Value.SendTime))
-				}else{
-					owd = append(owd,-1*time.Millisecond)
-				}
-				//******
-
-			}
-		} else {
-			ackedPackets = append(ackedPackets, el)
-			//*******
-			if ackFrame.Owdtimestamp[packetNumber].Sub(el.Value.SendTime)>0*time.Millisecond{
-				owd = append(owd,ackFrame.Owdtimestamp[packetNumber].Sub(el.Value.SendTime))
-				//fmt.Println("error:",packetNumber)
-			}else{
-				owd = append(owd,-1*time.Millisecond)
-			}
-		}
-    //********
 	}
+	return h.ackedPackets, h.receiveTimes
+}
 
-	return ackedPackets, owd, nil
+// appendAckedPackets appends the packets of the history acked by the ranges to h.ackedPackets, sorted by packet number.
+// If there are no ackRanges, all the packets from lowestAcked to largestAcked are acked.
+func (h *sentPacketHandler) appendAckedPackets(lowestAcked, largestAcked protocol.PacketNumber, ackRanges []wire.AckRange) {
+	if len(ackRanges) == 0 {
+		h.appendPacketsInRange(lowestAcked, largestAcked)
+		return
+	}
+	// The ACK range with the lowest First goes last
+	for i := len(ackRanges) - 1; i >= 0; i-- {
+		h.appendPacketsInRange(ackRanges[i].First, ackRanges[i].Last)
+	}
 }
 
-func (h *sentPacketHandler) determineNewlyAckedPacketsClosePath(f *wire.ClosePathFrame) ([]*PacketElement, error) {
-	var ackedPackets []*PacketElement
-	ackRangeIndex := 0
-	for el := h.packetHistory.Front(); el != nil; el = el.Next() {
-		packet := el.Value
-		packetNumber := packet.PacketNumber
+func (h *sentPacketHandler) appendPacketsInRange(first, last protocol.PacketNumber) {
+	front, back := h.packetHistory.Front(), h.packetHistory.Back()
+	if front == nil {
+		return
+	}
+	first = utils.MaxPacketNumber(first, front.Value.PacketNumber)
+	last = utils.MinPacketNumber(last, back.Value.PacketNumber)
+	for p := first; p <= last; p++ {
+		if el := h.packetHistory.Get(p); el != nil {
+			h.ackedPackets = append(h.ackedPackets, el)
+		}
+	}
+}
 
-		// Ignore packets below the LowestAcked
-		if packetNumber < f.LowestAcked {
+// owdSamples returns the one-way delays of the acked packets timestamped by the peer
+func owdSamples(ackedPackets []*PacketElement, receiveTimes []time.Time) []logging.OWDSample {
+	var samples []logging.OWDSample
+	for i, p := range ackedPackets {
+		if receiveTimes[i].IsZero() {
 			continue
 		}
-		// Break after LargestAcked is reached
-		if packetNumber > f.LargestAcked {
-			break
-		}
-
-		if f.HasMissingRanges() {
-			ackRange := f.AckRanges[len(f.AckRanges)-1-ackRangeIndex]
-
-			for packetNumber > ackRange.Last && ackRangeIndex < len(f.AckRanges)-1 {
-				ackRangeIndex++
-				ackRange = f.AckRanges[len(f.AckRanges)-1-ackRangeIndex]
-			}
+		samples = append(samples, logging.OWDSample{
+			PacketNumber: p.Value.PacketNumber,
+			OWD:          oneWayDelay(p.Value.SendTime, receiveTimes[i]),
+		})
+	}
+	return samples
+}
 
-			if packetNumber >= ackRange.First { // packet i contained in ACK range
-				if packetNumber > ackRange.Last {
-					return nil, fmt.Errorf("BUG: ackhandler would have acked wrong packet 0x%x, while evaluating range 0x%x -> 0x%x with ClosePath frame", packetNumber, ackRange.First, ackRange.Last)
-				}
-				ackedPackets = append(ackedPackets, el)
-			}
-		} else {
-			ackedPackets = append(ackedPackets, el)
-		}
+// oneWayDelay returns the one-way delay of a packet, congestion.UnknownOWD if the peer didn't timestamp it
+func oneWayDelay(sendTime, receiveTime time.Time) time.Duration {
+	if owd := receiveTime.Sub(sendTime); owd > 0 {
+		return owd
 	}
+	return congestion.UnknownOWD
+}
 
-	return ackedPackets, nil
+func (h *sentPacketHandler) determineNewlyAckedPacketsClosePath(f *wire.ClosePathFrame) []*PacketElement {
+	h.ackedPackets = h.ackedPackets[:0]
+	h.appendAckedPackets(f.LowestAcked, f.LargestAcked, f.AckRanges)
+	return h.ackedPackets
 }
 
 func (h *sentPacketHandler) maybeUpdateRTT(largestAcked protocol.PacketNumber, ackDelay time.Duration, rcvTime time.Time) bool {
-	for el := h.packetHistory.Front(); el != nil; el = el.Next() {
-		packet := el.Value
-		if packet.PacketNumber == largestAcked {
-			h.rttStats.UpdateRTT(rcvTime.Sub(packet.SendTime), ackDelay, time.Now())
-			return true
-		}
-		// Packets are sorted by number, so we can stop searching
-		if packet.PacketNumber > largestAcked {
-			break
-		}
+	if el := h.packetHistory.Get(largestAcked); el != nil {
+		h.rttStats.UpdateRTT(rcvTime.Sub(el.Value.SendTime), ackDelay, time.Now())
+		return true
 	}
 	return false
 }
 //******
 func (h *sentPacketHandler)computeOWD(largestAcked protocol.PacketNumber,PacketReceivedTime time.Time) []time.Duration{
 	var owd []time.Duration
-	for el := h.packetHistory.Front(); el != nil; el = el.Next() {
-		packet := el.Value
-		if packet.PacketNumber == largestAcked {
-			owd =append(owd,PacketReceivedTime.Sub(packet.SendTime))
-			return owd
-		}
-		// Packets are sorted by number, so we can stop searching
-		if packet.PacketNumber > largestAcked {
-			break
-		}
+	if el := h.packetHistory.Get(largestAcked); el != nil {
+		owd = append(owd, PacketReceivedTime.Sub(el.Value.SendTime))
 	}
 	return owd
 }
@@ -429,9 +497,9 @@ func (h *sentPacketHandler) detectLostPackets() {
 	maxRTT := float64(utils.MaxDuration(h.rttStats.LatestRTT(), h.rttStats.SmoothedRTT()))
 	delayUntilLost := time.Duration((1.0 + timeReorderingFraction) * maxRTT)
 
-	var lostPackets []*PacketElement
+	lostPackets := h.lostPackets[:0]
 	for el := h.packetHistory.Front(); el != nil; el = el.Next() {
-		packet := el.Value
+		packet := &el.Value
 
 		if packet.PacketNumber > h.LargestAcked {
 			break
@@ -448,12 +516,11 @@ func (h *sentPacketHandler) detectLostPackets() {
 		}
 	}
 
-	if len(lostPackets) > 0 {
-		for _, p := range lostPackets {
-			h.queuePacketForRetransmission(p)
-			h.congestion.OnPacketLost(p.Value.PacketNumber, p.Value.Length, h.bytesInFlight)
-		}
+	for _, p := range lostPackets {
+		packet := h.queuePacketForRetransmission(p)
+		h.onPacketLost(packet, logging.PacketLossTimeThreshold)
 	}
+	h.lostPackets = lostPackets
 }
 
 func (h *sentPacketHandler) SetInflightAsLost() {
@@ -471,9 +538,9 @@ func (h *sentPacketHandler) SetInflightAsLost() {
 
 	if len(lostPackets) > 0 {
 		for _, p := range lostPackets {
-			h.queuePacketForRetransmission(p)
+			packet := h.queuePacketForRetransmission(p)
 			// XXX (QDC): should we?
-			h.congestion.OnPacketLost(p.Value.PacketNumber, p.Value.Length, h.bytesInFlight)
+			h.onPacketLost(packet, logging.PacketLossPathClosed)
 		}
 	}
 }
@@ -510,19 +577,54 @@ func (h *sentPacketHandler) OnAlarm() {
 	}
 
 	h.updateLossDetectionAlarm()
+	h.traceMetrics()
 }
 
 func (h *sentPacketHandler) GetAlarmTimeout() time.Time {
 	return h.alarm
 }
 
-func (h *sentPacketHandler) onPacketAcked(packetElement *PacketElement) {
+func (h *sentPacketHandler) onPacketAcked(packetElement *PacketElement, rcvTime time.Time) {
 	h.bytesInFlight -= packetElement.Value.Length
+	h.delivered += packetElement.Value.Length
+	h.deliveredTime = rcvTime
 	h.rtoCount = 0
 	h.tlpCount = 0
 	h.packetHistory.Remove(packetElement)
 }
 
+// getRateSample samples the delivery rate since the most recently sent acked packet was sent
+func (h *sentPacketHandler) getRateSample(lastAcked *Packet, ackedBytes protocol.ByteCount, rcvTime time.Time) *congestion.RateSample {
+	if h.appLimitedUntil != 0 && h.delivered > h.appLimitedUntil {
+		h.appLimitedUntil = 0
+	}
+	h.firstSentTime = lastAcked.SendTime
+
+	sendElapsed := lastAcked.SendTime.Sub(lastAcked.FirstSentTime)
+	ackElapsed := h.deliveredTime.Sub(lastAcked.DeliveredTime)
+	sample := &congestion.RateSample{
+		Delivered:      h.delivered - lastAcked.Delivered,
+		Interval:       utils.MaxDuration(sendElapsed, ackElapsed),
+		PriorDelivered: lastAcked.Delivered,
+		RTT:            rcvTime.Sub(lastAcked.SendTime),
+		AckedBytes:     ackedBytes,
+		BytesInFlight:  h.bytesInFlight,
+		IsAppLimited:   lastAcked.IsAppLimited,
+	}
+	// Intervals shorter than the minimum RTT come from ACK compression, and would overestimate the rate
+	if sample.Interval < h.rttStats.MinRTT() {
+		sample.Interval = 0
+	}
+	return sample
+}
+
+func (h *sentPacketHandler) SetAppLimited() {
+	if h.bytesInFlight >= h.congestion.GetCongestionWindow() {
+		return
+	}
+	h.appLimitedUntil = utils.MaxByteCount(h.delivered+h.bytesInFlight, 1)
+}
+
 func (h *sentPacketHandler) DequeuePacketForRetransmission() *Packet {
 	if len(h.retransmissionQueue) == 0 {
 		return nil
@@ -560,6 +662,10 @@ func (h *sentPacketHandler) SendingAllowed() bool {
 	return !maxTrackedLimited && (!congestionLimited || haveRetransmissions)
 }
 
+func (h *sentPacketHandler) TimeUntilSend() time.Duration {
+	return h.pacer.TimeUntilSend(time.Now())
+}
+
 func (h *sentPacketHandler) retransmitTLP() {
 	if p := h.packetHistory.Back(); p != nil {
 		h.queuePacketForRetransmission(p)
@@ -586,23 +692,63 @@ func (h *sentPacketHandler) retransmitOldestTwoPackets() {
 }
 
 func (h *sentPacketHandler) queueRTO(el *PacketElement) {
-	packet := &el.Value
 	utils.Debugf(
 		"\tQueueing packet 0x%x for retransmission (RTO), %d outstanding",
-		packet.PacketNumber,
+		el.Value.PacketNumber,
 		h.packetHistory.Len(),
 	)
-	h.queuePacketForRetransmission(el)
+	packet := h.queuePacketForRetransmission(el)
 	h.losses++
-	h.congestion.OnPacketLost(packet.PacketNumber, packet.Length, h.bytesInFlight)
+	h.onPacketLost(packet, logging.PacketLossRTO)
+}
+
+// onPacketLost informs the congestion controller about a packet that was queued for retransmission
+func (h *sentPacketHandler) onPacketLost(packet *Packet, reason logging.PacketLossReason) {
+	h.congestion.OnPacketLost(&congestion.LossEvent{
+		PacketNumber:  packet.PacketNumber,
+		Bytes:         packet.Length,
+		BytesInFlight: h.bytesInFlight,
+		SendTime:      packet.SendTime,
+	})
+	h.ecn.LostPacket(packet.ECN)
+	if h.tracer != nil {
+		h.tracer.LostPacket(h.pathID, packet.PacketNumber, reason)
+	}
+}
+
+// onCongestionExperienced informs the congestion controller about new CE marks
+// Congestion controllers without a congestion event react as if the largest acked packet was lost, without its bytes.
+func (h *sentPacketHandler) onCongestionExperienced(largestAcked protocol.PacketNumber, sendTime time.Time) {
+	if cong, ok := h.congestion.(congestion.SendAlgorithmWithCongestionEvent); ok {
+		cong.OnCongestionEvent(&congestion.CongestionEvent{
+			PacketNumber:  largestAcked,
+			BytesInFlight: h.bytesInFlight,
+			SendTime:      sendTime,
+		})
+		return
+	}
+	h.congestion.OnPacketLost(&congestion.LossEvent{
+		PacketNumber:  largestAcked,
+		BytesInFlight: h.bytesInFlight,
+		SendTime:      sendTime,
+	})
 }
 
-func (h *sentPacketHandler) queuePacketForRetransmission(packetElement *PacketElement) {
-	packet := &packetElement.Value
+// traceMetrics traces the congestion window and the RTT estimates, which may change with every ACK and alarm
+func (h *sentPacketHandler) traceMetrics() {
+	if h.tracer != nil {
+		h.tracer.UpdatedMetrics(h.pathID, h.rttStats, h.congestion.GetCongestionWindow(), h.bytesInFlight)
+	}
+}
+
+// queuePacketForRetransmission moves the packet from the history to the retransmission queue, and returns it
+func (h *sentPacketHandler) queuePacketForRetransmission(packetElement *PacketElement) *Packet {
+	// The history reuses its elements, the queue needs a copy of the packet
+	packet := h.packetHistory.Remove(packetElement)
 	h.bytesInFlight -= packet.Length
-	h.retransmissionQueue = append(h.retransmissionQueue, packet)
-	h.packetHistory.Remove(packetElement)
+	h.retransmissionQueue = append(h.retransmissionQueue, &packet)
 	h.stopWaitingManager.QueuedRetransmissionForPacketNumber(packet.PacketNumber)
+	return &packet
 }
 
 func (h *sentPacketHandler) DuplicatePacket(packet *Packet) {
@@ -621,7 +767,7 @@ func (h *sentPacketHandler) computeRTOTimeout() time.Duration {
 }
 
 func (h *sentPacketHandler) hasMultipleOutstandingRetransmittablePackets() bool {
-	return h.packetHistory.Front() != nil && h.packetHistory.Front().Next() != nil
+	return h.packetHistory.Len() > 1
 }
 
 func (h *sentPacketHandler) computeTLPTimeout() time.Duration {
@@ -0,0 +1,59 @@
+package ackhandler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/congestion"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+)
+
+// Every iteration sends a packet and receives the ACK of the packet sent inFlight packets before,
+// so that the packet history always holds inFlight packets.
+// Every 32nd packet is reported missing by the ACKs, and declared lost.
+func benchmarkSentPacketHandler(b *testing.B, inFlight int) {
+	handler := NewSentPacketHandler(0, &congestion.RTTStats{}, nil, nil, nil)
+	frames := []wire.Frame{&wire.StreamFrame{StreamID: 5, Data: []byte("foobar")}}
+	ack := &wire.AckFrame{AckRanges: make([]wire.AckRange, 0, 2)}
+	var lost protocol.PacketNumber
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 1; i <= b.N+inFlight; i++ {
+		pn := protocol.PacketNumber(i)
+		if err := handler.SentPacket(&Packet{PacketNumber: pn, Frames: frames, Length: protocol.MaxPacketSize}); err != nil {
+			b.Fatal(err)
+		}
+		if i <= inFlight {
+			continue
+		}
+		acked := pn - protocol.PacketNumber(inFlight)
+		if acked%32 == 0 {
+			lost = acked
+			continue
+		}
+		ack.LargestAcked = acked
+		ack.LowestAcked = acked
+		ack.AckRanges = ack.AckRanges[:0]
+		if lost != 0 && lost == acked-1 {
+			ack.LowestAcked = lost - 1
+			ack.AckRanges = append(ack.AckRanges,
+				wire.AckRange{First: acked, Last: acked},
+				wire.AckRange{First: lost - 1, Last: lost - 1},
+			)
+		}
+		if err := handler.ReceivedAck(ack, pn, time.Now(), 0); err != nil {
+			b.Fatal(err)
+		}
+		handler.DequeuePacketForRetransmission()
+	}
+}
+
+func BenchmarkSentPacketHandler100(b *testing.B) {
+	benchmarkSentPacketHandler(b, 100)
+}
+
+func BenchmarkSentPacketHandlerMaxCongestionWindow(b *testing.B) {
+	benchmarkSentPacketHandler(b, protocol.DefaultMaxCongestionWindow)
+}
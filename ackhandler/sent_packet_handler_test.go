@@ -6,6 +6,7 @@ import (
 	"github.com/lucas-clemente/quic-go/congestion"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/logging"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -17,10 +18,8 @@ type mockCongestion struct {
 	getCongestionWindow     bool
 	packetsAcked            [][]interface{}
 	packetsLost             [][]interface{}
-}
-
-func (m *mockCongestion) TimeUntilSend(now time.Time, bytesInFlight protocol.ByteCount) time.Duration {
-	panic("not implemented")
+	ackEvents               []*congestion.AckEvent
+	lossEvents              []*congestion.LossEvent
 }
 
 func (m *mockCongestion) OnPacketSent(sentTime time.Time, bytesInFlight protocol.ByteCount, packetNumber protocol.PacketNumber, bytes protocol.ByteCount, isRetransmittable bool) bool {
@@ -50,12 +49,53 @@ func (m *mockCongestion) OnConnectionMigration()                  { panic("not i
 func (m *mockCongestion) SetSlowStartLargeReduction(enabled bool) { panic("not implemented") }
 func (m *mockCongestion) SmoothedRTT() time.Duration              { return defaultRTOTimeout / 10 }
 
-func (m *mockCongestion) OnPacketAcked(n protocol.PacketNumber, l protocol.ByteCount, bif protocol.ByteCount) {
-	m.packetsAcked = append(m.packetsAcked, []interface{}{n, l, bif})
+func (m *mockCongestion) OnPacketAcked(ev *congestion.AckEvent) {
+	m.packetsAcked = append(m.packetsAcked, []interface{}{ev.PacketNumber, ev.Bytes, ev.BytesInFlight})
+	m.ackEvents = append(m.ackEvents, ev)
+}
+
+func (m *mockCongestion) OnPacketLost(ev *congestion.LossEvent) {
+	m.packetsLost = append(m.packetsLost, []interface{}{ev.PacketNumber, ev.Bytes, ev.BytesInFlight})
+	m.lossEvents = append(m.lossEvents, ev)
+}
+
+type mockRateSampleCongestion struct {
+	mockCongestion
+	rateSamples []*congestion.RateSample
+}
+
+func (m *mockRateSampleCongestion) OnRateSample(rs *congestion.RateSample) {
+	m.rateSamples = append(m.rateSamples, rs)
+}
+
+type mockCongestionEventCongestion struct {
+	mockCongestion
+	congestionEvents []*congestion.CongestionEvent
+}
+
+func (m *mockCongestionEventCongestion) OnCongestionEvent(ev *congestion.CongestionEvent) {
+	m.congestionEvents = append(m.congestionEvents, ev)
+}
+
+type mockTracer struct {
+	logging.Tracer
+	pathID       protocol.PathID
+	owdSamples   []logging.OWDSample
+	lostPackets  map[protocol.PacketNumber]logging.PacketLossReason
+	metricsCount int
+}
+
+func (m *mockTracer) ProcessedAck(pathID protocol.PathID, largestAcked protocol.PacketNumber, samples []logging.OWDSample) {
+	m.pathID = pathID
+	m.owdSamples = append(m.owdSamples, samples...)
+}
+
+func (m *mockTracer) LostPacket(pathID protocol.PathID, packetNumber protocol.PacketNumber, reason logging.PacketLossReason) {
+	m.lostPackets[packetNumber] = reason
 }
 
-func (m *mockCongestion) OnPacketLost(n protocol.PacketNumber, l protocol.ByteCount, bif protocol.ByteCount) {
-	m.packetsLost = append(m.packetsLost, []interface{}{n, l, bif})
+func (m *mockTracer) UpdatedMetrics(pathID protocol.PathID, rttStats *congestion.RTTStats, congestionWindow, bytesInFlight protocol.ByteCount) {
+	m.metricsCount++
 }
 
 func retransmittablePacket(num protocol.PacketNumber) *Packet {
@@ -74,7 +114,7 @@ var _ = Describe("SentPacketHandler", func() {
 
 	BeforeEach(func() {
 		rttStats := &congestion.RTTStats{}
-		handler = NewSentPacketHandler(rttStats, nil, nil).(*sentPacketHandler)
+		handler = NewSentPacketHandler(0, rttStats, nil, nil, nil).(*sentPacketHandler)
 		streamFrame = wire.StreamFrame{
 			StreamID: 5,
 			Data:     []byte{0x13, 0x37},
@@ -298,10 +338,10 @@ var _ = Describe("SentPacketHandler", func() {
 					LargestAcked: protocol.PacketNumber(largestAcked),
 					LowestAcked:  1,
 				}
-				err := handler.ReceivedAck(&ack, 1337, time.Now())
+				err := handler.ReceivedAck(&ack, 1337, time.Now(), 0)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(handler.bytesInFlight).To(Equal(protocol.ByteCount(len(packets) - 3)))
-				err = handler.ReceivedAck(&ack, 1337, time.Now())
+				err = handler.ReceivedAck(&ack, 1337, time.Now(), 0)
 				Expect(err).To(MatchError(ErrDuplicateOrOutOfOrderAck))
 				Expect(handler.bytesInFlight).To(Equal(protocol.ByteCount(len(packets) - 3)))
 			})
@@ -310,10 +350,10 @@ var _ = Describe("SentPacketHandler", func() {
 				ack := wire.AckFrame{
 					LargestAcked: 3,
 				}
-				err := handler.ReceivedAck(&ack, 1337, time.Now())
+				err := handler.ReceivedAck(&ack, 1337, time.Now(), 0)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(handler.bytesInFlight).To(Equal(protocol.ByteCount(len(packets) - 3)))
-				err = handler.ReceivedAck(&ack, 1337-1, time.Now())
+				err = handler.ReceivedAck(&ack, 1337-1, time.Now(), 0)
 				Expect(err).To(MatchError(ErrDuplicateOrOutOfOrderAck))
 				Expect(handler.LargestAcked).To(Equal(protocol.PacketNumber(3)))
 				Expect(handler.bytesInFlight).To(Equal(protocol.ByteCount(len(packets) - 3)))
@@ -323,7 +363,7 @@ var _ = Describe("SentPacketHandler", func() {
 				ack := wire.AckFrame{
 					LargestAcked: packets[len(packets)-1].PacketNumber + 1337,
 				}
-				err := handler.ReceivedAck(&ack, 1, time.Now())
+				err := handler.ReceivedAck(&ack, 1, time.Now(), 0)
 				Expect(err).To(MatchError(errAckForUnsentPacket))
 				Expect(handler.bytesInFlight).To(Equal(protocol.ByteCount(len(packets))))
 			})
@@ -333,10 +373,10 @@ var _ = Describe("SentPacketHandler", func() {
 					LargestAcked: 3,
 					LowestAcked:  1,
 				}
-				err := handler.ReceivedAck(&ack, 1337, time.Now())
+				err := handler.ReceivedAck(&ack, 1337, time.Now(), 0)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(handler.bytesInFlight).To(Equal(protocol.ByteCount(len(packets) - 3)))
-				err = handler.ReceivedAck(&ack, 1337+1, time.Now())
+				err = handler.ReceivedAck(&ack, 1337+1, time.Now(), 0)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(handler.LargestAcked).To(Equal(protocol.PacketNumber(3)))
 				Expect(handler.bytesInFlight).To(Equal(protocol.ByteCount(len(packets) - 3)))
@@ -347,7 +387,7 @@ var _ = Describe("SentPacketHandler", func() {
 					LargestAcked: 12,
 					LowestAcked:  5,
 				}
-				err := handler.ReceivedAck(&ack, 1337, time.Now())
+				err := handler.ReceivedAck(&ack, 1337, time.Now(), 0)
 				Expect(err).To(MatchError(ErrAckForSkippedPacket))
 			})
 
@@ -360,7 +400,7 @@ var _ = Describe("SentPacketHandler", func() {
 						{First: 5, Last: 10},
 					},
 				}
-				err := handler.ReceivedAck(&ack, 1337, time.Now())
+				err := handler.ReceivedAck(&ack, 1337, time.Now(), 0)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(handler.LargestAcked).ToNot(BeZero())
 			})
@@ -372,7 +412,7 @@ var _ = Describe("SentPacketHandler", func() {
 					LargestAcked: 5,
 					LowestAcked:  1,
 				}
-				err := handler.ReceivedAck(&ack, 1, time.Now())
+				err := handler.ReceivedAck(&ack, 1, time.Now(), 0)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(handler.LargestAcked).To(Equal(protocol.PacketNumber(5)))
 				el := handler.packetHistory.Front()
@@ -388,7 +428,7 @@ var _ = Describe("SentPacketHandler", func() {
 					LargestAcked: 8,
 					LowestAcked:  2,
 				}
-				err := handler.ReceivedAck(&ack, 1, time.Now())
+				err := handler.ReceivedAck(&ack, 1, time.Now(), 0)
 				Expect(err).ToNot(HaveOccurred())
 				el := handler.packetHistory.Front()
 				Expect(el.Value.PacketNumber).To(Equal(protocol.PacketNumber(1)))
@@ -408,7 +448,7 @@ var _ = Describe("SentPacketHandler", func() {
 						{First: 2, Last: 3},
 					},
 				}
-				err := handler.ReceivedAck(&ack, 1, time.Now())
+				err := handler.ReceivedAck(&ack, 1, time.Now(), 0)
 				Expect(err).ToNot(HaveOccurred())
 				el := handler.packetHistory.Front()
 				Expect(el.Value.PacketNumber).To(Equal(protocol.PacketNumber(1)))
@@ -426,7 +466,7 @@ var _ = Describe("SentPacketHandler", func() {
 					LargestAcked: 8,
 					LowestAcked:  3,
 				}
-				err := handler.ReceivedAck(&ack, 1, time.Now())
+				err := handler.ReceivedAck(&ack, 1, time.Now(), 0)
 				Expect(err).ToNot(HaveOccurred())
 				el := handler.packetHistory.Front()
 				Expect(el.Value.PacketNumber).To(Equal(protocol.PacketNumber(1)))
@@ -446,7 +486,7 @@ var _ = Describe("SentPacketHandler", func() {
 						{First: 1, Last: 1},
 					},
 				}
-				err := handler.ReceivedAck(&ack, 1, time.Now())
+				err := handler.ReceivedAck(&ack, 1, time.Now(), 0)
 				Expect(err).ToNot(HaveOccurred())
 				el := handler.packetHistory.Front()
 				Expect(el.Value.PacketNumber).To(Equal(protocol.PacketNumber(2)))
@@ -470,7 +510,7 @@ var _ = Describe("SentPacketHandler", func() {
 						{First: 1, Last: 2},
 					},
 				}
-				err := handler.ReceivedAck(&ack1, 1, time.Now())
+				err := handler.ReceivedAck(&ack1, 1, time.Now(), 0)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(handler.bytesInFlight).To(Equal(protocol.ByteCount(len(packets) - 5)))
 				el := handler.packetHistory.Front()
@@ -479,7 +519,7 @@ var _ = Describe("SentPacketHandler", func() {
 					LargestAcked: protocol.PacketNumber(largestObserved),
 					LowestAcked:  1,
 				}
-				err = handler.ReceivedAck(&ack2, 2, time.Now())
+				err = handler.ReceivedAck(&ack2, 2, time.Now(), 0)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(handler.bytesInFlight).To(Equal(protocol.ByteCount(len(packets) - 6)))
 				Expect(handler.packetHistory.Front().Value.PacketNumber).To(Equal(protocol.PacketNumber(7)))
@@ -494,7 +534,7 @@ var _ = Describe("SentPacketHandler", func() {
 						{First: 1, Last: 2},
 					},
 				}
-				err := handler.ReceivedAck(&ack1, 1, time.Now())
+				err := handler.ReceivedAck(&ack1, 1, time.Now(), 0)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(handler.bytesInFlight).To(Equal(protocol.ByteCount(len(packets) - 5)))
 				el := handler.packetHistory.Front()
@@ -503,7 +543,7 @@ var _ = Describe("SentPacketHandler", func() {
 					LargestAcked: 7,
 					LowestAcked:  1,
 				}
-				err = handler.ReceivedAck(&ack2, 2, time.Now())
+				err = handler.ReceivedAck(&ack2, 2, time.Now(), 0)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(handler.bytesInFlight).To(Equal(protocol.ByteCount(len(packets) - 7)))
 				Expect(handler.packetHistory.Front().Value.PacketNumber).To(Equal(protocol.PacketNumber(8)))
@@ -514,7 +554,7 @@ var _ = Describe("SentPacketHandler", func() {
 					LargestAcked: 6,
 					LowestAcked:  1,
 				}
-				err := handler.ReceivedAck(&ack1, 1, time.Now())
+				err := handler.ReceivedAck(&ack1, 1, time.Now(), 0)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(handler.packetHistory.Front().Value.PacketNumber).To(Equal(protocol.PacketNumber(7)))
 				Expect(handler.bytesInFlight).To(Equal(protocol.ByteCount(len(packets) - 6)))
@@ -527,7 +567,7 @@ var _ = Describe("SentPacketHandler", func() {
 						{First: 1, Last: 1},
 					},
 				}
-				err = handler.ReceivedAck(&ack2, 2, time.Now())
+				err = handler.ReceivedAck(&ack2, 2, time.Now(), 0)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(handler.bytesInFlight).To(Equal(protocol.ByteCount(len(packets) - 6 - 3)))
 				Expect(handler.packetHistory.Front().Value.PacketNumber).To(Equal(protocol.PacketNumber(7)))
@@ -543,13 +583,13 @@ var _ = Describe("SentPacketHandler", func() {
 				getPacketElement(2).Value.SendTime = now.Add(-5 * time.Minute)
 				getPacketElement(6).Value.SendTime = now.Add(-1 * time.Minute)
 				// Now, check that the proper times are used when calculating the deltas
-				err := handler.ReceivedAck(&wire.AckFrame{LargestAcked: 1}, 1, time.Now())
+				err := handler.ReceivedAck(&wire.AckFrame{LargestAcked: 1}, 1, time.Now(), 0)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(handler.rttStats.LatestRTT()).To(BeNumerically("~", 10*time.Minute, 1*time.Second))
-				err = handler.ReceivedAck(&wire.AckFrame{LargestAcked: 2}, 2, time.Now())
+				err = handler.ReceivedAck(&wire.AckFrame{LargestAcked: 2}, 2, time.Now(), 0)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(handler.rttStats.LatestRTT()).To(BeNumerically("~", 5*time.Minute, 1*time.Second))
-				err = handler.ReceivedAck(&wire.AckFrame{LargestAcked: 6}, 3, time.Now())
+				err = handler.ReceivedAck(&wire.AckFrame{LargestAcked: 6}, 3, time.Now(), 0)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(handler.rttStats.LatestRTT()).To(BeNumerically("~", 1*time.Minute, 1*time.Second))
 			})
@@ -557,7 +597,7 @@ var _ = Describe("SentPacketHandler", func() {
 			It("uses the DelayTime in the ack frame", func() {
 				now := time.Now()
 				getPacketElement(1).Value.SendTime = now.Add(-10 * time.Minute)
-				err := handler.ReceivedAck(&wire.AckFrame{LargestAcked: 1, DelayTime: 5 * time.Minute}, 1, time.Now())
+				err := handler.ReceivedAck(&wire.AckFrame{LargestAcked: 1, DelayTime: 5 * time.Minute}, 1, time.Now(), 0)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(handler.rttStats.LatestRTT()).To(BeNumerically("~", 5*time.Minute, 1*time.Second))
 			})
@@ -583,7 +623,7 @@ var _ = Describe("SentPacketHandler", func() {
 			// Increase RTT, because the tests would be flaky otherwise
 			handler.rttStats.UpdateRTT(time.Minute, 0, time.Now())
 			// Ack a single packet so that we have non-RTO timings
-			handler.ReceivedAck(&wire.AckFrame{LargestAcked: 2, LowestAcked: 2}, 1, time.Now())
+			handler.ReceivedAck(&wire.AckFrame{LargestAcked: 2, LowestAcked: 2}, 1, time.Now(), 0)
 			Expect(handler.bytesInFlight).To(Equal(protocol.ByteCount(6)))
 		})
 
@@ -606,7 +646,7 @@ var _ = Describe("SentPacketHandler", func() {
 		Context("StopWaitings", func() {
 			It("gets a StopWaitingFrame", func() {
 				ack := wire.AckFrame{LargestAcked: 5, LowestAcked: 5}
-				err := handler.ReceivedAck(&ack, 2, time.Now())
+				err := handler.ReceivedAck(&ack, 2, time.Now(), 0)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(handler.GetStopWaitingFrame(false)).To(Equal(&wire.StopWaitingFrame{LeastUnacked: 6}))
 			})
@@ -644,7 +684,7 @@ var _ = Describe("SentPacketHandler", func() {
 				{First: 1, Last: 1},
 			},
 		}
-		err = handler.ReceivedAck(&ack, 1, time.Now())
+		err = handler.ReceivedAck(&ack, 1, time.Now(), 0)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(handler.bytesInFlight).To(Equal(protocol.ByteCount(2)))
 
@@ -681,7 +721,7 @@ var _ = Describe("SentPacketHandler", func() {
 		It("should call MaybeExitSlowStart and OnPacketAcked", func() {
 			handler.SentPacket(retransmittablePacket(1))
 			handler.SentPacket(retransmittablePacket(2))
-			err := handler.ReceivedAck(&wire.AckFrame{LargestAcked: 1, LowestAcked: 1}, 1, time.Now())
+			err := handler.ReceivedAck(&wire.AckFrame{LargestAcked: 1, LowestAcked: 1}, 1, time.Now(), 0)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(cong.maybeExitSlowStart).To(BeTrue())
 			Expect(cong.packetsAcked).To(BeEquivalentTo([][]interface{}{
@@ -690,6 +730,37 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(cong.packetsLost).To(BeEmpty())
 		})
 
+		It("passes the timestamps, the RTT sample and the SBD interval in the ack events", func() {
+			handler.SentPacket(retransmittablePacket(1))
+			handler.SentPacket(retransmittablePacket(2))
+			sendTime := handler.packetHistory.Front().Value.SendTime
+			rcvTime := sendTime.Add(10 * time.Millisecond)
+			ack := &wire.AckFrame{
+				LargestAcked: 2,
+				LowestAcked:  1,
+				Timestamps:   []wire.PacketTimestamp{{PacketNumber: 1, ReceiveTime: rcvTime}},
+			}
+			err := handler.ReceivedAck(ack, 1, time.Now(), 7)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cong.ackEvents).To(HaveLen(2))
+			Expect(cong.ackEvents[0].SendTime).To(Equal(sendTime))
+			Expect(cong.ackEvents[0].ReceiveTime).To(Equal(rcvTime))
+			Expect(cong.ackEvents[0].OWD).To(Equal(10 * time.Millisecond))
+			Expect(cong.ackEvents[0].RTT).To(Equal(handler.rttStats.LatestRTT()))
+			Expect(cong.ackEvents[0].SBDInterval).To(Equal(7))
+			// no timestamp for the second packet
+			Expect(cong.ackEvents[1].ReceiveTime.IsZero()).To(BeTrue())
+			Expect(cong.ackEvents[1].OWD).To(Equal(congestion.UnknownOWD))
+		})
+
+		It("passes an unknown OWD for the packets acked by a CLOSE_PATH frame", func() {
+			handler.SentPacket(retransmittablePacket(1))
+			err := handler.ReceivedClosePath(&wire.ClosePathFrame{LargestAcked: 1, LowestAcked: 1}, 1, time.Now())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cong.ackEvents).To(HaveLen(1))
+			Expect(cong.ackEvents[0].OWD).To(Equal(congestion.UnknownOWD))
+		})
+
 		It("should call MaybeExitSlowStart and OnPacketLost", func() {
 			handler.SentPacket(retransmittablePacket(1))
 			handler.SentPacket(retransmittablePacket(2))
@@ -735,6 +806,246 @@ var _ = Describe("SentPacketHandler", func() {
 		})
 	})
 
+	Context("ECN", func() {
+		var cong *mockCongestion
+
+		BeforeEach(func() {
+			cong = &mockCongestion{}
+			handler.congestion = cong
+		})
+
+		It("marks the retransmittable packets sent while testing the path", func() {
+			p := retransmittablePacket(1)
+			Expect(handler.SentPacket(p)).To(Succeed())
+			Expect(p.ECN).To(Equal(protocol.ECT0))
+			p = nonRetransmittablePacket(2)
+			Expect(handler.SentPacket(p)).To(Succeed())
+			Expect(p.ECN).To(Equal(protocol.ECNNon))
+			Expect(handler.GetCongestionState().ECN).To(Equal(ECNStateTesting))
+		})
+
+		It("validates the path when the peer reports the marks", func() {
+			handler.SentPacket(retransmittablePacket(1))
+			handler.SentPacket(retransmittablePacket(2))
+			ack := &wire.AckFrame{LargestAcked: 2, LowestAcked: 1, ECN: &wire.ECNCounts{ECT0: 2}}
+			Expect(handler.ReceivedAck(ack, 1, time.Now(), 0)).To(Succeed())
+			Expect(handler.GetCongestionState().ECN).To(Equal(ECNStateCapable))
+			Expect(cong.lossEvents).To(BeEmpty())
+		})
+
+		It("passes the CE marks as a congestion event", func() {
+			cong := &mockCongestionEventCongestion{}
+			handler.congestion = cong
+			handler.SentPacket(retransmittablePacket(1))
+			handler.SentPacket(retransmittablePacket(2))
+			handler.SentPacket(retransmittablePacket(3))
+			ack := &wire.AckFrame{LargestAcked: 2, LowestAcked: 1, ECN: &wire.ECNCounts{ECT0: 1, ECNCE: 1}}
+			Expect(handler.ReceivedAck(ack, 1, time.Now(), 0)).To(Succeed())
+			Expect(cong.lossEvents).To(BeEmpty())
+			Expect(cong.congestionEvents).To(HaveLen(1))
+			ev := cong.congestionEvents[0]
+			Expect(ev.PacketNumber).To(Equal(protocol.PacketNumber(2)))
+			Expect(ev.BytesInFlight).To(Equal(protocol.ByteCount(1)))
+			// a CE mark is not a loss
+			_, _, losses := handler.GetStatistics()
+			Expect(losses).To(BeZero())
+			Expect(handler.GetCongestionState().ECNCEMarks).To(BeEquivalentTo(1))
+		})
+
+		It("reacts to CE marks like to a loss if the congestion controller has no congestion event", func() {
+			handler.SentPacket(retransmittablePacket(1))
+			handler.SentPacket(retransmittablePacket(2))
+			handler.SentPacket(retransmittablePacket(3))
+			ack := &wire.AckFrame{LargestAcked: 2, LowestAcked: 1, ECN: &wire.ECNCounts{ECT0: 1, ECNCE: 1}}
+			Expect(handler.ReceivedAck(ack, 1, time.Now(), 0)).To(Succeed())
+			Expect(cong.lossEvents).To(HaveLen(1))
+			ev := cong.lossEvents[0]
+			Expect(ev.PacketNumber).To(Equal(protocol.PacketNumber(2)))
+			Expect(ev.Bytes).To(BeZero())
+			Expect(ev.BytesInFlight).To(Equal(protocol.ByteCount(1)))
+			// a CE mark is not a loss
+			_, _, losses := handler.GetStatistics()
+			Expect(losses).To(BeZero())
+			Expect(handler.GetCongestionState().ECNCEMarks).To(BeEquivalentTo(1))
+		})
+
+		It("stops marking packets if the ACKs don't report the marks", func() {
+			handler.SentPacket(retransmittablePacket(1))
+			ack := &wire.AckFrame{LargestAcked: 1, LowestAcked: 1}
+			Expect(handler.ReceivedAck(ack, 1, time.Now(), 0)).To(Succeed())
+			Expect(handler.GetCongestionState().ECN).To(Equal(ECNStateFailed))
+			p := retransmittablePacket(2)
+			Expect(handler.SentPacket(p)).To(Succeed())
+			Expect(p.ECN).To(Equal(protocol.ECNNon))
+		})
+	})
+
+	Context("pacing", func() {
+		It("paces packets after the initial burst", func() {
+			handler = NewSentPacketHandler(0, congestion.NewRTTStats(), nil, nil, nil).(*sentPacketHandler)
+			var pn protocol.PacketNumber
+			for handler.TimeUntilSend() == 0 {
+				Expect(pn).To(BeNumerically("<", protocol.InitialCongestionWindow))
+				pn++
+				err := handler.SentPacket(&Packet{PacketNumber: pn, Length: protocol.DefaultTCPMSS, Frames: []wire.Frame{&wire.PingFrame{}}})
+				Expect(err).ToNot(HaveOccurred())
+			}
+			Expect(handler.SendingAllowed()).To(BeTrue())
+			Expect(handler.TimeUntilSend()).To(BeNumerically("<=", 100*time.Millisecond))
+		})
+
+		It("doesn't charge the pacing budget for ACK-only packets", func() {
+			handler = NewSentPacketHandler(0, congestion.NewRTTStats(), nil, nil, nil).(*sentPacketHandler)
+			for pn := protocol.PacketNumber(1); pn <= 2*protocol.InitialCongestionWindow; pn++ {
+				err := handler.SentPacket(&Packet{PacketNumber: pn, Length: protocol.DefaultTCPMSS, Frames: []wire.Frame{&wire.AckFrame{}}})
+				Expect(err).ToNot(HaveOccurred())
+			}
+			Expect(handler.TimeUntilSend()).To(BeZero())
+		})
+	})
+
+	Context("congestion state", func() {
+		It("returns a snapshot of the congestion state", func() {
+			handler.SentPacket(retransmittablePacket(1))
+			handler.SentPacket(retransmittablePacket(2))
+			handler.tlpCount = 1
+			handler.rtoCount = 2
+			state := handler.GetCongestionState()
+			Expect(state.CongestionWindow).To(Equal(protocol.InitialCongestionWindow * protocol.DefaultTCPMSS))
+			Expect(state.SlowStartThreshold).To(Equal(protocol.DefaultMaxCongestionWindow * protocol.DefaultTCPMSS))
+			Expect(state.BytesInFlight).To(Equal(protocol.ByteCount(2)))
+			Expect(state.InSlowStart).To(BeTrue())
+			Expect(state.InRecovery).To(BeFalse())
+			Expect(state.TLPCount).To(BeEquivalentTo(1))
+			Expect(state.RTOCount).To(BeEquivalentTo(2))
+			Expect(state.Olia).To(BeNil())
+		})
+
+		It("includes the OLIA state", func() {
+			rttStats := congestion.NewRTTStats()
+			cong := congestion.NewOliaSender(congestion.NewOliaSenders(), rttStats, protocol.InitialCongestionWindow, protocol.DefaultMaxCongestionWindow)
+			handler = NewSentPacketHandler(0, rttStats, cong, nil, nil).(*sentPacketHandler)
+			state := handler.GetCongestionState()
+			Expect(state.Olia).ToNot(BeNil())
+			Expect(state.Olia.EpsilonDen).To(BeEquivalentTo(1))
+		})
+
+		It("doesn't require the debug info", func() {
+			handler.congestion = &mockCongestion{}
+			state := handler.GetCongestionState()
+			Expect(state.SlowStartThreshold).To(BeZero())
+			Expect(state.InSlowStart).To(BeFalse())
+		})
+	})
+
+	Context("tracing", func() {
+		var tracer *mockTracer
+
+		BeforeEach(func() {
+			tracer = &mockTracer{lostPackets: make(map[protocol.PacketNumber]logging.PacketLossReason)}
+			handler = NewSentPacketHandler(3, congestion.NewRTTStats(), nil, nil, tracer).(*sentPacketHandler)
+		})
+
+		It("traces the OWD samples of the timestamped packets", func() {
+			handler.SentPacket(retransmittablePacket(1))
+			handler.SentPacket(retransmittablePacket(2))
+			sendTime := handler.packetHistory.Back().Value.SendTime
+			ack := &wire.AckFrame{
+				LargestAcked: 2,
+				LowestAcked:  1,
+				Timestamps:   []wire.PacketTimestamp{{PacketNumber: 2, ReceiveTime: sendTime.Add(5 * time.Millisecond)}},
+			}
+			err := handler.ReceivedAck(ack, 1, time.Now(), 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tracer.pathID).To(Equal(protocol.PathID(3)))
+			Expect(tracer.owdSamples).To(HaveLen(1))
+			Expect(tracer.owdSamples[0].PacketNumber).To(Equal(protocol.PacketNumber(2)))
+			Expect(tracer.owdSamples[0].OWD).To(Equal(5 * time.Millisecond))
+			Expect(tracer.metricsCount).To(Equal(1))
+		})
+
+		It("traces lost packets with the reason", func() {
+			for i := protocol.PacketNumber(1); i <= 4; i++ {
+				handler.SentPacket(retransmittablePacket(i))
+			}
+			handler.packetHistory.Front().Value.SendTime = time.Now().Add(-time.Hour)
+			err := handler.ReceivedAck(&wire.AckFrame{LargestAcked: 2, LowestAcked: 2}, 1, time.Now(), 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tracer.lostPackets).To(Equal(map[protocol.PacketNumber]logging.PacketLossReason{1: logging.PacketLossTimeThreshold}))
+			handler.tlpCount = maxTailLossProbes
+			handler.OnAlarm()
+			Expect(tracer.lostPackets).To(HaveKeyWithValue(protocol.PacketNumber(3), logging.PacketLossRTO))
+			Expect(tracer.metricsCount).To(Equal(2))
+		})
+	})
+
+	Context("delivery rate sampling", func() {
+		var cong *mockRateSampleCongestion
+
+		BeforeEach(func() {
+			cong = &mockRateSampleCongestion{}
+			handler.congestion = cong
+		})
+
+		It("records the delivery state in sent packets", func() {
+			handler.SentPacket(retransmittablePacket(1))
+			handler.SentPacket(retransmittablePacket(2))
+			p1 := getPacketElement(1).Value
+			p2 := getPacketElement(2).Value
+			Expect(p2.Delivered).To(BeZero())
+			Expect(p2.FirstSentTime).To(Equal(p1.SendTime))
+			Expect(p2.DeliveredTime).To(Equal(p1.SendTime))
+			rcvTime := time.Now().Add(10 * time.Millisecond)
+			err := handler.ReceivedAck(&wire.AckFrame{LargestAcked: 1, LowestAcked: 1}, 1, rcvTime, 0)
+			Expect(err).ToNot(HaveOccurred())
+			handler.SentPacket(retransmittablePacket(3))
+			p3 := getPacketElement(3).Value
+			Expect(p3.Delivered).To(Equal(protocol.ByteCount(1)))
+			Expect(p3.DeliveredTime).To(Equal(rcvTime))
+			Expect(p3.FirstSentTime).To(Equal(p1.SendTime))
+		})
+
+		It("samples the delivery rate once per ACK", func() {
+			for i := 1; i <= 10; i++ {
+				handler.SentPacket(&Packet{PacketNumber: protocol.PacketNumber(i), Length: 1000, Frames: []wire.Frame{&wire.PingFrame{}}})
+			}
+			rcvTime := time.Now().Add(100 * time.Millisecond)
+			err := handler.ReceivedAck(&wire.AckFrame{LargestAcked: 10, LowestAcked: 1}, 1, rcvTime, 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cong.rateSamples).To(HaveLen(1))
+			rs := cong.rateSamples[0]
+			Expect(rs.Delivered).To(Equal(protocol.ByteCount(10000)))
+			Expect(rs.PriorDelivered).To(BeZero())
+			Expect(rs.AckedBytes).To(Equal(protocol.ByteCount(10000)))
+			Expect(rs.BytesInFlight).To(BeZero())
+			Expect(rs.Interval).To(BeNumerically("~", 100*time.Millisecond, 10*time.Millisecond))
+			Expect(rs.RTT).To(BeNumerically("~", 100*time.Millisecond, 10*time.Millisecond))
+			Expect(rs.DeliveryRate()).To(BeNumerically("~", 100000*congestion.BytesPerSecond, 10000*congestion.BytesPerSecond))
+			Expect(rs.IsAppLimited).To(BeFalse())
+		})
+
+		It("marks the packets sent while application limited", func() {
+			handler.SetAppLimited()
+			handler.SentPacket(retransmittablePacket(1))
+			handler.SentPacket(retransmittablePacket(2))
+			Expect(getPacketElement(1).Value.IsAppLimited).To(BeTrue())
+			err := handler.ReceivedAck(&wire.AckFrame{LargestAcked: 1, LowestAcked: 1}, 1, time.Now(), 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cong.rateSamples[0].IsAppLimited).To(BeTrue())
+			err = handler.ReceivedAck(&wire.AckFrame{LargestAcked: 2, LowestAcked: 2}, 2, time.Now(), 0)
+			Expect(err).ToNot(HaveOccurred())
+			handler.SentPacket(retransmittablePacket(3))
+			Expect(getPacketElement(3).Value.IsAppLimited).To(BeFalse())
+		})
+
+		It("is not application limited when the window is full", func() {
+			handler.SentPacket(&Packet{PacketNumber: 1, Length: protocol.DefaultTCPMSS, Frames: []wire.Frame{&wire.PingFrame{}}})
+			handler.SetAppLimited()
+			handler.SentPacket(retransmittablePacket(2))
+			Expect(getPacketElement(2).Value.IsAppLimited).To(BeFalse())
+		})
+	})
+
 	Context("calculating RTO", func() {
 		It("uses default RTO", func() {
 			Expect(handler.computeRTOTimeout()).To(Equal(defaultRTOTimeout))
@@ -777,7 +1088,7 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(err).NotTo(HaveOccurred())
 			Expect(handler.lossTime.IsZero()).To(BeTrue())
 
-			err = handler.ReceivedAck(&wire.AckFrame{LargestAcked: 2, LowestAcked: 2}, 1, time.Now().Add(time.Hour))
+			err = handler.ReceivedAck(&wire.AckFrame{LargestAcked: 2, LowestAcked: 2}, 1, time.Now().Add(time.Hour), 0)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(handler.lossTime.IsZero()).To(BeFalse())
 
@@ -800,7 +1111,7 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(err).NotTo(HaveOccurred())
 			Expect(handler.lossTime.IsZero()).To(BeTrue())
 
-			err = handler.ReceivedAck(&wire.AckFrame{LargestAcked: 1, LowestAcked: 1}, 1, time.Now().Add(time.Hour))
+			err = handler.ReceivedAck(&wire.AckFrame{LargestAcked: 1, LowestAcked: 1}, 1, time.Now().Add(time.Hour), 0)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(handler.lossTime.IsZero()).To(BeTrue())
 			Expect(handler.GetAlarmTimeout().Sub(time.Now())).To(BeNumerically("~", handler.computeRTOTimeout(), time.Minute))
@@ -0,0 +1,85 @@
+package quic
+
+import (
+	"net"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+const (
+	// maxGSOSegments is the maximum number of segments of a datagram sent with GSO (UDP_MAX_SEGMENTS)
+	maxGSOSegments = 64
+	// maxGSOPayloadSize is the maximum payload of a datagram sent with GSO, it must fit into an IPv4 packet
+	maxGSOPayloadSize = 65535 - 20 - 8
+	// groBufferSize is the size of the buffers receiving the datagrams coalesced by GRO
+	groBufferSize = 1 << 16
+	// groBatchSize is the number of buffers of a batched read with GRO, since they are much larger
+	groBatchSize = 8
+)
+
+// A batchConn reads and writes several datagrams with a single syscall (recvmmsg and sendmmsg on Linux).
+// ipv4.Message and ipv6.Message are the same type.
+type batchConn interface {
+	ReadBatch(ms []ipv4.Message, flags int) (int, error)
+	WriteBatch(ms []ipv4.Message, flags int) (int, error)
+}
+
+// newBatchConn returns nil if batched I/O is not supported for the PacketConn
+func newBatchConn(pconn net.PacketConn) batchConn {
+	if !batchIOSupported {
+		return nil
+	}
+	udpConn, ok := pconn.(*net.UDPConn)
+	if !ok {
+		return nil
+	}
+	if isIPv4Socket(udpConn) {
+		return ipv4.NewPacketConn(udpConn)
+	}
+	return ipv6.NewPacketConn(udpConn)
+}
+
+func isIPv4Socket(pconn net.PacketConn) bool {
+	addr, ok := pconn.LocalAddr().(*net.UDPAddr)
+	return ok && addr.IP.To4() != nil
+}
+
+// canWriteBatch says if a datagram to addr can be part of a batch written on the PacketConn.
+// The batch encodes IPv4 addresses as such, which an IPv6 socket doesn't accept.
+func canWriteBatch(pconn net.PacketConn, addr net.Addr) bool {
+	udpAddr, ok := addr.(*net.UDPAddr)
+	if !ok {
+		return false
+	}
+	return isIPv4Socket(pconn) == (udpAddr.IP.To4() != nil)
+}
+
+// newBatchMessages allocates the messages of a batch, with one buffer each
+func newBatchMessages(n int) []ipv4.Message {
+	ms := make([]ipv4.Message, n)
+	for i := range ms {
+		ms[i].Buffers = make([][]byte, 1)
+	}
+	return ms
+}
+
+// gsoSegments returns how many datagrams at the beginning of the queue can be sent as a single datagram with GSO.
+// The kernel splits it in segments of the size of the first datagram, only the last one can be shorter.
+func gsoSegments(queue [][]byte) int {
+	size := len(queue[0])
+	total := size
+	n := 1
+	for n < len(queue) && n < maxGSOSegments {
+		l := len(queue[n])
+		if l > size || total+l > maxGSOPayloadSize {
+			break
+		}
+		total += l
+		n++
+		if l < size {
+			break
+		}
+	}
+	return n
+}
@@ -0,0 +1,42 @@
+package quic
+
+import (
+	"syscall"
+	"unsafe"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// recvmmsg and sendmmsg are only available on Linux
+const batchIOSupported = true
+
+// parseControlMessages returns the ECN codepoint of a received datagram,
+// and its segment size if it was coalesced by GRO (0 otherwise).
+// It walks the control messages in place, since it's called for every datagram.
+func parseControlMessages(oob []byte) (protocol.ECN, int) {
+	var ecn protocol.ECN
+	var segmentSize int
+	hdrLen := syscall.CmsgLen(0)
+	for len(oob) >= hdrLen {
+		h := (*syscall.Cmsghdr)(unsafe.Pointer(&oob[0]))
+		l := int(h.Len)
+		if l < hdrLen || l > len(oob) {
+			break
+		}
+		data := oob[hdrLen:l]
+		switch {
+		case h.Level == syscall.IPPROTO_UDP && h.Type == udpGRO && len(data) >= 4:
+			segmentSize = int(*(*int32)(unsafe.Pointer(&data[0])))
+		case h.Level == syscall.IPPROTO_IP && h.Type == syscall.IP_TOS && len(data) >= 1:
+			ecn = protocol.ECN(data[0] & ecnMask)
+		case h.Level == syscall.IPPROTO_IPV6 && h.Type == syscall.IPV6_TCLASS && len(data) >= 4:
+			ecn = protocol.ECN(*(*int32)(unsafe.Pointer(&data[0])) & ecnMask)
+		}
+		space := syscall.CmsgSpace(l - hdrLen)
+		if space > len(oob) {
+			break
+		}
+		oob = oob[space:]
+	}
+	return ecn, segmentSize
+}
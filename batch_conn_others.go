@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package quic
+
+import "github.com/lucas-clemente/quic-go/internal/protocol"
+
+// recvmmsg and sendmmsg are only available on Linux
+const batchIOSupported = false
+
+func parseControlMessages(oob []byte) (protocol.ECN, int) { return protocol.ECNNon, 0 }
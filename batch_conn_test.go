@@ -0,0 +1,188 @@
+package quic
+
+import (
+	"net"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Batched I/O", func() {
+	It("only batches UDP sockets", func() {
+		Expect(newBatchConn(&mockPacketConn{})).To(BeNil())
+		udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		defer udpConn.Close()
+		Expect(newBatchConn(udpConn) != nil).To(Equal(batchIOSupported))
+	})
+
+	It("only batches datagrams to an address of the socket's family", func() {
+		ipv4Conn := &mockPacketConn{addr: &net.UDPAddr{IP: net.IPv4zero}}
+		ipv6Conn := &mockPacketConn{addr: &net.UDPAddr{IP: net.IPv6unspecified}}
+		ipv4Addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1234}
+		ipv6Addr := &net.UDPAddr{IP: net.IPv6loopback, Port: 1234}
+		Expect(canWriteBatch(ipv4Conn, ipv4Addr)).To(BeTrue())
+		Expect(canWriteBatch(ipv4Conn, ipv6Addr)).To(BeFalse())
+		Expect(canWriteBatch(ipv6Conn, ipv6Addr)).To(BeTrue())
+		Expect(canWriteBatch(ipv6Conn, ipv4Addr)).To(BeFalse())
+		Expect(canWriteBatch(ipv4Conn, nil)).To(BeFalse())
+	})
+
+	It("reads batches of packets", func() {
+		udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		sender, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		defer sender.Close()
+		pcm := &pconnManager{
+			rcvRawPackets: make(chan *receivedRawPacket),
+			errorConn:     make(chan error, 1),
+		}
+		go pcm.listen(udpConn)
+
+		for _, data := range []string{"foo", "bar", "baz"} {
+			_, err := sender.WriteTo([]byte(data), udpConn.LocalAddr())
+			Expect(err).ToNot(HaveOccurred())
+		}
+		for _, data := range []string{"foo", "bar", "baz"} {
+			var p *receivedRawPacket
+			Eventually(pcm.rcvRawPackets).Should(Receive(&p))
+			Expect(p.rcvPconn).To(Equal(udpConn))
+			Expect(p.remoteAddr.String()).To(Equal(sender.LocalAddr().String()))
+			Expect(string(p.data)).To(Equal(data))
+			Expect(cap(p.data)).To(BeEquivalentTo(protocol.MaxReceivePacketSize))
+			Expect(p.rcvTime).To(BeTemporally("~", time.Now(), time.Second))
+		}
+
+		// closing the socket stops the listener
+		udpConn.Close()
+		Eventually(pcm.errorConn).Should(Receive())
+	})
+
+	It("groups datagrams of the same size for GSO", func() {
+		queue := func(sizes ...int) [][]byte {
+			q := make([][]byte, len(sizes))
+			for i, size := range sizes {
+				q[i] = make([]byte, size)
+			}
+			return q
+		}
+		Expect(gsoSegments(queue(100))).To(Equal(1))
+		Expect(gsoSegments(queue(100, 100, 100))).To(Equal(3))
+		// only the last segment can be shorter
+		Expect(gsoSegments(queue(100, 100, 50, 100))).To(Equal(3))
+		Expect(gsoSegments(queue(100, 200, 100))).To(Equal(1))
+		sizes := func(n, size int) []int {
+			s := make([]int, n)
+			for i := range s {
+				s[i] = size
+			}
+			return s
+		}
+		Expect(gsoSegments(queue(sizes(maxGSOSegments+10, 100)...))).To(Equal(maxGSOSegments))
+		// the datagram must not exceed the maximum UDP payload
+		Expect(gsoSegments(queue(sizes(maxGSOSegments, 1400)...))).To(Equal(maxGSOPayloadSize / 1400))
+	})
+
+	It("splits coalesced packets", func() {
+		pcm := &pconnManager{rcvRawPackets: make(chan *receivedRawPacket, 3)}
+		pconn := &mockPacketConn{}
+		addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1234}
+		rcvTime := time.Now()
+		pcm.splitCoalescedPacket(pconn, addr, []byte("foobarba"), 3, protocol.ECNCE, rcvTime)
+		for _, data := range []string{"foo", "bar", "ba"} {
+			var p *receivedRawPacket
+			Expect(pcm.rcvRawPackets).To(Receive(&p))
+			Expect(p.rcvPconn).To(Equal(pconn))
+			Expect(p.remoteAddr).To(Equal(addr))
+			Expect(string(p.data)).To(Equal(data))
+			Expect(cap(p.data)).To(BeEquivalentTo(protocol.MaxReceivePacketSize))
+			Expect(p.rcvTime).To(Equal(rcvTime))
+			Expect(p.ecn).To(Equal(protocol.ECNCE))
+		}
+		// a packet which wasn't coalesced
+		pcm.splitCoalescedPacket(pconn, addr, []byte("foobar"), 0, protocol.ECNNon, rcvTime)
+		var p *receivedRawPacket
+		Expect(pcm.rcvRawPackets).To(Receive(&p))
+		Expect(string(p.data)).To(Equal("foobar"))
+	})
+
+	It("receives packets coalesced with GRO", func() {
+		udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		defer udpConn.Close()
+		sender, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		defer sender.Close()
+		if !batchIOSupported || !enableGRO(udpConn) || !gsoSupported(sender) {
+			Skip("GSO and GRO are not supported")
+		}
+		Expect(groEnabled(udpConn)).To(BeTrue())
+		pcm := &pconnManager{
+			rcvRawPackets: make(chan *receivedRawPacket),
+			errorConn:     make(chan error, 1),
+		}
+		go pcm.listen(udpConn)
+
+		// the sender uses GSO, so the datagrams are coalesced on the loopback interface
+		c := &conn{pconn: sender, currentAddr: udpConn.LocalAddr()}
+		datagrams := []string{"foo", "bar", "ba"}
+		for _, data := range datagrams {
+			Expect(c.Queue(append(getPacketBuffer(), data...), protocol.ECNNon)).To(Succeed())
+		}
+		Expect(c.Flush()).To(Succeed())
+		for _, data := range datagrams {
+			var p *receivedRawPacket
+			Eventually(pcm.rcvRawPackets).Should(Receive(&p))
+			Expect(p.rcvPconn).To(Equal(udpConn))
+			Expect(p.remoteAddr.String()).To(Equal(sender.LocalAddr().String()))
+			Expect(string(p.data)).To(Equal(data))
+		}
+	})
+
+	for _, network := range []string{"udp4", "udp6"} {
+		network := network
+
+		It("sends and receives ECN codepoints over "+network, func() {
+			ip := net.IPv4(127, 0, 0, 1)
+			if network == "udp6" {
+				ip = net.IPv6loopback
+			}
+			udpConn, err := net.ListenUDP(network, &net.UDPAddr{IP: ip})
+			if err != nil {
+				Skip(network + " is not supported")
+			}
+			defer udpConn.Close()
+			sender, err := net.ListenUDP(network, &net.UDPAddr{IP: ip})
+			Expect(err).ToNot(HaveOccurred())
+			defer sender.Close()
+			if !batchIOSupported || !enableECN(udpConn) {
+				Skip("ECN is not supported")
+			}
+			pcm := &pconnManager{
+				rcvRawPackets: make(chan *receivedRawPacket),
+				errorConn:     make(chan error, 1),
+			}
+			go pcm.listen(udpConn)
+
+			c := &conn{pconn: sender, currentAddr: udpConn.LocalAddr()}
+			// a single datagram, and a batch
+			marks := [][]protocol.ECN{{protocol.ECT0}, {protocol.ECT0, protocol.ECNNon, protocol.ECNCE}}
+			for _, ecns := range marks {
+				for _, ecn := range ecns {
+					Expect(c.Queue(append(getPacketBuffer(), "foo"...), ecn)).To(Succeed())
+				}
+				Expect(c.Flush()).To(Succeed())
+				for _, ecn := range ecns {
+					var p *receivedRawPacket
+					Eventually(pcm.rcvRawPackets).Should(Receive(&p))
+					Expect(string(p.data)).To(Equal("foo"))
+					Expect(p.ecn).To(Equal(ecn))
+				}
+			}
+		})
+	}
+})
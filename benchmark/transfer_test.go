@@ -0,0 +1,65 @@
+package benchmark
+
+import (
+	"crypto/tls"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	quic "github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+)
+
+// BenchmarkTransfer writes on a stream through the public API, on the loopback interface.
+// The packets are read and written in batches where the platform supports it (recvmmsg and sendmmsg on Linux).
+// The comparison with a syscall per datagram is benchmarked in the quic package, since it can't be switched from here.
+func BenchmarkTransfer(b *testing.B) {
+	const chunkSize = 64 * 1024
+
+	ln, err := quic.ListenAddr("localhost:0", testdata.GetTLSConfig(), nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer ln.Close()
+	received := make(chan int64, 1)
+	go func() {
+		sess, err := ln.Accept()
+		if err != nil {
+			received <- 0
+			return
+		}
+		str, err := sess.AcceptStream()
+		if err != nil {
+			received <- 0
+			return
+		}
+		n, _ := io.Copy(ioutil.Discard, str)
+		received <- n
+	}()
+
+	sess, err := quic.DialAddr(ln.Addr().String(), &tls.Config{InsecureSkipVerify: true}, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer sess.Close(nil)
+	str, err := sess.OpenStreamSync()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	data := make([]byte, chunkSize)
+	b.SetBytes(chunkSize)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := str.Write(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+	if err := str.Close(); err != nil {
+		b.Fatal(err)
+	}
+	if n := <-received; n != int64(b.N)*chunkSize {
+		b.Fatalf("received %d bytes, expected %d", n, int64(b.N)*chunkSize)
+	}
+	b.StopTimer()
+}
@@ -196,9 +196,16 @@ func populateClientConfig(config *Config) *Config {
 		RequestConnectionIDTruncation:         config.RequestConnectionIDTruncation,
 		MaxReceiveStreamFlowControlWindow:     maxReceiveStreamFlowControlWindow,
 		MaxReceiveConnectionFlowControlWindow: maxReceiveConnectionFlowControlWindow,
-		KeepAlive:      config.KeepAlive,
-		CacheHandshake: config.CacheHandshake,
-		CreatePaths:    config.CreatePaths,
+		KeepAlive:                             config.KeepAlive,
+		CacheHandshake:                        config.CacheHandshake,
+		CreatePaths:                           config.CreatePaths,
+		BackupInterfaces:                      config.BackupInterfaces,
+		PathCosts:                             config.PathCosts,
+		CongestionControl:                     config.CongestionControl,
+		NewCongestionControl:                  config.NewCongestionControl,
+		HyStartPlusPlus:                       config.HyStartPlusPlus,
+		NewTracer:                             config.NewTracer,
+		NewPacketCapture:                      config.NewPacketCapture,
 	}
 }
 
@@ -351,6 +358,7 @@ func (c *client) handlePacket(rcvRawPacket *receivedRawPacket) {
 		data:         packet[len(packet)-r.Len():],
 		rcvTime:      rcvTime,
 		rcvPconn:     pconn,
+		ecn:          rcvRawPacket.ecn,
 	})
 }
 
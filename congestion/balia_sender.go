@@ -0,0 +1,151 @@
+package congestion
+
+import (
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// baliaMaxAlpha bounds the alpha used on loss, so that a loss removes at most 3/4 of the window
+const baliaMaxAlpha = 1.5
+
+// BaliaSender implements the Balanced Linked Adaptation algorithm of MPTCP
+// (Peng, Walid, Hwang and Low, "Multipath TCP: Analysis, Design and Implementation", IEEE/ACM ToN 2016)
+type BaliaSender struct {
+	coupledRenoSender
+
+	// The paths this one is coupled with, i.e., its SBD group. If nil, it is coupled with all the BALIA senders
+	coupledSet map[protocol.PathID]bool
+
+	// SBD holds the estimates of the shared bottleneck detection, see BaliaSbdDecision
+	SBD Sbd
+	// Whether the last SBD decision found the path bottlenecked
+	sbdBottlenecked bool
+}
+
+// NewBaliaSender makes a new BALIA sender, coupled with the other senders of baliaSenders
+// The caller has to add the sender to baliaSenders
+func NewBaliaSender(baliaSenders *CoupledSenders, rttStats *RTTStats, initialCongestionWindow, initialMaxCongestionWindow protocol.PacketNumber) SendAlgorithmWithDebugInfo {
+	b := &BaliaSender{}
+	b.coupledRenoSender = newCoupledRenoSender(b, baliaSenders, rttStats, initialCongestionWindow, initialMaxCongestionWindow)
+	return b
+}
+
+// SetCoupledSet restricts the coupling to a set of paths, e.g., the SBD group of the path
+// The path of the sender is always coupled with itself. A nil set couples the sender with all the paths again.
+func (b *BaliaSender) SetCoupledSet(pathIDs []protocol.PathID) {
+	if pathIDs == nil {
+		b.coupledSet = nil
+		return
+	}
+	b.coupledSet = make(map[protocol.PathID]bool, len(pathIDs))
+	for _, pathID := range pathIDs {
+		b.coupledSet[pathID] = true
+	}
+}
+
+// coupledSenders returns the senders this one is coupled with, itself included
+func (b *BaliaSender) coupledSenders() map[protocol.PathID]CoupledSender {
+	senders := b.coupledWith()
+	if b.coupledSet == nil {
+		return senders
+	}
+	for pathID, sender := range senders {
+		if !b.coupledSet[pathID] && sender != CoupledSender(b) {
+			delete(senders, pathID)
+		}
+	}
+	return senders
+}
+
+// alpha returns max_k(x_k) / x_r, x being the rates of the coupled paths
+// It is 1 if the path has no RTT sample yet.
+func (b *BaliaSender) alpha() float64 {
+	x := coupledRate(b)
+	if x == 0 {
+		return 1
+	}
+	best := x
+	for _, bs := range b.coupledSenders() {
+		if r := coupledRate(bs); r > best {
+			best = r
+		}
+	}
+	return best / x
+}
+
+// congestionAvoidanceIncrease returns the increase of the congestion window, in packets, when ackedBytes are acked:
+// (x_r / rtt_r) / (sum_k x_k)^2 * (1 + alpha_r) / 2 * (4 + alpha_r) / 5 per acked packet
+func (b *BaliaSender) congestionAvoidanceIncrease(ackedBytes protocol.ByteCount) float64 {
+	acked := float64(ackedBytes) / float64(protocol.DefaultTCPMSS)
+	x := coupledRate(b)
+	if x == 0 {
+		// Uncoupled increase until the path has an RTT sample
+		return acked / float64(b.congestionWindow)
+	}
+	var sumRates float64
+	for _, bs := range b.coupledSenders() {
+		sumRates += coupledRate(bs)
+	}
+	alpha := b.alpha()
+	return acked * x / b.rttStats.SmoothedRTT().Seconds() / (sumRates * sumRates) * (1 + alpha) / 2 * (4 + alpha) / 5
+}
+
+// Alpha returns the current alpha of the path
+func (b *BaliaSender) Alpha() float64 {
+	return b.alpha()
+}
+
+func (b *BaliaSender) OnPacketAcked(ev *AckEvent) {
+	b.SBD.addOWD(ev.OWD)
+	b.coupledRenoSender.OnPacketAcked(ev)
+}
+
+func (b *BaliaSender) OnConnectionMigration() {
+	b.coupledRenoSender.OnConnectionMigration()
+	b.coupledSet = nil
+	b.sbdBottlenecked = false
+	b.SBD.clearEstimates()
+}
+
+// RenoBeta returns the backoff factor after a loss with the current alpha: w_r -= w_r / 2 * min(alpha_r, 1.5)
+func (b *BaliaSender) RenoBeta() float32 {
+	alpha := b.alpha()
+	if alpha > baliaMaxAlpha {
+		alpha = baliaMaxAlpha
+	}
+	return float32(1 - alpha/2)
+}
+
+// BaliaSbdDecision couples every BALIA sender with the paths sharing its bottleneck, as the SBD of OLIA does:
+// the paths without bottleneck are coupled together, the bottlenecked ones with the paths whose estimates compare.
+// Potentially failed paths have no fresh samples and keep their set. It returns the groups of the paths.
+func BaliaSbdDecision(baliaSenders *CoupledSenders) map[protocol.PathID][]protocol.PathID {
+	senders := baliaSenders.Coupled()
+	var notBottlenecked []protocol.PathID
+	bottlenecked := make(map[protocol.PathID]*Sbd)
+	for pathID, sender := range senders {
+		bs := sender.(*BaliaSender)
+		single(&bs.SBD)
+		bs.sbdBottlenecked = bs.SBD.bottlenecked(bs.sbdBottlenecked)
+		if bs.sbdBottlenecked {
+			bottlenecked[pathID] = &bs.SBD
+		} else {
+			notBottlenecked = append(notBottlenecked, pathID)
+		}
+	}
+	groups := make(map[protocol.PathID][]protocol.PathID, len(senders))
+	for _, pathID := range notBottlenecked {
+		groups[pathID] = notBottlenecked
+	}
+	for _, group := range partitionSbd(bottlenecked) {
+		for _, pathID := range group {
+			groups[pathID] = group
+		}
+	}
+	for pathID, group := range groups {
+		senders[pathID].(*BaliaSender).SetCoupledSet(group)
+	}
+	for _, sender := range baliaSenders.All() {
+		sender.(*BaliaSender).SBD.clearEstimates()
+	}
+	return groups
+}
@@ -0,0 +1,142 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BALIA Sender", func() {
+	var (
+		baliaSenders *CoupledSenders
+		sender1      *BaliaSender
+		sender2      *BaliaSender
+	)
+
+	newSender := func(pathID protocol.PathID, rtt time.Duration) *BaliaSender {
+		rttStats := NewRTTStats()
+		if rtt != 0 {
+			rttStats.UpdateRTT(rtt, 0, time.Now())
+		}
+		s := NewBaliaSender(baliaSenders, rttStats, initialCongestionWindowPackets, MaxCongestionWindow).(*BaliaSender)
+		baliaSenders.Add(pathID, s)
+		return s
+	}
+
+	BeforeEach(func() {
+		baliaSenders = NewCoupledSenders()
+		sender1 = newSender(1, 100*time.Millisecond)
+		sender2 = newSender(3, 50*time.Millisecond)
+	})
+
+	It("behaves like Reno with a single path", func() {
+		baliaSenders.Remove(3)
+		Expect(sender1.Alpha()).To(Equal(1.0))
+		Expect(sender1.congestionAvoidanceIncrease(protocol.DefaultTCPMSS)).To(BeNumerically("~", 1/float64(initialCongestionWindowPackets), 1e-9))
+		Expect(sender1.RenoBeta()).To(Equal(float32(0.5)))
+	})
+
+	It("computes alpha from the rates of the paths", func() {
+		// x1 = 10 / 0.1 = 100, x2 = 10 / 0.05 = 200
+		Expect(sender1.Alpha()).To(BeNumerically("~", 2, 1e-9))
+		Expect(sender2.Alpha()).To(BeNumerically("~", 1, 1e-9))
+	})
+
+	It("increases the windows following the BALIA formula", func() {
+		sender1.congestionWindow = 10
+		sender2.congestionWindow = 20
+		// x1 = 100, x2 = 400, alpha1 = 4, alpha2 = 1
+		Expect(sender1.congestionAvoidanceIncrease(protocol.DefaultTCPMSS)).To(BeNumerically("~", 100./0.1/(500*500)*(1+4.)/2*(4+4.)/5, 1e-9))
+		Expect(sender2.congestionAvoidanceIncrease(protocol.DefaultTCPMSS)).To(BeNumerically("~", 400./0.05/(500*500), 1e-9))
+		Expect(sender2.congestionAvoidanceIncrease(2 * protocol.DefaultTCPMSS)).To(BeNumerically("~", 2*400./0.05/(500*500), 1e-9))
+	})
+
+	It("uses the uncoupled increase without RTT sample", func() {
+		sender3 := newSender(5, 0)
+		Expect(sender3.Alpha()).To(Equal(1.0))
+		Expect(sender3.congestionAvoidanceIncrease(protocol.DefaultTCPMSS)).To(BeNumerically("~", 1/float64(initialCongestionWindowPackets), 1e-9))
+	})
+
+	It("only couples with its set", func() {
+		sender3 := newSender(5, 25*time.Millisecond)
+		// x1 = 100, x2 = 200, x3 = 400
+		Expect(sender1.Alpha()).To(BeNumerically("~", 4, 1e-9))
+		sender1.SetCoupledSet([]protocol.PathID{1, 3})
+		sender2.SetCoupledSet([]protocol.PathID{1, 3})
+		sender3.SetCoupledSet([]protocol.PathID{5})
+		Expect(sender1.Alpha()).To(BeNumerically("~", 2, 1e-9))
+		Expect(sender3.Alpha()).To(BeNumerically("~", 1, 1e-9))
+		Expect(sender3.congestionAvoidanceIncrease(protocol.DefaultTCPMSS)).To(BeNumerically("~", 1/float64(initialCongestionWindowPackets), 1e-9))
+		sender1.SetCoupledSet(nil)
+		Expect(sender1.Alpha()).To(BeNumerically("~", 4, 1e-9))
+	})
+
+	It("couples the paths sharing a bottleneck, as decided by the SBD", func() {
+		sender3 := newSender(5, 25*time.Millisecond)
+		// Paths 1 and 3 have no samples, and thus no bottleneck. Path 5 loses half of its packets.
+		sender3.OnPacketAcked(&AckEvent{PacketNumber: 1, Bytes: protocol.DefaultTCPMSS, OWD: 10 * time.Millisecond})
+		sender3.SBD.Pac_loss1 = [2]uint64{0, 50}
+		sender3.SBD.Pac_ack = [2]uint64{0, 100}
+		groups := BaliaSbdDecision(baliaSenders)
+		Expect(groups).To(HaveLen(3))
+		Expect(groups[1]).To(ConsistOf(protocol.PathID(1), protocol.PathID(3)))
+		Expect(groups[3]).To(ConsistOf(protocol.PathID(1), protocol.PathID(3)))
+		Expect(groups[5]).To(ConsistOf(protocol.PathID(5)))
+		Expect(sender1.Alpha()).To(BeNumerically("~", 2, 1e-9))
+		// Path 5 is on its own, as with Reno
+		Expect(sender3.congestionAvoidanceIncrease(protocol.DefaultTCPMSS)).To(BeNumerically("~", 1/float64(sender3.congestionWindow), 1e-9))
+		// A new SBD interval starts
+		Expect(sender3.SBD.owd[0]).To(BeEmpty())
+	})
+
+	It("doesn't couple with closed and potentially failed paths", func() {
+		sender3 := newSender(5, 25*time.Millisecond)
+		baliaSenders.SetPotentiallyFailed(5, true)
+		Expect(sender1.Alpha()).To(BeNumerically("~", 2, 1e-9))
+		Expect(sender3.Alpha()).To(BeNumerically("~", 1, 1e-9))
+		baliaSenders.SetPotentiallyFailed(5, false)
+		Expect(sender1.Alpha()).To(BeNumerically("~", 4, 1e-9))
+		baliaSenders.Remove(5)
+		Expect(sender1.Alpha()).To(BeNumerically("~", 2, 1e-9))
+	})
+
+	It("grows the window in congestion avoidance", func() {
+		sender2.slowstartThreshold = 10
+		bytesInFlight := sender2.GetCongestionWindow()
+		var pn protocol.PacketNumber
+		for sender2.congestionWindow == 10 {
+			pn++
+			sender2.OnPacketAcked(&AckEvent{PacketNumber: pn, Bytes: protocol.DefaultTCPMSS, BytesInFlight: bytesInFlight, OWD: UnknownOWD})
+			Expect(pn).To(BeNumerically("<", 1000))
+		}
+		Expect(sender2.congestionWindow).To(Equal(protocol.PacketNumber(11)))
+	})
+
+	It("halves the window of the fastest path on loss", func() {
+		sender2.congestionWindow = 20
+		sender2.OnPacketSent(time.Now(), 0, 1, protocol.DefaultTCPMSS, true)
+		sender2.OnPacketLost(&LossEvent{PacketNumber: 1, Bytes: protocol.DefaultTCPMSS, BytesInFlight: 20 * protocol.DefaultTCPMSS})
+		Expect(sender2.congestionWindow).To(Equal(protocol.PacketNumber(10)))
+		Expect(sender2.SlowstartThreshold()).To(Equal(protocol.PacketNumber(10)))
+	})
+
+	It("decreases the window of slower paths more, at most by 3/4", func() {
+		sender1.congestionWindow = 20
+		sender2.congestionWindow = 40
+		// alpha1 = 400 / 200 = 2, capped at 1.5
+		Expect(sender1.RenoBeta()).To(Equal(float32(0.25)))
+		sender1.OnPacketSent(time.Now(), 0, 1, protocol.DefaultTCPMSS, true)
+		sender1.OnPacketLost(&LossEvent{PacketNumber: 1, Bytes: protocol.DefaultTCPMSS, BytesInFlight: 20 * protocol.DefaultTCPMSS})
+		Expect(sender1.congestionWindow).To(Equal(protocol.PacketNumber(5)))
+	})
+
+	It("enforces the minimum window", func() {
+		sender1.congestionWindow = 3
+		sender2.congestionWindow = 40
+		sender1.OnPacketSent(time.Now(), 0, 1, protocol.DefaultTCPMSS, true)
+		sender1.OnPacketLost(&LossEvent{PacketNumber: 1, Bytes: protocol.DefaultTCPMSS, BytesInFlight: 3 * protocol.DefaultTCPMSS})
+		Expect(sender1.congestionWindow).To(Equal(defaultMinimumCongestionWindow))
+	})
+})
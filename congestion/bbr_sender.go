@@ -0,0 +1,446 @@
+package congestion
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// The modes of the BBR state machine
+type bbrMode int
+
+const (
+	// Exponential growth until the bottleneck bandwidth is found
+	bbrStartup bbrMode = iota
+	// Drain the queue created during startup
+	bbrDrain
+	// Cruise at the bottleneck bandwidth, probing for more once in a while
+	bbrProbeBW
+	// Drain the queues to measure the minimum RTT
+	bbrProbeRTT
+)
+
+const (
+	// bbrHighGain is 2/ln(2), the smallest gain doubling the sending rate every round trip
+	bbrHighGain  = 2.885
+	bbrDrainGain = 1 / bbrHighGain
+	// The congestion window gain in ProbeBW, to handle delayed and stretched ACKs
+	bbrCwndGain = 2
+	// Number of round trips during which the bandwidth samples are kept
+	bbrBandwidthWindow = 10
+	// Time during which a minimum RTT sample is kept before probing again
+	bbrMinRTTWindow = 10 * time.Second
+	// Minimum time spent in ProbeRTT
+	bbrProbeRTTDuration = 200 * time.Millisecond
+	// The bandwidth has to grow by 25% in a round trip to stay in startup
+	bbrFullBandwidthThreshold = 1.25
+	// Number of round trips without growth after which the pipe is considered full
+	bbrFullBandwidthRounds = 3
+	// Minimum congestion window in packets, also used in ProbeRTT
+	bbrMinCongestionWindow = 4
+)
+
+// The pacing gains of the ProbeBW cycle, each used during one minimum RTT
+var bbrPacingGainCycle = [...]float64{1.25, 0.75, 1, 1, 1, 1, 1, 1}
+
+// BbrSender implements BBR v1 (draft-cardwell-iccrg-bbr-congestion-control-00)
+// It follows the Linux implementation, and needs a delivery rate sample for each ACK
+type BbrSender struct {
+	clock    Clock
+	rttStats *RTTStats
+	mode     bbrMode
+
+	// Maximum delivery rate in bits per second over the last bbrBandwidthWindow round trips
+	maxBandwidth utils.WindowedMax
+	// Number of round trips since the start of the connection
+	roundCount uint64
+	// Bytes delivered when the current round trip ends
+	nextRoundDelivered protocol.ByteCount
+	roundStart         bool
+
+	minRTT          time.Duration
+	minRTTTimestamp time.Time
+	// Time at which ProbeRTT ends, zero until the inflight went down to the minimum window
+	probeRTTDoneTime  time.Time
+	probeRTTRoundDone bool
+
+	pacingGain float64
+	cwndGain   float64
+	// Current phase of the ProbeBW cycle, and time it started
+	cycleIndex int
+	cycleStart time.Time
+
+	// Bandwidth used to detect that the pipe is full
+	fullBandwidth        Bandwidth
+	fullBandwidthRounds  int
+	fullBandwidthReached bool
+
+	congestionWindow protocol.ByteCount
+	// Congestion window saved before a loss episode or ProbeRTT, restored afterwards
+	priorCongestionWindow protocol.ByteCount
+	// Bytes lost since the last rate sample
+	lostBytes protocol.ByteCount
+	// Packet conservation during the first round trip of a recovery episode
+	packetConservation bool
+	// Start a new round trip with the next sample, for packet conservation
+	restartRound bool
+
+	largestSentPacketNumber  protocol.PacketNumber
+	largestAckedPacketNumber protocol.PacketNumber
+	// The loss episode ends once a packet sent after its start is acked
+	endOfRecovery protocol.PacketNumber
+
+	initialCongestionWindow protocol.ByteCount
+	maxCongestionWindow     protocol.ByteCount
+}
+
+// NewBbrSender makes a new BBR sender
+func NewBbrSender(clock Clock, rttStats *RTTStats, initialCongestionWindow, initialMaxCongestionWindow protocol.PacketNumber) SendAlgorithmWithDebugInfo {
+	b := &BbrSender{
+		clock:                   clock,
+		rttStats:                rttStats,
+		initialCongestionWindow: protocol.ByteCount(initialCongestionWindow) * protocol.DefaultTCPMSS,
+		maxCongestionWindow:     protocol.ByteCount(initialMaxCongestionWindow) * protocol.DefaultTCPMSS,
+	}
+	b.reset()
+	return b
+}
+
+func (b *BbrSender) reset() {
+	b.mode = bbrStartup
+	b.maxBandwidth = utils.WindowedMax{}
+	b.roundCount = 0
+	b.nextRoundDelivered = 0
+	b.roundStart = false
+	b.minRTT = 0
+	b.minRTTTimestamp = b.clock.Now()
+	b.probeRTTDoneTime = time.Time{}
+	b.probeRTTRoundDone = false
+	b.pacingGain = bbrHighGain
+	b.cwndGain = bbrHighGain
+	b.cycleIndex = 0
+	b.cycleStart = time.Time{}
+	b.fullBandwidth = 0
+	b.fullBandwidthRounds = 0
+	b.fullBandwidthReached = false
+	b.congestionWindow = b.initialCongestionWindow
+	b.priorCongestionWindow = 0
+	b.lostBytes = 0
+	b.packetConservation = false
+	b.restartRound = false
+	b.largestSentPacketNumber = 0
+	b.largestAckedPacketNumber = 0
+	b.endOfRecovery = 0
+}
+
+func (b *BbrSender) minCongestionWindow() protocol.ByteCount {
+	return bbrMinCongestionWindow * protocol.DefaultTCPMSS
+}
+
+// bdp returns the bandwidth-delay product multiplied by gain, in bytes
+func (b *BbrSender) bdp(bw Bandwidth, gain float64) protocol.ByteCount {
+	// Without RTT sample, the initial window is the best guess
+	if b.minRTT == 0 {
+		return b.initialCongestionWindow
+	}
+	return protocol.ByteCount(gain * float64(bw/BytesPerSecond) * b.minRTT.Seconds())
+}
+
+// targetCongestionWindow is the congestion window BBR aims at with the gain gain
+func (b *BbrSender) targetCongestionWindow(gain float64) protocol.ByteCount {
+	// Allow a few more packets, to keep the pipe full when the ACKs are stretched
+	return b.bdp(Bandwidth(b.maxBandwidth.Get()), gain) + 3*protocol.DefaultTCPMSS
+}
+
+// PacingRate returns the rate at which the packets should be paced
+func (b *BbrSender) PacingRate() Bandwidth {
+	bw := Bandwidth(b.maxBandwidth.Get())
+	if bw == 0 {
+		// Pace the initial window over the RTT, or 1ms without RTT sample
+		rtt := b.rttStats.SmoothedRTT()
+		if rtt == 0 {
+			rtt = time.Millisecond
+		}
+		bw = BandwidthFromDelta(b.initialCongestionWindow, rtt)
+	}
+	return Bandwidth(b.pacingGain * float64(bw))
+}
+
+// OnRateSample updates the model of the path and the congestion window
+func (b *BbrSender) OnRateSample(rs *RateSample) {
+	b.updateBandwidth(rs)
+	b.updateCyclePhase(rs)
+	b.checkFullBandwidthReached(rs)
+	b.checkDrain(rs)
+	b.updateMinRTT(rs)
+	b.setCongestionWindow(rs)
+	b.lostBytes = 0
+}
+
+func (b *BbrSender) updateBandwidth(rs *RateSample) {
+	delivered := rs.PriorDelivered + rs.Delivered
+	b.roundStart = false
+	if b.restartRound {
+		b.restartRound = false
+		b.nextRoundDelivered = delivered
+	} else if rs.PriorDelivered >= b.nextRoundDelivered {
+		b.nextRoundDelivered = delivered
+		b.roundCount++
+		b.roundStart = true
+		b.packetConservation = false
+	}
+
+	bw := rs.DeliveryRate()
+	if bw == 0 {
+		return
+	}
+	// Application limited samples underestimate the bandwidth, unless they are larger than the current estimate
+	if !rs.IsAppLimited || uint64(bw) >= b.maxBandwidth.Get() {
+		b.maxBandwidth.Update(bbrBandwidthWindow, b.roundCount, uint64(bw))
+	}
+}
+
+func (b *BbrSender) enterProbeBW() {
+	b.mode = bbrProbeBW
+	b.cwndGain = bbrCwndGain
+	// Start at a random phase, but not at the draining one
+	b.cycleIndex = rand.Intn(len(bbrPacingGainCycle) - 1)
+	if b.cycleIndex >= 1 {
+		b.cycleIndex++
+	}
+	b.cycleStart = b.clock.Now()
+	b.pacingGain = bbrPacingGainCycle[b.cycleIndex]
+}
+
+func (b *BbrSender) updateCyclePhase(rs *RateSample) {
+	if b.mode != bbrProbeBW {
+		return
+	}
+	now := b.clock.Now()
+	fullLength := now.Sub(b.cycleStart) > b.minRTT
+	// The bytes in flight before the ACK
+	inflight := rs.BytesInFlight + rs.AckedBytes
+	bw := Bandwidth(b.maxBandwidth.Get())
+
+	var next bool
+	switch {
+	case b.pacingGain == 1:
+		next = fullLength
+	case b.pacingGain > 1:
+		// Probe until the queue is built or losses occur
+		next = fullLength && (b.lostBytes > 0 || inflight >= b.bdp(bw, b.pacingGain))
+	default:
+		// Drain until the queue is empty
+		next = fullLength || inflight <= b.bdp(bw, 1)
+	}
+	if next {
+		b.cycleIndex = (b.cycleIndex + 1) % len(bbrPacingGainCycle)
+		b.cycleStart = now
+		b.pacingGain = bbrPacingGainCycle[b.cycleIndex]
+	}
+}
+
+func (b *BbrSender) checkFullBandwidthReached(rs *RateSample) {
+	if b.fullBandwidthReached || !b.roundStart || rs.IsAppLimited {
+		return
+	}
+	bw := Bandwidth(b.maxBandwidth.Get())
+	if float64(bw) >= float64(b.fullBandwidth)*bbrFullBandwidthThreshold {
+		b.fullBandwidth = bw
+		b.fullBandwidthRounds = 0
+		return
+	}
+	b.fullBandwidthRounds++
+	b.fullBandwidthReached = b.fullBandwidthRounds >= bbrFullBandwidthRounds
+}
+
+func (b *BbrSender) checkDrain(rs *RateSample) {
+	if b.mode == bbrStartup && b.fullBandwidthReached {
+		b.mode = bbrDrain
+		b.pacingGain = bbrDrainGain
+		b.cwndGain = bbrHighGain
+	}
+	if b.mode == bbrDrain && rs.BytesInFlight <= b.bdp(Bandwidth(b.maxBandwidth.Get()), 1) {
+		b.enterProbeBW()
+	}
+}
+
+func (b *BbrSender) saveCongestionWindow() {
+	if b.InRecovery() || b.mode == bbrProbeRTT {
+		b.priorCongestionWindow = utils.MaxByteCount(b.priorCongestionWindow, b.congestionWindow)
+	} else {
+		b.priorCongestionWindow = b.congestionWindow
+	}
+}
+
+func (b *BbrSender) updateMinRTT(rs *RateSample) {
+	now := b.clock.Now()
+	expired := now.After(b.minRTTTimestamp.Add(bbrMinRTTWindow))
+	if rs.RTT > 0 && (b.minRTT == 0 || rs.RTT < b.minRTT || expired) {
+		b.minRTT = rs.RTT
+		b.minRTTTimestamp = now
+	}
+
+	if expired && b.mode != bbrProbeRTT {
+		b.mode = bbrProbeRTT
+		b.pacingGain = 1
+		b.cwndGain = 1
+		b.saveCongestionWindow()
+		b.probeRTTDoneTime = time.Time{}
+	}
+	if b.mode != bbrProbeRTT {
+		return
+	}
+	if b.probeRTTDoneTime.IsZero() {
+		if rs.BytesInFlight <= b.minCongestionWindow() {
+			b.probeRTTDoneTime = now.Add(bbrProbeRTTDuration)
+			b.probeRTTRoundDone = false
+			b.restartRound = true
+		}
+		return
+	}
+	if b.roundStart {
+		b.probeRTTRoundDone = true
+	}
+	if b.probeRTTRoundDone && !now.Before(b.probeRTTDoneTime) {
+		b.minRTTTimestamp = now
+		b.congestionWindow = utils.MaxByteCount(b.congestionWindow, b.priorCongestionWindow)
+		if b.fullBandwidthReached {
+			b.enterProbeBW()
+		} else {
+			b.mode = bbrStartup
+			b.pacingGain = bbrHighGain
+			b.cwndGain = bbrHighGain
+		}
+	}
+}
+
+func (b *BbrSender) setCongestionWindow(rs *RateSample) {
+	if b.InRecovery() && b.largestAckedPacketNumber > b.endOfRecovery {
+		// End of the loss episode
+		b.congestionWindow = utils.MaxByteCount(b.congestionWindow, b.priorCongestionWindow)
+		b.packetConservation = false
+		b.endOfRecovery = 0
+	}
+
+	if b.packetConservation {
+		// Send one packet per packet acked
+		b.congestionWindow = utils.MaxByteCount(b.congestionWindow, rs.BytesInFlight+rs.AckedBytes)
+	} else {
+		target := b.targetCongestionWindow(b.cwndGain)
+		if b.fullBandwidthReached {
+			b.congestionWindow = utils.MinByteCount(b.congestionWindow+rs.AckedBytes, target)
+		} else if b.congestionWindow < target || rs.PriorDelivered+rs.Delivered < b.initialCongestionWindow {
+			b.congestionWindow += rs.AckedBytes
+		}
+	}
+	b.congestionWindow = utils.MinByteCount(b.congestionWindow, b.maxCongestionWindow)
+	b.congestionWindow = utils.MaxByteCount(b.congestionWindow, b.minCongestionWindow())
+	if b.mode == bbrProbeRTT {
+		b.congestionWindow = utils.MinByteCount(b.congestionWindow, b.minCongestionWindow())
+	}
+}
+
+func (b *BbrSender) OnPacketSent(sentTime time.Time, bytesInFlight protocol.ByteCount, packetNumber protocol.PacketNumber, bytes protocol.ByteCount, isRetransmittable bool) bool {
+	if !isRetransmittable {
+		return false
+	}
+	b.largestSentPacketNumber = packetNumber
+	return true
+}
+
+func (b *BbrSender) GetCongestionWindow() protocol.ByteCount {
+	return b.congestionWindow
+}
+
+// MaybeExitSlowStart is a no-op, BBR leaves startup when the bandwidth stops growing
+func (b *BbrSender) MaybeExitSlowStart() {}
+
+// OnPacketAcked only tracks the acked packets, the window is updated with the rate sample of the ACK
+func (b *BbrSender) OnPacketAcked(ev *AckEvent) {
+	b.largestAckedPacketNumber = utils.MaxPacketNumber(ev.PacketNumber, b.largestAckedPacketNumber)
+}
+
+func (b *BbrSender) OnPacketLost(ev *LossEvent) {
+	b.lostBytes += ev.Bytes
+	if b.InRecovery() {
+		b.congestionWindow = utils.MaxByteCount(b.congestionWindow-utils.MinByteCount(ev.Bytes, b.congestionWindow), protocol.DefaultTCPMSS)
+		return
+	}
+	// Start a loss episode, with packet conservation during the next round trip
+	b.saveCongestionWindow()
+	b.endOfRecovery = b.largestSentPacketNumber
+	b.packetConservation = true
+	b.restartRound = true
+	b.congestionWindow = utils.MaxByteCount(ev.BytesInFlight, protocol.DefaultTCPMSS)
+}
+
+// OnCongestionEvent is a no-op, BBR follows its model of the path rather than the congestion signals
+func (b *BbrSender) OnCongestionEvent(ev *CongestionEvent) {}
+
+// SetNumEmulatedConnections is a no-op for BBR
+func (b *BbrSender) SetNumEmulatedConnections(n int) {}
+
+// OnRetransmissionTimeout is called on an retransmission timeout
+func (b *BbrSender) OnRetransmissionTimeout(packetsRetransmitted bool) {
+	if !packetsRetransmitted {
+		return
+	}
+	b.saveCongestionWindow()
+	b.endOfRecovery = b.largestSentPacketNumber
+	b.packetConservation = false
+	b.congestionWindow = b.minCongestionWindow()
+}
+
+func (b *BbrSender) OnConnectionMigration() {
+	b.reset()
+}
+
+// RetransmissionDelay gives the RTO retransmission time
+func (b *BbrSender) RetransmissionDelay() time.Duration {
+	if b.rttStats.SmoothedRTT() == 0 {
+		return 0
+	}
+	return b.rttStats.SmoothedRTT() + b.rttStats.MeanDeviation()*4
+}
+
+func (b *BbrSender) SmoothedRTT() time.Duration {
+	return b.rttStats.SmoothedRTT()
+}
+
+// SetSlowStartLargeReduction is a no-op for BBR
+func (b *BbrSender) SetSlowStartLargeReduction(enabled bool) {}
+
+// BandwidthEstimate returns the estimated bottleneck bandwidth
+func (b *BbrSender) BandwidthEstimate() Bandwidth {
+	return Bandwidth(b.maxBandwidth.Get())
+}
+
+// HybridSlowStart returns nil, BBR doesn't use hybrid slow start
+func (b *BbrSender) HybridSlowStart() *HybridSlowStart {
+	return nil
+}
+
+// SlowstartThreshold returns the maximum window during startup, and the congestion window afterwards
+func (b *BbrSender) SlowstartThreshold() protocol.PacketNumber {
+	if b.InSlowStart() {
+		return protocol.PacketNumber(b.maxCongestionWindow / protocol.DefaultTCPMSS)
+	}
+	return protocol.PacketNumber(b.congestionWindow / protocol.DefaultTCPMSS)
+}
+
+// RenoBeta returns 1, BBR doesn't reduce its window multiplicatively on loss
+func (b *BbrSender) RenoBeta() float32 {
+	return 1
+}
+
+func (b *BbrSender) InRecovery() bool {
+	return b.endOfRecovery != 0
+}
+
+// InSlowStart returns true during startup
+func (b *BbrSender) InSlowStart() bool {
+	return b.mode == bbrStartup
+}
@@ -0,0 +1,210 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BBR Sender", func() {
+	const (
+		rtt        = 100 * time.Millisecond
+		roundBytes = 50 * protocol.DefaultTCPMSS
+	)
+
+	var (
+		sender    *BbrSender
+		clock     mockClock
+		delivered protocol.ByteCount
+	)
+
+	// bandwidth of roundBytes delivered per RTT
+	bandwidth := BandwidthFromDelta(roundBytes, rtt)
+
+	BeforeEach(func() {
+		clock = mockClock{}
+		delivered = 0
+		rttStats := NewRTTStats()
+		sender = NewBbrSender(&clock, rttStats, initialCongestionWindowPackets, MaxCongestionWindow).(*BbrSender)
+	})
+
+	// deliverSample feeds an ACK for bytes delivered during the last RTT, received elapsed after the previous one
+	deliverSample := func(bytes, bytesInFlight protocol.ByteCount, elapsed, sampleRTT time.Duration) {
+		rs := &RateSample{
+			Delivered:      bytes,
+			Interval:       rtt,
+			PriorDelivered: delivered,
+			RTT:            sampleRTT,
+			AckedBytes:     bytes,
+			BytesInFlight:  bytesInFlight,
+		}
+		delivered += bytes
+		clock.Advance(elapsed)
+		sender.OnRateSample(rs)
+	}
+
+	deliver := func(bytes, bytesInFlight protocol.ByteCount) {
+		deliverSample(bytes, bytesInFlight, rtt, rtt)
+	}
+
+	// fillPipe runs startup until the bandwidth stops growing, keeping the queue built
+	fillPipe := func() {
+		for i := 0; i < 4; i++ {
+			deliver(roundBytes, 3*roundBytes)
+		}
+		Expect(sender.fullBandwidthReached).To(BeTrue())
+	}
+
+	It("starts with the high gain", func() {
+		Expect(sender.InSlowStart()).To(BeTrue())
+		Expect(sender.GetCongestionWindow()).To(Equal(protocol.ByteCount(initialCongestionWindowPackets) * protocol.DefaultTCPMSS))
+		Expect(sender.BandwidthEstimate()).To(BeZero())
+		// the initial window paced over 1ms
+		Expect(sender.PacingRate()).To(BeNumerically("~", bbrHighGain*float64(BandwidthFromDelta(sender.initialCongestionWindow, time.Millisecond)), 1000))
+	})
+
+	It("keeps the maximum bandwidth over the bandwidth window", func() {
+		deliver(roundBytes, 0)
+		Expect(sender.BandwidthEstimate()).To(Equal(bandwidth))
+		Expect(sender.PacingRate()).To(BeNumerically("~", bbrHighGain*float64(bandwidth), 1000))
+		for i := 0; i < bbrBandwidthWindow; i++ {
+			deliver(roundBytes/2, 0)
+			Expect(sender.BandwidthEstimate()).To(Equal(bandwidth))
+		}
+		deliver(roundBytes/2, 0)
+		Expect(sender.BandwidthEstimate()).To(Equal(bandwidth / 2))
+	})
+
+	It("ignores application limited samples below the estimate", func() {
+		deliver(roundBytes, 0)
+		sender.OnRateSample(&RateSample{Delivered: 2 * roundBytes, Interval: rtt, PriorDelivered: delivered, IsAppLimited: true})
+		Expect(sender.BandwidthEstimate()).To(Equal(2 * bandwidth))
+		sender.OnRateSample(&RateSample{Delivered: roundBytes / 10, Interval: rtt, PriorDelivered: delivered, IsAppLimited: true})
+		Expect(sender.BandwidthEstimate()).To(Equal(2 * bandwidth))
+	})
+
+	It("stays in startup while the bandwidth grows", func() {
+		for bytes := protocol.DefaultTCPMSS; bytes < roundBytes; bytes *= 2 {
+			deliver(bytes, 0)
+		}
+		Expect(sender.InSlowStart()).To(BeTrue())
+		Expect(sender.fullBandwidthReached).To(BeFalse())
+	})
+
+	It("grows the window by the acked bytes in startup", func() {
+		cwnd := sender.GetCongestionWindow()
+		deliver(5*protocol.DefaultTCPMSS, 0)
+		Expect(sender.GetCongestionWindow()).To(Equal(cwnd + 5*protocol.DefaultTCPMSS))
+	})
+
+	It("drains the queue once the bandwidth stopped growing, then probes the bandwidth", func() {
+		fillPipe()
+		Expect(sender.mode).To(Equal(bbrDrain))
+		Expect(sender.pacingGain).To(Equal(bbrDrainGain))
+		Expect(sender.InSlowStart()).To(BeFalse())
+		deliver(roundBytes, roundBytes)
+		Expect(sender.mode).To(Equal(bbrProbeBW))
+		Expect(sender.pacingGain).To(Or(Equal(1.25), Equal(1.0)))
+		Expect(sender.cwndGain).To(BeEquivalentTo(bbrCwndGain))
+	})
+
+	It("bounds the window in ProbeBW", func() {
+		fillPipe()
+		deliver(roundBytes, roundBytes)
+		for i := 0; i < 10; i++ {
+			deliver(roundBytes, roundBytes)
+		}
+		Expect(sender.GetCongestionWindow()).To(Equal(2*roundBytes + 3*protocol.DefaultTCPMSS))
+	})
+
+	It("cycles the pacing gain", func() {
+		fillPipe()
+		deliver(roundBytes, roundBytes)
+		sender.cycleIndex = 0
+		sender.pacingGain = bbrPacingGainCycle[0]
+		sender.cycleStart = clock.Now()
+		// not probing for long enough
+		deliverSample(roundBytes, 2*roundBytes, rtt/2, rtt)
+		Expect(sender.pacingGain).To(Equal(1.25))
+		// probed for a min RTT, but the queue isn't built yet
+		deliver(roundBytes, 0)
+		Expect(sender.pacingGain).To(Equal(1.25))
+		deliver(roundBytes, 2*roundBytes)
+		Expect(sender.pacingGain).To(Equal(0.75))
+		Expect(sender.PacingRate()).To(BeNumerically("~", 0.75*float64(bandwidth), 1000))
+		// the queue is drained
+		deliverSample(roundBytes, 0, rtt/2, rtt)
+		Expect(sender.pacingGain).To(Equal(1.0))
+		for i := 0; i < 6; i++ {
+			deliverSample(roundBytes, roundBytes, rtt+time.Millisecond, rtt)
+		}
+		Expect(sender.pacingGain).To(Equal(1.25))
+	})
+
+	It("probes the min RTT when it expires", func() {
+		deliver(roundBytes, 0)
+		cwnd := sender.GetCongestionWindow()
+		clock.Advance(bbrMinRTTWindow)
+		deliverSample(roundBytes, roundBytes, rtt, 2*rtt)
+		Expect(sender.mode).To(Equal(bbrProbeRTT))
+		Expect(sender.minRTT).To(Equal(2 * rtt))
+		Expect(sender.GetCongestionWindow()).To(Equal(bbrMinCongestionWindow * protocol.DefaultTCPMSS))
+		deliver(roundBytes, roundBytes)
+		Expect(sender.probeRTTDoneTime.IsZero()).To(BeTrue())
+		Expect(sender.minRTT).To(Equal(rtt))
+		// the inflight reached the minimum window
+		deliver(roundBytes, bbrMinCongestionWindow*protocol.DefaultTCPMSS)
+		Expect(sender.probeRTTDoneTime).To(Equal(clock.Now().Add(bbrProbeRTTDuration)))
+		deliver(roundBytes, 0)
+		Expect(sender.mode).To(Equal(bbrProbeRTT))
+		deliver(roundBytes, 0)
+		Expect(sender.mode).ToNot(Equal(bbrProbeRTT))
+		Expect(sender.GetCongestionWindow()).To(BeNumerically(">=", cwnd))
+	})
+
+	It("conserves packets during the first round of a loss episode, and restores the window", func() {
+		cwnd := sender.GetCongestionWindow()
+		for pn := protocol.PacketNumber(1); pn <= 10; pn++ {
+			sender.OnPacketSent(clock.Now(), 0, pn, protocol.DefaultTCPMSS, true)
+		}
+		sender.OnPacketLost(&LossEvent{PacketNumber: 1, Bytes: protocol.DefaultTCPMSS, BytesInFlight: 5 * protocol.DefaultTCPMSS})
+		Expect(sender.InRecovery()).To(BeTrue())
+		Expect(sender.GetCongestionWindow()).To(Equal(5 * protocol.DefaultTCPMSS))
+		sender.OnPacketLost(&LossEvent{PacketNumber: 2, Bytes: protocol.DefaultTCPMSS, BytesInFlight: 4 * protocol.DefaultTCPMSS})
+		Expect(sender.GetCongestionWindow()).To(Equal(4 * protocol.DefaultTCPMSS))
+		sender.OnPacketAcked(&AckEvent{PacketNumber: 3, Bytes: protocol.DefaultTCPMSS, BytesInFlight: 5 * protocol.DefaultTCPMSS, OWD: UnknownOWD})
+		sender.OnPacketAcked(&AckEvent{PacketNumber: 4, Bytes: protocol.DefaultTCPMSS, BytesInFlight: 4 * protocol.DefaultTCPMSS, OWD: UnknownOWD})
+		deliver(2*protocol.DefaultTCPMSS, 4*protocol.DefaultTCPMSS)
+		Expect(sender.GetCongestionWindow()).To(Equal(6 * protocol.DefaultTCPMSS))
+		sender.OnPacketSent(clock.Now(), 0, 11, protocol.DefaultTCPMSS, true)
+		sender.OnPacketAcked(&AckEvent{PacketNumber: 11, Bytes: protocol.DefaultTCPMSS, OWD: UnknownOWD})
+		deliver(protocol.DefaultTCPMSS, 0)
+		Expect(sender.InRecovery()).To(BeFalse())
+		Expect(sender.GetCongestionWindow()).To(BeNumerically(">=", cwnd))
+	})
+
+	It("doesn't react to CE marks", func() {
+		cwnd := sender.GetCongestionWindow()
+		sender.OnPacketSent(clock.Now(), 0, 1, protocol.DefaultTCPMSS, true)
+		sender.OnCongestionEvent(&CongestionEvent{PacketNumber: 1, BytesInFlight: 0})
+		Expect(sender.InRecovery()).To(BeFalse())
+		Expect(sender.GetCongestionWindow()).To(Equal(cwnd))
+	})
+
+	It("uses the minimum window after a retransmission timeout", func() {
+		sender.OnPacketSent(clock.Now(), 0, 1, protocol.DefaultTCPMSS, true)
+		sender.OnRetransmissionTimeout(true)
+		Expect(sender.GetCongestionWindow()).To(Equal(bbrMinCongestionWindow * protocol.DefaultTCPMSS))
+		Expect(sender.InRecovery()).To(BeTrue())
+	})
+
+	It("resets on connection migration", func() {
+		fillPipe()
+		sender.OnConnectionMigration()
+		Expect(sender.InSlowStart()).To(BeTrue())
+		Expect(sender.BandwidthEstimate()).To(BeZero())
+		Expect(sender.GetCongestionWindow()).To(Equal(sender.initialCongestionWindow))
+	})
+})
@@ -0,0 +1,297 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// coupledRenoAlgorithm is implemented by the coupled senders built on coupledRenoSender, e.g. LIA and BALIA
+type coupledRenoAlgorithm interface {
+	CoupledSender
+	// congestionAvoidanceIncrease returns the increase of the congestion window, in packets, when ackedBytes are acked
+	congestionAvoidanceIncrease(ackedBytes protocol.ByteCount) float64
+	// RenoBeta returns the backoff factor after a loss
+	RenoBeta() float32
+}
+
+// coupledRenoSender is the part shared by the coupled senders that follow Reno, apart from their window increase
+// in congestion avoidance and their backoff factor: slow start, PRR, the NewReno loss recovery and the bookkeeping
+// of the coupled senders.
+type coupledRenoSender struct {
+	algorithm coupledRenoAlgorithm
+	// All the senders of the session using the same algorithm, this one included
+	senders *CoupledSenders
+
+	slowStart SlowStart
+	prr       PrrSender
+	rttStats  *RTTStats
+	stats     connectionStats
+
+	// Track the largest packet that has been sent.
+	largestSentPacketNumber protocol.PacketNumber
+
+	// Track the largest packet that has been acked.
+	largestAckedPacketNumber protocol.PacketNumber
+
+	// Track the largest packet number outstanding when a CWND cutback occurs.
+	largestSentAtLastCutback protocol.PacketNumber
+
+	// Congestion window in packets.
+	congestionWindow protocol.PacketNumber
+
+	// Slow start congestion window in packets, aka ssthresh.
+	slowstartThreshold protocol.PacketNumber
+
+	// Whether the last loss event caused us to exit slowstart.
+	// Used for stats collection of slowstartPacketsLost
+	lastCutbackExitedSlowstart bool
+
+	// When true, exit slow start with large cutback of congestion window.
+	slowStartLargeReduction bool
+
+	// Minimum congestion window in packets.
+	minCongestionWindow protocol.PacketNumber
+
+	// Maximum number of outstanding packets for tcp.
+	maxTCPCongestionWindow protocol.PacketNumber
+
+	// Fraction of packet the congestion window grew by since its last increment
+	congestionWindowCount float64
+
+	initialCongestionWindow    protocol.PacketNumber
+	initialMaxCongestionWindow protocol.PacketNumber
+}
+
+func newCoupledRenoSender(algorithm coupledRenoAlgorithm, senders *CoupledSenders, rttStats *RTTStats, initialCongestionWindow, initialMaxCongestionWindow protocol.PacketNumber) coupledRenoSender {
+	return coupledRenoSender{
+		algorithm:                  algorithm,
+		senders:                    senders,
+		rttStats:                   rttStats,
+		slowStart:                  &HybridSlowStart{},
+		initialCongestionWindow:    initialCongestionWindow,
+		initialMaxCongestionWindow: initialMaxCongestionWindow,
+		congestionWindow:           initialCongestionWindow,
+		minCongestionWindow:        defaultMinimumCongestionWindow,
+		slowstartThreshold:         initialMaxCongestionWindow,
+		maxTCPCongestionWindow:     initialMaxCongestionWindow,
+	}
+}
+
+func (c *coupledRenoSender) coupledState() (protocol.PacketNumber, time.Duration) {
+	return c.congestionWindow, c.rttStats.SmoothedRTT()
+}
+
+// coupledWith returns the senders this one is coupled with, itself included
+func (c *coupledRenoSender) coupledWith() map[protocol.PathID]CoupledSender {
+	return c.senders.coupledWith(c.algorithm)
+}
+
+func (c *coupledRenoSender) OnPacketSent(sentTime time.Time, bytesInFlight protocol.ByteCount, packetNumber protocol.PacketNumber, bytes protocol.ByteCount, isRetransmittable bool) bool {
+	// Only update bytesInFlight for data packets.
+	if !isRetransmittable {
+		return false
+	}
+	if c.InRecovery() {
+		// PRR is used when in recovery.
+		c.prr.OnPacketSent(bytes)
+	}
+	c.largestSentPacketNumber = packetNumber
+	c.slowStart.OnPacketSent(packetNumber)
+	return true
+}
+
+func (c *coupledRenoSender) GetCongestionWindow() protocol.ByteCount {
+	return protocol.ByteCount(c.congestionWindow) * protocol.DefaultTCPMSS
+}
+
+func (c *coupledRenoSender) GetSlowStartThreshold() protocol.ByteCount {
+	return protocol.ByteCount(c.slowstartThreshold) * protocol.DefaultTCPMSS
+}
+
+func (c *coupledRenoSender) ExitSlowstart() {
+	c.slowstartThreshold = c.congestionWindow
+}
+
+func (c *coupledRenoSender) MaybeExitSlowStart() {
+	if c.InSlowStart() && c.slowStart.ShouldExitSlowStart(c.rttStats.LatestRTT(), c.rttStats.MinRTT(), c.GetCongestionWindow()/protocol.DefaultTCPMSS) {
+		c.ExitSlowstart()
+	}
+}
+
+func (c *coupledRenoSender) isCwndLimited(bytesInFlight protocol.ByteCount) bool {
+	congestionWindow := c.GetCongestionWindow()
+	if bytesInFlight >= congestionWindow {
+		return true
+	}
+	availableBytes := congestionWindow - bytesInFlight
+	slowStartLimited := c.InSlowStart() && bytesInFlight > congestionWindow/2
+	return slowStartLimited || availableBytes <= maxBurstBytes
+}
+
+func (c *coupledRenoSender) maybeIncreaseCwnd(ackedBytes protocol.ByteCount, bytesInFlight protocol.ByteCount) {
+	// Do not increase the congestion window unless the sender is close to using
+	// the current window.
+	if !c.isCwndLimited(bytesInFlight) {
+		return
+	}
+	if c.congestionWindow >= c.maxTCPCongestionWindow {
+		return
+	}
+	if c.InSlowStart() {
+		// TCP slow start, exponential growth, increase by one for each ACK.
+		c.congestionWindow += c.slowStart.CongestionWindowIncrease()
+		return
+	}
+	c.congestionWindowCount += c.algorithm.congestionAvoidanceIncrease(ackedBytes)
+	if c.congestionWindowCount >= 1 {
+		increase := protocol.PacketNumber(c.congestionWindowCount)
+		c.congestionWindow = utils.MinPacketNumber(c.maxTCPCongestionWindow, c.congestionWindow+increase)
+		c.congestionWindowCount -= float64(increase)
+	}
+}
+
+func (c *coupledRenoSender) OnPacketAcked(ev *AckEvent) {
+	c.largestAckedPacketNumber = utils.MaxPacketNumber(ev.PacketNumber, c.largestAckedPacketNumber)
+
+	if c.InRecovery() {
+		// PRR is used when in recovery
+		c.prr.OnPacketAcked(ev.Bytes)
+		return
+	}
+	c.maybeIncreaseCwnd(ev.Bytes, ev.BytesInFlight)
+	if c.InSlowStart() {
+		c.slowStart.OnPacketAcked(ev.PacketNumber)
+	}
+}
+
+func (c *coupledRenoSender) OnPacketLost(ev *LossEvent) {
+	// TCP NewReno (RFC6582) says that once a loss occurs, any losses in packets
+	// already sent should be treated as a single loss event, since it's expected.
+	if ev.PacketNumber <= c.largestSentAtLastCutback {
+		if c.lastCutbackExitedSlowstart {
+			c.stats.slowstartPacketsLost++
+			c.stats.slowstartBytesLost += ev.Bytes
+			if c.slowStartLargeReduction {
+				if c.stats.slowstartPacketsLost == 1 || (c.stats.slowstartBytesLost/protocol.DefaultTCPMSS) > (c.stats.slowstartBytesLost-ev.Bytes)/protocol.DefaultTCPMSS {
+					// Reduce congestion window by 1 for every mss of bytes lost.
+					c.congestionWindow = utils.MaxPacketNumber(c.congestionWindow-1, c.minCongestionWindow)
+				}
+				c.slowstartThreshold = c.congestionWindow
+			}
+		}
+		return
+	}
+	c.lastCutbackExitedSlowstart = c.InSlowStart()
+	if c.InSlowStart() {
+		c.stats.slowstartPacketsLost++
+	}
+	c.reduceCongestionWindow(ev.BytesInFlight)
+}
+
+// OnCongestionEvent reduces the window as a loss does, without counting a lost packet
+func (c *coupledRenoSender) OnCongestionEvent(ev *CongestionEvent) {
+	// The CE marks of the packets sent before the last reduction belong to the same congestion event
+	if ev.PacketNumber <= c.largestSentAtLastCutback {
+		return
+	}
+	c.lastCutbackExitedSlowstart = c.InSlowStart()
+	c.reduceCongestionWindow(ev.BytesInFlight)
+}
+
+// reduceCongestionWindow cuts the window on a congestion event, i.e. a loss or CE marks
+func (c *coupledRenoSender) reduceCongestionWindow(bytesInFlight protocol.ByteCount) {
+	c.prr.OnPacketLost(bytesInFlight)
+
+	if c.slowStartLargeReduction && c.InSlowStart() {
+		c.congestionWindow = c.congestionWindow - 1
+	} else {
+		c.congestionWindow = protocol.PacketNumber(float32(c.congestionWindow) * c.algorithm.RenoBeta())
+	}
+	// Enforce a minimum congestion window.
+	if c.congestionWindow < c.minCongestionWindow {
+		c.congestionWindow = c.minCongestionWindow
+	}
+	c.slowstartThreshold = c.congestionWindow
+	c.largestSentAtLastCutback = c.largestSentPacketNumber
+	// reset packet count from congestion avoidance mode. We start
+	// counting again when we're out of recovery.
+	c.congestionWindowCount = 0
+}
+
+// SetNumEmulatedConnections is a no-op, the coupling already accounts for the other paths
+func (c *coupledRenoSender) SetNumEmulatedConnections(n int) {}
+
+// OnRetransmissionTimeout is called on an retransmission timeout
+func (c *coupledRenoSender) OnRetransmissionTimeout(packetsRetransmitted bool) {
+	c.largestSentAtLastCutback = 0
+	if !packetsRetransmitted {
+		return
+	}
+	c.slowStart.Restart()
+	c.slowstartThreshold = c.congestionWindow / 2
+	c.congestionWindow = c.minCongestionWindow
+	c.congestionWindowCount = 0
+}
+
+func (c *coupledRenoSender) OnConnectionMigration() {
+	c.slowStart.Restart()
+	c.prr = PrrSender{}
+	c.largestSentPacketNumber = 0
+	c.largestAckedPacketNumber = 0
+	c.largestSentAtLastCutback = 0
+	c.lastCutbackExitedSlowstart = false
+	c.congestionWindowCount = 0
+	c.congestionWindow = c.initialCongestionWindow
+	c.slowstartThreshold = c.initialMaxCongestionWindow
+	c.maxTCPCongestionWindow = c.initialMaxCongestionWindow
+}
+
+// RetransmissionDelay gives the RTO retransmission time
+func (c *coupledRenoSender) RetransmissionDelay() time.Duration {
+	if c.rttStats.SmoothedRTT() == 0 {
+		return 0
+	}
+	return c.rttStats.SmoothedRTT() + c.rttStats.MeanDeviation()*4
+}
+
+func (c *coupledRenoSender) SmoothedRTT() time.Duration {
+	return c.rttStats.SmoothedRTT()
+}
+
+func (c *coupledRenoSender) SetSlowStartLargeReduction(enabled bool) {
+	c.slowStartLargeReduction = enabled
+}
+
+func (c *coupledRenoSender) BandwidthEstimate() Bandwidth {
+	srtt := c.rttStats.SmoothedRTT()
+	if srtt == 0 {
+		// If we haven't measured an rtt, the bandwidth estimate is unknown.
+		return 0
+	}
+	return BandwidthFromDelta(c.GetCongestionWindow(), srtt)
+}
+
+// HybridSlowStart returns the hybrid slow start instance for testing, nil if another slow start is used
+func (c *coupledRenoSender) HybridSlowStart() *HybridSlowStart {
+	hybridSlowStart, _ := c.slowStart.(*HybridSlowStart)
+	return hybridSlowStart
+}
+
+// SetSlowStart replaces the slow start algorithm, e.g. by HyStart++
+func (c *coupledRenoSender) SetSlowStart(slowStart SlowStart) {
+	c.slowStart = slowStart
+}
+
+func (c *coupledRenoSender) SlowstartThreshold() protocol.PacketNumber {
+	return c.slowstartThreshold
+}
+
+func (c *coupledRenoSender) InRecovery() bool {
+	return c.largestAckedPacketNumber <= c.largestSentAtLastCutback && c.largestAckedPacketNumber != 0
+}
+
+func (c *coupledRenoSender) InSlowStart() bool {
+	return c.GetCongestionWindow() < c.GetSlowStartThreshold()
+}
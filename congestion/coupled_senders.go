@@ -0,0 +1,115 @@
+package congestion
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// A CoupledSender is a sender whose window increase depends on the senders of the other paths of the connection
+type CoupledSender interface {
+	// coupledState returns the congestion window, in packets, and the smoothed RTT of the path
+	coupledState() (protocol.PacketNumber, time.Duration)
+}
+
+// coupledRate returns the sending rate of a coupled sender in packets per second, or 0 without RTT sample
+func coupledRate(sender CoupledSender) float64 {
+	congestionWindow, srtt := sender.coupledState()
+	rtt := srtt.Seconds()
+	if rtt == 0 {
+		return 0
+	}
+	return float64(congestionWindow) / rtt
+}
+
+// CoupledSenders holds the coupled senders of a connection, one per path, all using the same algorithm
+// It is safe for concurrent use, since paths are created by the path manager while ACKs are handled by the session
+type CoupledSenders struct {
+	mutex sync.RWMutex
+
+	senders map[protocol.PathID]CoupledSender
+	// Potentially failed paths are not coupled with the others until they recover
+	potentiallyFailed map[protocol.PathID]bool
+}
+
+// NewCoupledSenders makes an empty set of coupled senders
+func NewCoupledSenders() *CoupledSenders {
+	return &CoupledSenders{
+		senders:           make(map[protocol.PathID]CoupledSender),
+		potentiallyFailed: make(map[protocol.PathID]bool),
+	}
+}
+
+// Add registers the sender of a path
+func (s *CoupledSenders) Add(pathID protocol.PathID, sender CoupledSender) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.senders[pathID] = sender
+	delete(s.potentiallyFailed, pathID)
+}
+
+// Remove deregisters the sender of a closed path
+func (s *CoupledSenders) Remove(pathID protocol.PathID) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.senders, pathID)
+	delete(s.potentiallyFailed, pathID)
+}
+
+// SetPotentiallyFailed excludes a potentially failed path from the coupling, or adds it back once it recovered
+func (s *CoupledSenders) SetPotentiallyFailed(pathID protocol.PathID, failed bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.senders[pathID]; !ok {
+		return
+	}
+	if failed {
+		s.potentiallyFailed[pathID] = true
+	} else {
+		delete(s.potentiallyFailed, pathID)
+	}
+}
+
+// Get returns the sender of a path
+func (s *CoupledSenders) Get(pathID protocol.PathID) (CoupledSender, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	sender, ok := s.senders[pathID]
+	return sender, ok
+}
+
+// All returns a copy of the registered senders, including those of potentially failed paths
+func (s *CoupledSenders) All() map[protocol.PathID]CoupledSender {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	senders := make(map[protocol.PathID]CoupledSender, len(s.senders))
+	for pathID, sender := range s.senders {
+		senders[pathID] = sender
+	}
+	return senders
+}
+
+// Coupled returns a copy of the senders of the paths that are not potentially failed
+func (s *CoupledSenders) Coupled() map[protocol.PathID]CoupledSender {
+	return s.coupledWith(nil)
+}
+
+// coupledWith returns the coupled senders, always including self
+func (s *CoupledSenders) coupledWith(self CoupledSender) map[protocol.PathID]CoupledSender {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	senders := make(map[protocol.PathID]CoupledSender, len(s.senders))
+	for pathID, sender := range s.senders {
+		if !s.potentiallyFailed[pathID] || sender == self {
+			senders[pathID] = sender
+		}
+	}
+	return senders
+}
@@ -14,11 +14,11 @@ const (
 )
 
 type cubicSender struct {
-	hybridSlowStart HybridSlowStart
-	prr             PrrSender
-	rttStats        *RTTStats
-	stats           connectionStats
-	cubic           *Cubic
+	slowStart SlowStart
+	prr       PrrSender
+	rttStats  *RTTStats
+	stats     connectionStats
+	cubic     *Cubic
 
 	reno bool
 
@@ -64,6 +64,7 @@ type cubicSender struct {
 func NewCubicSender(clock Clock, rttStats *RTTStats, reno bool, initialCongestionWindow, initialMaxCongestionWindow protocol.PacketNumber) SendAlgorithmWithDebugInfo {
 	return &cubicSender{
 		rttStats:                   rttStats,
+		slowStart:                  &HybridSlowStart{},
 		initialCongestionWindow:    initialCongestionWindow,
 		initialMaxCongestionWindow: initialMaxCongestionWindow,
 		congestionWindow:           initialCongestionWindow,
@@ -97,7 +98,7 @@ func (c *cubicSender) OnPacketSent(sentTime time.Time, bytesInFlight protocol.By
 		c.prr.OnPacketSent(bytes)
 	}
 	c.largestSentPacketNumber = packetNumber
-	c.hybridSlowStart.OnPacketSent(packetNumber)
+	c.slowStart.OnPacketSent(packetNumber)
 	return true
 }
 
@@ -126,33 +127,33 @@ func (c *cubicSender) SlowstartThreshold() protocol.PacketNumber {
 }
 
 func (c *cubicSender) MaybeExitSlowStart() {
-	if c.InSlowStart() && c.hybridSlowStart.ShouldExitSlowStart(c.rttStats.LatestRTT(), c.rttStats.MinRTT(), c.GetCongestionWindow()/protocol.DefaultTCPMSS) {
+	if c.InSlowStart() && c.slowStart.ShouldExitSlowStart(c.rttStats.LatestRTT(), c.rttStats.MinRTT(), c.GetCongestionWindow()/protocol.DefaultTCPMSS) {
 		c.ExitSlowstart()
 	}
 }
 
-func (c *cubicSender) OnPacketAcked(ackedPacketNumber protocol.PacketNumber, ackedBytes protocol.ByteCount, bytesInFlight protocol.ByteCount,owd time.Duration,count int,pac_loss uint64) {
-	c.largestAckedPacketNumber = utils.MaxPacketNumber(ackedPacketNumber, c.largestAckedPacketNumber)
+func (c *cubicSender) OnPacketAcked(ev *AckEvent) {
+	c.largestAckedPacketNumber = utils.MaxPacketNumber(ev.PacketNumber, c.largestAckedPacketNumber)
 	if c.InRecovery() {
 		// PRR is used when in recovery.
-		c.prr.OnPacketAcked(ackedBytes)
+		c.prr.OnPacketAcked(ev.Bytes)
 		return
 	}
-	c.maybeIncreaseCwnd(ackedPacketNumber, ackedBytes, bytesInFlight)
+	c.maybeIncreaseCwnd(ev.PacketNumber, ev.Bytes, ev.BytesInFlight)
 	if c.InSlowStart() {
-		c.hybridSlowStart.OnPacketAcked(ackedPacketNumber)
+		c.slowStart.OnPacketAcked(ev.PacketNumber)
 	}
 }
 
-func (c *cubicSender) OnPacketLost(packetNumber protocol.PacketNumber, lostBytes protocol.ByteCount, bytesInFlight protocol.ByteCount) {
+func (c *cubicSender) OnPacketLost(ev *LossEvent) {
 	// TCP NewReno (RFC6582) says that once a loss occurs, any losses in packets
 	// already sent should be treated as a single loss event, since it's expected.
-	if packetNumber <= c.largestSentAtLastCutback {
+	if ev.PacketNumber <= c.largestSentAtLastCutback {
 		if c.lastCutbackExitedSlowstart {
 			c.stats.slowstartPacketsLost++
-			c.stats.slowstartBytesLost += lostBytes
+			c.stats.slowstartBytesLost += ev.Bytes
 			if c.slowStartLargeReduction {
-				if c.stats.slowstartPacketsLost == 1 || (c.stats.slowstartBytesLost/protocol.DefaultTCPMSS) > (c.stats.slowstartBytesLost-lostBytes)/protocol.DefaultTCPMSS {
+				if c.stats.slowstartPacketsLost == 1 || (c.stats.slowstartBytesLost/protocol.DefaultTCPMSS) > (c.stats.slowstartBytesLost-ev.Bytes)/protocol.DefaultTCPMSS {
 					// Reduce congestion window by 1 for every mss of bytes lost.
 					c.congestionWindow = utils.MaxPacketNumber(c.congestionWindow-1, c.minCongestionWindow)
 				}
@@ -165,7 +166,21 @@ func (c *cubicSender) OnPacketLost(packetNumber protocol.PacketNumber, lostBytes
 	if c.InSlowStart() {
 		c.stats.slowstartPacketsLost++
 	}
+	c.reduceCongestionWindow(ev.BytesInFlight)
+}
+
+// OnCongestionEvent reduces the window as a loss does, without counting a lost packet
+func (c *cubicSender) OnCongestionEvent(ev *CongestionEvent) {
+	// The CE marks of the packets sent before the last reduction belong to the same congestion event
+	if ev.PacketNumber <= c.largestSentAtLastCutback {
+		return
+	}
+	c.lastCutbackExitedSlowstart = c.InSlowStart()
+	c.reduceCongestionWindow(ev.BytesInFlight)
+}
 
+// reduceCongestionWindow cuts the window on a congestion event, i.e. a loss or CE marks
+func (c *cubicSender) reduceCongestionWindow(bytesInFlight protocol.ByteCount) {
 	c.prr.OnPacketLost(bytesInFlight)
 
 	// TODO(chromium): Separate out all of slow start into a separate class.
@@ -209,7 +224,7 @@ func (c *cubicSender) maybeIncreaseCwnd(ackedPacketNumber protocol.PacketNumber,
 	}
 	if c.InSlowStart() {
 		// TCP slow start, exponential growth, increase by one for each ACK.
-		c.congestionWindow++
+		c.congestionWindow += c.slowStart.CongestionWindowIncrease()
 		return
 	}
 	if c.reno {
@@ -246,9 +261,15 @@ func (c *cubicSender) BandwidthEstimate() Bandwidth {
 	return BandwidthFromDelta(c.GetCongestionWindow(), srtt)
 }
 
-// HybridSlowStart returns the hybrid slow start instance for testing
+// HybridSlowStart returns the hybrid slow start instance for testing, nil if another slow start is used
 func (c *cubicSender) HybridSlowStart() *HybridSlowStart {
-	return &c.hybridSlowStart
+	hybridSlowStart, _ := c.slowStart.(*HybridSlowStart)
+	return hybridSlowStart
+}
+
+// SetSlowStart replaces the slow start algorithm, e.g. by HyStart++
+func (c *cubicSender) SetSlowStart(slowStart SlowStart) {
+	c.slowStart = slowStart
 }
 
 // SetNumEmulatedConnections sets the number of emulated connections
@@ -263,7 +284,7 @@ func (c *cubicSender) OnRetransmissionTimeout(packetsRetransmitted bool) {
 	if !packetsRetransmitted {
 		return
 	}
-	c.hybridSlowStart.Restart()
+	c.slowStart.Restart()
 	c.cubic.Reset()
 	c.slowstartThreshold = c.congestionWindow / 2
 	c.congestionWindow = c.minCongestionWindow
@@ -271,7 +292,7 @@ func (c *cubicSender) OnRetransmissionTimeout(packetsRetransmitted bool) {
 
 // OnConnectionMigration is called when the connection is migrated (?)
 func (c *cubicSender) OnConnectionMigration() {
-	c.hybridSlowStart.Restart()
+	c.slowStart.Restart()
 	c.prr = PrrSender{}
 	c.largestSentPacketNumber = 0
 	c.largestAckedPacketNumber = 0
@@ -25,7 +25,7 @@ const MaxCongestionWindow = protocol.PacketNumber(200)
 
 var _ = Describe("Cubic Sender", func() {
 	var (
-		sender            SendAlgorithmWithDebugInfo
+		sender            *cubicSender
 		clock             mockClock
 		bytesInFlight     protocol.ByteCount
 		packetNumber      protocol.PacketNumber
@@ -39,7 +39,7 @@ var _ = Describe("Cubic Sender", func() {
 		ackedPacketNumber = 0
 		clock = mockClock{}
 		rttStats = NewRTTStats()
-		sender = NewCubicSender(&clock, rttStats, true /*reno*/, initialCongestionWindowPackets, MaxCongestionWindow)
+		sender = NewCubicSender(&clock, rttStats, true /*reno*/, initialCongestionWindowPackets, MaxCongestionWindow).(*cubicSender)
 	})
 
 	SendAvailableSendWindowLen := func(packetLength protocol.ByteCount) int {
@@ -62,7 +62,7 @@ var _ = Describe("Cubic Sender", func() {
 		sender.MaybeExitSlowStart()
 		for i := 0; i < n; i++ {
 			ackedPacketNumber++
-			sender.OnPacketAcked(ackedPacketNumber, packetLength, bytesInFlight)
+			sender.OnPacketAcked(&AckEvent{PacketNumber: ackedPacketNumber, Bytes: packetLength, BytesInFlight: bytesInFlight, OWD: UnknownOWD})
 		}
 		bytesInFlight -= protocol.ByteCount(n) * packetLength
 		clock.Advance(time.Millisecond)
@@ -71,14 +71,14 @@ var _ = Describe("Cubic Sender", func() {
 	LoseNPacketsLen := func(n int, packetLength protocol.ByteCount) {
 		for i := 0; i < n; i++ {
 			ackedPacketNumber++
-			sender.OnPacketLost(ackedPacketNumber, packetLength, bytesInFlight)
+			sender.OnPacketLost(&LossEvent{PacketNumber: ackedPacketNumber, Bytes: packetLength, BytesInFlight: bytesInFlight})
 		}
 		bytesInFlight -= protocol.ByteCount(n) * packetLength
 	}
 
 	// Does not increment acked_packet_number_.
 	LosePacket := func(number protocol.PacketNumber) {
-		sender.OnPacketLost(number, protocol.DefaultTCPMSS, bytesInFlight)
+		sender.OnPacketLost(&LossEvent{PacketNumber: number, Bytes: protocol.DefaultTCPMSS, BytesInFlight: bytesInFlight})
 		bytesInFlight -= protocol.DefaultTCPMSS
 	}
 
@@ -421,7 +421,7 @@ var _ = Describe("Cubic Sender", func() {
 	It("slow start max send window", func() {
 		const kMaxCongestionWindowTCP = 50
 		const kNumberOfAcks = 100
-		sender = NewCubicSender(&clock, rttStats, false, initialCongestionWindowPackets, kMaxCongestionWindowTCP)
+		sender = NewCubicSender(&clock, rttStats, false, initialCongestionWindowPackets, kMaxCongestionWindowTCP).(*cubicSender)
 
 		for i := 0; i < kNumberOfAcks; i++ {
 			// Send our full send window.
@@ -435,7 +435,7 @@ var _ = Describe("Cubic Sender", func() {
 	It("tcp reno max congestion window", func() {
 		const kMaxCongestionWindowTCP = 50
 		const kNumberOfAcks = 1000
-		sender = NewCubicSender(&clock, rttStats, false, initialCongestionWindowPackets, kMaxCongestionWindowTCP)
+		sender = NewCubicSender(&clock, rttStats, false, initialCongestionWindowPackets, kMaxCongestionWindowTCP).(*cubicSender)
 
 		SendAvailableSendWindow()
 		AckNPackets(2)
@@ -457,7 +457,7 @@ var _ = Describe("Cubic Sender", func() {
 		// Set to 10000 to compensate for small cubic alpha.
 		const kNumberOfAcks = 10000
 
-		sender = NewCubicSender(&clock, rttStats, false, initialCongestionWindowPackets, kMaxCongestionWindowTCP)
+		sender = NewCubicSender(&clock, rttStats, false, initialCongestionWindowPackets, kMaxCongestionWindowTCP).(*cubicSender)
 
 		SendAvailableSendWindow()
 		AckNPackets(2)
@@ -477,7 +477,7 @@ var _ = Describe("Cubic Sender", func() {
 	It("tcp cubic reset epoch on quiescence", func() {
 		const kMaxCongestionWindow = 50
 		const kMaxCongestionWindowBytes = kMaxCongestionWindow * protocol.DefaultTCPMSS
-		sender = NewCubicSender(&clock, rttStats, false, initialCongestionWindowPackets, kMaxCongestionWindow)
+		sender = NewCubicSender(&clock, rttStats, false, initialCongestionWindowPackets, kMaxCongestionWindow).(*cubicSender)
 
 		num_sent := SendAvailableSendWindow()
 
@@ -517,7 +517,7 @@ var _ = Describe("Cubic Sender", func() {
 	It("tcp cubic shifted epoch on quiescence", func() {
 		const kMaxCongestionWindow = 50
 		const kMaxCongestionWindowBytes = kMaxCongestionWindow * protocol.DefaultTCPMSS
-		sender = NewCubicSender(&clock, rttStats, false, initialCongestionWindowPackets, kMaxCongestionWindow)
+		sender = NewCubicSender(&clock, rttStats, false, initialCongestionWindowPackets, kMaxCongestionWindow).(*cubicSender)
 
 		num_sent := SendAvailableSendWindow()
 
@@ -570,6 +570,22 @@ var _ = Describe("Cubic Sender", func() {
 		Expect(post_loss_window).To(BeNumerically(">", sender.GetCongestionWindow()))
 	})
 
+	It("reduces the window once per window on CE marks, without counting a loss", func() {
+		SendAvailableSendWindow()
+		initial_window := sender.GetCongestionWindow()
+		sender.OnCongestionEvent(&CongestionEvent{PacketNumber: ackedPacketNumber + 1, BytesInFlight: bytesInFlight})
+		post_ce_window := sender.GetCongestionWindow()
+		Expect(initial_window).To(BeNumerically(">", post_ce_window))
+		Expect(sender.stats.slowstartPacketsLost).To(BeZero())
+		// CE marks of packets sent before the reduction
+		sender.OnCongestionEvent(&CongestionEvent{PacketNumber: packetNumber - 1, BytesInFlight: bytesInFlight})
+		Expect(sender.GetCongestionWindow()).To(Equal(post_ce_window))
+
+		// CE marks of a later packet decrease the window again
+		sender.OnCongestionEvent(&CongestionEvent{PacketNumber: packetNumber, BytesInFlight: bytesInFlight})
+		Expect(post_ce_window).To(BeNumerically(">", sender.GetCongestionWindow()))
+	})
+
 	It("don't track ack packets", func() {
 		// Send a packet with no retransmittable data, and ensure it's not tracked.
 		Expect(sender.OnPacketSent(clock.Now(), bytesInFlight, packetNumber, protocol.DefaultTCPMSS, false)).To(BeFalse())
@@ -0,0 +1,47 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// UnknownOWD is the OWD of the acked packets whose one-way delay is unknown, e.g., because the peer didn't timestamp them
+const UnknownOWD = -time.Millisecond
+
+// An AckEvent describes a packet acknowledged by an ACK frame
+type AckEvent struct {
+	PacketNumber protocol.PacketNumber
+	// Bytes is the size of the acknowledged packet
+	Bytes protocol.ByteCount
+	// BytesInFlight is the number of bytes in flight after the packet was acknowledged
+	BytesInFlight protocol.ByteCount
+	SendTime      time.Time
+	// ReceiveTime is the time the peer received the packet, zero if the ACK didn't carry a timestamp for it
+	ReceiveTime time.Time
+	// OWD is the one-way delay of the packet, UnknownOWD if it is unknown
+	OWD time.Duration
+	// RTT is the RTT sample taken on the ACK, zero if the ACK didn't update the RTT
+	RTT time.Duration
+	// SBDInterval is the index of the shared bottleneck detection interval the ACK was received in
+	SBDInterval int
+}
+
+// A LossEvent describes a packet declared lost
+type LossEvent struct {
+	PacketNumber protocol.PacketNumber
+	// Bytes is the size of the lost packet
+	Bytes protocol.ByteCount
+	// BytesInFlight is the number of bytes in flight after the packet was removed from flight
+	BytesInFlight protocol.ByteCount
+	SendTime      time.Time
+}
+
+// A CongestionEvent describes new CE marks reported by an ACK frame, a loss-equivalent congestion signal (RFC 3168)
+type CongestionEvent struct {
+	// PacketNumber is the largest packet newly acknowledged by the ACK frame
+	PacketNumber protocol.PacketNumber
+	// BytesInFlight is the number of bytes in flight after the acknowledged packets were removed from flight
+	BytesInFlight protocol.ByteCount
+	SendTime      time.Time
+}
@@ -85,6 +85,11 @@ func (s *HybridSlowStart) ShouldExitSlowStart(latestRTT time.Duration, minRTT ti
 	return congestionWindow >= hybridStartLowWindow && s.hystartFound
 }
 
+// CongestionWindowIncrease returns the number of packets the window grows by for an acked packet
+func (s *HybridSlowStart) CongestionWindowIncrease() protocol.PacketNumber {
+	return 1
+}
+
 // OnPacketSent is called when a packet was sent
 func (s *HybridSlowStart) OnPacketSent(packetNumber protocol.PacketNumber) {
 	s.lastSentPacketNumber = packetNumber
@@ -0,0 +1,140 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// Constants of RFC 9406, section 4.3
+const (
+	hystartPlusPlusMinRTTThreshold = 4 * time.Millisecond
+	hystartPlusPlusMaxRTTThreshold = 16 * time.Millisecond
+	hystartPlusPlusMinRTTDivisor   = 8
+	// Number of RTT samples needed in a round before checking for a delay increase
+	hystartPlusPlusNRTTSample = 8
+	// The window grows 4 times slower during conservative slow start
+	hystartPlusPlusCSSGrowthDivisor = 4
+	// Number of rounds of conservative slow start before entering congestion avoidance
+	hystartPlusPlusCSSRounds = 5
+)
+
+// HyStartPlusPlus implements HyStart++ (RFC 9406)
+// Instead of leaving slow start as soon as the RTT increases, it enters a conservative slow start phase.
+// It goes back to slow start if the RTT increase was spurious, which happens on paths with noisy RTT.
+type HyStartPlusPlus struct {
+	endPacketNumber      protocol.PacketNumber
+	lastSentPacketNumber protocol.PacketNumber
+	started              bool
+
+	lastRoundMinRTT    time.Duration
+	currentRoundMinRTT time.Duration
+	rttSampleCount     uint32
+
+	// Conservative slow start
+	inCSS             bool
+	cssBaselineMinRTT time.Duration
+	cssRounds         uint32
+	// Acked packets not yet counted in the window during conservative slow start
+	cssAckedPackets protocol.PacketNumber
+
+	exitFound bool
+}
+
+var _ SlowStart = &HyStartPlusPlus{}
+
+// NewHyStartPlusPlus makes a new HyStart++ slow start
+func NewHyStartPlusPlus() *HyStartPlusPlus {
+	return &HyStartPlusPlus{}
+}
+
+// startRound is called when the first packet of a round is acked
+func (s *HyStartPlusPlus) startRound(lastSent protocol.PacketNumber) {
+	s.endPacketNumber = lastSent
+	s.lastRoundMinRTT = s.currentRoundMinRTT
+	s.currentRoundMinRTT = 0
+	s.rttSampleCount = 0
+	s.started = true
+}
+
+// ShouldExitSlowStart is called on every ACK updating the RTT
+// It returns true once the conservative slow start lasted hystartPlusPlusCSSRounds rounds
+func (s *HyStartPlusPlus) ShouldExitSlowStart(latestRTT time.Duration, minRTT time.Duration, congestionWindow protocol.ByteCount) bool {
+	if !s.started {
+		s.startRound(s.lastSentPacketNumber)
+	}
+	if s.exitFound {
+		return true
+	}
+	s.rttSampleCount++
+	if s.currentRoundMinRTT == 0 || latestRTT < s.currentRoundMinRTT {
+		s.currentRoundMinRTT = latestRTT
+	}
+	if s.rttSampleCount < hystartPlusPlusNRTTSample || s.lastRoundMinRTT == 0 {
+		return false
+	}
+	if s.inCSS {
+		// The RTT increase was spurious, go back to slow start
+		if s.currentRoundMinRTT < s.cssBaselineMinRTT {
+			s.inCSS = false
+			s.cssBaselineMinRTT = 0
+		}
+		return false
+	}
+	rttThreshold := utils.MaxDuration(hystartPlusPlusMinRTTThreshold, utils.MinDuration(s.lastRoundMinRTT/hystartPlusPlusMinRTTDivisor, hystartPlusPlusMaxRTTThreshold))
+	if s.currentRoundMinRTT >= s.lastRoundMinRTT+rttThreshold {
+		s.inCSS = true
+		s.cssBaselineMinRTT = s.currentRoundMinRTT
+		s.cssRounds = 0
+		s.cssAckedPackets = 0
+	}
+	return false
+}
+
+// OnPacketSent is called when a packet was sent
+func (s *HyStartPlusPlus) OnPacketSent(packetNumber protocol.PacketNumber) {
+	s.lastSentPacketNumber = packetNumber
+}
+
+// OnPacketAcked ends the round when its last packet is acked
+func (s *HyStartPlusPlus) OnPacketAcked(ackedPacketNumber protocol.PacketNumber) {
+	if s.endPacketNumber >= ackedPacketNumber {
+		return
+	}
+	s.started = false
+	if s.inCSS {
+		s.cssRounds++
+		if s.cssRounds >= hystartPlusPlusCSSRounds {
+			s.exitFound = true
+		}
+	}
+}
+
+// CongestionWindowIncrease returns the number of packets the window grows by for an acked packet
+func (s *HyStartPlusPlus) CongestionWindowIncrease() protocol.PacketNumber {
+	if !s.inCSS {
+		return 1
+	}
+	s.cssAckedPackets++
+	if s.cssAckedPackets < hystartPlusPlusCSSGrowthDivisor {
+		return 0
+	}
+	s.cssAckedPackets = 0
+	return 1
+}
+
+// InConservativeSlowStart returns true during the conservative slow start phase
+func (s *HyStartPlusPlus) InConservativeSlowStart() bool {
+	return s.inCSS
+}
+
+// Started returns true if a round is started
+func (s *HyStartPlusPlus) Started() bool {
+	return s.started
+}
+
+// Restart the slow start phase
+func (s *HyStartPlusPlus) Restart() {
+	*s = HyStartPlusPlus{lastSentPacketNumber: s.lastSentPacketNumber}
+}
@@ -0,0 +1,117 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HyStart++", func() {
+	const (
+		baseRTT     = 100 * time.Millisecond
+		roundLength = 16
+	)
+
+	var (
+		slowStart    *HyStartPlusPlus
+		packetNumber protocol.PacketNumber
+	)
+
+	BeforeEach(func() {
+		slowStart = NewHyStartPlusPlus()
+		packetNumber = 0
+	})
+
+	// round sends a window of packets and acks them with the same RTT, it returns true if slow start should be exited
+	round := func(rtt time.Duration) bool {
+		first := packetNumber + 1
+		for i := 0; i < roundLength; i++ {
+			packetNumber++
+			slowStart.OnPacketSent(packetNumber)
+		}
+		exit := false
+		for pn := first; pn <= packetNumber; pn++ {
+			exit = slowStart.ShouldExitSlowStart(rtt, baseRTT, 100)
+			slowStart.OnPacketAcked(pn)
+		}
+		return exit
+	}
+
+	It("grows the window by one packet per ACK in slow start", func() {
+		Expect(round(baseRTT)).To(BeFalse())
+		Expect(round(baseRTT)).To(BeFalse())
+		Expect(slowStart.InConservativeSlowStart()).To(BeFalse())
+		Expect(slowStart.CongestionWindowIncrease()).To(Equal(protocol.PacketNumber(1)))
+	})
+
+	It("doesn't react to RTT increases below the threshold", func() {
+		round(baseRTT)
+		// the threshold is baseRTT / 8
+		Expect(round(baseRTT + 12*time.Millisecond)).To(BeFalse())
+		Expect(slowStart.InConservativeSlowStart()).To(BeFalse())
+	})
+
+	It("uses a minimum threshold of 4ms", func() {
+		round(10 * time.Millisecond)
+		round(13 * time.Millisecond)
+		Expect(slowStart.InConservativeSlowStart()).To(BeFalse())
+		round(17 * time.Millisecond)
+		Expect(slowStart.InConservativeSlowStart()).To(BeTrue())
+	})
+
+	It("enters conservative slow start when the RTT increases, growing the window 4 times slower", func() {
+		round(baseRTT)
+		Expect(round(baseRTT + 15*time.Millisecond)).To(BeFalse())
+		Expect(slowStart.InConservativeSlowStart()).To(BeTrue())
+		var increase protocol.PacketNumber
+		for i := 0; i < 8; i++ {
+			increase += slowStart.CongestionWindowIncrease()
+		}
+		Expect(increase).To(Equal(protocol.PacketNumber(2)))
+	})
+
+	It("goes back to slow start if the RTT increase was spurious", func() {
+		round(baseRTT)
+		round(baseRTT + 15*time.Millisecond)
+		Expect(slowStart.InConservativeSlowStart()).To(BeTrue())
+		Expect(round(baseRTT)).To(BeFalse())
+		Expect(slowStart.InConservativeSlowStart()).To(BeFalse())
+		Expect(slowStart.CongestionWindowIncrease()).To(Equal(protocol.PacketNumber(1)))
+	})
+
+	It("exits slow start after 5 rounds of conservative slow start", func() {
+		round(baseRTT)
+		round(baseRTT + 15*time.Millisecond)
+		for i := 1; i < hystartPlusPlusCSSRounds; i++ {
+			Expect(round(baseRTT + 15*time.Millisecond)).To(BeFalse())
+		}
+		// the last round ends with the first ACK of the next one
+		Expect(round(baseRTT + 15*time.Millisecond)).To(BeTrue())
+	})
+
+	It("restarts", func() {
+		round(baseRTT)
+		round(baseRTT + 15*time.Millisecond)
+		slowStart.Restart()
+		Expect(slowStart.Started()).To(BeFalse())
+		Expect(slowStart.InConservativeSlowStart()).To(BeFalse())
+		Expect(round(baseRTT + 15*time.Millisecond)).To(BeFalse())
+	})
+
+	It("can replace the hybrid slow start of a sender", func() {
+		rttStats := NewRTTStats()
+		sender := NewCubicSender(&mockClock{}, rttStats, false, initialCongestionWindowPackets, MaxCongestionWindow)
+		Expect(sender.HybridSlowStart()).ToNot(BeNil())
+		sender.(SendAlgorithmWithSlowStart).SetSlowStart(slowStart)
+		Expect(sender.HybridSlowStart()).To(BeNil())
+		slowStart.inCSS = true
+		cwnd := sender.GetCongestionWindow()
+		for pn := protocol.PacketNumber(1); pn <= 4; pn++ {
+			sender.OnPacketSent(time.Now(), 0, pn, protocol.DefaultTCPMSS, true)
+			sender.OnPacketAcked(&AckEvent{PacketNumber: pn, Bytes: protocol.DefaultTCPMSS, BytesInFlight: cwnd, OWD: UnknownOWD})
+		}
+		Expect(sender.GetCongestionWindow()).To(Equal(cwnd + protocol.DefaultTCPMSS))
+	})
+})
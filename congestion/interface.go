@@ -7,13 +7,13 @@ import (
 )
 
 // A SendAlgorithm performs congestion control and calculates the congestion window
+// The packets are paced by the sent packet handler, see Pacer
 type SendAlgorithm interface {
-	TimeUntilSend(now time.Time, bytesInFlight protocol.ByteCount) time.Duration
 	OnPacketSent(sentTime time.Time, bytesInFlight protocol.ByteCount, packetNumber protocol.PacketNumber, bytes protocol.ByteCount, isRetransmittable bool) bool
 	GetCongestionWindow() protocol.ByteCount
 	MaybeExitSlowStart()
-	OnPacketAcked(number protocol.PacketNumber, ackedBytes protocol.ByteCount, bytesInFlight protocol.ByteCount,owd time.Duration,count int,pac_loss uint64)
-	OnPacketLost(number protocol.PacketNumber, lostBytes protocol.ByteCount, bytesInFlight protocol.ByteCount)
+	OnPacketAcked(ev *AckEvent)
+	OnPacketLost(ev *LossEvent)
 	SetNumEmulatedConnections(n int)
 	OnRetransmissionTimeout(packetsRetransmitted bool)
 	OnConnectionMigration()
@@ -24,6 +24,24 @@ type SendAlgorithm interface {
 	SetSlowStartLargeReduction(enabled bool)
 }
 
+// A SlowStart decides when a sender leaves slow start, and how fast the window grows until then
+type SlowStart interface {
+	OnPacketSent(packetNumber protocol.PacketNumber)
+	OnPacketAcked(ackedPacketNumber protocol.PacketNumber)
+	// ShouldExitSlowStart is called on every ACK updating the RTT, congestionWindow is in packets
+	ShouldExitSlowStart(latestRTT time.Duration, minRTT time.Duration, congestionWindow protocol.ByteCount) bool
+	// CongestionWindowIncrease returns the number of packets the window grows by for an acked packet
+	CongestionWindowIncrease() protocol.PacketNumber
+	Started() bool
+	Restart()
+}
+
+// SendAlgorithmWithSlowStart is a SendAlgorithm whose slow start algorithm can be replaced
+type SendAlgorithmWithSlowStart interface {
+	SendAlgorithm
+	SetSlowStart(slowStart SlowStart)
+}
+
 // SendAlgorithmWithDebugInfo adds some debug functions to SendAlgorithm
 type SendAlgorithmWithDebugInfo interface {
 	SendAlgorithm
@@ -35,4 +53,20 @@ type SendAlgorithmWithDebugInfo interface {
 	SlowstartThreshold() protocol.PacketNumber
 	RenoBeta() float32
 	InRecovery() bool
+	InSlowStart() bool
+}
+
+// SendAlgorithmWithRateSample is a SendAlgorithm driven by delivery rate samples
+type SendAlgorithmWithRateSample interface {
+	SendAlgorithm
+	// OnRateSample is called once per ACK, after OnPacketAcked and OnPacketLost were called for all the packets it acknowledged or declared lost
+	OnRateSample(sample *RateSample)
+}
+
+// SendAlgorithmWithCongestionEvent is a SendAlgorithm reacting to the CE marks reported by the peer
+// The other senders get a LossEvent without bytes instead.
+type SendAlgorithmWithCongestionEvent interface {
+	SendAlgorithm
+	// OnCongestionEvent reduces the window as a loss does, at most once per round trip, but no packet was lost
+	OnCongestionEvent(ev *CongestionEvent)
 }
@@ -0,0 +1,81 @@
+package congestion
+
+import (
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// liaBeta is the backoff factor after a loss, LIA decreases the window as standard TCP does (RFC 6356, section 3)
+const liaBeta float32 = 0.5
+
+// LiaSender implements the Linked Increases Algorithm of MPTCP (RFC 6356)
+type LiaSender struct {
+	coupledRenoSender
+}
+
+// NewLiaSender makes a new LIA sender, coupled with the other senders of liaSenders
+// The caller has to add the sender to liaSenders
+func NewLiaSender(liaSenders *CoupledSenders, rttStats *RTTStats, initialCongestionWindow, initialMaxCongestionWindow protocol.PacketNumber) SendAlgorithmWithDebugInfo {
+	l := &LiaSender{}
+	l.coupledRenoSender = newCoupledRenoSender(l, liaSenders, rttStats, initialCongestionWindow, initialMaxCongestionWindow)
+	return l
+}
+
+// liaAlpha computes the aggressiveness factor of the coupled senders, following RFC 6356:
+// alpha = cwnd_total * max_i(cwnd_i / rtt_i^2) / (sum_i(cwnd_i / rtt_i))^2
+// Paths without RTT sample only count in cwnd_total.
+func liaAlpha(liaSenders map[protocol.PathID]CoupledSender) float64 {
+	var total, best, sumRates float64
+	for _, ls := range liaSenders {
+		congestionWindow, srtt := ls.coupledState()
+		cwnd := float64(congestionWindow)
+		total += cwnd
+		rtt := srtt.Seconds()
+		if rtt == 0 {
+			continue
+		}
+		if r := cwnd / (rtt * rtt); r > best {
+			best = r
+		}
+		sumRates += cwnd / rtt
+	}
+	if sumRates == 0 {
+		return 1
+	}
+	return total * best / (sumRates * sumRates)
+}
+
+// totalCongestionWindow returns the sum of the congestion windows of the coupled senders, in packets
+func totalCongestionWindow(liaSenders map[protocol.PathID]CoupledSender) protocol.PacketNumber {
+	var total protocol.PacketNumber
+	for _, ls := range liaSenders {
+		cwnd, _ := ls.coupledState()
+		total += cwnd
+	}
+	return total
+}
+
+// Alpha returns the current aggressiveness factor shared by the coupled senders
+func (l *LiaSender) Alpha() float64 {
+	return liaAlpha(l.coupledWith())
+}
+
+// congestionAvoidanceIncrease returns the increase of the congestion window, in packets, when ackedBytes are acked:
+// min(alpha * bytes_acked * MSS / cwnd_total, bytes_acked * MSS / cwnd_i), with windows in bytes (RFC 6356, section 3)
+func (l *LiaSender) congestionAvoidanceIncrease(ackedBytes protocol.ByteCount) float64 {
+	acked := float64(ackedBytes) / float64(protocol.DefaultTCPMSS)
+	uncoupled := acked / float64(l.congestionWindow)
+	coupledSenders := l.coupledWith()
+	total := totalCongestionWindow(coupledSenders)
+	if total == 0 {
+		return uncoupled
+	}
+	coupled := liaAlpha(coupledSenders) * acked / float64(total)
+	if coupled < uncoupled {
+		return coupled
+	}
+	return uncoupled
+}
+
+func (l *LiaSender) RenoBeta() float32 {
+	return liaBeta
+}
@@ -0,0 +1,163 @@
+package congestion
+
+import (
+	"math"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LIA Sender", func() {
+	var (
+		liaSenders *CoupledSenders
+		sender1    *LiaSender
+		sender2    *LiaSender
+	)
+
+	newSender := func(pathID protocol.PathID, rtt time.Duration) *LiaSender {
+		rttStats := NewRTTStats()
+		if rtt != 0 {
+			rttStats.UpdateRTT(rtt, 0, time.Now())
+		}
+		s := NewLiaSender(liaSenders, rttStats, initialCongestionWindowPackets, MaxCongestionWindow).(*LiaSender)
+		liaSenders.Add(pathID, s)
+		return s
+	}
+
+	// referenceIncrease is the increase of RFC 6356, section 3, with all windows in bytes
+	referenceIncrease := func(s *LiaSender, ackedBytes protocol.ByteCount) float64 {
+		var total, best, sumRates float64
+		for _, sender := range liaSenders.Coupled() {
+			ls := sender.(*LiaSender)
+			cwnd := float64(ls.GetCongestionWindow())
+			rtt := ls.rttStats.SmoothedRTT().Seconds()
+			total += cwnd
+			best = math.Max(best, cwnd/(rtt*rtt))
+			sumRates += cwnd / rtt
+		}
+		alpha := total * best / (sumRates * sumRates)
+		mss := float64(protocol.DefaultTCPMSS)
+		inc := math.Min(alpha*float64(ackedBytes)*mss/total, float64(ackedBytes)*mss/float64(s.GetCongestionWindow()))
+		// back to packets
+		return inc / mss
+	}
+
+	BeforeEach(func() {
+		liaSenders = NewCoupledSenders()
+		sender1 = newSender(1, 100*time.Millisecond)
+		sender2 = newSender(3, 50*time.Millisecond)
+	})
+
+	It("behaves like Reno with a single path", func() {
+		liaSenders.Remove(3)
+		Expect(sender1.Alpha()).To(BeNumerically("~", 1, 1e-9))
+		Expect(sender1.congestionAvoidanceIncrease(protocol.DefaultTCPMSS)).To(BeNumerically("~", 1/float64(initialCongestionWindowPackets), 1e-9))
+	})
+
+	It("computes alpha as in RFC 6356", func() {
+		sender1.congestionWindow = 10
+		sender2.congestionWindow = 20
+		// cwnd_total = 30, max(cwnd_i/rtt_i^2) = 20/0.05^2 = 8000, sum(cwnd_i/rtt_i) = 100 + 400 = 500
+		Expect(sender1.Alpha()).To(BeNumerically("~", 30.*8000/(500*500), 1e-9))
+		Expect(sender2.Alpha()).To(Equal(sender1.Alpha()))
+	})
+
+	It("ignores paths without RTT sample in the rates", func() {
+		sender3 := newSender(5, 0)
+		Expect(liaAlpha(liaSenders.Coupled())).To(BeNumerically("~", 30.*4000/(300*300), 1e-9))
+		Expect(sender3.Alpha()).To(Equal(sender1.Alpha()))
+	})
+
+	It("stops coupling with closed paths", func() {
+		sender1.congestionWindow = 10
+		sender2.congestionWindow = 20
+		liaSenders.Remove(3)
+		Expect(sender1.Alpha()).To(BeNumerically("~", 1, 1e-9))
+		Expect(sender1.congestionAvoidanceIncrease(protocol.DefaultTCPMSS)).To(BeNumerically("~", 0.1, 1e-9))
+	})
+
+	It("doesn't couple with potentially failed paths until they recover", func() {
+		sender1.congestionWindow = 10
+		sender2.congestionWindow = 20
+		alpha := sender1.Alpha()
+		liaSenders.SetPotentiallyFailed(3, true)
+		Expect(sender1.Alpha()).To(BeNumerically("~", 1, 1e-9))
+		// the potentially failed path is still coupled with the others
+		Expect(sender2.Alpha()).To(Equal(alpha))
+		liaSenders.SetPotentiallyFailed(3, false)
+		Expect(sender1.Alpha()).To(Equal(alpha))
+	})
+
+	It("increases the windows following the reference formula", func() {
+		for _, cwnds := range [][2]protocol.PacketNumber{{10, 10}, {10, 40}, {80, 5}, {33, 17}} {
+			sender1.congestionWindow = cwnds[0]
+			sender2.congestionWindow = cwnds[1]
+			for _, s := range []*LiaSender{sender1, sender2} {
+				for _, acked := range []protocol.ByteCount{protocol.DefaultTCPMSS, 2 * protocol.DefaultTCPMSS, 500} {
+					Expect(s.congestionAvoidanceIncrease(acked)).To(BeNumerically("~", referenceIncrease(s, acked), 1e-9))
+				}
+			}
+		}
+	})
+
+	It("is never more aggressive than a single TCP flow on a path", func() {
+		sender1.congestionWindow = 40
+		sender2.congestionWindow = 2
+		Expect(sender2.congestionAvoidanceIncrease(protocol.DefaultTCPMSS)).To(BeNumerically("<=", 0.5))
+	})
+
+	It("grows the window in congestion avoidance", func() {
+		sender1.slowstartThreshold = 10
+		sender1.congestionWindow = 10
+		sender2.congestionWindow = 10
+		bytesInFlight := sender1.GetCongestionWindow()
+		var acked float64
+		var pn protocol.PacketNumber
+		for sender1.congestionWindow == 10 {
+			pn++
+			acked += sender1.congestionAvoidanceIncrease(protocol.DefaultTCPMSS)
+			sender1.OnPacketAcked(&AckEvent{PacketNumber: pn, Bytes: protocol.DefaultTCPMSS, BytesInFlight: bytesInFlight, OWD: UnknownOWD})
+			Expect(pn).To(BeNumerically("<", 1000))
+		}
+		Expect(acked).To(BeNumerically(">=", 1))
+		Expect(sender1.congestionWindow).To(Equal(protocol.PacketNumber(11)))
+	})
+
+	It("grows the window by one packet per ACK in slow start", func() {
+		bytesInFlight := sender1.GetCongestionWindow()
+		sender1.OnPacketAcked(&AckEvent{PacketNumber: 1, Bytes: protocol.DefaultTCPMSS, BytesInFlight: bytesInFlight, OWD: UnknownOWD})
+		Expect(sender1.congestionWindow).To(Equal(initialCongestionWindowPackets + 1))
+	})
+
+	It("halves the window on loss", func() {
+		sender1.congestionWindow = 20
+		sender1.OnPacketSent(time.Now(), 0, 1, protocol.DefaultTCPMSS, true)
+		sender1.OnPacketLost(&LossEvent{PacketNumber: 1, Bytes: protocol.DefaultTCPMSS, BytesInFlight: 20 * protocol.DefaultTCPMSS})
+		Expect(sender1.congestionWindow).To(Equal(protocol.PacketNumber(10)))
+		Expect(sender1.SlowstartThreshold()).To(Equal(protocol.PacketNumber(10)))
+		Expect(sender1.InRecovery()).To(BeFalse())
+		Expect(sender2.congestionWindow).To(Equal(initialCongestionWindowPackets))
+	})
+
+	It("halves the window once per round trip on CE marks", func() {
+		sender1.congestionWindow = 20
+		sender1.OnPacketSent(time.Now(), 0, 1, protocol.DefaultTCPMSS, true)
+		sender1.OnPacketSent(time.Now(), 0, 2, protocol.DefaultTCPMSS, true)
+		sender1.OnCongestionEvent(&CongestionEvent{PacketNumber: 1, BytesInFlight: 19 * protocol.DefaultTCPMSS})
+		Expect(sender1.congestionWindow).To(Equal(protocol.PacketNumber(10)))
+		Expect(sender1.SlowstartThreshold()).To(Equal(protocol.PacketNumber(10)))
+		Expect(sender1.stats.slowstartPacketsLost).To(BeZero())
+		// the CE marks of the packets sent before the reduction belong to the same congestion event
+		sender1.OnCongestionEvent(&CongestionEvent{PacketNumber: 2, BytesInFlight: 18 * protocol.DefaultTCPMSS})
+		Expect(sender1.congestionWindow).To(Equal(protocol.PacketNumber(10)))
+	})
+
+	It("resets the window on retransmission timeout", func() {
+		sender1.congestionWindow = 20
+		sender1.OnRetransmissionTimeout(true)
+		Expect(sender1.congestionWindow).To(Equal(defaultMinimumCongestionWindow))
+		Expect(sender1.SlowstartThreshold()).To(Equal(protocol.PacketNumber(10)))
+	})
+})
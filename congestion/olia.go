@@ -42,16 +42,39 @@ type Sbd struct {
 
   Pac_ack [2]uint64
   pac_est float64
+	//the number of packets the peer received with a CE mark
+	Pac_ce [2]uint64
+	ce_est float64
 	dur time.Duration
 	count float64
 }
 //******
+// congestionEst is the rate of the congestion signals of the interval, the losses and the CE marks
+func (s *Sbd) congestionEst() float64 {
+	return s.pac_est + s.ce_est
+}
 //******
 func (o *Olia)UpdateSbdVar(owd time.Duration){
-	if owd >0 {
-		o.SBD.owd[o.SBD.Sbdcount] = append(o.SBD.owd[o.SBD.Sbdcount], owd)
+	o.SBD.addOWD(owd)
+}
+// addOWD records a one-way delay sample in the current SBD interval, unknown OWDs are ignored
+func (s *Sbd) addOWD(owd time.Duration) {
+	if owd > 0 {
+		s.owd[s.Sbdcount] = append(s.owd[s.Sbdcount], owd)
 	}
 }
+// clearEstimates drops the samples and the estimates, once the SBD decided
+func (s *Sbd) clearEstimates() {
+	s.Sbdcount = 0
+	s.skew_est = 0
+	s.freq_est = 0
+	s.var_est = 0 * time.Nanosecond
+	s.pac_est = 0
+	s.ce_est = 0
+	s.owd2 = 0 * time.Nanosecond
+	s.owd1 = []time.Duration{}
+	s.owd = [50][]time.Duration{}
+}
 
 //*****************************************************************************************
 
@@ -97,6 +120,28 @@ func (o *Olia) OnPacketLost() {
 	o.loss2 = o.loss3
 }
 
+// OliaState is a snapshot of the OLIA variables of a path
+type OliaState struct {
+	// Bytes acked two losses ago, at the last loss, and now
+	Loss1 protocol.ByteCount
+	Loss2 protocol.ByteCount
+	Loss3 protocol.ByteCount
+	// Epsilon is EpsilonNum / EpsilonDen
+	EpsilonNum int
+	EpsilonDen uint32
+}
+
+// State returns a snapshot of the OLIA variables
+func (o *Olia) State() OliaState {
+	return OliaState{
+		Loss1:      o.loss1,
+		Loss2:      o.loss2,
+		Loss3:      o.loss3,
+		EpsilonNum: o.epsilonNum,
+		EpsilonDen: o.epsilonDen,
+	}
+}
+
 func (o *Olia) CongestionWindowAfterAck(currentCongestionWindow protocol.PacketNumber, rate protocol.ByteCount, cwndScaled uint64) protocol.PacketNumber {
 	newCongestionWindow := currentCongestionWindow
 	incDen := uint64(o.epsilonDen) * uint64(currentCongestionWindow) * uint64(rate)
@@ -19,12 +19,12 @@ const p_d = 0.1
 const p_l = 0.1
 
 type OliaSender struct {
-	hybridSlowStart HybridSlowStart
+	slowStart       SlowStart
 	prr             PrrSender
 	rttStats        *RTTStats
 	stats           connectionStats
 	Olia            *Olia
-	oliaSenders     map[protocol.PathID]*OliaSender
+	oliaSenders     *OliaSenders
 	Sbd_set         decision_set
 	//******
 	//  bs             map[int]map[protocol.PathID]*OliaSender
@@ -75,9 +75,10 @@ type decision_set struct {
 //*****************
 
 
-func NewOliaSender(oliaSenders map[protocol.PathID]*OliaSender, rttStats *RTTStats, initialCongestionWindow, initialMaxCongestionWindow protocol.PacketNumber) SendAlgorithmWithDebugInfo {
+func NewOliaSender(oliaSenders *OliaSenders, rttStats *RTTStats, initialCongestionWindow, initialMaxCongestionWindow protocol.PacketNumber) SendAlgorithmWithDebugInfo {
 	return &OliaSender{
 		rttStats:                   rttStats,
+		slowStart:                  &HybridSlowStart{},
 		initialCongestionWindow:    initialCongestionWindow,
 		initialMaxCongestionWindow: initialMaxCongestionWindow,
 		congestionWindow:           initialCongestionWindow,
@@ -111,7 +112,7 @@ func (o *OliaSender) OnPacketSent(sentTime time.Time, bytesInFlight protocol.Byt
 		o.prr.OnPacketSent(bytes)
 	}
 	o.largestSentPacketNumber = packetNumber
-	o.hybridSlowStart.OnPacketSent(packetNumber)
+	o.slowStart.OnPacketSent(packetNumber)
 	return true
 }
 
@@ -128,7 +129,7 @@ func (o *OliaSender) ExitSlowstart() {
 }
 
 func (o *OliaSender) MaybeExitSlowStart() {
-	if o.InSlowStart() && o.hybridSlowStart.ShouldExitSlowStart(o.rttStats.LatestRTT(), o.rttStats.MinRTT(), o.GetCongestionWindow()/protocol.DefaultTCPMSS) {
+	if o.InSlowStart() && o.slowStart.ShouldExitSlowStart(o.rttStats.LatestRTT(), o.rttStats.MinRTT(), o.GetCongestionWindow()/protocol.DefaultTCPMSS) {
 		o.ExitSlowstart()
 	}
 }
@@ -143,6 +144,11 @@ func (o *OliaSender) isCwndLimited(bytesInFlight protocol.ByteCount) bool {
 	return slowStartLimited || availableBytes <= maxBurstBytes
 }
 
+// coupledSenders returns the senders this one is coupled with, itself included
+func (o *OliaSender) coupledSenders() map[protocol.PathID]*OliaSender {
+	return o.oliaSenders.coupledWith(o)
+}
+
 func getMaxCwnd(m map[protocol.PathID]*OliaSender) protocol.PacketNumber {
 	var bestCwnd protocol.PacketNumber
 	for _, os := range m {
@@ -182,10 +188,11 @@ func (o *OliaSender) getEpsilon() {
 	var M uint8
 	var BNotM uint8
 
+	oliaSenders := o.coupledSenders()
 
 	// TODO: integrate this in the following loop - we just want to iterate once
-	maxCwnd := getMaxCwnd(o.oliaSenders)
-	for _, os := range o.oliaSenders {
+	maxCwnd := getMaxCwnd(oliaSenders)
+	for _, os := range oliaSenders {
 		tmpRTT = os.rttStats.SmoothedRTT() * os.rttStats.SmoothedRTT()
 		tmpBytes = os.Olia.SmoothedBytesBetweenLosses()
 		if int64(tmpBytes) * bestRTT.Nanoseconds() >= int64(bestBytes) * tmpRTT.Nanoseconds() {
@@ -196,7 +203,7 @@ func (o *OliaSender) getEpsilon() {
 
 	// TODO: integrate this here in getMaxCwnd and in the previous loop
 	// Find the size of M and BNotM
-	for _, os := range o.oliaSenders {
+	for _, os := range oliaSenders {
 		tmpCwnd = os.congestionWindow
 		if tmpCwnd == maxCwnd {
 			M++
@@ -210,7 +217,7 @@ func (o *OliaSender) getEpsilon() {
 	}
 
 	// Check if the path is in M or BNotM and set the value of epsilon accordingly
-	for _, os := range o.oliaSenders {
+	for _, os := range oliaSenders {
 		if BNotM == 0 {
 			os.Olia.epsilonNum = 0
 			os.Olia.epsilonDen = 1
@@ -221,10 +228,10 @@ func (o *OliaSender) getEpsilon() {
 
 			if tmpCwnd < maxCwnd && int64(tmpBytes) * bestRTT.Nanoseconds() >= int64(bestBytes) * tmpRTT.Nanoseconds() {
 				os.Olia.epsilonNum = 1
-				os.Olia.epsilonDen = uint32(len(o.oliaSenders)) * uint32(BNotM)
+				os.Olia.epsilonDen = uint32(len(oliaSenders)) * uint32(BNotM)
 			} else if tmpCwnd == maxCwnd {
 				os.Olia.epsilonNum = -1
-				os.Olia.epsilonDen = uint32(len(o.oliaSenders)) * uint32(M)
+				os.Olia.epsilonDen = uint32(len(oliaSenders)) * uint32(M)
 			} else {
 				os.Olia.epsilonNum = 0
 				os.Olia.epsilonDen = 1
@@ -311,13 +318,13 @@ func (o *OliaSender) maybeIncreaseCwnd(ackedPacketNumber protocol.PacketNumber,
 	//if o.Sbd_set.flag{
 	//	m =o.Sbd_set.Set
 	//}else{
-		m =o.oliaSenders
+		m =o.coupledSenders()
 	//}
 
   //********
 	if o.InSlowStart() {
 		// TCP slow start, exponential growth, increase by one for each ACK.
-		o.congestionWindow++
+		o.congestionWindow += o.slowStart.CongestionWindowIncrease()
 		return
 	} else {
 		o.getEpsilon1(m)
@@ -376,41 +383,41 @@ func single1(o *Olia,pathid protocol.PathID){
 	//utils.Infof("freq_est:%f",o.SBD.freq_est)
 	//utils.Infof("pac_loss:%f",o.SBD.pac_est)
 }
-func single(o *Olia,pathid protocol.PathID){
+func single(s *Sbd){
 	var OWD []time.Duration
 	length :=0
 	lenOfowd :=0
-	for _,owd := range o.SBD.owd {
+	for _,owd := range s.owd {
 		//	fmt.Println(len(owd))
 		if len(owd)>0{
 			length++
 			lenOfowd  += len(owd)
-			//o.SBD.owd1 = append(o.SBD.owd1,sum(owd)/time.Duration(len(owd)))
+			//s.owd1 = append(s.owd1,sum(owd)/time.Duration(len(owd)))
 			OWD = append(OWD,time.Duration(float64(sum(owd)/time.Nanosecond)/float64(len(owd))))
 			if length==0{
-				o.SBD.owd1 = append(o.SBD.owd1,OWD[length])
+				s.owd1 = append(s.owd1,OWD[length])
 			}else{
-				o.SBD.owd1 = append(o.SBD.owd1,OWD[length-1])
+				s.owd1 = append(s.owd1,OWD[length-1])
 			}
 		}
 	}
 	//if len(OWD)==0{
-	//	fmt.Println(o.SBD.owd)
+	//	fmt.Println(s.owd)
 	//}
-	//o.SBD.owd1 = append(o.SBD.owd1,OWD[0])
-	//o.SBD.owd1 = append(o.SBD.owd1,OWD[:len(OWD)-1]...)
-	o.SBD.owd2 =time.Duration(float64(sum(o.SBD.owd1)/time.Nanosecond)/float64(length))
+	//s.owd1 = append(s.owd1,OWD[0])
+	//s.owd1 = append(s.owd1,OWD[:len(OWD)-1]...)
+	s.owd2 =time.Duration(float64(sum(s.owd1)/time.Nanosecond)/float64(length))
 	var skew_base float64
 	var var_base time.Duration
 	i :=0
-	for _,owd := range o.SBD.owd {
+	for _,owd := range s.owd {
 		if len(owd)>0{
 			for _,v :=range owd{
-				var_base += time.Duration(math.Abs(float64(v - o.SBD.owd1[i])))
-				//var_base += time.Duration(math.Pow(float64(v - o.SBD.owd1[i]),2))
-				if v < o.SBD.owd2{
+				var_base += time.Duration(math.Abs(float64(v - s.owd1[i])))
+				//var_base += time.Duration(math.Pow(float64(v - s.owd1[i]),2))
+				if v < s.owd2{
 					skew_base++
-				}else if v > o.SBD.owd2{
+				}else if v > s.owd2{
 					skew_base--
 				}
 			}
@@ -418,27 +425,28 @@ func single(o *Olia,pathid protocol.PathID){
 		}
 	}
 	if lenOfowd !=0{
-		o.SBD.skew_est = skew_base/float64(lenOfowd)
-		o.SBD.var_est = time.Duration(float64(var_base/time.Nanosecond)/float64(lenOfowd))
-		o.SBD.pac_est = float64(o.SBD.Pac_loss1[1]-o.SBD.Pac_loss1[0])/float64(o.SBD.Pac_ack[1]-o.SBD.Pac_ack[0])
+		s.skew_est = skew_base/float64(lenOfowd)
+		s.var_est = time.Duration(float64(var_base/time.Nanosecond)/float64(lenOfowd))
+		s.pac_est = float64(s.Pac_loss1[1]-s.Pac_loss1[0])/float64(s.Pac_ack[1]-s.Pac_ack[0])
+		s.ce_est = float64(s.Pac_ce[1]-s.Pac_ce[0])/float64(s.Pac_ack[1]-s.Pac_ack[0])
 
 
-		for j :=0;j<len(o.SBD.owd1)-1;j++{
-			if ((o.SBD.owd1[j]<(o.SBD.owd2-time.Duration(float64(p_v)*float64(o.SBD.var_est/time.Nanosecond))))&&(o.SBD.owd1[j+1]>(o.SBD.owd2+time.Duration(float64(p_v)*float64(o.SBD.var_est/time.Nanosecond)))))||
-				((o.SBD.owd1[j+1]<(o.SBD.owd2-time.Duration(float64(p_v)*float64(o.SBD.var_est/time.Nanosecond))))&&(o.SBD.owd1[j]>(o.SBD.owd2+time.Duration(float64(p_v)*float64(o.SBD.var_est/time.Nanosecond))))){
-				o.SBD.freq_est +=1/float64(len(o.SBD.owd1))
+		for j :=0;j<len(s.owd1)-1;j++{
+			if ((s.owd1[j]<(s.owd2-time.Duration(float64(p_v)*float64(s.var_est/time.Nanosecond))))&&(s.owd1[j+1]>(s.owd2+time.Duration(float64(p_v)*float64(s.var_est/time.Nanosecond)))))||
+				((s.owd1[j+1]<(s.owd2-time.Duration(float64(p_v)*float64(s.var_est/time.Nanosecond))))&&(s.owd1[j]>(s.owd2+time.Duration(float64(p_v)*float64(s.var_est/time.Nanosecond))))){
+				s.freq_est +=1/float64(len(s.owd1))
 			}
 		}
 	}
 
-	//utils.Infof("skew_est:%f",o.SBD.skew_est)
-	//utils.Infof("var_est:%s",o.SBD.var_est)
-	//utils.Infof("freq_est:%f",o.SBD.freq_est)
-	//utils.Infof("pac_loss:%f",o.SBD.pac_est)
+	//utils.Infof("skew_est:%f",s.skew_est)
+	//utils.Infof("var_est:%s",s.var_est)
+	//utils.Infof("freq_est:%f",s.freq_est)
+	//utils.Infof("pac_loss:%f",s.pac_est)
 }
 
 func (o *OliaSender) CalculateParameter(){
-	for pathid, os := range o.oliaSenders{
+	for _, os := range o.oliaSenders.Coupled(){
 
 			//fmt.Println(pathid)
 			//for _,owd:= range os.Olia.SBD.owd{
@@ -447,48 +455,45 @@ func (o *OliaSender) CalculateParameter(){
 			//	}
 			//}
       //fmt.Println()
-		single(os.Olia,pathid)
+		single(&os.Olia.SBD)
 		//}
 	}
 	//o.oliaSenders[1].SaveData()
 }
-func (o *OliaSender) partition(G map[protocol.PathID]*OliaSender){
-	var flag map[protocol.PathID]bool
-	var group []protocol.PathID
-	var set map[protocol.PathID]*OliaSender
-	set = make(map[protocol.PathID]*OliaSender)
-	flag = make(map[protocol.PathID]bool)
-	for pathid,_ := range G{
-		flag[pathid] = false
-	}
-	for pathid1,_ :=range G{
-		if !flag[pathid1]{
-			flag[pathid1]=true
-			i :=0
-			group = append(group, pathid1)
-			for {
-				if i==len(group){
-					for _,id :=range group{
-						set[id] = G[id]
-					}
-					for _,id :=range group{
-						G[id].Sbd_set.Set = set
-					}
-					group = []protocol.PathID{}
-					set = make(map[protocol.PathID]*OliaSender)
-					break
-				}else{
-					pathid :=group[i]
-					i++
-					for pathid2, os2 :=range G{
-						if !flag[pathid2]&&compare(G[pathid],os2){
-							  flag[pathid2] = true
-								group = append(group, pathid2)
-						}
-					}
+// partitionSbd groups the bottlenecked paths whose SBD estimates compare, transitively
+func partitionSbd(G map[protocol.PathID]*Sbd) [][]protocol.PathID {
+	var groups [][]protocol.PathID
+	flag := make(map[protocol.PathID]bool)
+	for pathid1 := range G {
+		if flag[pathid1] {
+			continue
+		}
+		flag[pathid1] = true
+		group := []protocol.PathID{pathid1}
+		for i := 0; i < len(group); i++ {
+			for pathid2, sbd2 := range G {
+				if !flag[pathid2] && compare(G[group[i]], sbd2) {
+					flag[pathid2] = true
+					group = append(group, pathid2)
 				}
 			}
-
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
+func (o *OliaSender) partition(G map[protocol.PathID]*OliaSender){
+	estimates := make(map[protocol.PathID]*Sbd, len(G))
+	for pathid, os := range G {
+		estimates[pathid] = &os.Olia.SBD
+	}
+	for _, group := range partitionSbd(estimates) {
+		set := make(map[protocol.PathID]*OliaSender)
+		for _, id := range group {
+			set[id] = G[id]
+		}
+		for _, id := range group {
+			G[id].Sbd_set.Set = set
 		}
 	}
 	//var v time.Duration
@@ -541,7 +546,7 @@ func compare1(first *OliaSender,second *OliaSender) bool{
 		(math.Abs(float64(first.Olia.SBD.dur-second.Olia.SBD.dur)) <= float64(time.Duration(10*p_mad*v)/10)) {
 
 		if ploss > p_l {
-			if math.Abs(first.Olia.SBD.pac_est-second.Olia.SBD.pac_est) <= p_d*ploss {
+			if math.Abs(first.Olia.SBD.congestionEst()-second.Olia.SBD.congestionEst()) <= p_d*ploss {
 				return true
 			}
 		} else {
@@ -550,27 +555,28 @@ func compare1(first *OliaSender,second *OliaSender) bool{
 	}
 	return false
 }
-func compare(first *OliaSender,second *OliaSender) bool{
+func compare(first *Sbd,second *Sbd) bool{
 	var v time.Duration
 	var ploss float64
 
-	if first.Olia.SBD.var_est > second.Olia.SBD.var_est {
-		v = first.Olia.SBD.var_est
+	if first.var_est > second.var_est {
+		v = first.var_est
 	} else {
-		v = second.Olia.SBD.var_est
+		v = second.var_est
 	}
-	if first.Olia.SBD.pac_est > second.Olia.SBD.pac_est {
-		ploss = first.Olia.SBD.pac_est
+	// CE marks are congestion signals as well as losses
+	if first.congestionEst() > second.congestionEst() {
+		ploss = first.congestionEst()
 	} else {
-		ploss = second.Olia.SBD.pac_est
+		ploss = second.congestionEst()
 	}
 
-	if (math.Abs(first.Olia.SBD.freq_est-second.Olia.SBD.freq_est) <= p_f) &&
-		(math.Abs(first.Olia.SBD.skew_est-second.Olia.SBD.skew_est) <= p_s) &&
-		(math.Abs(float64(first.Olia.SBD.var_est-second.Olia.SBD.var_est)) <= float64(time.Duration(10*p_mad*v)/10)) {
+	if (math.Abs(first.freq_est-second.freq_est) <= p_f) &&
+		(math.Abs(first.skew_est-second.skew_est) <= p_s) &&
+		(math.Abs(float64(first.var_est-second.var_est)) <= float64(time.Duration(10*p_mad*v)/10)) {
 
 		if ploss > p_l {
-			if math.Abs(first.Olia.SBD.pac_est-second.Olia.SBD.pac_est) <= p_d*ploss {
+			if math.Abs(first.congestionEst()-second.congestionEst()) <= p_d*ploss {
 				return true
 			}
 		} else {
@@ -579,35 +585,34 @@ func compare(first *OliaSender,second *OliaSender) bool{
 	}
 	return false
 }
+// bottlenecked tells if the estimates show a bottleneck on the path, previously is the last decision for the path
+func (s *Sbd) bottlenecked(previously bool) bool {
+	return s.skew_est < c_s || (s.skew_est < c_h && previously) || s.congestionEst() > p_l
+}
 func (o *OliaSender) clearSBD(){
-	for _, os := range o.oliaSenders {
-		os.Olia.SBD.Sbdcount = 0
-		os.Olia.SBD.skew_est = 0
-		os.Olia.SBD.freq_est = 0
-		os.Olia.SBD.var_est = 0*time.Nanosecond
-		os.Olia.SBD.pac_est = 0
-		os.Olia.SBD.owd2 = 0*time.Nanosecond
-		os.Olia.SBD.owd1 = []time.Duration{}
-		os.Olia.SBD.owd = [50][]time.Duration{}
+	for _, os := range o.oliaSenders.All() {
+		os.Olia.SBD.clearEstimates()
 	}
 }
 
 func (o *OliaSender) SbdDecision(){
 
+	// Potentially failed paths have no fresh samples, leave them out of the decision
+	oliaSenders := o.oliaSenders.Coupled()
 	var G map[protocol.PathID]*OliaSender = make(map[protocol.PathID]*OliaSender)
-	for pathID, os := range o.oliaSenders {
+	for pathID, os := range oliaSenders {
 		os.Sbd_set.flag = true
 		os.Sbd_set.Set = make(map[protocol.PathID]*OliaSender)
-		if (os.Olia.SBD.skew_est < c_s)||(os.Olia.SBD.skew_est < c_h&&os.Sbd_set.B==1)||(os.Olia.SBD.pac_est>p_l){
+		if os.Olia.SBD.bottlenecked(os.Sbd_set.B == 1) {
 			os.Sbd_set.B = 1
 			G[pathID] = os
 		}else{
 			os.Sbd_set.B = 0
 		}
 	}
-	for _, os := range o.oliaSenders {
+	for _, os := range oliaSenders {
 		if os.Sbd_set.B == 0 {
-			for pathID1, os1 := range o.oliaSenders {
+			for pathID1, os1 := range oliaSenders {
 				if os1.Sbd_set.B == 0{
 					os.Sbd_set.Set[pathID1] = os1
 				}
@@ -617,7 +622,7 @@ func (o *OliaSender) SbdDecision(){
 	o.partition(G)
 
 	var Pathid []int
-	for pathid,_:=range o.oliaSenders{
+	for pathid,_:=range oliaSenders{
 		Pathid = append(Pathid,int(pathid))
 	}
 	sort.Ints(Pathid)
@@ -628,39 +633,39 @@ func (o *OliaSender) SbdDecision(){
 
   fmt.Printf("\n%-12s","skew_est")
 	for _,i := range Pathid{
-		fmt.Printf("%-12.4f",o.oliaSenders[protocol.PathID(i)].Olia.SBD.skew_est)
+		fmt.Printf("%-12.4f",oliaSenders[protocol.PathID(i)].Olia.SBD.skew_est)
 	}
 
 	fmt.Printf("\n%-12s","var_est")
 	for _,i := range Pathid{
-		fmt.Printf("%-12s",o.oliaSenders[protocol.PathID(i)].Olia.SBD.var_est)
+		fmt.Printf("%-12s",oliaSenders[protocol.PathID(i)].Olia.SBD.var_est)
 	}
 
 	fmt.Printf("\n%-12s","freq_est")
 	for _,i := range Pathid{
-		fmt.Printf("%-12.4f",o.oliaSenders[protocol.PathID(i)].Olia.SBD.freq_est)
+		fmt.Printf("%-12.4f",oliaSenders[protocol.PathID(i)].Olia.SBD.freq_est)
 	}
 
 	fmt.Printf("\n%-12s","pac_loss")
 	for _,i := range Pathid{
-		fmt.Printf("%-12f",o.oliaSenders[protocol.PathID(i)].Olia.SBD.pac_est)
+		fmt.Printf("%-12f",oliaSenders[protocol.PathID(i)].Olia.SBD.pac_est)
 	}
 
 	fmt.Printf("\n%-12s","set")
 	for _,i := range Pathid{
-		fmt.Printf("%-12d",len(o.oliaSenders[protocol.PathID(i)].Sbd_set.Set))
+		fmt.Printf("%-12d",len(oliaSenders[protocol.PathID(i)].Sbd_set.Set))
 	}
 	fmt.Printf("\n%-12s","packet")
 	//
 	for _,i := range Pathid{
 
-		fmt.Printf("%-12d",o.oliaSenders[protocol.PathID(i)].Olia.SBD.Pac_ack[1]-o.oliaSenders[protocol.PathID(i)].Olia.SBD.Pac_ack[0])
+		fmt.Printf("%-12d",oliaSenders[protocol.PathID(i)].Olia.SBD.Pac_ack[1]-oliaSenders[protocol.PathID(i)].Olia.SBD.Pac_ack[0])
 
 	}
 	fmt.Println()
 	//for _,i := range Pathid{
 	//	fmt.Print(strconv.Itoa(i)+"ms")
-	//	for _,owd:= range o.oliaSenders[protocol.PathID(i)].Olia.SBD.owd{
+	//	for _,owd:= range oliaSenders[protocol.PathID(i)].Olia.SBD.owd{
 	//		for _,m := range owd{
 	//			fmt.Print(m)
 	//		}
@@ -671,37 +676,37 @@ func (o *OliaSender) SbdDecision(){
 	o.clearSBD()
 }
 func (o *OliaSender) Clearset(){
-	for _,os :=range o.oliaSenders{
+	for _,os :=range o.oliaSenders.All(){
 		os.Sbd_set.Set = make(map[protocol.PathID]*OliaSender)
 	}
 }
 //*********************************
-func (o *OliaSender) OnPacketAcked(ackedPacketNumber protocol.PacketNumber, ackedBytes protocol.ByteCount, bytesInFlight protocol.ByteCount,OWD time.Duration,count int,pac_loss uint64) {
-	o.largestAckedPacketNumber = utils.MaxPacketNumber(ackedPacketNumber, o.largestAckedPacketNumber)
+func (o *OliaSender) OnPacketAcked(ev *AckEvent) {
+	o.largestAckedPacketNumber = utils.MaxPacketNumber(ev.PacketNumber, o.largestAckedPacketNumber)
 
 	if o.InRecovery() {
 		// PRR is used when in recovery
-		o.prr.OnPacketAcked(ackedBytes)
+		o.prr.OnPacketAcked(ev.Bytes)
 		return
 	}
-	o.Olia.UpdateAckedSinceLastLoss(ackedBytes)
-	//o.Olia.UpdateSbdVar(OWD)
+	o.Olia.UpdateAckedSinceLastLoss(ev.Bytes)
+	//o.Olia.UpdateSbdVar(ev.OWD)
 	//******
-	o.maybeIncreaseCwnd(ackedPacketNumber, ackedBytes, bytesInFlight,OWD)
+	o.maybeIncreaseCwnd(ev.PacketNumber, ev.Bytes, ev.BytesInFlight, ev.OWD)
 	if o.InSlowStart() {
-		o.hybridSlowStart.OnPacketAcked(ackedPacketNumber)
+		o.slowStart.OnPacketAcked(ev.PacketNumber)
 	}
 }
 
-func (o *OliaSender) OnPacketLost(packetNumber protocol.PacketNumber, lostBytes protocol.ByteCount, bytesInFlight protocol.ByteCount) {
+func (o *OliaSender) OnPacketLost(ev *LossEvent) {
 	// TCP NewReno (RFC6582) says that once a loss occurs, any losses in packets
 	// already sent should be treated as a single loss event, since it's expected.
-	if packetNumber <= o.largestSentAtLastCutback {
+	if ev.PacketNumber <= o.largestSentAtLastCutback {
 		if o.lastCutbackExitedSlowstart {
 			o.stats.slowstartPacketsLost++
-			o.stats.slowstartBytesLost += lostBytes
+			o.stats.slowstartBytesLost += ev.Bytes
 			if o.slowStartLargeReduction {
-				if o.stats.slowstartPacketsLost == 1 || (o.stats.slowstartBytesLost/protocol.DefaultTCPMSS) > (o.stats.slowstartBytesLost - lostBytes)/protocol.DefaultTCPMSS {
+				if o.stats.slowstartPacketsLost == 1 || (o.stats.slowstartBytesLost/protocol.DefaultTCPMSS) > (o.stats.slowstartBytesLost - ev.Bytes)/protocol.DefaultTCPMSS {
 					// Reduce congestion window by 1 for every mss of bytes lost.
 					o.congestionWindow = utils.MaxPacketNumber(o.congestionWindow-1, o.minCongestionWindow)
 				}
@@ -714,7 +719,21 @@ func (o *OliaSender) OnPacketLost(packetNumber protocol.PacketNumber, lostBytes
 	if o.InSlowStart() {
 		o.stats.slowstartPacketsLost++
 	}
+	o.reduceCongestionWindow(ev.BytesInFlight)
+}
 
+// OnCongestionEvent reduces the window as a loss does, without counting a lost packet
+func (o *OliaSender) OnCongestionEvent(ev *CongestionEvent) {
+	// The CE marks of the packets sent before the last reduction belong to the same congestion event
+	if ev.PacketNumber <= o.largestSentAtLastCutback {
+		return
+	}
+	o.lastCutbackExitedSlowstart = o.InSlowStart()
+	o.reduceCongestionWindow(ev.BytesInFlight)
+}
+
+// reduceCongestionWindow cuts the window on a congestion event, i.e. a loss or CE marks
+func (o *OliaSender) reduceCongestionWindow(bytesInFlight protocol.ByteCount) {
 	o.prr.OnPacketLost(bytesInFlight)
 	o.Olia.OnPacketLost()
 
@@ -746,14 +765,14 @@ func (o *OliaSender) OnRetransmissionTimeout(packetsRetransmitted bool) {
 	if !packetsRetransmitted {
 		return
 	}
-	o.hybridSlowStart.Restart()
+	o.slowStart.Restart()
 	o.Olia.Reset()
 	o.slowstartThreshold = o.congestionWindow / 2
 	o.congestionWindow = o.minCongestionWindow
 }
 
 func (o *OliaSender) OnConnectionMigration() {
-	o.hybridSlowStart.Restart()
+	o.slowStart.Restart()
 	o.prr = PrrSender{}
 	o.largestSentPacketNumber = 0
 	o.largestAckedPacketNumber = 0
@@ -793,9 +812,15 @@ func (o *OliaSender) BandwidthEstimate() Bandwidth {
 	return BandwidthFromDelta(o.GetCongestionWindow(), srtt)
 }
 
-// HybridSlowStart returns the hybrid slow start instance for testing
+// HybridSlowStart returns the hybrid slow start instance for testing, nil if another slow start is used
 func (o *OliaSender) HybridSlowStart() *HybridSlowStart {
-	return &o.hybridSlowStart
+	hybridSlowStart, _ := o.slowStart.(*HybridSlowStart)
+	return hybridSlowStart
+}
+
+// SetSlowStart replaces the slow start algorithm, e.g. by HyStart++
+func (o *OliaSender) SetSlowStart(slowStart SlowStart) {
+	o.slowStart = slowStart
 }
 
 func (o *OliaSender) SlowstartThreshold() protocol.PacketNumber {
@@ -0,0 +1,102 @@
+package congestion
+
+import (
+	"sync"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// OliaSenders holds the OLIA senders of a connection, one per path
+// It is safe for concurrent use, since paths are created by the path manager while ACKs and the SBD timer are handled by the session
+type OliaSenders struct {
+	mutex sync.RWMutex
+
+	senders map[protocol.PathID]*OliaSender
+	// Potentially failed paths are not coupled with the others until they recover
+	potentiallyFailed map[protocol.PathID]bool
+}
+
+// NewOliaSenders makes an empty set of OLIA senders
+func NewOliaSenders() *OliaSenders {
+	return &OliaSenders{
+		senders:           make(map[protocol.PathID]*OliaSender),
+		potentiallyFailed: make(map[protocol.PathID]bool),
+	}
+}
+
+// Add registers the sender of a path
+func (s *OliaSenders) Add(pathID protocol.PathID, sender *OliaSender) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.senders[pathID] = sender
+	delete(s.potentiallyFailed, pathID)
+}
+
+// Remove deregisters the sender of a closed path
+func (s *OliaSenders) Remove(pathID protocol.PathID) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.senders, pathID)
+	delete(s.potentiallyFailed, pathID)
+	// Don't keep the sender in the shared bottleneck of the others until the next decision
+	for _, os := range s.senders {
+		delete(os.Sbd_set.Set, pathID)
+	}
+}
+
+// SetPotentiallyFailed excludes a potentially failed path from the coupling, or adds it back once it recovered
+func (s *OliaSenders) SetPotentiallyFailed(pathID protocol.PathID, failed bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.senders[pathID]; !ok {
+		return
+	}
+	if failed {
+		s.potentiallyFailed[pathID] = true
+	} else {
+		delete(s.potentiallyFailed, pathID)
+	}
+}
+
+// Get returns the sender of a path
+func (s *OliaSenders) Get(pathID protocol.PathID) (*OliaSender, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	os, ok := s.senders[pathID]
+	return os, ok
+}
+
+// All returns a copy of the registered senders, including those of potentially failed paths
+func (s *OliaSenders) All() map[protocol.PathID]*OliaSender {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	senders := make(map[protocol.PathID]*OliaSender, len(s.senders))
+	for pathID, os := range s.senders {
+		senders[pathID] = os
+	}
+	return senders
+}
+
+// Coupled returns a copy of the senders of the paths that are not potentially failed
+func (s *OliaSenders) Coupled() map[protocol.PathID]*OliaSender {
+	return s.coupledWith(nil)
+}
+
+// coupledWith returns the coupled senders, always including self
+func (s *OliaSenders) coupledWith(self *OliaSender) map[protocol.PathID]*OliaSender {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	senders := make(map[protocol.PathID]*OliaSender, len(s.senders))
+	for pathID, os := range s.senders {
+		if !s.potentiallyFailed[pathID] || os == self {
+			senders[pathID] = os
+		}
+	}
+	return senders
+}
@@ -0,0 +1,115 @@
+package congestion
+
+import (
+	"sync"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("OLIA senders", func() {
+	var (
+		oliaSenders *OliaSenders
+		sender1     *OliaSender
+		sender3     *OliaSender
+	)
+
+	newSender := func(pathID protocol.PathID) *OliaSender {
+		s := NewOliaSender(oliaSenders, NewRTTStats(), initialCongestionWindowPackets, MaxCongestionWindow).(*OliaSender)
+		oliaSenders.Add(pathID, s)
+		return s
+	}
+
+	BeforeEach(func() {
+		oliaSenders = NewOliaSenders()
+		sender1 = newSender(1)
+		sender3 = newSender(3)
+	})
+
+	It("registers the senders", func() {
+		os, ok := oliaSenders.Get(1)
+		Expect(ok).To(BeTrue())
+		Expect(os).To(BeIdenticalTo(sender1))
+		Expect(oliaSenders.All()).To(HaveLen(2))
+		Expect(oliaSenders.Coupled()).To(HaveLen(2))
+	})
+
+	It("deregisters the senders of closed paths", func() {
+		sender3.Sbd_set.Set = map[protocol.PathID]*OliaSender{1: sender1, 3: sender3}
+		oliaSenders.Remove(1)
+		_, ok := oliaSenders.Get(1)
+		Expect(ok).To(BeFalse())
+		Expect(oliaSenders.All()).To(HaveLen(1))
+		Expect(sender3.Sbd_set.Set).ToNot(HaveKey(protocol.PathID(1)))
+		Expect(sender3.coupledSenders()).To(HaveLen(1))
+	})
+
+	It("doesn't couple potentially failed paths until they recover", func() {
+		oliaSenders.SetPotentiallyFailed(1, true)
+		Expect(oliaSenders.Coupled()).To(HaveLen(1))
+		Expect(oliaSenders.Coupled()).To(HaveKey(protocol.PathID(3)))
+		Expect(oliaSenders.All()).To(HaveLen(2))
+		oliaSenders.SetPotentiallyFailed(1, false)
+		Expect(oliaSenders.Coupled()).To(HaveLen(2))
+	})
+
+	It("couples a sender with itself even if its path is potentially failed", func() {
+		oliaSenders.SetPotentiallyFailed(1, true)
+		Expect(sender1.coupledSenders()).To(HaveLen(2))
+		Expect(sender3.coupledSenders()).To(HaveLen(1))
+	})
+
+	It("ignores unknown paths", func() {
+		oliaSenders.SetPotentiallyFailed(5, true)
+		Expect(oliaSenders.Coupled()).To(HaveLen(2))
+		newSender(5)
+		Expect(oliaSenders.Coupled()).To(HaveLen(3))
+	})
+
+	It("computes epsilon without the potentially failed paths", func() {
+		sender1.Olia.epsilonDen = 42
+		oliaSenders.SetPotentiallyFailed(1, true)
+		sender3.getEpsilon()
+		// sender3 is alone, so it has the largest window
+		Expect(sender3.Olia.epsilonNum).To(BeZero())
+		Expect(sender3.Olia.epsilonDen).To(BeEquivalentTo(1))
+		Expect(sender1.Olia.epsilonDen).To(BeEquivalentTo(42))
+	})
+
+	It("returns a snapshot of the OLIA state", func() {
+		sender1.Olia.epsilonNum = -1
+		sender1.Olia.epsilonDen = 4
+		sender1.Olia.UpdateAckedSinceLastLoss(1000)
+		sender1.Olia.OnPacketLost()
+		Expect(sender1.Olia.State()).To(Equal(OliaState{
+			Loss1:      0,
+			Loss2:      1000,
+			Loss3:      1000,
+			EpsilonNum: -1,
+			EpsilonDen: 4,
+		}))
+	})
+
+	It("is safe for concurrent use", func() {
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(2)
+			go func(pathID protocol.PathID) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				newSender(pathID)
+				oliaSenders.SetPotentiallyFailed(pathID, true)
+				oliaSenders.Remove(pathID)
+			}(protocol.PathID(2*i + 5))
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				sender1.coupledSenders()
+				oliaSenders.All()
+			}()
+		}
+		wg.Wait()
+		Expect(oliaSenders.All()).To(HaveLen(2))
+	})
+})
@@ -0,0 +1,98 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+const (
+	// Number of packets that can be sent back-to-back
+	pacerMaxBurstPackets = 10
+	// Minimum delay between two bursts, shorter delays are not worth a timer
+	pacerMinDelay = time.Millisecond
+)
+
+// SendAlgorithmWithPacingRate is a SendAlgorithm computing its own pacing rate
+type SendAlgorithmWithPacingRate interface {
+	SendAlgorithm
+	PacingRate() Bandwidth
+}
+
+// A Pacer spreads the packets sent on a path over time, using a token bucket
+// It paces at the rate of the congestion controller if it has one, and slightly faster than a congestion window per SRTT otherwise
+type Pacer struct {
+	cong     SendAlgorithm
+	rttStats *RTTStats
+
+	budgetAtLastSent protocol.ByteCount
+	lastSentTime     time.Time
+}
+
+// NewPacer makes a new pacer for the congestion controller cong
+func NewPacer(cong SendAlgorithm, rttStats *RTTStats) *Pacer {
+	return &Pacer{
+		cong:     cong,
+		rttStats: rttStats,
+	}
+}
+
+// PacingRate returns the rate at which the packets are sent
+func (p *Pacer) PacingRate() Bandwidth {
+	if cong, ok := p.cong.(SendAlgorithmWithPacingRate); ok {
+		return cong.PacingRate()
+	}
+	srtt := p.rttStats.SmoothedRTT()
+	if srtt == 0 {
+		srtt = time.Duration(p.rttStats.InitialRTTus()) * time.Microsecond
+	}
+	if srtt == 0 {
+		// Without any RTT, there is nothing to pace on
+		return 0
+	}
+	// Pace faster than the window, so that the window remains the limit
+	return BandwidthFromDelta(p.cong.GetCongestionWindow(), srtt) * 5 / 4
+}
+
+// maxBurstSize is the size of the bucket, enough to send during pacerMinDelay
+func (p *Pacer) maxBurstSize(rate Bandwidth) protocol.ByteCount {
+	burst := protocol.ByteCount(float64(rate/BytesPerSecond) * pacerMinDelay.Seconds())
+	return utils.MaxByteCount(burst, pacerMaxBurstPackets*protocol.DefaultTCPMSS)
+}
+
+// Budget returns the number of bytes that can be sent at time now
+func (p *Pacer) Budget(now time.Time) protocol.ByteCount {
+	rate := p.PacingRate()
+	if p.lastSentTime.IsZero() {
+		return p.maxBurstSize(rate)
+	}
+	budget := p.budgetAtLastSent + protocol.ByteCount(float64(rate/BytesPerSecond)*now.Sub(p.lastSentTime).Seconds())
+	return utils.MinByteCount(budget, p.maxBurstSize(rate))
+}
+
+// OnPacketSent consumes the budget used by a packet
+func (p *Pacer) OnPacketSent(sentTime time.Time, bytes protocol.ByteCount) {
+	budget := p.Budget(sentTime)
+	if bytes > budget {
+		p.budgetAtLastSent = 0
+	} else {
+		p.budgetAtLastSent = budget - bytes
+	}
+	p.lastSentTime = sentTime
+}
+
+// TimeUntilSend returns the delay until a full-sized packet can be sent, 0 if it can be sent now
+func (p *Pacer) TimeUntilSend(now time.Time) time.Duration {
+	budget := p.Budget(now)
+	if budget >= protocol.DefaultTCPMSS {
+		return 0
+	}
+	rate := p.PacingRate() / BytesPerSecond
+	if rate == 0 {
+		// Without rate, there is nothing to pace
+		return 0
+	}
+	delay := time.Duration(float64(protocol.DefaultTCPMSS-budget) / float64(rate) * float64(time.Second))
+	return utils.MaxDuration(delay, pacerMinDelay)
+}
@@ -0,0 +1,78 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Pacer", func() {
+	var (
+		clock    mockClock
+		rttStats *RTTStats
+		pacer    *Pacer
+	)
+
+	// 10 packets per 100ms, paced 25% faster
+	const rate = 10 * protocol.DefaultTCPMSS * 10 * 5 / 4
+
+	BeforeEach(func() {
+		clock = mockClock(time.Now())
+		rttStats = NewRTTStats()
+		rttStats.UpdateRTT(100*time.Millisecond, 0, clock.Now())
+		pacer = NewPacer(NewCubicSender(&clock, rttStats, false, initialCongestionWindowPackets, MaxCongestionWindow), rttStats)
+	})
+
+	sendBurst := func() {
+		for i := 0; i < pacerMaxBurstPackets; i++ {
+			Expect(pacer.TimeUntilSend(clock.Now())).To(BeZero())
+			pacer.OnPacketSent(clock.Now(), protocol.DefaultTCPMSS)
+		}
+	}
+
+	It("paces slightly faster than a congestion window per RTT", func() {
+		Expect(pacer.PacingRate()).To(Equal(Bandwidth(rate) * BytesPerSecond))
+	})
+
+	It("uses the initial RTT without RTT sample", func() {
+		pacer = NewPacer(NewCubicSender(&clock, NewRTTStats(), false, initialCongestionWindowPackets, MaxCongestionWindow), NewRTTStats())
+		Expect(pacer.PacingRate()).To(Equal(Bandwidth(rate) * BytesPerSecond))
+	})
+
+	It("uses the pacing rate of the congestion controller", func() {
+		bbr := NewBbrSender(&clock, rttStats, initialCongestionWindowPackets, MaxCongestionWindow)
+		pacer = NewPacer(bbr, rttStats)
+		Expect(pacer.PacingRate()).To(Equal(bbr.(*BbrSender).PacingRate()))
+	})
+
+	It("allows an initial burst", func() {
+		Expect(pacer.Budget(clock.Now())).To(Equal(pacerMaxBurstPackets * protocol.DefaultTCPMSS))
+		sendBurst()
+		Expect(pacer.Budget(clock.Now())).To(BeZero())
+		Expect(pacer.TimeUntilSend(clock.Now())).ToNot(BeZero())
+	})
+
+	It("returns the time until a packet can be sent", func() {
+		sendBurst()
+		delay := time.Duration(float64(protocol.DefaultTCPMSS) / float64(rate) * float64(time.Second))
+		Expect(pacer.TimeUntilSend(clock.Now())).To(Equal(delay))
+		clock.Advance(delay / 2)
+		Expect(pacer.TimeUntilSend(clock.Now())).To(BeNumerically("~", delay/2, time.Microsecond))
+		clock.Advance(delay / 2)
+		Expect(pacer.TimeUntilSend(clock.Now())).To(BeZero())
+	})
+
+	It("doesn't accumulate more than a burst", func() {
+		sendBurst()
+		clock.Advance(time.Hour)
+		Expect(pacer.Budget(clock.Now())).To(Equal(pacerMaxBurstPackets * protocol.DefaultTCPMSS))
+	})
+
+	It("waits at least the minimum delay", func() {
+		sendBurst()
+		pacer.budgetAtLastSent = protocol.DefaultTCPMSS - 1
+		Expect(pacer.TimeUntilSend(clock.Now())).To(Equal(pacerMinDelay))
+	})
+})
@@ -0,0 +1,34 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// A RateSample is a delivery rate sample, generated for every ACK acknowledging new packets
+// It follows draft-cheng-iccrg-delivery-rate-estimation
+type RateSample struct {
+	// Bytes delivered during the sample interval
+	Delivered protocol.ByteCount
+	// Duration of the sample interval
+	Interval time.Duration
+	// Bytes delivered on the path when the most recently sent acked packet was sent
+	PriorDelivered protocol.ByteCount
+	// RTT of the most recently sent acked packet
+	RTT time.Duration
+	// Bytes newly acked by the ACK
+	AckedBytes protocol.ByteCount
+	// Bytes in flight once the ACK has been processed
+	BytesInFlight protocol.ByteCount
+	// Whether the sender was application limited when the acked packets were sent
+	IsAppLimited bool
+}
+
+// DeliveryRate returns the delivery rate of the sample, or 0 if the sample is invalid
+func (rs *RateSample) DeliveryRate() Bandwidth {
+	if rs.Interval <= 0 {
+		return 0
+	}
+	return BandwidthFromDelta(rs.Delivered, rs.Interval)
+}
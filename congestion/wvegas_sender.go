@@ -0,0 +1,317 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+const (
+	// Number of packets the coupled paths should keep in the bottleneck queues, shared between them according to their rates
+	wvegasTotalAlpha = 10
+	// Number of queued packets above which the path leaves slow start
+	wvegasGamma = 1
+	// wvegasBeta is the backoff factor after a loss
+	wvegasBeta float32 = 0.5
+)
+
+// WVegasSender implements weighted Vegas, a delay-based coupled congestion control for MPTCP
+// (Cao, Xu and Fu, "Delay-based Congestion Control for Multipath TCP", ICNP 2012)
+// The queueing delay is measured on the one-way delays of the packets when they are available, and on the RTT otherwise.
+type WVegasSender struct {
+	clock    Clock
+	prr      PrrSender
+	rttStats *RTTStats
+	stats    connectionStats
+	// All the wVegas senders of the session, this one included
+	wvegasSenders *CoupledSenders
+
+	// Smallest one-way delay observed, zero if none
+	baseOWD time.Duration
+	// Smallest queueing delay sample of the current round, negative if there is none
+	roundQueueingDelay time.Duration
+	// Smallest queueing delay sampled since the last backoff, zero if none
+	queueingDelay time.Duration
+	// Time at which the current round ends, one SRTT after it started
+	roundEnd time.Time
+
+	// Track the largest packet that has been sent.
+	largestSentPacketNumber protocol.PacketNumber
+
+	// Track the largest packet that has been acked.
+	largestAckedPacketNumber protocol.PacketNumber
+
+	// Track the largest packet number outstanding when a CWND cutback occurs.
+	largestSentAtLastCutback protocol.PacketNumber
+
+	// Congestion window in packets.
+	congestionWindow protocol.PacketNumber
+
+	// Slow start congestion window in packets, aka ssthresh.
+	slowstartThreshold protocol.PacketNumber
+
+	// Minimum congestion window in packets.
+	minCongestionWindow protocol.PacketNumber
+
+	// Maximum number of outstanding packets for tcp.
+	maxTCPCongestionWindow protocol.PacketNumber
+
+	initialCongestionWindow    protocol.PacketNumber
+	initialMaxCongestionWindow protocol.PacketNumber
+}
+
+// NewWVegasSender makes a new wVegas sender, coupled with the other senders of wvegasSenders
+// The caller has to add the sender to wvegasSenders
+func NewWVegasSender(wvegasSenders *CoupledSenders, clock Clock, rttStats *RTTStats, initialCongestionWindow, initialMaxCongestionWindow protocol.PacketNumber) SendAlgorithmWithDebugInfo {
+	return &WVegasSender{
+		clock:                      clock,
+		rttStats:                   rttStats,
+		wvegasSenders:              wvegasSenders,
+		roundQueueingDelay:         -1,
+		initialCongestionWindow:    initialCongestionWindow,
+		initialMaxCongestionWindow: initialMaxCongestionWindow,
+		congestionWindow:           initialCongestionWindow,
+		minCongestionWindow:        defaultMinimumCongestionWindow,
+		slowstartThreshold:         initialMaxCongestionWindow,
+		maxTCPCongestionWindow:     initialMaxCongestionWindow,
+	}
+}
+
+func (w *WVegasSender) coupledState() (protocol.PacketNumber, time.Duration) {
+	return w.congestionWindow, w.rttStats.SmoothedRTT()
+}
+
+// Weight returns the share of the rate of the coupled paths sent on this path
+func (w *WVegasSender) Weight() float64 {
+	x := coupledRate(w)
+	if x == 0 {
+		return 1
+	}
+	var total float64
+	for _, ws := range w.wvegasSenders.coupledWith(w) {
+		total += coupledRate(ws)
+	}
+	return x / total
+}
+
+// alpha returns the number of packets this path should keep queued
+func (w *WVegasSender) alpha() float64 {
+	return w.Weight() * wvegasTotalAlpha
+}
+
+// queueingDelaySample computes the queueing delay experienced by an acked packet
+func (w *WVegasSender) queueingDelaySample(owd time.Duration) time.Duration {
+	if owd > 0 {
+		if w.baseOWD == 0 || owd < w.baseOWD {
+			w.baseOWD = owd
+		}
+		return owd - w.baseOWD
+	}
+	if w.rttStats.MinRTT() == 0 {
+		return 0
+	}
+	return utils.MaxDuration(w.rttStats.LatestRTT()-w.rttStats.MinRTT(), 0)
+}
+
+// queuedPackets estimates the number of packets of the path waiting in the bottleneck queue
+func (w *WVegasSender) queuedPackets(queueingDelay time.Duration) float64 {
+	baseRTT := w.rttStats.MinRTT()
+	if baseRTT == 0 {
+		return 0
+	}
+	return float64(w.congestionWindow) * queueingDelay.Seconds() / (baseRTT + queueingDelay).Seconds()
+}
+
+// onRoundEnd adjusts the congestion window once per round trip, with the smallest queueing delay of the round
+func (w *WVegasSender) onRoundEnd(queueingDelay time.Duration) {
+	// Back off when the queueing delay doubled since it was last at its minimum
+	if w.queueingDelay == 0 || queueingDelay < w.queueingDelay {
+		w.queueingDelay = queueingDelay
+	}
+	if w.queueingDelay > 0 && queueingDelay >= 2*w.queueingDelay {
+		baseRTT := w.rttStats.MinRTT()
+		w.congestionWindow = protocol.PacketNumber(float64(w.congestionWindow) * baseRTT.Seconds() / (2 * (baseRTT + queueingDelay).Seconds()))
+		w.congestionWindow = utils.MaxPacketNumber(w.congestionWindow, w.minCongestionWindow)
+		w.slowstartThreshold = w.congestionWindow
+		w.queueingDelay = 0
+		return
+	}
+
+	diff := w.queuedPackets(queueingDelay)
+	if w.InSlowStart() {
+		if diff > wvegasGamma {
+			w.ExitSlowstart()
+		}
+		return
+	}
+	alpha := w.alpha()
+	if diff > alpha {
+		w.congestionWindow = utils.MaxPacketNumber(w.congestionWindow-1, w.minCongestionWindow)
+	} else if diff < alpha && w.congestionWindow < w.maxTCPCongestionWindow {
+		w.congestionWindow++
+	}
+}
+
+func (w *WVegasSender) OnPacketSent(sentTime time.Time, bytesInFlight protocol.ByteCount, packetNumber protocol.PacketNumber, bytes protocol.ByteCount, isRetransmittable bool) bool {
+	// Only update bytesInFlight for data packets.
+	if !isRetransmittable {
+		return false
+	}
+	if w.InRecovery() {
+		// PRR is used when in recovery.
+		w.prr.OnPacketSent(bytes)
+	}
+	w.largestSentPacketNumber = packetNumber
+	return true
+}
+
+func (w *WVegasSender) GetCongestionWindow() protocol.ByteCount {
+	return protocol.ByteCount(w.congestionWindow) * protocol.DefaultTCPMSS
+}
+
+func (w *WVegasSender) GetSlowStartThreshold() protocol.ByteCount {
+	return protocol.ByteCount(w.slowstartThreshold) * protocol.DefaultTCPMSS
+}
+
+func (w *WVegasSender) ExitSlowstart() {
+	w.slowstartThreshold = w.congestionWindow
+}
+
+// MaybeExitSlowStart is a no-op, wVegas leaves slow start when packets start queueing
+func (w *WVegasSender) MaybeExitSlowStart() {}
+
+func (w *WVegasSender) OnPacketAcked(ev *AckEvent) {
+	w.largestAckedPacketNumber = utils.MaxPacketNumber(ev.PacketNumber, w.largestAckedPacketNumber)
+
+	queueingDelay := w.queueingDelaySample(ev.OWD)
+	if w.roundQueueingDelay < 0 || queueingDelay < w.roundQueueingDelay {
+		w.roundQueueingDelay = queueingDelay
+	}
+
+	if w.InRecovery() {
+		// PRR is used when in recovery
+		w.prr.OnPacketAcked(ev.Bytes)
+		return
+	}
+
+	if w.InSlowStart() && w.congestionWindow < w.maxTCPCongestionWindow {
+		w.congestionWindow++
+	}
+
+	now := w.clock.Now()
+	if now.Before(w.roundEnd) {
+		return
+	}
+	if !w.roundEnd.IsZero() {
+		w.onRoundEnd(w.roundQueueingDelay)
+	}
+	w.roundQueueingDelay = -1
+	w.roundEnd = now.Add(w.rttStats.SmoothedRTT())
+}
+
+func (w *WVegasSender) OnPacketLost(ev *LossEvent) {
+	// TCP NewReno (RFC6582) says that once a loss occurs, any losses in packets
+	// already sent should be treated as a single loss event, since it's expected.
+	if ev.PacketNumber <= w.largestSentAtLastCutback {
+		return
+	}
+	if w.InSlowStart() {
+		w.stats.slowstartPacketsLost++
+	}
+	w.reduceCongestionWindow(ev.BytesInFlight)
+}
+
+// OnCongestionEvent reduces the window as a loss does, without counting a lost packet
+func (w *WVegasSender) OnCongestionEvent(ev *CongestionEvent) {
+	// The CE marks of the packets sent before the last reduction belong to the same congestion event
+	if ev.PacketNumber <= w.largestSentAtLastCutback {
+		return
+	}
+	w.reduceCongestionWindow(ev.BytesInFlight)
+}
+
+// reduceCongestionWindow cuts the window on a congestion event, i.e. a loss or CE marks
+func (w *WVegasSender) reduceCongestionWindow(bytesInFlight protocol.ByteCount) {
+	w.prr.OnPacketLost(bytesInFlight)
+
+	w.congestionWindow = protocol.PacketNumber(float32(w.congestionWindow) * w.RenoBeta())
+	// Enforce a minimum congestion window.
+	if w.congestionWindow < w.minCongestionWindow {
+		w.congestionWindow = w.minCongestionWindow
+	}
+	w.slowstartThreshold = w.congestionWindow
+	w.largestSentAtLastCutback = w.largestSentPacketNumber
+}
+
+// SetNumEmulatedConnections is a no-op, the coupling already accounts for the other paths
+func (w *WVegasSender) SetNumEmulatedConnections(n int) {}
+
+// OnRetransmissionTimeout is called on an retransmission timeout
+func (w *WVegasSender) OnRetransmissionTimeout(packetsRetransmitted bool) {
+	w.largestSentAtLastCutback = 0
+	if !packetsRetransmitted {
+		return
+	}
+	w.slowstartThreshold = w.congestionWindow / 2
+	w.congestionWindow = w.minCongestionWindow
+}
+
+func (w *WVegasSender) OnConnectionMigration() {
+	w.prr = PrrSender{}
+	w.largestSentPacketNumber = 0
+	w.largestAckedPacketNumber = 0
+	w.largestSentAtLastCutback = 0
+	w.baseOWD = 0
+	w.queueingDelay = 0
+	w.roundQueueingDelay = -1
+	w.roundEnd = time.Time{}
+	w.congestionWindow = w.initialCongestionWindow
+	w.slowstartThreshold = w.initialMaxCongestionWindow
+	w.maxTCPCongestionWindow = w.initialMaxCongestionWindow
+}
+
+// RetransmissionDelay gives the RTO retransmission time
+func (w *WVegasSender) RetransmissionDelay() time.Duration {
+	if w.rttStats.SmoothedRTT() == 0 {
+		return 0
+	}
+	return w.rttStats.SmoothedRTT() + w.rttStats.MeanDeviation()*4
+}
+
+func (w *WVegasSender) SmoothedRTT() time.Duration {
+	return w.rttStats.SmoothedRTT()
+}
+
+// SetSlowStartLargeReduction is a no-op for wVegas
+func (w *WVegasSender) SetSlowStartLargeReduction(enabled bool) {}
+
+func (w *WVegasSender) BandwidthEstimate() Bandwidth {
+	srtt := w.rttStats.SmoothedRTT()
+	if srtt == 0 {
+		// If we haven't measured an rtt, the bandwidth estimate is unknown.
+		return 0
+	}
+	return BandwidthFromDelta(w.GetCongestionWindow(), srtt)
+}
+
+// HybridSlowStart returns nil, wVegas doesn't use hybrid slow start
+func (w *WVegasSender) HybridSlowStart() *HybridSlowStart {
+	return nil
+}
+
+func (w *WVegasSender) SlowstartThreshold() protocol.PacketNumber {
+	return w.slowstartThreshold
+}
+
+func (w *WVegasSender) RenoBeta() float32 {
+	return wvegasBeta
+}
+
+func (w *WVegasSender) InRecovery() bool {
+	return w.largestAckedPacketNumber <= w.largestSentAtLastCutback && w.largestAckedPacketNumber != 0
+}
+
+func (w *WVegasSender) InSlowStart() bool {
+	return w.GetCongestionWindow() < w.GetSlowStartThreshold()
+}
@@ -0,0 +1,167 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("wVegas Sender", func() {
+	const baseOWD = 20 * time.Millisecond
+
+	var (
+		wvegasSenders *CoupledSenders
+		clock         mockClock
+		sender1       *WVegasSender
+		sender2       *WVegasSender
+		packetNumber  protocol.PacketNumber
+	)
+
+	newSender := func(pathID protocol.PathID, rtt time.Duration) *WVegasSender {
+		rttStats := NewRTTStats()
+		rttStats.UpdateRTT(rtt, 0, clock.Now())
+		s := NewWVegasSender(wvegasSenders, &clock, rttStats, initialCongestionWindowPackets, MaxCongestionWindow).(*WVegasSender)
+		wvegasSenders.Add(pathID, s)
+		return s
+	}
+
+	ack := func(s *WVegasSender, owd time.Duration) {
+		packetNumber++
+		s.OnPacketAcked(&AckEvent{PacketNumber: packetNumber, Bytes: protocol.DefaultTCPMSS, BytesInFlight: s.GetCongestionWindow(), OWD: owd})
+	}
+
+	// startCongestionAvoidance leaves slow start and starts a round, without queueing delay
+	startCongestionAvoidance := func(s *WVegasSender, cwnd protocol.PacketNumber) {
+		s.congestionWindow = cwnd
+		s.slowstartThreshold = cwnd
+		ack(s, baseOWD)
+	}
+
+	BeforeEach(func() {
+		clock = mockClock{}
+		packetNumber = 0
+		wvegasSenders = NewCoupledSenders()
+		sender1 = newSender(1, 100*time.Millisecond)
+		sender2 = newSender(3, 50*time.Millisecond)
+	})
+
+	It("weights the paths with their rates", func() {
+		// x1 = 10 / 0.1 = 100, x2 = 10 / 0.05 = 200
+		Expect(sender1.Weight()).To(BeNumerically("~", 1./3, 1e-9))
+		Expect(sender2.Weight()).To(BeNumerically("~", 2./3, 1e-9))
+		wvegasSenders.Remove(3)
+		Expect(sender1.Weight()).To(Equal(1.0))
+	})
+
+	It("doesn't weight with potentially failed paths until they recover", func() {
+		wvegasSenders.SetPotentiallyFailed(3, true)
+		Expect(sender1.Weight()).To(Equal(1.0))
+		wvegasSenders.SetPotentiallyFailed(3, false)
+		Expect(sender1.Weight()).To(BeNumerically("~", 1./3, 1e-9))
+	})
+
+	It("grows the window by one packet per ACK in slow start", func() {
+		ack(sender1, baseOWD)
+		Expect(sender1.congestionWindow).To(Equal(initialCongestionWindowPackets + 1))
+	})
+
+	It("leaves slow start when packets start queueing", func() {
+		ack(sender1, baseOWD)
+		clock.Advance(50 * time.Millisecond)
+		ack(sender1, 40*time.Millisecond)
+		Expect(sender1.InSlowStart()).To(BeTrue())
+		clock.Advance(50 * time.Millisecond)
+		// 13 packets * 20ms / 120ms > gamma
+		ack(sender1, 40*time.Millisecond)
+		Expect(sender1.InSlowStart()).To(BeFalse())
+		Expect(sender1.SlowstartThreshold()).To(Equal(initialCongestionWindowPackets + 3))
+	})
+
+	It("adjusts the window only once per round trip", func() {
+		startCongestionAvoidance(sender1, 100)
+		for i := 0; i < 10; i++ {
+			clock.Advance(5 * time.Millisecond)
+			ack(sender1, 60*time.Millisecond)
+		}
+		Expect(sender1.congestionWindow).To(Equal(protocol.PacketNumber(100)))
+		clock.Advance(50 * time.Millisecond)
+		ack(sender1, 60*time.Millisecond)
+		Expect(sender1.congestionWindow).To(Equal(protocol.PacketNumber(99)))
+	})
+
+	It("uses the smallest queueing delay of the round", func() {
+		wvegasSenders.Remove(3)
+		startCongestionAvoidance(sender1, 100)
+		clock.Advance(50 * time.Millisecond)
+		// 100 packets * 5ms / 105ms < alpha
+		ack(sender1, baseOWD+5*time.Millisecond)
+		clock.Advance(50 * time.Millisecond)
+		ack(sender1, baseOWD+50*time.Millisecond)
+		Expect(sender1.congestionWindow).To(Equal(protocol.PacketNumber(101)))
+	})
+
+	It("shifts traffic away from the congested path", func() {
+		sender2 = newSender(3, 100*time.Millisecond)
+		startCongestionAvoidance(sender1, 50)
+		startCongestionAvoidance(sender2, 50)
+		clock.Advance(100 * time.Millisecond)
+		// alpha = 5 on both paths, 50 * 2ms / 102ms < 5 < 50 * 20ms / 120ms
+		ack(sender1, baseOWD+2*time.Millisecond)
+		ack(sender2, baseOWD+20*time.Millisecond)
+		Expect(sender1.congestionWindow).To(Equal(protocol.PacketNumber(51)))
+		Expect(sender2.congestionWindow).To(Equal(protocol.PacketNumber(49)))
+	})
+
+	It("backs off when the queueing delay doubles", func() {
+		wvegasSenders.Remove(3)
+		startCongestionAvoidance(sender1, 100)
+		clock.Advance(100 * time.Millisecond)
+		ack(sender1, baseOWD+10*time.Millisecond)
+		Expect(sender1.congestionWindow).To(Equal(protocol.PacketNumber(101)))
+		clock.Advance(100 * time.Millisecond)
+		ack(sender1, baseOWD+30*time.Millisecond)
+		// 101 * 100ms / (2 * 130ms)
+		Expect(sender1.congestionWindow).To(Equal(protocol.PacketNumber(38)))
+		Expect(sender1.SlowstartThreshold()).To(Equal(protocol.PacketNumber(38)))
+	})
+
+	It("falls back to the RTT without one-way delay", func() {
+		wvegasSenders.Remove(3)
+		startCongestionAvoidance(sender1, 100)
+		sender1.rttStats.UpdateRTT(130*time.Millisecond, 0, clock.Now())
+		clock.Advance(200 * time.Millisecond)
+		// 100 packets * 30ms / 130ms > alpha
+		ack(sender1, UnknownOWD)
+		Expect(sender1.congestionWindow).To(Equal(protocol.PacketNumber(99)))
+	})
+
+	It("halves the window on loss", func() {
+		sender1.congestionWindow = 20
+		sender1.OnPacketSent(clock.Now(), 0, 1, protocol.DefaultTCPMSS, true)
+		sender1.OnPacketLost(&LossEvent{PacketNumber: 1, Bytes: protocol.DefaultTCPMSS, BytesInFlight: 20 * protocol.DefaultTCPMSS})
+		Expect(sender1.congestionWindow).To(Equal(protocol.PacketNumber(10)))
+		Expect(sender1.SlowstartThreshold()).To(Equal(protocol.PacketNumber(10)))
+		Expect(sender2.congestionWindow).To(Equal(initialCongestionWindowPackets))
+	})
+
+	It("halves the window once per round trip on CE marks", func() {
+		sender1.congestionWindow = 20
+		sender1.OnPacketSent(clock.Now(), 0, 1, protocol.DefaultTCPMSS, true)
+		sender1.OnPacketSent(clock.Now(), 0, 2, protocol.DefaultTCPMSS, true)
+		sender1.OnCongestionEvent(&CongestionEvent{PacketNumber: 1, BytesInFlight: 19 * protocol.DefaultTCPMSS})
+		Expect(sender1.congestionWindow).To(Equal(protocol.PacketNumber(10)))
+		Expect(sender1.stats.slowstartPacketsLost).To(BeZero())
+		// the CE marks of the packets sent before the reduction belong to the same congestion event
+		sender1.OnCongestionEvent(&CongestionEvent{PacketNumber: 2, BytesInFlight: 18 * protocol.DefaultTCPMSS})
+		Expect(sender1.congestionWindow).To(Equal(protocol.PacketNumber(10)))
+	})
+
+	It("resets the window on retransmission timeout", func() {
+		sender1.congestionWindow = 20
+		sender1.OnRetransmissionTimeout(true)
+		Expect(sender1.congestionWindow).To(Equal(defaultMinimumCongestionWindow))
+		Expect(sender1.SlowstartThreshold()).To(Equal(protocol.PacketNumber(10)))
+	})
+})
@@ -3,10 +3,20 @@ package quic
 import (
 	"net"
 	"sync"
+
+	"golang.org/x/net/ipv4"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 )
 
 type connection interface {
 	Write([]byte) error
+	// Queue queues a datagram, which is written by the next Flush with the ECN codepoint.
+	// The buffer must come from the packet buffer pool, it's put back after being written.
+	Queue([]byte, protocol.ECN) error
+	// Flush writes the queued datagrams, with a single syscall if batched I/O is supported
+	Flush() error
 	Read([]byte) (int, net.Addr, error)
 	Close() error
 	LocalAddr() net.Addr
@@ -19,6 +29,18 @@ type conn struct {
 
 	pconn       net.PacketConn
 	currentAddr net.Addr
+
+	queueMutex sync.Mutex
+	queue      [][]byte
+	queueECN   []protocol.ECN
+	// batch is nil if batched writes are not supported, it's set up by the first flush
+	batch       batchConn
+	batchMsgs   []ipv4.Message
+	batchLoaded bool
+	// gso is set if the kernel segments the datagrams (UDP_SEGMENT), it's cleared if the interface doesn't support it
+	gso bool
+	// oob holds the control messages of the datagrams written, i.e. the segment size and the ECN codepoint
+	oob []byte
 }
 
 var _ connection = &conn{}
@@ -28,6 +50,137 @@ func (c *conn) Write(p []byte) error {
 	return err
 }
 
+func (c *conn) Queue(p []byte, ecn protocol.ECN) error {
+	c.queueMutex.Lock()
+	defer c.queueMutex.Unlock()
+
+	c.queue = append(c.queue, p)
+	c.queueECN = append(c.queueECN, ecn)
+	if len(c.queue) >= protocol.MaxBatchSize {
+		return c.flush()
+	}
+	return nil
+}
+
+func (c *conn) Flush() error {
+	c.queueMutex.Lock()
+	defer c.queueMutex.Unlock()
+
+	return c.flush()
+}
+
+// flush must be called with the queueMutex held
+func (c *conn) flush() error {
+	if len(c.queue) == 0 {
+		return nil
+	}
+	defer func() {
+		for i, p := range c.queue {
+			putPacketBuffer(p)
+			c.queue[i] = nil
+		}
+		c.queue = c.queue[:0]
+		c.queueECN = c.queueECN[:0]
+	}()
+
+	if !c.batchLoaded {
+		c.batchLoaded = true
+		if c.batch = newBatchConn(c.pconn); c.batch != nil {
+			c.batchMsgs = make([]ipv4.Message, 0, protocol.MaxBatchSize)
+			c.gso = gsoSupported(c.pconn)
+		}
+		c.oob = make([]byte, 0, protocol.MaxBatchSize*(gsoOOBSize+ecnOOBSize))
+	}
+	addr := c.RemoteAddr()
+	if c.batch == nil || len(c.queue) == 1 || !canWriteBatch(c.pconn, addr) {
+		// Fall back to one syscall per datagram
+		for i, p := range c.queue {
+			if err := c.writeTo(p, c.queueECN[i], addr); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	sent, err := c.writeBatch(c.queue, c.queueECN, addr)
+	if err != nil && c.gso && isGSOError(err) {
+		// The interface can't segment the datagrams, don't use GSO anymore
+		if utils.Debug() {
+			utils.Debugf("Disabling GSO on %s: %s", c.pconn.LocalAddr(), err)
+		}
+		c.gso = false
+		_, err = c.writeBatch(c.queue[sent:], c.queueECN[sent:], addr)
+	}
+	return err
+}
+
+// writeTo writes a single datagram, the ECN codepoint is set with a control message
+func (c *conn) writeTo(p []byte, ecn protocol.ECN, addr net.Addr) error {
+	if ecn != protocol.ECNNon {
+		udpConn, ok := c.pconn.(*net.UDPConn)
+		udpAddr, isUDPAddr := addr.(*net.UDPAddr)
+		if ok && isUDPAddr {
+			if oob := appendECN(c.oob[:0], udpAddr.IP.To4() != nil, ecn); len(oob) > 0 {
+				_, _, err := udpConn.WriteMsgUDP(p, oob, udpAddr)
+				return err
+			}
+		}
+	}
+	_, err := c.pconn.WriteTo(p, addr)
+	return err
+}
+
+// writeBatch writes the datagrams with sendmmsg, and returns how many of them were written.
+// If GSO is supported, consecutive datagrams of the same size and ECN codepoint are sent as a single one, which the kernel segments.
+func (c *conn) writeBatch(queue [][]byte, ecns []protocol.ECN, addr net.Addr) (int, error) {
+	ipv4Addr := isIPv4Socket(c.pconn)
+	batch := c.batchMsgs[:0]
+	oob := c.oob[:0]
+	for i := 0; i < len(queue); {
+		n := 1
+		if c.gso {
+			n = gsoSegments(queue[i:])
+			for j := 1; j < n; j++ {
+				if ecns[i+j] != ecns[i] {
+					n = j
+					break
+				}
+			}
+		}
+		m := ipv4.Message{Buffers: queue[i : i+n], Addr: addr}
+		start := len(oob)
+		if n > 1 {
+			oob = appendSegmentSize(oob, uint16(len(queue[i])))
+		}
+		if ecns[i] != protocol.ECNNon {
+			oob = appendECN(oob, ipv4Addr, ecns[i])
+		}
+		if len(oob) > start {
+			m.OOB = oob[start:]
+		}
+		batch = append(batch, m)
+		i += n
+	}
+	defer func() {
+		for i := range batch {
+			batch[i] = ipv4.Message{}
+		}
+	}()
+
+	var sent int
+	for ms := batch; len(ms) > 0; {
+		n, err := c.batch.WriteBatch(ms, 0)
+		if err != nil {
+			return sent, err
+		}
+		for _, m := range ms[:n] {
+			sent += len(m.Buffers)
+		}
+		ms = ms[n:]
+	}
+	return sent, nil
+}
+
 func (c *conn) Read(p []byte) (int, net.Addr, error) {
 	return c.pconn.ReadFrom(p)
 }
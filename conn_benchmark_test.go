@@ -0,0 +1,151 @@
+package quic
+
+import (
+	"net"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/ipv4"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// These benchmarks compare the batched I/O (recvmmsg and sendmmsg on Linux) of the conn and of the pconnManager
+// with their fallback to one syscall per datagram. Every iteration writes and reads protocol.MaxBatchSize datagrams
+// of a full size on the loopback interface.
+
+const benchmarkDatagramSize = 1252
+
+// singlePacketConn hides the *net.UDPConn, so that no batched I/O is used on it
+type singlePacketConn struct {
+	net.PacketConn
+}
+
+type benchmarkUDPPair struct {
+	sender, receiver *net.UDPConn
+	// batchReceiver is nil if batched I/O is not supported
+	batchReceiver batchConn
+	msgs          []ipv4.Message
+}
+
+func newBenchmarkUDPPair(b *testing.B) *benchmarkUDPPair {
+	sender, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		b.Fatal(err)
+	}
+	receiver, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		b.Fatal(err)
+	}
+	p := &benchmarkUDPPair{
+		sender:        sender,
+		receiver:      receiver,
+		batchReceiver: newBatchConn(receiver),
+		msgs:          newBatchMessages(protocol.MaxBatchSize),
+	}
+	for i := range p.msgs {
+		p.msgs[i].Buffers[0] = make([]byte, protocol.MaxReceivePacketSize)
+	}
+	b.SetBytes(protocol.MaxBatchSize * benchmarkDatagramSize)
+	return p
+}
+
+func (p *benchmarkUDPPair) Close() {
+	p.sender.Close()
+	p.receiver.Close()
+}
+
+// read reads the datagrams written by an iteration, with as few syscalls as possible
+func (p *benchmarkUDPPair) read(b *testing.B) {
+	if p.batchReceiver == nil {
+		for i := 0; i < protocol.MaxBatchSize; i++ {
+			if _, _, err := p.receiver.ReadFrom(p.msgs[0].Buffers[0]); err != nil {
+				b.Fatal(err)
+			}
+		}
+		return
+	}
+	for read := 0; read < protocol.MaxBatchSize; {
+		n, err := p.batchReceiver.ReadBatch(p.msgs[read:], 0)
+		if err != nil {
+			b.Fatal(err)
+		}
+		read += n
+	}
+}
+
+func benchmarkConnFlush(b *testing.B, batched bool) {
+	p := newBenchmarkUDPPair(b)
+	defer p.Close()
+	c := &conn{pconn: p.sender, currentAddr: p.receiver.LocalAddr()}
+	if !batched {
+		c.batchLoaded = true
+		c.oob = make([]byte, 0, ecnOOBSize)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < protocol.MaxBatchSize; j++ {
+			if err := c.Queue(getPacketBuffer()[:benchmarkDatagramSize], protocol.ECNNon); err != nil {
+				b.Fatal(err)
+			}
+		}
+		if err := c.Flush(); err != nil {
+			b.Fatal(err)
+		}
+		p.read(b)
+	}
+}
+
+// BenchmarkConnFlush queues the datagrams on a conn, and flushes them
+func BenchmarkConnFlush(b *testing.B) {
+	b.Run("single", func(b *testing.B) { benchmarkConnFlush(b, false) })
+	b.Run("batch", func(b *testing.B) {
+		if !batchIOSupported {
+			b.Skip("batched I/O is not supported")
+		}
+		benchmarkConnFlush(b, true)
+	})
+}
+
+func benchmarkPconnManagerListen(b *testing.B, batched bool) {
+	p := newBenchmarkUDPPair(b)
+	defer p.Close()
+	var received sync.WaitGroup
+	pcm := &pconnManager{
+		errorConn: make(chan error, 1),
+		dispatch: func(rcvRawPacket *receivedRawPacket) {
+			putPacketBuffer(rcvRawPacket.data)
+			received.Done()
+		},
+	}
+	if batched {
+		go pcm.listenBatch(p.receiver, p.batchReceiver)
+	} else {
+		go pcm.listen(singlePacketConn{p.receiver})
+	}
+
+	data := make([]byte, benchmarkDatagramSize)
+	addr := p.receiver.LocalAddr()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		received.Add(protocol.MaxBatchSize)
+		for j := 0; j < protocol.MaxBatchSize; j++ {
+			if _, err := p.sender.WriteTo(data, addr); err != nil {
+				b.Fatal(err)
+			}
+		}
+		received.Wait()
+	}
+}
+
+// BenchmarkPconnManagerListen reads the datagrams with the listening goroutine of the pconnManager
+func BenchmarkPconnManagerListen(b *testing.B) {
+	b.Run("single", func(b *testing.B) { benchmarkPconnManagerListen(b, false) })
+	b.Run("batch", func(b *testing.B) {
+		if !batchIOSupported {
+			b.Skip("batched I/O is not supported")
+		}
+		benchmarkPconnManagerListen(b, true)
+	})
+}
@@ -4,8 +4,14 @@ import (
 	"bytes"
 	"io"
 	"net"
+	"os"
+	"syscall"
 	"time"
 
+	"golang.org/x/net/ipv4"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -44,6 +50,26 @@ func (c *mockPacketConn) SetWriteDeadline(t time.Time) error { panic("not implem
 
 var _ net.PacketConn = &mockPacketConn{}
 
+// mockBatchConn fails to write datagrams sent with GSO, like an interface without checksum offload
+type mockBatchConn struct {
+	written [][]byte
+}
+
+func (c *mockBatchConn) ReadBatch(ms []ipv4.Message, flags int) (int, error) {
+	panic("not implemented")
+}
+func (c *mockBatchConn) WriteBatch(ms []ipv4.Message, flags int) (int, error) {
+	for i, m := range ms {
+		if len(m.Buffers) > 1 {
+			return i, &net.OpError{Op: "write", Net: "udp", Err: os.NewSyscallError("sendmmsg", syscall.EIO)}
+		}
+		c.written = append(c.written, append([]byte{}, m.Buffers[0]...))
+	}
+	return len(ms), nil
+}
+
+var _ batchConn = &mockBatchConn{}
+
 var _ = Describe("Connection", func() {
 	var c *conn
 	var packetConn *mockPacketConn
@@ -67,6 +93,102 @@ var _ = Describe("Connection", func() {
 		Expect(packetConn.dataWrittenTo.String()).To(Equal("192.168.100.200:1337"))
 	})
 
+	It("queues packets until they are flushed", func() {
+		p := append(getPacketBuffer(), []byte("foo")...)
+		Expect(c.Queue(p, protocol.ECNNon)).To(Succeed())
+		p = append(getPacketBuffer(), []byte("bar")...)
+		Expect(c.Queue(p, protocol.ECNNon)).To(Succeed())
+		Expect(packetConn.dataWritten.Len()).To(BeZero())
+		Expect(c.Flush()).To(Succeed())
+		Expect(packetConn.dataWritten.Bytes()).To(Equal([]byte("foobar")))
+		Expect(packetConn.dataWrittenTo.String()).To(Equal("192.168.100.200:1337"))
+		// the queue is empty after flushing
+		Expect(c.Flush()).To(Succeed())
+		Expect(packetConn.dataWritten.Len()).To(Equal(6))
+	})
+
+	It("flushes when the queue is full", func() {
+		for i := 0; i < protocol.MaxBatchSize; i++ {
+			Expect(c.Queue(append(getPacketBuffer(), 'a'), protocol.ECNNon)).To(Succeed())
+		}
+		Expect(packetConn.dataWritten.Len()).To(Equal(protocol.MaxBatchSize))
+	})
+
+	It("writes a batch on a UDP socket", func() {
+		receiver, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		defer receiver.Close()
+		sender, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		defer sender.Close()
+		c = &conn{pconn: sender, currentAddr: receiver.LocalAddr()}
+		for _, data := range []string{"foo", "bar", "baz"} {
+			Expect(c.Queue(append(getPacketBuffer(), data...), protocol.ECNNon)).To(Succeed())
+		}
+		Expect(c.Flush()).To(Succeed())
+		Expect(c.batch != nil).To(Equal(batchIOSupported))
+		receiver.SetReadDeadline(time.Now().Add(time.Second))
+		b := make([]byte, 10)
+		for _, data := range []string{"foo", "bar", "baz"} {
+			n, addr, err := receiver.ReadFrom(b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(addr.String()).To(Equal(sender.LocalAddr().String()))
+			Expect(string(b[:n])).To(Equal(data))
+		}
+	})
+
+	It("sends consecutive datagrams of the same size with GSO", func() {
+		receiver, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		defer receiver.Close()
+		sender, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		defer sender.Close()
+		if !batchIOSupported || !gsoSupported(sender) {
+			Skip("GSO is not supported")
+		}
+		c = &conn{pconn: sender, currentAddr: receiver.LocalAddr()}
+		datagrams := [][]byte{
+			bytes.Repeat([]byte{'a'}, 100),
+			bytes.Repeat([]byte{'b'}, 100),
+			bytes.Repeat([]byte{'c'}, 100),
+			bytes.Repeat([]byte{'d'}, 50),
+			bytes.Repeat([]byte{'e'}, 100),
+		}
+		for _, data := range datagrams {
+			Expect(c.Queue(append(getPacketBuffer(), data...), protocol.ECNNon)).To(Succeed())
+		}
+		Expect(c.Flush()).To(Succeed())
+		Expect(c.gso).To(BeTrue())
+		// the receiver doesn't use GRO, so the kernel segments the datagrams
+		receiver.SetReadDeadline(time.Now().Add(time.Second))
+		b := make([]byte, 200)
+		for _, data := range datagrams {
+			n, _, err := receiver.ReadFrom(b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b[:n]).To(Equal(data))
+		}
+	})
+
+	It("stops using GSO if the interface doesn't support it", func() {
+		if !batchIOSupported {
+			Skip("GSO is not supported")
+		}
+		packetConn.addr = &net.UDPAddr{IP: net.IPv4zero}
+		batch := &mockBatchConn{}
+		c.batchLoaded = true
+		c.batch = batch
+		c.batchMsgs = make([]ipv4.Message, 0, protocol.MaxBatchSize)
+		c.gso = true
+		c.oob = make([]byte, 0, protocol.MaxBatchSize*(gsoOOBSize+ecnOOBSize))
+		for _, data := range []string{"foo", "bar", "baz"} {
+			Expect(c.Queue(append(getPacketBuffer(), data...), protocol.ECNNon)).To(Succeed())
+		}
+		Expect(c.Flush()).To(Succeed())
+		Expect(c.gso).To(BeFalse())
+		Expect(batch.written).To(Equal([][]byte{[]byte("foo"), []byte("bar"), []byte("baz")}))
+	})
+
 	It("reads", func() {
 		packetConn.dataToRead = []byte("foo")
 		packetConn.dataReadFrom = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1336}
@@ -0,0 +1,46 @@
+package quic
+
+import (
+	"net"
+	"syscall"
+	"unsafe"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// ecnMask selects the ECN codepoint of the TOS / traffic class
+const ecnMask = 0x3
+
+// ecnOOBSize is the size of the control message carrying the TOS / traffic class of a datagram
+var ecnOOBSize = syscall.CmsgSpace(4)
+
+// enableECN asks the kernel to report the TOS / traffic class of the received datagrams.
+// Both options are set, since an IPv6 socket also receives IPv4 datagrams.
+// It returns false if neither is supported.
+func enableECN(pconn net.PacketConn) bool {
+	var enabled bool
+	controlUDPSocket(pconn, func(fd int) {
+		errV4 := syscall.SetsockoptInt(fd, syscall.IPPROTO_IP, syscall.IP_RECVTOS, 1)
+		errV6 := syscall.SetsockoptInt(fd, syscall.IPPROTO_IPV6, syscall.IPV6_RECVTCLASS, 1)
+		enabled = errV4 == nil || errV6 == nil
+	})
+	return enabled
+}
+
+// appendECN appends the control message marking a datagram with the ECN codepoint.
+// The IPv4 option must be used for IPv4 destinations, even on an IPv6 socket.
+func appendECN(b []byte, ipv4 bool, ecn protocol.ECN) []byte {
+	start := len(b)
+	b = append(b, make([]byte, ecnOOBSize)...)
+	h := (*syscall.Cmsghdr)(unsafe.Pointer(&b[start]))
+	if ipv4 {
+		h.Level = syscall.IPPROTO_IP
+		h.Type = syscall.IP_TOS
+	} else {
+		h.Level = syscall.IPPROTO_IPV6
+		h.Type = syscall.IPV6_TCLASS
+	}
+	h.SetLen(syscall.CmsgLen(4))
+	*(*int32)(unsafe.Pointer(&b[start+syscall.CmsgLen(0)])) = int32(ecn)
+	return b
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+// +build !linux
+
+package quic
+
+import (
+	"net"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// The ECN codepoints are only set and read on Linux
+
+var ecnOOBSize = 0
+
+func enableECN(pconn net.PacketConn) bool { return false }
+
+func appendECN(b []byte, ipv4 bool, ecn protocol.ECN) []byte { return b }
@@ -11,6 +11,7 @@ import (
 	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/hpack"
 
+	quic "github.com/lucas-clemente/quic-go"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -23,6 +24,7 @@ type mockStream struct {
 	reset        bool
 	closed       bool
 	remoteClosed bool
+	priority     quic.StreamPriority
 
 	unblockRead chan struct{}
 	ctx         context.Context
@@ -48,6 +50,12 @@ func (s *mockStream) SetReadDeadline(time.Time) error              { panic("not
 func (s *mockStream) SetWriteDeadline(time.Time) error             { panic("not implemented") }
 func (s *mockStream) GetBytesSent() (protocol.ByteCount, error)    { panic("not implemented") }
 func (s *mockStream) GetBytesRetrans() (protocol.ByteCount, error) { panic("not implemented") }
+func (s *mockStream) SetPathPreference(quic.PathPreference)        { panic("not implemented") }
+func (s *mockStream) PathPreference() quic.PathPreference          { panic("not implemented") }
+func (s *mockStream) SetPriority(p quic.StreamPriority)            { s.priority = p }
+func (s *mockStream) Priority() quic.StreamPriority                { return s.priority }
+func (s *mockStream) SetDeliveryDeadline(time.Duration)            { panic("not implemented") }
+func (s *mockStream) DeliveryDeadline() time.Duration              { panic("not implemented") }
 
 func (s *mockStream) Read(p []byte) (int, error) {
 	n, _ := s.dataToRead.Read(p)
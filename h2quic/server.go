@@ -23,6 +23,7 @@ import (
 type streamCreator interface {
 	quic.Session
 	GetOrOpenStream(protocol.StreamID) (quic.Stream, error)
+	GetStream(protocol.StreamID) quic.Stream
 }
 
 type remoteCloser interface {
@@ -152,6 +153,9 @@ func (s *Server) handleRequest(session streamCreator, headerStream quic.Stream,
 	if err != nil {
 		return qerr.Error(qerr.HeadersStreamDataDecompressFailure, "cannot read frame")
 	}
+	if h2priorityFrame, ok := h2frame.(*http2.PriorityFrame); ok {
+		return s.handlePriorityFrame(session, h2priorityFrame)
+	}
 	h2headersFrame, ok := h2frame.(*http2.HeadersFrame)
 	if !ok {
 		return qerr.Error(qerr.InvalidHeadersStreamData, "expected a header frame")
@@ -186,6 +190,9 @@ func (s *Server) handleRequest(session streamCreator, headerStream quic.Stream,
 	if dataStream == nil {
 		return nil
 	}
+	if h2headersFrame.HasPriority() {
+		dataStream.SetPriority(streamPriorityFromHTTP2(h2headersFrame.Priority))
+	}
 
 	var streamEnded bool
 	if h2headersFrame.StreamEnded() {
@@ -239,6 +246,24 @@ func (s *Server) handleRequest(session streamCreator, headerStream quic.Stream,
 	return nil
 }
 
+func (s *Server) handlePriorityFrame(session streamCreator, h2priorityFrame *http2.PriorityFrame) error {
+	// Don't open the stream, a PRIORITY frame doesn't count as using it.
+	// The priorities of the streams that are not open, not opened yet or already closed, are ignored.
+	if dataStream := session.GetStream(protocol.StreamID(h2priorityFrame.StreamID)); dataStream != nil {
+		dataStream.SetPriority(streamPriorityFromHTTP2(h2priorityFrame.PriorityParam))
+	}
+	return nil
+}
+
+// streamPriorityFromHTTP2 converts HTTP/2 priority information, whose weights go from 0 to 255, to a stream priority
+func streamPriorityFromHTTP2(p http2.PriorityParam) quic.StreamPriority {
+	return quic.StreamPriority{
+		Weight:    uint16(p.Weight) + 1,
+		DependsOn: protocol.StreamID(p.StreamDep),
+		Exclusive: p.Exclusive,
+	}
+}
+
 // Close the server immediately, aborting requests and sending CONNECTION_CLOSE frames to connected clients.
 // Close in combination with ListenAndServe() (instead of Serve()) may race if it is called before a UDP socket is established.
 func (s *Server) Close() error {
@@ -36,11 +36,16 @@ type mockSession struct {
 	streamOpenErr       error
 	ctx                 context.Context
 	ctxCancel           context.CancelFunc
+	streamsOpened       int
 }
 
 func (s *mockSession) GetOrOpenStream(id protocol.StreamID) (quic.Stream, error) {
+	s.streamsOpened++
 	return s.dataStream, nil
 }
+func (s *mockSession) GetStream(id protocol.StreamID) quic.Stream {
+	return s.dataStream
+}
 func (s *mockSession) AcceptStream() (quic.Stream, error) { return s.streamToAccept, nil }
 func (s *mockSession) OpenStream() (quic.Stream, error) {
 	if s.streamOpenErr != nil {
@@ -71,6 +76,9 @@ func (s *mockSession) RemoteAddr() net.Addr {
 func (s *mockSession) Context() context.Context {
 	return s.ctx
 }
+func (s *mockSession) PathStats() []quic.PathStats {
+	panic("not implemented")
+}
 
 var _ = Describe("H2 server", func() {
 	var (
@@ -144,6 +152,44 @@ var _ = Describe("H2 server", func() {
 			}).Should(Equal([]byte{0x0, 0x0, 0x1, 0x1, 0x4, 0x0, 0x0, 0x0, 0x5, 0x88})) // 0x88 is 200
 		})
 
+		It("sets the priority of the data stream", func() {
+			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+			err := http2.NewFramer(&headerStream.dataToRead, nil).WriteHeaders(http2.HeadersFrameParam{
+				StreamID:   5,
+				EndHeaders: true,
+				EndStream:  true,
+				// Taken from https://http2.github.io/http2-spec/compression.html#request.examples.with.huffman.coding
+				BlockFragment: []byte{0x82, 0x86, 0x84, 0x41, 0x8c, 0xf1, 0xe3, 0xc2, 0xe5, 0xf2, 0x3a, 0x6b, 0xa0, 0xab, 0x90, 0xf4, 0xff},
+				Priority:      http2.PriorityParam{StreamDep: 7, Weight: 41},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			err = s.handleRequest(session, headerStream, &sync.Mutex{}, hpackDecoder, h2framer)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dataStream.priority).To(Equal(quic.StreamPriority{Weight: 42, DependsOn: 7}))
+		})
+
+		It("handles PRIORITY frames", func() {
+			err := http2.NewFramer(&headerStream.dataToRead, nil).WritePriority(5, http2.PriorityParam{
+				StreamDep: 3,
+				Exclusive: true,
+				Weight:    255,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			err = s.handleRequest(session, headerStream, &sync.Mutex{}, hpackDecoder, h2framer)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dataStream.priority).To(Equal(quic.StreamPriority{Weight: 256, DependsOn: 3, Exclusive: true}))
+			Expect(session.streamsOpened).To(BeZero())
+		})
+
+		It("ignores PRIORITY frames for streams that are not open", func() {
+			session.dataStream = nil
+			err := http2.NewFramer(&headerStream.dataToRead, nil).WritePriority(5, http2.PriorityParam{Weight: 255})
+			Expect(err).ToNot(HaveOccurred())
+			err = s.handleRequest(session, headerStream, &sync.Mutex{}, hpackDecoder, h2framer)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(session.streamsOpened).To(BeZero())
+		})
+
 		It("correctly handles a panicking handler", func() {
 			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				panic("foobar")
@@ -6,8 +6,11 @@ import (
 	"net"
 	"time"
 
+	"github.com/lucas-clemente/quic-go/ackhandler"
+	"github.com/lucas-clemente/quic-go/congestion"
 	"github.com/lucas-clemente/quic-go/internal/handshake"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/logging"
 )
 
 // The StreamID is the ID of a QUIC stream.
@@ -19,6 +22,39 @@ type VersionNumber = protocol.VersionNumber
 // A Cookie can be used to verify the ownership of the client address.
 type Cookie = handshake.Cookie
 
+// A StreamPriority tells how the sending capacity is shared between streams, following the HTTP/2 model.
+// A stream is only served when the stream it depends on has nothing to send,
+// and streams at the same level get a share of the capacity proportional to their weight.
+type StreamPriority struct {
+	// Weight of the stream, between 1 and 256.
+	// If zero, the default weight of 16 is used.
+	Weight uint16
+	// DependsOn is the stream this stream depends on.
+	// If zero, or if that stream is not open, the stream does not depend on any stream.
+	DependsOn StreamID
+	// Exclusive marks an exclusive dependency. It is kept for HTTP/2, but not used for scheduling yet.
+	Exclusive bool
+}
+
+// StreamDeadlineExceeded is the error code of the RST_STREAM sent when a stream misses its delivery deadline.
+// It lies outside of the range of the gQUIC stream error codes.
+const StreamDeadlineExceeded uint32 = 0x100
+
+// A PathID identifies a path of a multipath QUIC connection.
+type PathID = protocol.PathID
+
+// A PathPreference tells the scheduler on which paths the data of a stream should be sent.
+// The zero value lets the scheduler use any path.
+// If none of the preferred paths is usable anymore, the data is sent on any path.
+type PathPreference struct {
+	// Pinned restricts the stream to the path PathID.
+	Pinned bool
+	PathID PathID
+	// Interface restricts the stream to the paths using the local interface with this name (e.g. "wlan0").
+	// It is ignored if Pinned is set.
+	Interface string
+}
+
 // Stream is the interface implemented by QUIC streams
 type Stream interface {
 	// Read reads data from the stream.
@@ -55,6 +91,21 @@ type Stream interface {
 	GetBytesSent() (protocol.ByteCount, error)
 	// GetBytesRetrans returns the number of bytes of the stream that were retransmitted to the peer
 	GetBytesRetrans() (protocol.ByteCount, error)
+	// SetPathPreference sets the paths on which the data of the stream should be sent.
+	// It applies to data not sent yet, including retransmissions.
+	SetPathPreference(PathPreference)
+	// PathPreference returns the path preference of the stream.
+	PathPreference() PathPreference
+	// SetPriority sets the priority of the stream.
+	SetPriority(StreamPriority)
+	// Priority returns the priority of the stream.
+	Priority() StreamPriority
+	// SetDeliveryDeadline sets the time within which the data passed to Write must reach the peer.
+	// Data that can no longer make it is dropped, and the stream is reset with the error code StreamDeadlineExceeded.
+	// It applies to the following calls to Write. A zero value disables the deadline.
+	SetDeliveryDeadline(time.Duration)
+	// DeliveryDeadline returns the delivery deadline of the stream.
+	DeliveryDeadline() time.Duration
 }
 
 // A Session is a QUIC connection between two peers.
@@ -78,6 +129,95 @@ type Session interface {
 	// The context is cancelled when the session is closed.
 	// Warning: This API should not be considered stable and might change soon.
 	Context() context.Context
+	// PathStats returns statistics about the paths of the connection, including the closed ones.
+	PathStats() []PathStats
+}
+
+// PathStats contains statistics about a path.
+type PathStats struct {
+	PathID     PathID
+	LocalAddr  net.Addr
+	RemoteAddr net.Addr
+	// Interface is the name of the local interface used by the path, if known
+	Interface string
+	Open      bool
+	// PotentiallyFailed is set when the path stopped getting acknowledgments
+	PotentiallyFailed bool
+	Backup            bool
+
+	PacketsSent     uint64
+	Retransmissions uint64
+	Losses          uint64
+	// BytesSent counts the bytes of all packets sent on the path, including retransmissions
+	BytesSent protocol.ByteCount
+
+	// CostPerMB is the cost configured for the path, zero if none
+	CostPerMB float64
+	// BudgetExhausted is set when the byte budget of the path is used up
+	BudgetExhausted bool
+
+	// Congestion state of the path
+	CongestionWindow   protocol.ByteCount
+	SlowStartThreshold protocol.ByteCount
+	BytesInFlight      protocol.ByteCount
+	InRecovery         bool
+	InSlowStart        bool
+	BandwidthEstimate  congestion.Bandwidth
+	MinRTT             time.Duration
+	LatestRTT          time.Duration
+	SmoothedRTT        time.Duration
+	RTTVar             time.Duration
+	// TLPCount and RTOCount count the probes sent since the last ACK
+	TLPCount uint32
+	RTOCount uint32
+	// Olia contains the loss counters and epsilon of OLIA, nil if the path doesn't use OLIA
+	Olia *congestion.OliaState
+	// ECN is the state of the ECN validation of the path.
+	// ECNCEMarks counts the packets the peer received with a CE mark.
+	ECN        ackhandler.ECNState
+	ECNCEMarks uint64
+}
+
+// A CongestionControlAlgorithm is a congestion control algorithm that can be used on the paths of a session.
+type CongestionControlAlgorithm int
+
+const (
+	// CongestionControlDefault uses OLIA for the paths of multipath sessions, and Cubic otherwise.
+	CongestionControlDefault CongestionControlAlgorithm = iota
+	// CongestionControlCubic uses Cubic on every path.
+	CongestionControlCubic
+	// CongestionControlReno uses Reno on every path.
+	CongestionControlReno
+	// CongestionControlOlia couples the paths with OLIA.
+	// The initial path of multipath sessions, only used for the handshake, keeps Cubic.
+	CongestionControlOlia
+	// CongestionControlLia couples the paths with LIA (RFC 6356).
+	// The initial path of multipath sessions, only used for the handshake, keeps Cubic.
+	CongestionControlLia
+	// CongestionControlBalia couples the paths with BALIA.
+	// The initial path of multipath sessions, only used for the handshake, keeps Cubic.
+	CongestionControlBalia
+	// CongestionControlWVegas couples the paths with wVegas, a delay-based controller moving traffic away from paths with growing queues.
+	// The initial path of multipath sessions, only used for the handshake, keeps Cubic.
+	CongestionControlWVegas
+	// CongestionControlBbr uses BBR on every path.
+	CongestionControlBbr
+)
+
+// A PathCost describes the cost of sending data through a local interface or address.
+type PathCost struct {
+	// Interface is the name of the local interface (e.g. "rmnet0") the cost applies to.
+	Interface string
+	// Addr is the local IP address the cost applies to, if Interface is not set.
+	Addr net.IP
+	// CostPerMB is the price of sending one MB. Cheaper paths are preferred by the scheduler.
+	CostPerMB float64
+	// Budget is the maximum number of bytes that may be sent on the paths using this interface or address.
+	// Once it is exhausted, these paths stop carrying data. If zero, there is no budget.
+	Budget uint64
+	// BudgetWindow is the period after which the budget is renewed.
+	// If zero, the budget applies to the whole connection.
+	BudgetWindow time.Duration
 }
 
 // A NonFWSession is a QUIC connection between two peers half-way through the handshake.
@@ -123,6 +263,31 @@ type Config struct {
 	CacheHandshake bool
 	// Should the host try to create new paths, if possible?
 	CreatePaths bool
+	// BackupInterfaces lists the names of the local interfaces (e.g. "rmnet0") whose paths should only be used as backup.
+	// Such paths only carry PING frames as long as another path is usable, and take over the traffic once all other paths
+	// are potentially failed or closed. The preference is advertised to the peer, so that it handles these paths the same way.
+	BackupInterfaces []string
+	// PathCosts gives the cost of the local interfaces or addresses, and optionally a byte budget for them.
+	// The first matching entry applies. Paths without any matching entry are free.
+	PathCosts []PathCost
+	// CongestionControl is the congestion control algorithm used on the paths.
+	// If zero, OLIA is used for the paths of multipath sessions, and Cubic otherwise.
+	CongestionControl CongestionControlAlgorithm
+	// NewCongestionControl creates the congestion controller of a path. If set, CongestionControl is ignored.
+	// It is called for every path of the session, and must return a new SendAlgorithm every time.
+	NewCongestionControl func(pathID PathID, rttStats *congestion.RTTStats) congestion.SendAlgorithm
+	// HyStartPlusPlus makes the congestion controllers leave slow start with HyStart++ (RFC 9406) instead of the hybrid slow start.
+	// It avoids premature exits from slow start on paths with a noisy RTT. Controllers without slow start, like BBR, ignore it.
+	// It doesn't apply to the controllers created by NewCongestionControl, which can use congestion.SendAlgorithmWithSlowStart.
+	HyStartPlusPlus bool
+	// NewTracer creates the tracer of a session, which receives structured events about its packets, paths and congestion control.
+	// It is called once per session. The qlog package provides a tracer writing qlog files.
+	NewTracer func(perspective logging.Perspective, connectionID logging.ConnectionID) logging.Tracer
+	// NewPacketCapture creates the file that every datagram sent and received by a session is written to, in the pcapng format.
+	// Every path is a pcapng interface, and the datagrams get fake IP and UDP headers built from the path addresses.
+	// It is called once per session, and the file is closed when the session is closed.
+	// The packets can be decrypted with the keys logged to the tls.Config.KeyLogWriter.
+	NewPacketCapture func(perspective logging.Perspective, connectionID logging.ConnectionID) io.WriteCloser
 }
 
 // A Listener for incoming QUIC connections
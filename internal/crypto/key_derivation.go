@@ -38,6 +38,20 @@ func DeriveAESKeys(mc MintController, pers protocol.Perspective) (AEAD, error) {
 	return NewAEADAESGCM(otherKey, myKey, otherIV, myIV)
 }
 
+// ComputeTrafficSecrets computes the 1-RTT secrets of the client and the server, which the AES keys are derived from
+func ComputeTrafficSecrets(mc MintController) (clientSecret, serverSecret []byte, err error) {
+	cs := mc.GetCipherSuite()
+	clientSecret, err = mc.ComputeExporter(clientExporterLabel, nil, cs.Hash.Size())
+	if err != nil {
+		return nil, nil, err
+	}
+	serverSecret, err = mc.ComputeExporter(serverExporterLabel, nil, cs.Hash.Size())
+	if err != nil {
+		return nil, nil, err
+	}
+	return clientSecret, serverSecret, nil
+}
+
 func computeKeyAndIV(mc MintController, label string) (key, iv []byte, err error) {
 	cs := mc.GetCipherSuite()
 	secret, err := mc.ComputeExporter(label, nil, cs.Hash.Size())
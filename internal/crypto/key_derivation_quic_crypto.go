@@ -33,6 +33,14 @@ func DeriveQuicCryptoAESKeys(forwardSecure bool, sharedSecret, nonces []byte, co
 	return NewAEADAESGCM12(otherKey, myKey, otherIV, myIV)
 }
 
+// DeriveQuicCryptoAESKeyMaterial derives the keys and IVs that DeriveQuicCryptoAESKeys uses, e.g. for logging them.
+// The client and the server encrypt the packets they send with their own key and IV.
+func DeriveQuicCryptoAESKeyMaterial(forwardSecure bool, sharedSecret, nonces []byte, connID protocol.ConnectionID, chlo []byte, scfg []byte, cert []byte, divNonce []byte) (clientKey, clientIV, serverKey, serverIV []byte, err error) {
+	// without swapping, the other key is the client's and my key is the server's
+	clientKey, serverKey, clientIV, serverIV, err = deriveKeys(forwardSecure, sharedSecret, nonces, connID, chlo, scfg, cert, divNonce, 16, false)
+	return
+}
+
 // deriveKeys derives the keys and the IVs
 // swap should be set true if generating the values for the client, and false for the server
 func deriveKeys(forwardSecure bool, sharedSecret, nonces []byte, connID protocol.ConnectionID, chlo, scfg, cert, divNonce []byte, keyLen int, swap bool) ([]byte, []byte, []byte, []byte, error) {
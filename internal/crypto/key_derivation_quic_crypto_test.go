@@ -156,6 +156,24 @@ var _ = Describe("QUIC Crypto Key Derivation", func() {
 			Expect(aesgcm.myIV).To(Equal([]byte{0x64, 0xef, 0x3c, 0x9}))
 		})
 
+		It("returns the key material of the client and the server", func() {
+			clientKey, clientIV, serverKey, serverIV, err := DeriveQuicCryptoAESKeyMaterial(
+				false,
+				[]byte("0123456789012345678901"),
+				[]byte("nonce"),
+				protocol.ConnectionID(42),
+				[]byte("chlo"),
+				[]byte("scfg"),
+				[]byte("cert"),
+				[]byte("divnonce"),
+			)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(clientKey).To(HaveLen(16))
+			Expect(serverKey).To(HaveLen(16))
+			Expect(clientIV).To(Equal([]byte{0x64, 0xef, 0x3c, 0x9}))
+			Expect(serverIV).To(Equal([]byte{0x1c, 0xec, 0xac, 0x9b}))
+		})
+
 		It("derives forward secure keys", func() {
 			aead, err := DeriveQuicCryptoAESKeys(
 				true,
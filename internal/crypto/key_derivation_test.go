@@ -61,4 +61,11 @@ var _ = Describe("Key Derivation", func() {
 		_, err := DeriveAESKeys(&mockMintController{hash: crypto.SHA256, computerError: testErr}, protocol.PerspectiveClient)
 		Expect(err).To(MatchError(testErr))
 	})
+
+	It("computes the traffic secrets", func() {
+		clientSecret, serverSecret, err := ComputeTrafficSecrets(&mockMintController{hash: crypto.SHA256})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(clientSecret).To(Equal([]byte(clientExporterLabel)))
+		Expect(serverSecret).To(Equal([]byte(serverExporterLabel)))
+	})
 })
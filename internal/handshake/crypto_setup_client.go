@@ -60,6 +60,9 @@ type cryptoSetupClient struct {
 
 	params               *TransportParameters
 	connectionParameters ConnectionParametersManager
+
+	// keyLogWriter is nil if the keys are not logged
+	keyLogWriter io.Writer
 }
 
 var _ CryptoSetup = &cryptoSetupClient{}
@@ -82,6 +85,10 @@ func NewCryptoSetupClient(
 	params *TransportParameters,
 	negotiatedVersions []protocol.VersionNumber,
 ) (CryptoSetup, error) {
+	var keyLogWriter io.Writer
+	if tlsConfig != nil {
+		keyLogWriter = tlsConfig.KeyLogWriter
+	}
 	return &cryptoSetupClient{
 		hostname:             hostname,
 		connID:               connID,
@@ -96,6 +103,7 @@ func NewCryptoSetupClient(
 		negotiatedVersions:   negotiatedVersions,
 		divNonceChan:         make(chan []byte),
 		params:               params,
+		keyLogWriter:         keyLogWriter,
 	}, nil
 }
 
@@ -401,6 +409,11 @@ func (h *cryptoSetupClient) handleSHLOMessage(cryptoData map[Tag][]byte) error {
 	if err != nil {
 		return err
 	}
+	if h.keyLogWriter != nil {
+		if err := logQuicCryptoKeys(h.keyLogWriter, true, ephermalSharedSecret, nonce, h.connID, h.lastSentCHLO, h.serverConfig.Get(), leafCert, nil); err != nil {
+			return err
+		}
+	}
 
 	err = h.connectionParameters.SetFromMap(cryptoData)
 	if err != nil {
@@ -634,6 +647,11 @@ func (h *cryptoSetupClient) maybeUpgradeCrypto() error {
 		if err != nil {
 			return err
 		}
+		if h.keyLogWriter != nil {
+			if err := logQuicCryptoKeys(h.keyLogWriter, false, h.serverConfig.sharedSecret, nonce, h.connID, h.lastSentCHLO, h.serverConfig.Get(), leafCert, h.diversificationNonce); err != nil {
+				return err
+			}
+		}
 
 		h.aeadChanged <- protocol.EncryptionSecure
 	}
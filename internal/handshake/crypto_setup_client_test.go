@@ -5,6 +5,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/crypto"
@@ -438,6 +439,15 @@ var _ = Describe("Client Crypto Setup", func() {
 			Expect(aeadChanged).To(BeClosed())
 		})
 
+		It("logs the forward-secure keys", func() {
+			keyLog := &bytes.Buffer{}
+			cs.keyLogWriter = keyLog
+			err := cs.handleSHLOMessage(shloMap)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keyLog.String()).To(HavePrefix(keyLogLabelQuicCryptoClientForwardSecure + " "))
+			Expect(strings.Count(keyLog.String(), "\n")).To(Equal(2))
+		})
+
 		It("reads the connection paramaters", func() {
 			shloMap[TagICSL] = []byte{3, 0, 0, 0} // 3 seconds
 			err := cs.handleSHLOMessage(shloMap)
@@ -633,6 +643,16 @@ var _ = Describe("Client Crypto Setup", func() {
 			Expect(aeadChanged).ToNot(BeClosed())
 		})
 
+		It("logs the keys of the secureAEAD", func() {
+			keyLog := &bytes.Buffer{}
+			cs.keyLogWriter = keyLog
+			cs.serverVerified = true
+			err := cs.maybeUpgradeCrypto()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keyLog.String()).To(HavePrefix(keyLogLabelQuicCryptoClientSecure + " "))
+			Expect(keyLog.String()).To(ContainSubstring("\n" + keyLogLabelQuicCryptoServerSecure + " "))
+		})
+
 		It("uses the server nonce, if the server sent one", func() {
 			cs.serverVerified = true
 			cs.sno = []byte("server nonce")
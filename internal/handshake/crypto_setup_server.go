@@ -389,6 +389,11 @@ func (h *cryptoSetupServer) handleCHLO(sni string, data []byte, cryptoData map[T
 	if err != nil {
 		return nil, err
 	}
+	if h.scfg.KeyLogWriter != nil {
+		if err := logQuicCryptoKeys(h.scfg.KeyLogWriter, false, sharedSecret, clientNonce, h.connID, data, h.scfg.Get(), certUncompressed, h.diversificationNonce); err != nil {
+			return nil, err
+		}
+	}
 
 	h.aeadChanged <- protocol.EncryptionSecure
 
@@ -416,6 +421,11 @@ func (h *cryptoSetupServer) handleCHLO(sni string, data []byte, cryptoData map[T
 	if err != nil {
 		return nil, err
 	}
+	if h.scfg.KeyLogWriter != nil {
+		if err := logQuicCryptoKeys(h.scfg.KeyLogWriter, true, ephermalSharedSecret, fsNonce.Bytes(), h.connID, data, h.scfg.Get(), certUncompressed, nil); err != nil {
+			return nil, err
+		}
+	}
 
 	err = h.connectionParameters.SetFromMap(cryptoData)
 	if err != nil {
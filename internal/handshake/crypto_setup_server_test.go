@@ -5,6 +5,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"net"
+	"strings"
 
 	"github.com/lucas-clemente/quic-go/internal/crypto"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
@@ -350,6 +351,24 @@ var _ = Describe("Server Crypto Setup", func() {
 			Expect(cs.forwardSecureAEAD.(*mockAEAD).encLevel).To(Equal(protocol.EncryptionForwardSecure))
 		})
 
+		It("logs the keys", func() {
+			keyLog := &bytes.Buffer{}
+			cs.scfg.KeyLogWriter = keyLog
+			_, err := cs.handleCHLO("", []byte("chlo-data"), map[Tag][]byte{
+				TagPUBS: []byte("pubs-c"),
+				TagNONC: nonce32,
+				TagAEAD: aead,
+				TagKEXS: kexs,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			lines := strings.Split(strings.TrimSuffix(keyLog.String(), "\n"), "\n")
+			Expect(lines).To(HaveLen(4))
+			Expect(lines[0]).To(HavePrefix(keyLogLabelQuicCryptoClientSecure + " "))
+			Expect(lines[1]).To(HavePrefix(keyLogLabelQuicCryptoServerSecure + " "))
+			Expect(lines[2]).To(HavePrefix(keyLogLabelQuicCryptoClientForwardSecure + " "))
+			Expect(lines[3]).To(HavePrefix(keyLogLabelQuicCryptoServerForwardSecure + " "))
+		})
+
 		It("handles long handshake", func() {
 			HandshakeMessage{
 				Tag: TagCHLO,
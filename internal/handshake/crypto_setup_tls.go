@@ -24,6 +24,10 @@ type cryptoSetupTLS struct {
 	mintConf *mint.Config
 	conn     crypto.MintController
 
+	// keyLogWriter is nil if the secrets are not logged
+	keyLogWriter io.Writer
+	clientHello  *clientHelloRecorder
+
 	nullAEAD crypto.AEAD
 	aead     crypto.AEAD
 
@@ -44,6 +48,15 @@ func NewCryptoSetupTLS(
 		return nil, err
 	}
 	mintConf.ServerName = hostname
+	var keyLogWriter io.Writer
+	if tlsConfig != nil {
+		keyLogWriter = tlsConfig.KeyLogWriter
+	}
+	var clientHello *clientHelloRecorder
+	if keyLogWriter != nil {
+		clientHello = newClientHelloRecorder(cryptoStream, perspective)
+		cryptoStream = clientHello
+	}
 	var conn *mint.Conn
 	if perspective == protocol.PerspectiveServer {
 		conn = mint.Server(&fakeConn{cryptoStream}, mintConf)
@@ -54,6 +67,8 @@ func NewCryptoSetupTLS(
 		perspective:   perspective,
 		mintConf:      mintConf,
 		conn:          &mintController{conn},
+		keyLogWriter:  keyLogWriter,
+		clientHello:   clientHello,
 		nullAEAD:      crypto.NewNullAEAD(perspective, version),
 		keyDerivation: crypto.DeriveAESKeys,
 		aeadChanged:   aeadChanged,
@@ -70,6 +85,11 @@ func (h *cryptoSetupTLS) HandleCryptoStream() error {
 	if err != nil {
 		return err
 	}
+	if h.keyLogWriter != nil {
+		if err := logTrafficSecrets(h.keyLogWriter, h.conn, h.clientHello.clientRandom()); err != nil {
+			return err
+		}
+	}
 	h.mutex.Lock()
 	h.aead = aead
 	h.mutex.Unlock()
@@ -0,0 +1,121 @@
+package handshake
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/lucas-clemente/quic-go/internal/crypto"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// The labels of the key log lines, in the NSS key log format read by Wireshark:
+// <label> <client random or connection ID> <secret>, with hex encoded values.
+const (
+	keyLogLabelClientTrafficSecret = "QUIC_CLIENT_TRAFFIC_SECRET_0"
+	keyLogLabelServerTrafficSecret = "QUIC_SERVER_TRAFFIC_SECRET_0"
+
+	// gQUIC has no client random, so its keys are identified by the connection ID,
+	// and the secret is the AES key followed by the IV.
+	keyLogLabelQuicCryptoClientSecure        = "QUIC_CRYPTO_CLIENT_SECURE"
+	keyLogLabelQuicCryptoServerSecure        = "QUIC_CRYPTO_SERVER_SECURE"
+	keyLogLabelQuicCryptoClientForwardSecure = "QUIC_CRYPTO_CLIENT_FORWARD_SECURE"
+	keyLogLabelQuicCryptoServerForwardSecure = "QUIC_CRYPTO_SERVER_FORWARD_SECURE"
+)
+
+const (
+	// the ClientHello starts with a record header, a handshake header and the legacy version
+	clientRandomOffset = 5 + 4 + 2
+	clientRandomLen    = 32
+
+	tlsRecordTypeHandshake  = 22
+	tlsHandshakeClientHello = 1
+)
+
+// A clientHelloRecorder records the beginning of the ClientHello, since the key log lines of TLS contain the client random.
+// The client records what it writes on the crypto stream, the server what it reads.
+type clientHelloRecorder struct {
+	io.ReadWriter
+
+	mutex  sync.Mutex
+	sent   bool
+	prefix []byte
+}
+
+func newClientHelloRecorder(stream io.ReadWriter, pers protocol.Perspective) *clientHelloRecorder {
+	return &clientHelloRecorder{
+		ReadWriter: stream,
+		sent:       pers == protocol.PerspectiveClient,
+		prefix:     make([]byte, 0, clientRandomOffset+clientRandomLen),
+	}
+}
+
+func (r *clientHelloRecorder) Read(b []byte) (int, error) {
+	n, err := r.ReadWriter.Read(b)
+	if !r.sent {
+		r.record(b[:n])
+	}
+	return n, err
+}
+
+func (r *clientHelloRecorder) Write(b []byte) (int, error) {
+	if r.sent {
+		r.record(b)
+	}
+	return r.ReadWriter.Write(b)
+}
+
+func (r *clientHelloRecorder) record(b []byte) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if n := cap(r.prefix) - len(r.prefix); n < len(b) {
+		b = b[:n]
+	}
+	r.prefix = append(r.prefix, b...)
+}
+
+// clientRandom returns the client random, or nil if no ClientHello was recorded
+func (r *clientHelloRecorder) clientRandom() []byte {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if len(r.prefix) < cap(r.prefix) || r.prefix[0] != tlsRecordTypeHandshake || r.prefix[5] != tlsHandshakeClientHello {
+		return nil
+	}
+	return r.prefix[clientRandomOffset:]
+}
+
+// logTrafficSecrets writes the 1-RTT secrets of a TLS handshake to the key log
+func logTrafficSecrets(w io.Writer, mc crypto.MintController, clientRandom []byte) error {
+	if clientRandom == nil {
+		return fmt.Errorf("CryptoSetup: no ClientHello recorded for the key log")
+	}
+	clientSecret, serverSecret, err := crypto.ComputeTrafficSecrets(mc)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s %x %x\n%s %x %x\n",
+		keyLogLabelClientTrafficSecret, clientRandom, clientSecret,
+		keyLogLabelServerTrafficSecret, clientRandom, serverSecret,
+	)
+	return err
+}
+
+// logQuicCryptoKeys writes the keys and IVs of a gQUIC encryption level to the key log.
+// It takes the same parameters as the key derivation.
+func logQuicCryptoKeys(w io.Writer, forwardSecure bool, sharedSecret, nonces []byte, connID protocol.ConnectionID, chlo []byte, scfg []byte, cert []byte, divNonce []byte) error {
+	clientKey, clientIV, serverKey, serverIV, err := crypto.DeriveQuicCryptoAESKeyMaterial(forwardSecure, sharedSecret, nonces, connID, chlo, scfg, cert, divNonce)
+	if err != nil {
+		return err
+	}
+	clientLabel, serverLabel := keyLogLabelQuicCryptoClientSecure, keyLogLabelQuicCryptoServerSecure
+	if forwardSecure {
+		clientLabel, serverLabel = keyLogLabelQuicCryptoClientForwardSecure, keyLogLabelQuicCryptoServerForwardSecure
+	}
+	_, err = fmt.Fprintf(w, "%s %016x %x%x\n%s %016x %x%x\n",
+		clientLabel, uint64(connID), clientKey, clientIV,
+		serverLabel, uint64(connID), serverKey, serverIV,
+	)
+	return err
+}
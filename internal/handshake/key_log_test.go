@@ -0,0 +1,100 @@
+package handshake
+
+import (
+	"bytes"
+	gocrypto "crypto"
+	"fmt"
+	"strings"
+
+	"github.com/bifurcation/mint"
+	"github.com/lucas-clemente/quic-go/internal/crypto"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type exportingMintController struct {
+	fakeMintController
+}
+
+func (h *exportingMintController) GetCipherSuite() mint.CipherSuiteParams {
+	return mint.CipherSuiteParams{Hash: gocrypto.SHA256, KeyLen: 16, IvLen: 12}
+}
+
+func (h *exportingMintController) ComputeExporter(label string, context []byte, keyLength int) ([]byte, error) {
+	return []byte(label)[:keyLength], nil
+}
+
+var _ = Describe("Key log", func() {
+	// the beginning of a ClientHello, with a client random of 0x01 bytes
+	clientHello := append([]byte{tlsRecordTypeHandshake, 3, 1, 0, 0xc8, tlsHandshakeClientHello, 0, 0, 0xc4, 3, 3}, bytes.Repeat([]byte{1}, clientRandomLen)...)
+	clientHello = append(clientHello, []byte("cipher suites")...)
+
+	Context("recording the ClientHello", func() {
+		It("records what the client writes", func() {
+			stream := newMockStream()
+			r := newClientHelloRecorder(stream, protocol.PerspectiveClient)
+			Expect(r.clientRandom()).To(BeNil())
+			_, err := r.Write(clientHello[:10])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(r.clientRandom()).To(BeNil())
+			_, err = r.Write(clientHello[10:])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(r.clientRandom()).To(Equal(bytes.Repeat([]byte{1}, clientRandomLen)))
+			Expect(stream.dataWritten.Bytes()).To(Equal(clientHello))
+		})
+
+		It("records what the server reads", func() {
+			stream := newMockStream()
+			stream.dataToRead.Write(clientHello)
+			r := newClientHelloRecorder(stream, protocol.PerspectiveServer)
+			_, err := r.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			b := make([]byte, len(clientHello))
+			_, err = r.Read(b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(r.clientRandom()).To(Equal(bytes.Repeat([]byte{1}, clientRandomLen)))
+		})
+
+		It("doesn't return a client random for other messages", func() {
+			r := newClientHelloRecorder(newMockStream(), protocol.PerspectiveClient)
+			_, err := r.Write(bytes.Repeat([]byte{0}, 100))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(r.clientRandom()).To(BeNil())
+		})
+	})
+
+	It("logs the traffic secrets of a TLS handshake", func() {
+		keyLog := &bytes.Buffer{}
+		tlsConf := testdata.GetTLSConfig()
+		tlsConf.KeyLogWriter = keyLog
+		aeadChanged := make(chan protocol.EncryptionLevel, 2)
+		csInt, err := NewCryptoSetupTLS("", protocol.PerspectiveClient, protocol.VersionTLS, tlsConf, newMockStream(), aeadChanged)
+		Expect(err).ToNot(HaveOccurred())
+		cs := csInt.(*cryptoSetupTLS)
+		_, err = cs.clientHello.Write(clientHello)
+		Expect(err).ToNot(HaveOccurred())
+		cs.conn = &exportingMintController{fakeMintController{result: mint.AlertNoAlert}}
+		cs.keyDerivation = mockKeyDerivation
+		Expect(cs.HandleCryptoStream()).To(Succeed())
+		random := strings.Repeat("01", clientRandomLen)
+		Expect(keyLog.String()).To(Equal(fmt.Sprintf("%s %s %x\n%s %s %x\n",
+			keyLogLabelClientTrafficSecret, random, "EXPORTER-QUIC client 1-RTT Secre",
+			keyLogLabelServerTrafficSecret, random, "EXPORTER-QUIC server 1-RTT Secre",
+		)))
+	})
+
+	It("logs the gQUIC keys and IVs", func() {
+		keyLog := &bytes.Buffer{}
+		err := logQuicCryptoKeys(keyLog, true, []byte("shared secret"), []byte("nonce"), 0xdecafbad, []byte("chlo"), []byte("scfg"), []byte("cert"), nil)
+		Expect(err).ToNot(HaveOccurred())
+		clientKey, clientIV, serverKey, serverIV, err := crypto.DeriveQuicCryptoAESKeyMaterial(true, []byte("shared secret"), []byte("nonce"), 0xdecafbad, []byte("chlo"), []byte("scfg"), []byte("cert"), nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(keyLog.String()).To(Equal(fmt.Sprintf("%s 00000000decafbad %x%x\n%s 00000000decafbad %x%x\n",
+			keyLogLabelQuicCryptoClientForwardSecure, clientKey, clientIV,
+			keyLogLabelQuicCryptoServerForwardSecure, serverKey, serverIV,
+		)))
+	})
+})
@@ -3,6 +3,7 @@ package handshake
 import (
 	"bytes"
 	"crypto/rand"
+	"io"
 
 	"github.com/lucas-clemente/quic-go/internal/crypto"
 )
@@ -13,6 +14,9 @@ type ServerConfig struct {
 	certChain crypto.CertChain
 	ID        []byte
 	obit      []byte
+
+	// KeyLogWriter is the tls.Config.KeyLogWriter of the server, the keys of the sessions are written to it
+	KeyLogWriter io.Writer
 }
 
 // NewServerConfig creates a new server config
@@ -0,0 +1,111 @@
+package pcapng
+
+import (
+	"encoding/binary"
+	"net"
+)
+
+const (
+	ipv4HeaderLen = 20
+	ipv6HeaderLen = 40
+	udpHeaderLen  = 8
+
+	protocolUDP = 17
+	// the TTL or hop limit of the fake IP headers
+	hopLimit = 64
+)
+
+// udpAddr returns the UDP address of a path, or the unspecified address if it's unknown
+func udpAddr(addr net.Addr) *net.UDPAddr {
+	if a, ok := addr.(*net.UDPAddr); ok && a != nil {
+		return a
+	}
+	return &net.UDPAddr{IP: net.IPv4zero}
+}
+
+// ipAddrs returns the addresses used in the IP header, with the same length.
+// An IPv4 address stays IPv4 if the other address is unspecified,
+// since the local address of a path is often the wildcard address of a dual-stack socket.
+func ipAddrs(src, dst net.IP) (net.IP, net.IP) {
+	src4, dst4 := src.To4(), dst.To4()
+	switch {
+	case src4 != nil && dst4 != nil:
+		return src4, dst4
+	case src4 != nil && dst.IsUnspecified():
+		return src4, net.IPv4zero.To4()
+	case dst4 != nil && src.IsUnspecified():
+		return net.IPv4zero.To4(), dst4
+	}
+	return src.To16(), dst.To16()
+}
+
+// buildDatagram prepends an IP and an UDP header to the data
+func buildDatagram(src, dst *net.UDPAddr, data []byte) []byte {
+	srcIP, dstIP := ipAddrs(src.IP, dst.IP)
+	if srcIP == nil {
+		srcIP = net.IPv6unspecified
+	}
+	if dstIP == nil {
+		dstIP = net.IPv6unspecified
+	}
+	udpLen := udpHeaderLen + len(data)
+
+	var b []byte
+	if len(srcIP) == net.IPv4len {
+		b = make([]byte, ipv4HeaderLen, ipv4HeaderLen+udpLen)
+		b[0] = 0x45 // version 4, header length of 5 words
+		binary.BigEndian.PutUint16(b[2:], uint16(ipv4HeaderLen+udpLen))
+		b[8] = hopLimit
+		b[9] = protocolUDP
+		copy(b[12:], srcIP)
+		copy(b[16:], dstIP)
+		binary.BigEndian.PutUint16(b[10:], ^fold(checksum(0, b)))
+	} else {
+		b = make([]byte, ipv6HeaderLen, ipv6HeaderLen+udpLen)
+		b[0] = 0x60 // version 6
+		binary.BigEndian.PutUint16(b[4:], uint16(udpLen))
+		b[6] = protocolUDP
+		b[7] = hopLimit
+		copy(b[8:], srcIP)
+		copy(b[24:], dstIP)
+	}
+	ipLen := len(b)
+
+	var udp [udpHeaderLen]byte
+	binary.BigEndian.PutUint16(udp[0:], uint16(src.Port))
+	binary.BigEndian.PutUint16(udp[2:], uint16(dst.Port))
+	binary.BigEndian.PutUint16(udp[4:], uint16(udpLen))
+	b = append(b, udp[:]...)
+	b = append(b, data...)
+
+	// the checksum covers a pseudo header with the addresses, the protocol and the UDP length
+	sum := checksum(0, srcIP)
+	sum = checksum(sum, dstIP)
+	sum += protocolUDP + uint32(udpLen)
+	sum = checksum(sum, b[ipLen:])
+	cs := ^fold(sum)
+	if cs == 0 {
+		// a zero checksum means that no checksum was computed
+		cs = 0xffff
+	}
+	binary.BigEndian.PutUint16(b[ipLen+6:], cs)
+	return b
+}
+
+// checksum adds the 16 bit words of b to the one's complement sum
+func checksum(sum uint32, b []byte) uint32 {
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(b[i:]))
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	return sum
+}
+
+func fold(sum uint32) uint16 {
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return uint16(sum)
+}
@@ -0,0 +1,168 @@
+// Package pcapng writes the datagrams of a session to a pcapng file, so that they can be inspected with Wireshark.
+// Every path is a pcapng interface, and the datagrams get fake IP and UDP headers built from the path addresses.
+package pcapng
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+const (
+	blockTypeSectionHeader        uint32 = 0x0a0d0d0a
+	blockTypeInterfaceDescription uint32 = 0x00000001
+	blockTypeEnhancedPacket       uint32 = 0x00000006
+
+	byteOrderMagic uint32 = 0x1a2b3c4d
+
+	// linkTypeRaw means that the packets begin with an IPv4 or IPv6 header
+	linkTypeRaw uint16 = 101
+
+	optionEndOfOpt uint16 = 0
+	optionComment  uint16 = 1
+	optionIfName   uint16 = 2
+	optionEPBFlags uint16 = 2
+
+	epbFlagInbound  uint32 = 1
+	epbFlagOutbound uint32 = 2
+)
+
+// A Writer writes the datagrams sent and received on the paths of a session.
+// It is safe for concurrent use.
+type Writer struct {
+	mutex sync.Mutex
+
+	w      *bufio.Writer
+	closer io.Closer
+
+	// interfaces maps the path IDs to the pcapng interface IDs
+	interfaces map[protocol.PathID]uint32
+
+	// err is the first write error, no more blocks are written after it
+	err    error
+	closed bool
+}
+
+// NewWriter creates a new Writer and writes the section header.
+// comment is written in the section header, it can be empty.
+func NewWriter(w io.WriteCloser, comment string) *Writer {
+	pw := &Writer{
+		w:          bufio.NewWriter(w),
+		closer:     w,
+		interfaces: make(map[protocol.PathID]uint32),
+	}
+	body := make([]byte, 16)
+	binary.LittleEndian.PutUint32(body, byteOrderMagic)
+	binary.LittleEndian.PutUint16(body[4:], 1) // major version
+	binary.LittleEndian.PutUint16(body[6:], 0) // minor version
+	// the section length is not specified
+	binary.LittleEndian.PutUint64(body[8:], 0xffffffffffffffff)
+	if len(comment) > 0 {
+		body = appendOption(body, optionComment, []byte(comment))
+		body = appendOption(body, optionEndOfOpt, nil)
+	}
+	pw.writeBlock(blockTypeSectionHeader, body)
+	return pw
+}
+
+// WritePacket writes a datagram sent or received on a path.
+// The first datagram of a path adds an interface for it.
+func (w *Writer) WritePacket(pathID protocol.PathID, t time.Time, src, dst net.Addr, data []byte, outbound bool) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.err != nil || w.closed {
+		return
+	}
+	ifaceID, ok := w.interfaces[pathID]
+	if !ok {
+		ifaceID = uint32(len(w.interfaces))
+		w.interfaces[pathID] = ifaceID
+		w.writeInterfaceDescription(pathID)
+	}
+
+	packet := buildDatagram(udpAddr(src), udpAddr(dst), data)
+	body := make([]byte, 20, 20+len(packet)+16)
+	binary.LittleEndian.PutUint32(body, ifaceID)
+	ts := uint64(t.UnixNano() / int64(time.Microsecond))
+	binary.LittleEndian.PutUint32(body[4:], uint32(ts>>32))
+	binary.LittleEndian.PutUint32(body[8:], uint32(ts))
+	binary.LittleEndian.PutUint32(body[12:], uint32(len(packet)))
+	binary.LittleEndian.PutUint32(body[16:], uint32(len(packet)))
+	body = append(body, packet...)
+	body = pad(body)
+	flags := make([]byte, 4)
+	if outbound {
+		binary.LittleEndian.PutUint32(flags, epbFlagOutbound)
+	} else {
+		binary.LittleEndian.PutUint32(flags, epbFlagInbound)
+	}
+	body = appendOption(body, optionEPBFlags, flags)
+	body = appendOption(body, optionEndOfOpt, nil)
+	w.writeBlock(blockTypeEnhancedPacket, body)
+}
+
+// Close flushes the capture and closes the underlying writer.
+func (w *Writer) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if w.err == nil {
+		w.err = w.w.Flush()
+	}
+	if err := w.closer.Close(); err != nil && w.err == nil {
+		w.err = err
+	}
+	return w.err
+}
+
+func (w *Writer) writeInterfaceDescription(pathID protocol.PathID) {
+	body := make([]byte, 8)
+	binary.LittleEndian.PutUint16(body, linkTypeRaw)
+	// the snap length is not limited
+	binary.LittleEndian.PutUint32(body[4:], 0)
+	body = appendOption(body, optionIfName, []byte(fmt.Sprintf("path %d", pathID)))
+	body = appendOption(body, optionEndOfOpt, nil)
+	w.writeBlock(blockTypeInterfaceDescription, body)
+}
+
+// writeBlock writes a block, the body must be padded to 32 bits
+func (w *Writer) writeBlock(blockType uint32, body []byte) {
+	if w.err != nil {
+		return
+	}
+	length := uint32(len(body) + 12)
+	b := make([]byte, 8, length)
+	binary.LittleEndian.PutUint32(b, blockType)
+	binary.LittleEndian.PutUint32(b[4:], length)
+	b = append(b, body...)
+	b = append(b, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(b[length-4:], length)
+	_, w.err = w.w.Write(b)
+}
+
+func appendOption(b []byte, code uint16, value []byte) []byte {
+	var h [4]byte
+	binary.LittleEndian.PutUint16(h[:], code)
+	binary.LittleEndian.PutUint16(h[2:], uint16(len(value)))
+	b = append(b, h[:]...)
+	b = append(b, value...)
+	return pad(b)
+}
+
+func pad(b []byte) []byte {
+	for len(b)%4 != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
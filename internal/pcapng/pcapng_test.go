@@ -0,0 +1,155 @@
+package pcapng
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type bufferWriteCloser struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *bufferWriteCloser) Close() error {
+	b.closed = true
+	return nil
+}
+
+type block struct {
+	blockType uint32
+	body      []byte
+}
+
+// readBlocks splits a pcapng file in blocks, and checks the lengths
+func readBlocks(data []byte) []block {
+	var blocks []block
+	for len(data) > 0 {
+		Expect(len(data)).To(BeNumerically(">=", 12))
+		length := binary.LittleEndian.Uint32(data[4:])
+		Expect(length % 4).To(BeZero())
+		Expect(binary.LittleEndian.Uint32(data[length-4:])).To(Equal(length))
+		blocks = append(blocks, block{
+			blockType: binary.LittleEndian.Uint32(data),
+			body:      data[8 : length-4],
+		})
+		data = data[length:]
+	}
+	return blocks
+}
+
+var _ = Describe("pcapng writer", func() {
+	var (
+		buf    *bufferWriteCloser
+		writer *Writer
+		client = &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234}
+		server = &net.UDPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 4433}
+	)
+
+	BeforeEach(func() {
+		buf = &bufferWriteCloser{}
+		writer = NewWriter(buf, "connection 0xdecafbad")
+	})
+
+	It("writes the section header", func() {
+		Expect(writer.Close()).To(Succeed())
+		Expect(buf.closed).To(BeTrue())
+		blocks := readBlocks(buf.Bytes())
+		Expect(blocks).To(HaveLen(1))
+		Expect(blocks[0].blockType).To(Equal(blockTypeSectionHeader))
+		Expect(binary.LittleEndian.Uint32(blocks[0].body)).To(Equal(byteOrderMagic))
+		Expect(blocks[0].body).To(ContainSubstring("connection 0xdecafbad"))
+	})
+
+	It("writes an interface per path", func() {
+		t := time.Unix(1500000000, 123456000)
+		writer.WritePacket(1, t, client, server, []byte("foo"), true)
+		writer.WritePacket(3, t, client, server, []byte("bar"), true)
+		writer.WritePacket(1, t, server, client, []byte("foobar"), false)
+		Expect(writer.Close()).To(Succeed())
+		blocks := readBlocks(buf.Bytes())
+		Expect(blocks).To(HaveLen(6))
+		Expect(blocks[1].blockType).To(Equal(blockTypeInterfaceDescription))
+		Expect(binary.LittleEndian.Uint16(blocks[1].body)).To(Equal(linkTypeRaw))
+		Expect(blocks[1].body).To(ContainSubstring("path 1"))
+		Expect(blocks[3].blockType).To(Equal(blockTypeInterfaceDescription))
+		Expect(blocks[3].body).To(ContainSubstring("path 3"))
+		for i, ifaceID := range map[int]uint32{2: 0, 4: 1, 5: 0} {
+			Expect(blocks[i].blockType).To(Equal(blockTypeEnhancedPacket))
+			Expect(binary.LittleEndian.Uint32(blocks[i].body)).To(Equal(ifaceID))
+		}
+		epb := blocks[5].body
+		ts := uint64(binary.LittleEndian.Uint32(epb[4:]))<<32 | uint64(binary.LittleEndian.Uint32(epb[8:]))
+		Expect(ts).To(Equal(uint64(1500000000123456)))
+		Expect(binary.LittleEndian.Uint32(epb[12:])).To(BeEquivalentTo(ipv4HeaderLen + udpHeaderLen + 6))
+		packet := epb[20 : 20+ipv4HeaderLen+udpHeaderLen+6]
+		Expect(net.IP(packet[12:16]).Equal(server.IP)).To(BeTrue())
+		Expect(packet[ipv4HeaderLen+udpHeaderLen:]).To(Equal([]byte("foobar")))
+		// the direction is inbound
+		Expect(binary.LittleEndian.Uint32(epb[len(epb)-8:])).To(Equal(epbFlagInbound))
+	})
+
+	It("doesn't write after closing", func() {
+		Expect(writer.Close()).To(Succeed())
+		n := buf.Len()
+		writer.WritePacket(1, time.Now(), client, server, []byte("foo"), true)
+		Expect(writer.Close()).To(Succeed())
+		Expect(buf.Len()).To(Equal(n))
+	})
+
+	Context("fake headers", func() {
+		// verify returns true if the one's complement sum of b, plus the initial sum, is 0xffff
+		verify := func(sum uint32, b []byte) bool {
+			return fold(checksum(sum, b)) == 0xffff
+		}
+
+		It("builds IPv4 and UDP headers", func() {
+			data := []byte("foobar!")
+			b := buildDatagram(client, server, data)
+			Expect(b).To(HaveLen(ipv4HeaderLen + udpHeaderLen + len(data)))
+			Expect(b[0]).To(Equal(byte(0x45)))
+			Expect(b[9]).To(Equal(byte(protocolUDP)))
+			Expect(binary.BigEndian.Uint16(b[2:])).To(BeEquivalentTo(len(b)))
+			Expect(net.IP(b[12:16]).Equal(client.IP)).To(BeTrue())
+			Expect(net.IP(b[16:20]).Equal(server.IP)).To(BeTrue())
+			Expect(verify(0, b[:ipv4HeaderLen])).To(BeTrue())
+			udp := b[ipv4HeaderLen:]
+			Expect(binary.BigEndian.Uint16(udp[0:])).To(BeEquivalentTo(1234))
+			Expect(binary.BigEndian.Uint16(udp[2:])).To(BeEquivalentTo(4433))
+			Expect(binary.BigEndian.Uint16(udp[4:])).To(BeEquivalentTo(udpHeaderLen + len(data)))
+			pseudo := checksum(checksum(0, b[12:20]), []byte{0, protocolUDP})
+			pseudo += uint32(len(udp))
+			Expect(verify(pseudo, udp)).To(BeTrue())
+		})
+
+		It("builds IPv6 headers", func() {
+			src := &net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 1}
+			dst := &net.UDPAddr{IP: net.ParseIP("2001:db8::2"), Port: 2}
+			b := buildDatagram(src, dst, []byte("foo"))
+			Expect(b).To(HaveLen(ipv6HeaderLen + udpHeaderLen + 3))
+			Expect(b[0] >> 4).To(Equal(byte(6)))
+			Expect(binary.BigEndian.Uint16(b[4:])).To(BeEquivalentTo(udpHeaderLen + 3))
+			Expect(net.IP(b[8:24]).Equal(src.IP)).To(BeTrue())
+			Expect(net.IP(b[24:40]).Equal(dst.IP)).To(BeTrue())
+			udp := b[ipv6HeaderLen:]
+			pseudo := checksum(0, b[8:40]) + protocolUDP + uint32(len(udp))
+			Expect(verify(pseudo, udp)).To(BeTrue())
+		})
+
+		It("uses IPv4 headers if the local address is the IPv6 wildcard", func() {
+			src := &net.UDPAddr{IP: net.IPv6unspecified, Port: 1}
+			b := buildDatagram(src, server, nil)
+			Expect(b).To(HaveLen(ipv4HeaderLen + udpHeaderLen))
+			Expect(net.IP(b[12:16]).Equal(net.IPv4zero)).To(BeTrue())
+		})
+
+		It("uses the unspecified address if the address is unknown", func() {
+			b := buildDatagram(udpAddr(nil), server, nil)
+			Expect(b).To(HaveLen(ipv4HeaderLen + udpHeaderLen))
+		})
+	})
+})
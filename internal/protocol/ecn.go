@@ -0,0 +1,29 @@
+package protocol
+
+// ECN is the ECN codepoint of the IP header, i.e. the two lowest bits of the TOS / traffic class
+type ECN uint8
+
+const (
+	// ECNNon means that the packet is not ECN capable (Not-ECT)
+	ECNNon ECN = 0
+	// ECT1 is the ECN Capable Transport codepoint 1
+	ECT1 ECN = 1
+	// ECT0 is the ECN Capable Transport codepoint 0, which is used to mark the packets we send
+	ECT0 ECN = 2
+	// ECNCE means that a router experienced congestion (Congestion Experienced)
+	ECNCE ECN = 3
+)
+
+func (e ECN) String() string {
+	switch e {
+	case ECNNon:
+		return "Not-ECT"
+	case ECT1:
+		return "ECT(1)"
+	case ECT0:
+		return "ECT(0)"
+	case ECNCE:
+		return "CE"
+	}
+	return "unknown"
+}
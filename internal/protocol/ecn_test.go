@@ -0,0 +1,16 @@
+package protocol
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ECN", func() {
+	It("has the correct string representation", func() {
+		Expect(ECNNon.String()).To(Equal("Not-ECT"))
+		Expect(ECT1.String()).To(Equal("ECT(1)"))
+		Expect(ECT0.String()).To(Equal("ECT(0)"))
+		Expect(ECNCE.String()).To(Equal("CE"))
+		Expect(ECN(42).String()).To(Equal("unknown"))
+	})
+})
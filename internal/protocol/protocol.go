@@ -45,6 +45,9 @@ const MaxByteCount = ByteCount(math.MaxUint64)
 // Ethernet's max packet size is 1500 bytes,  1500 - 48 = 1452.
 const MaxReceivePacketSize ByteCount = 1452
 
+// MaxBatchSize is the maximum number of datagrams read or written with a single syscall
+const MaxBatchSize = 32
+
 // DefaultTCPMSS is the default maximum packet size used in the Linux TCP implementation.
 // Used in QUIC for congestion window computations in bytes.
 const DefaultTCPMSS ByteCount = 1460
@@ -70,6 +70,18 @@ const MaxStreamsMultiplier = 1.1
 // MaxStreamsMinimumIncrement is the slack the client is allowed for the maximum number of streams per connection, needed e.g. when packets are out of order or dropped. The minimum of this absolute increment and the procentual increase specified by MaxStreamsMultiplier is used.
 const MaxStreamsMinimumIncrement = 10
 
+// DefaultStreamWeight is the weight of a stream without priority, as in HTTP/2
+const DefaultStreamWeight = 16
+
+// MaxStreamWeight is the maximum weight of a stream
+const MaxStreamWeight = 256
+
+// MaxStreamDependencyDepth is the maximum length of a chain of stream dependencies considered for scheduling
+const MaxStreamDependencyDepth = 16
+
+// DefaultDeliveryRTT is the RTT assumed for a path without RTT sample when estimating delivery times
+const DefaultDeliveryRTT = 100 * time.Millisecond
+
 // MaxNewStreamIDDelta is the maximum difference between and a newly opened Stream and the highest StreamID that a client has ever opened
 // note that the number of streams is half this value, since the client can only open streams with open StreamID
 const MaxNewStreamIDDelta = 4 * MaxStreamsPerConnection
@@ -77,6 +89,14 @@ const MaxNewStreamIDDelta = 4 * MaxStreamsPerConnection
 // MaxSessionUnprocessedPackets is the max number of packets stored in each session that are not yet processed.
 const MaxSessionUnprocessedPackets = DefaultMaxCongestionWindow
 
+// NumSessionShards is the number of shards of the sessions of a server.
+// Every shard handles the packets received for its sessions on its own goroutine.
+const NumSessionShards = 16
+
+// MaxShardUnprocessedPackets is the max number of received packets queued in a shard of the sessions of a server.
+// Packets received for a full shard are dropped.
+const MaxShardUnprocessedPackets = 8 * MaxBatchSize
+
 // SkipPacketAveragePeriodLength is the average period length in which one packet number is skipped to prevent an Optimistic ACK attack
 const SkipPacketAveragePeriodLength PacketNumber = 500
 
@@ -92,6 +112,9 @@ const MaxTrackedSentPackets = 2 * DefaultMaxCongestionWindow
 // MaxTrackedReceivedAckRanges is the maximum number of ACK ranges tracked
 const MaxTrackedReceivedAckRanges = DefaultMaxCongestionWindow
 
+// MaxTrackedReceivedTimestamps is the maximum number of receive timestamps tracked per path, and reported in an ACK frame
+const MaxTrackedReceivedTimestamps = 32
+
 // MaxPacketsReceivedBeforeAckSend is the number of packets that can be received before an ACK frame is sent
 const MaxPacketsReceivedBeforeAckSend = 20
 
@@ -15,11 +15,15 @@ const (
 	VersionUnsupported VersionNumber = -1
 	VersionUnknown     VersionNumber = -2
 	VersionMP          VersionNumber = 512
+	VersionMPBackup    VersionNumber = 513 // VersionMP with the backup flag in the ADD_ADDRESS frames
+	VersionMPECN       VersionNumber = 514 // VersionMPBackup with the ECN counts in the ACK frames
 )
 
 // SupportedVersions lists the versions that the server supports
 // must be in sorted descending order
 var SupportedVersions = []VersionNumber{
+	VersionMPECN,
+	VersionMPBackup,
 	VersionMP,
 	Version39,
 	Version38,
@@ -31,6 +35,16 @@ func (vn VersionNumber) UsesTLS() bool {
 	return vn == VersionTLS
 }
 
+// UsesBackupFlag says if the ADD_ADDRESS frames of this QUIC version can flag backup addresses
+func (vn VersionNumber) UsesBackupFlag() bool {
+	return vn >= VersionMPBackup
+}
+
+// UsesECNCounts says if the ACK frames of this QUIC version carry the ECN counts
+func (vn VersionNumber) UsesECNCounts() bool {
+	return vn >= VersionMPECN
+}
+
 func (vn VersionNumber) String() string {
 	switch vn {
 	case VersionWhatever:
@@ -20,3 +20,12 @@ func (a *AtomicBool) Set(value bool) {
 func (a *AtomicBool) Get() bool {
 	return atomic.LoadInt32(&a.v) != 0
 }
+
+// Swap sets the value, and returns the previous one
+func (a *AtomicBool) Swap(value bool) bool {
+	var n int32
+	if value {
+		n = 1
+	}
+	return atomic.SwapInt32(&a.v, n) != 0
+}
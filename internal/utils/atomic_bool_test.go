@@ -26,4 +26,12 @@ var _ = Describe("Atomic Bool", func() {
 		a.Set(false)
 		Expect(a.Get()).To(BeFalse())
 	})
+
+	It("swaps the value", func() {
+		Expect(a.Swap(true)).To(BeFalse())
+		Expect(a.Get()).To(BeTrue())
+		Expect(a.Swap(false)).To(BeTrue())
+		Expect(a.Swap(false)).To(BeFalse())
+		Expect(a.Get()).To(BeFalse())
+	})
 })
@@ -145,3 +145,57 @@ func MinPacketNumber(a, b protocol.PacketNumber) protocol.PacketNumber {
 	}
 	return b
 }
+
+// A WindowedMax tracks the maximum of a value over a sliding window of time, using Kathleen Nichols' algorithm
+// The time unit is up to the caller, e.g. round trips
+type WindowedMax struct {
+	// The best, second best and third best samples of the window
+	s [3]windowedSample
+}
+
+type windowedSample struct {
+	t uint64
+	v uint64
+}
+
+// Get returns the maximum of the window
+func (m *WindowedMax) Get() uint64 {
+	return m.s[0].v
+}
+
+// Reset forgets all the samples, and starts a new window with this one
+func (m *WindowedMax) Reset(t, v uint64) uint64 {
+	val := windowedSample{t: t, v: v}
+	m.s[0], m.s[1], m.s[2] = val, val, val
+	return v
+}
+
+// Update adds a sample taken at time t and returns the maximum of the samples taken during the last window
+func (m *WindowedMax) Update(window, t, v uint64) uint64 {
+	val := windowedSample{t: t, v: v}
+	// New maximum, or nothing left in the window
+	if v >= m.s[0].v || t-m.s[2].t > window {
+		return m.Reset(t, v)
+	}
+	if v >= m.s[1].v {
+		m.s[1], m.s[2] = val, val
+	} else if v >= m.s[2].v {
+		m.s[2] = val
+	}
+
+	dt := t - m.s[0].t
+	if dt > window {
+		// The best sample left the window, promote the next ones
+		m.s[0], m.s[1], m.s[2] = m.s[1], m.s[2], val
+		if t-m.s[0].t > window {
+			m.s[0], m.s[1], m.s[2] = m.s[1], m.s[2], val
+		}
+	} else if m.s[1].t == m.s[0].t && dt > window/4 {
+		// A quarter of the window passed without a second best sample
+		m.s[1], m.s[2] = val, val
+	} else if m.s[2].t == m.s[1].t && dt > window/2 {
+		// Half of the window passed without a third best sample
+		m.s[2] = val
+	}
+	return m.s[0].v
+}
@@ -89,4 +89,36 @@ var _ = Describe("Min / Max", func() {
 		Expect(AbsDuration(time.Microsecond)).To(Equal(time.Microsecond))
 		Expect(AbsDuration(-time.Microsecond)).To(Equal(time.Microsecond))
 	})
+
+	Context("WindowedMax", func() {
+		var m WindowedMax
+
+		BeforeEach(func() {
+			m = WindowedMax{}
+			m.Reset(0, 50)
+		})
+
+		It("takes new maximums immediately", func() {
+			Expect(m.Update(10, 1, 60)).To(Equal(uint64(60)))
+			Expect(m.Get()).To(Equal(uint64(60)))
+		})
+
+		It("keeps the maximum during the window", func() {
+			Expect(m.Update(10, 3, 30)).To(Equal(uint64(50)))
+			Expect(m.Update(10, 6, 20)).To(Equal(uint64(50)))
+			Expect(m.Update(10, 10, 10)).To(Equal(uint64(50)))
+		})
+
+		It("falls back to the next best samples when the maximum expires", func() {
+			m.Update(10, 3, 30)
+			m.Update(10, 6, 20)
+			Expect(m.Update(10, 11, 10)).To(Equal(uint64(30)))
+			Expect(m.Update(10, 14, 10)).To(Equal(uint64(20)))
+		})
+
+		It("restarts when all the samples expired", func() {
+			m.Update(10, 3, 30)
+			Expect(m.Update(10, 30, 5)).To(Equal(uint64(5)))
+		})
+	})
 })
@@ -0,0 +1,227 @@
+package utils
+
+import (
+	"math/bits"
+	"time"
+)
+
+const (
+	wheelLevels    = 4
+	wheelSlotsBits = 6
+	wheelSlots     = 1 << wheelSlotsBits
+	wheelSlotMask  = wheelSlots - 1
+	// maxWheelDelta is the number of ticks covered by the wheel, the timers expiring later are moved down when
+	// the wheel reaches the last slot, until they expire
+	maxWheelDelta = 1<<(wheelSlotsBits*wheelLevels) - 1
+)
+
+// A TimerWheel is a hierarchical timer wheel, holding many timers with the resolution of a tick.
+// The slots of the level 0 hold the timers expiring within the next wheelSlots ticks, one tick per slot, and the
+// slots of every higher level are wheelSlots times longer. When the wheel reaches a slot of a higher level, its
+// timers are moved down to the lower levels. Setting and stopping a timer are done in constant time.
+// It's not safe for concurrent use, the timers are set and fired by the goroutine owning the wheel.
+type TimerWheel struct {
+	start time.Time
+	tick  time.Duration
+
+	// current is the next tick to be processed, the timers expiring before it already fired
+	current int64
+
+	// Every slot is a doubly linked list of timers
+	slots [wheelLevels][wheelSlots]*WheelTimer
+	// A bit per slot, set if the slot holds a timer
+	occupied [wheelLevels]uint64
+	// The list of the timers that are firing
+	firing *WheelTimer
+}
+
+// A WheelTimer is a timer of a TimerWheel
+type WheelTimer struct {
+	wheel *TimerWheel
+	f     func()
+
+	pending bool
+	expiry  int64 // tick
+	// level is -1 if the timer is in the firing list
+	level, slot int
+	prev, next  *WheelTimer
+}
+
+// NewTimerWheel creates a timer wheel starting at start.
+func NewTimerWheel(start time.Time, tick time.Duration) *TimerWheel {
+	return &TimerWheel{start: start, tick: tick}
+}
+
+// NewTimer creates a timer that is not set.
+// f is called by Advance when the timer expires, it may be nil if the timer is only used to wake up the owner of the wheel.
+func (w *TimerWheel) NewTimer(f func()) *WheelTimer {
+	return &WheelTimer{wheel: w, f: f}
+}
+
+// Reset sets the timer to expire at deadline, rounded up to the next tick.
+// If the deadline passed, the timer expires at the next call of Advance.
+func (t *WheelTimer) Reset(deadline time.Time) {
+	w := t.wheel
+	expiry := w.tickAfter(deadline)
+	if expiry < w.current {
+		expiry = w.current
+	}
+	if t.pending {
+		if t.expiry == expiry {
+			// No need to move the timer
+			return
+		}
+		w.remove(t)
+	}
+	t.expiry = expiry
+	w.insert(t)
+}
+
+// Stop stops the timer, it doesn't fire anymore
+func (t *WheelTimer) Stop() {
+	if t.pending {
+		t.wheel.remove(t)
+	}
+}
+
+// Pending returns true if the timer is set and didn't fire yet
+func (t *WheelTimer) Pending() bool {
+	return t.pending
+}
+
+// NextDeadline returns the time at which Advance must be called next, or the zero time if no timer is set.
+// It may be earlier than the next expiry, if timers have to be moved down the levels first.
+func (w *TimerWheel) NextDeadline() time.Time {
+	tick, ok := w.nextTick()
+	if !ok {
+		return time.Time{}
+	}
+	return w.start.Add(time.Duration(tick) * w.tick)
+}
+
+// Advance fires the timers that expired at now, in the order of their expiry.
+// The timers can be set or stopped by the functions they call.
+func (w *TimerWheel) Advance(now time.Time) {
+	if now.Before(w.start) {
+		return
+	}
+	target := int64(now.Sub(w.start) / w.tick)
+	for w.current <= target {
+		tick, ok := w.nextTick()
+		if !ok || tick > target {
+			w.current = target + 1
+			return
+		}
+		w.current = tick
+		w.cascade(tick)
+		// The timers set while firing expire at the next tick at the earliest
+		w.current = tick + 1
+		w.fire(tick)
+	}
+}
+
+// tickAfter returns the first tick at or after t
+func (w *TimerWheel) tickAfter(t time.Time) int64 {
+	d := t.Sub(w.start)
+	if d <= 0 {
+		return 0
+	}
+	return int64((d + w.tick - 1) / w.tick)
+}
+
+func (w *TimerWheel) insert(t *WheelTimer) {
+	delta := t.expiry - w.current
+	at := t.expiry
+	if delta > maxWheelDelta {
+		at = w.current + maxWheelDelta
+	}
+	level := 0
+	for level < wheelLevels-1 && delta >= 1<<uint(wheelSlotsBits*(level+1)) {
+		level++
+	}
+	slot := int(at>>uint(wheelSlotsBits*level)) & wheelSlotMask
+
+	t.level, t.slot = level, slot
+	t.prev = nil
+	t.next = w.slots[level][slot]
+	if t.next != nil {
+		t.next.prev = t
+	}
+	w.slots[level][slot] = t
+	w.occupied[level] |= 1 << uint(slot)
+	t.pending = true
+}
+
+func (w *TimerWheel) remove(t *WheelTimer) {
+	if t.next != nil {
+		t.next.prev = t.prev
+	}
+	if t.prev != nil {
+		t.prev.next = t.next
+	} else if t.level < 0 {
+		w.firing = t.next
+	} else {
+		w.slots[t.level][t.slot] = t.next
+		if t.next == nil {
+			w.occupied[t.level] &^= 1 << uint(t.slot)
+		}
+	}
+	t.prev, t.next = nil, nil
+	t.pending = false
+}
+
+// detach empties a slot, and returns its list of timers
+func (w *TimerWheel) detach(level, slot int) *WheelTimer {
+	t := w.slots[level][slot]
+	w.slots[level][slot] = nil
+	w.occupied[level] &^= 1 << uint(slot)
+	return t
+}
+
+// cascade moves the timers of the higher level slots starting at tick down the levels, starting from the top level
+func (w *TimerWheel) cascade(tick int64) {
+	for level := wheelLevels - 1; level > 0; level-- {
+		shift := uint(wheelSlotsBits * level)
+		if tick&(1<<shift-1) != 0 {
+			continue
+		}
+		for t := w.detach(level, int(tick>>shift)&wheelSlotMask); t != nil; {
+			next := t.next
+			w.insert(t)
+			t = next
+		}
+	}
+}
+
+func (w *TimerWheel) fire(tick int64) {
+	w.firing = w.detach(0, int(tick)&wheelSlotMask)
+	for t := w.firing; t != nil; t = t.next {
+		t.level = -1
+	}
+	for w.firing != nil {
+		t := w.firing
+		w.remove(t)
+		if t.f != nil {
+			t.f()
+		}
+	}
+}
+
+// nextTick returns the first tick, at or after the current one, at which a timer fires or is moved down
+func (w *TimerWheel) nextTick() (int64, bool) {
+	var next int64
+	var found bool
+	for level := 0; level < wheelLevels; level++ {
+		if w.occupied[level] == 0 {
+			continue
+		}
+		shift := uint(wheelSlotsBits * level)
+		// The first slot of the level reached at or after the current tick
+		first := (w.current + 1<<shift - 1) >> shift
+		off := bits.TrailingZeros64(bits.RotateLeft64(w.occupied[level], -int(first&wheelSlotMask)))
+		if tick := (first + int64(off)) << shift; !found || tick < next {
+			next, found = tick, true
+		}
+	}
+	return next, found
+}
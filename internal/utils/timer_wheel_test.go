@@ -0,0 +1,184 @@
+package utils
+
+import (
+	"math/rand"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Timer wheel", func() {
+	const tick = time.Millisecond
+
+	var (
+		start time.Time
+		wheel *TimerWheel
+		fired []int
+	)
+
+	newTimer := func(id int) *WheelTimer {
+		return wheel.NewTimer(func() { fired = append(fired, id) })
+	}
+
+	BeforeEach(func() {
+		start = time.Now()
+		wheel = NewTimerWheel(start, tick)
+		fired = nil
+	})
+
+	It("has no deadline without timers", func() {
+		Expect(wheel.NextDeadline()).To(BeZero())
+		wheel.NewTimer(nil)
+		Expect(wheel.NextDeadline()).To(BeZero())
+		wheel.Advance(start.Add(time.Hour))
+		Expect(wheel.NextDeadline()).To(BeZero())
+	})
+
+	It("fires a timer at its deadline", func() {
+		t := newTimer(1)
+		t.Reset(start.Add(10 * tick))
+		Expect(t.Pending()).To(BeTrue())
+		Expect(wheel.NextDeadline()).To(Equal(start.Add(10 * tick)))
+		wheel.Advance(start.Add(10*tick - 1))
+		Expect(fired).To(BeEmpty())
+		wheel.Advance(start.Add(10 * tick))
+		Expect(fired).To(Equal([]int{1}))
+		Expect(t.Pending()).To(BeFalse())
+		Expect(wheel.NextDeadline()).To(BeZero())
+	})
+
+	It("rounds the deadlines up to the next tick", func() {
+		t := newTimer(1)
+		t.Reset(start.Add(10*tick + 1))
+		Expect(wheel.NextDeadline()).To(Equal(start.Add(11 * tick)))
+		wheel.Advance(start.Add(10*tick + 1))
+		Expect(fired).To(BeEmpty())
+		wheel.Advance(start.Add(11 * tick))
+		Expect(fired).To(Equal([]int{1}))
+	})
+
+	It("fires the timers in the order of their deadlines", func() {
+		newTimer(3).Reset(start.Add(300 * tick))
+		newTimer(1).Reset(start.Add(5 * tick))
+		newTimer(2).Reset(start.Add(70 * tick))
+		wheel.Advance(start.Add(time.Second))
+		Expect(fired).To(Equal([]int{1, 2, 3}))
+	})
+
+	It("fires a timer set in the past at the next advance", func() {
+		wheel.Advance(start.Add(100 * tick))
+		newTimer(1).Reset(start)
+		Expect(wheel.NextDeadline()).To(Equal(start.Add(101 * tick)))
+		wheel.Advance(start.Add(100 * tick))
+		Expect(fired).To(BeEmpty())
+		wheel.Advance(start.Add(101 * tick))
+		Expect(fired).To(Equal([]int{1}))
+	})
+
+	It("resets and stops timers", func() {
+		t := newTimer(1)
+		t.Reset(start.Add(10 * tick))
+		t.Reset(start.Add(20 * tick))
+		wheel.Advance(start.Add(15 * tick))
+		Expect(fired).To(BeEmpty())
+		t.Stop()
+		Expect(t.Pending()).To(BeFalse())
+		wheel.Advance(start.Add(time.Second))
+		Expect(fired).To(BeEmpty())
+		// stopping it twice has no effect
+		t.Stop()
+	})
+
+	It("fires the timers beyond the last level", func() {
+		t := newTimer(1)
+		// the wheel covers 64^4 ticks, about 4.6 hours
+		deadline := start.Add(10 * time.Hour)
+		t.Reset(deadline)
+		// The deadline is the time when the timer is moved down a level
+		Expect(wheel.NextDeadline()).To(BeTemporally("<", deadline))
+		var advances int
+		for d := wheel.NextDeadline(); !d.IsZero() && d.Before(deadline); d = wheel.NextDeadline() {
+			wheel.Advance(d)
+			advances++
+		}
+		Expect(advances).To(BeNumerically("<", 10))
+		Expect(fired).To(BeEmpty())
+		Expect(wheel.NextDeadline()).To(Equal(deadline))
+		wheel.Advance(deadline)
+		Expect(fired).To(Equal([]int{1}))
+	})
+
+	It("lets the timers reset themselves when they fire", func() {
+		var t *WheelTimer
+		t = wheel.NewTimer(func() {
+			fired = append(fired, len(fired))
+			t.Reset(start.Add(time.Duration(len(fired)) * 64 * tick))
+		})
+		t.Reset(start)
+		wheel.Advance(start.Add(64*5*tick - 1))
+		Expect(fired).To(Equal([]int{0, 1, 2, 3, 4}))
+		Expect(t.Pending()).To(BeTrue())
+	})
+
+	It("lets the timers stop the other timers firing at the same tick", func() {
+		var t2 *WheelTimer
+		t1 := wheel.NewTimer(func() {
+			fired = append(fired, 1)
+			t2.Stop()
+		})
+		t2 = wheel.NewTimer(func() { fired = append(fired, 2) })
+		// the timers of a slot fire in the reverse order of their insertion
+		t2.Reset(start.Add(5 * tick))
+		t1.Reset(start.Add(5 * tick))
+		wheel.Advance(start.Add(time.Second))
+		Expect(fired).To(Equal([]int{1}))
+		Expect(t2.Pending()).To(BeFalse())
+	})
+
+	It("fires every timer when it expires, and not before", func() {
+		r := rand.New(rand.NewSource(42))
+		timers := make([]*WheelTimer, 200)
+		deadlines := make([]time.Time, len(timers))
+		for i := range timers {
+			i := i
+			timers[i] = wheel.NewTimer(func() { fired = append(fired, i) })
+		}
+		randomDeadline := func(now time.Time) time.Time {
+			// After now, since a timer set to a past deadline fires at the next tick.
+			// Up to about 17 minutes, to cover all the levels.
+			return now.Add(1 + time.Duration(r.Int63n(int64(tick)<<uint(r.Intn(21)))))
+		}
+		now := start
+		for step := 0; step < 5000; step++ {
+			i := r.Intn(len(timers))
+			switch r.Intn(4) {
+			case 0:
+				timers[i].Stop()
+			default:
+				deadlines[i] = randomDeadline(now)
+				timers[i].Reset(deadlines[i])
+			}
+			if r.Intn(3) == 0 {
+				next := wheel.NextDeadline()
+				if r.Intn(2) == 0 || next.IsZero() {
+					now = now.Add(time.Duration(r.Int63n(int64(100 * tick))))
+				} else {
+					now = next
+				}
+				fired = fired[:0]
+				wheel.Advance(now)
+				for _, j := range fired {
+					Expect(deadlines[j]).ToNot(BeTemporally(">", now))
+				}
+				// the deadlines are rounded up to the next tick
+				currentTick := start.Add(now.Sub(start).Truncate(tick))
+				for j, t := range timers {
+					if t.Pending() {
+						Expect(deadlines[j]).To(BeTemporally(">", currentTick))
+					}
+				}
+			}
+		}
+	})
+})
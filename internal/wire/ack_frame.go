@@ -3,6 +3,7 @@ package wire
 import (
 	"bytes"
 	"errors"
+	"io"
 	//"fmt"
 	"time"
 
@@ -11,8 +12,12 @@ import (
 )
 
 //******
-const time1 = "2006-01-02 15:04:05"
+
+// The receive timestamps are encoded as nanoseconds since timestampEpoch
+var timestampEpoch = time.Date(2020, 11, 26, 20, 30, 50, 0, time.UTC)
+
 //******
+
 var (
 	// ErrInvalidAckRanges occurs when a client sends inconsistent ACK ranges
 	ErrInvalidAckRanges = errors.New("AckFrame: ACK frame contains invalid ACK ranges")
@@ -32,11 +37,30 @@ type AckFrame struct {
 	LowestAcked  protocol.PacketNumber
 	AckRanges    []AckRange // has to be ordered. The highest ACK range goes first, the lowest ACK range goes last
 
+	// Timestamps are the times the packets were received, not sorted, in order to compute one-way delays.
+	// The ReceivedPacketHandler reuses the slice for its next ACK frame.
+	Timestamps []PacketTimestamp
 	// time when the LargestAcked was receiveid
 	// this field Will not be set for received ACKs frames
-	Owdtimestamp       map[protocol.PacketNumber]time.Time
 	PacketReceivedTime time.Time
 	DelayTime          time.Duration
+
+	// ECN contains the ECN counts of the path, nil if the receiver didn't receive any ECN marked packet on it.
+	// Like the timestamps, it's an extension of the ACK frame.
+	ECN *ECNCounts
+}
+
+// A PacketTimestamp is the time a packet was received
+type PacketTimestamp struct {
+	PacketNumber protocol.PacketNumber
+	ReceiveTime  time.Time
+}
+
+// ECNCounts are the numbers of packets received on a path with each ECN codepoint
+type ECNCounts struct {
+	ECT0  uint64
+	ECT1  uint64
+	ECNCE uint64
 }
 
 // ParseAckFrame reads an ACK frame
@@ -169,20 +193,51 @@ func ParseAckFrame(r *bytes.Reader, version protocol.VersionNumber) (*AckFrame,
 		return nil, ErrInvalidAckRanges
 	}
 	//******
-	frame.Owdtimestamp = make(map[protocol.PacketNumber]time.Time)
-	str,_ := time.Parse(time1,"2020-11-26 20:30:50")
-	len,_:= utils.GetByteOrder(version).ReadUint32(r)
-  for i :=0; i<int(len) ;i++{
-  	number,_ := utils.GetByteOrder(version).ReadUint64(r)
-		f1,_:= utils.GetByteOrder(version).ReadUint64(r)
-  	frame.Owdtimestamp[protocol.PacketNumber(number)] = str.Add(time.Duration(f1))
+	numTimestamps, err := utils.GetByteOrder(version).ReadUint32(r)
+	if err != nil {
+		return nil, err
+	}
+	// Don't allocate more timestamps than the frame can hold, 16 bytes each
+	if int64(numTimestamps)*16 > int64(r.Len()) {
+		return nil, io.EOF
+	}
+	if numTimestamps > 0 {
+		frame.Timestamps = make([]PacketTimestamp, numTimestamps)
+	}
+	for i := range frame.Timestamps {
+		number, err := utils.GetByteOrder(version).ReadUint64(r)
+		if err != nil {
+			return nil, err
+		}
+		offset, err := utils.GetByteOrder(version).ReadUint64(r)
+		if err != nil {
+			return nil, err
+		}
+		frame.Timestamps[i] = PacketTimestamp{
+			PacketNumber: protocol.PacketNumber(number),
+			ReceiveTime:  timestampEpoch.Add(time.Duration(offset)),
+		}
 	}
 
-	//fmt.Println(len(frame.Owdtimestamp))
 	//f1,_:= utils.GetByteOrder(version).ReadUint64(r)
 	//frame.PacketReceivedTime =str.Add(time.Duration(f1))
 
 	//******
+	if version.UsesECNCounts() {
+		hasECN, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if hasECN != 0 {
+			frame.ECN = &ECNCounts{}
+			for _, count := range []*uint64{&frame.ECN.ECT0, &frame.ECN.ECT1, &frame.ECN.ECNCE} {
+				if *count, err = utils.GetByteOrder(version).ReadUint64(r); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
 	var numTimestamp byte
 	numTimestamp, err = r.ReadByte()
 	if err != nil {
@@ -364,18 +419,28 @@ func (f *AckFrame) Write(b *bytes.Buffer, version protocol.VersionNumber) error
 	}
 
 	//******
-	str,_ := time.Parse(time1,"2020-11-26 20:30:50")
-	utils.GetByteOrder(version).WriteUint32(b, uint32(len(f.Owdtimestamp)))
-	for number,stamp := range f.Owdtimestamp{
-		dur := stamp.Sub(str)
-		utils.GetByteOrder(version).WriteUint64(b, uint64(number))
-		utils.GetByteOrder(version).WriteUint64(b, uint64(dur))
+	utils.GetByteOrder(version).WriteUint32(b, uint32(len(f.Timestamps)))
+	for _, t := range f.Timestamps {
+		utils.GetByteOrder(version).WriteUint64(b, uint64(t.PacketNumber))
+		utils.GetByteOrder(version).WriteUint64(b, uint64(t.ReceiveTime.Sub(timestampEpoch)))
 	}
 	//dur :=f.PacketReceivedTime.Sub(str)
 	//utils.GetByteOrder(version).WriteUint64(b, uint64(dur))
 
 	//******
 
+	// The ECN counts are dropped if the peer doesn't know them
+	if version.UsesECNCounts() {
+		if f.ECN == nil {
+			b.WriteByte(0)
+		} else {
+			b.WriteByte(1)
+			utils.GetByteOrder(version).WriteUint64(b, f.ECN.ECT0)
+			utils.GetByteOrder(version).WriteUint64(b, f.ECN.ECT1)
+			utils.GetByteOrder(version).WriteUint64(b, f.ECN.ECNCE)
+		}
+	}
+
 	b.WriteByte(0) // no timestamps
 	return nil
 }
@@ -395,6 +460,17 @@ func (f *AckFrame) MinLength(version protocol.VersionNumber) (protocol.ByteCount
 
 	length += (1 + 2) * 0 /* TODO: num_timestamps */
 
+	// the receive timestamps (packet number and time, 8 bytes each) are preceded by their number
+	length += 4 + 16*protocol.ByteCount(len(f.Timestamps))
+
+	// the ECN counts are preceded by a byte saying if they are present
+	if version.UsesECNCounts() {
+		length++
+		if f.ECN != nil {
+			length += 3 * 8
+		}
+	}
+
 	if f.PathID != protocol.InitialPathID {
 		length += 1
 	}
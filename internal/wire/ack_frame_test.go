@@ -628,6 +628,43 @@ var _ = Describe("AckFrame", func() {
 				})
 			})
 		})
+
+		Context("ECN counts", func() {
+			It("parses the ECN counts", func() {
+				b := bytes.NewReader([]byte{0x40,
+					0x3,       // largest acked
+					0x0, 0x8e, // delay time
+					0x3,        // block length
+					0, 0, 0, 0, // num receive timestamps
+					0x1,                       // has ECN counts
+					0, 0, 0, 0, 0, 0, 0, 0x10, // ECT(0)
+					0, 0, 0, 0, 0, 0, 0, 0x1, // ECT(1)
+					0, 0, 0, 0, 0, 0, 0, 0x2, // CE
+					0,
+				})
+				frame, err := ParseAckFrame(b, protocol.VersionMPECN)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frame.LargestAcked).To(Equal(protocol.PacketNumber(3)))
+				Expect(frame.ECN).To(Equal(&ECNCounts{ECT0: 0x10, ECT1: 1, ECNCE: 2}))
+				Expect(b.Len()).To(BeZero())
+			})
+
+			It("parses an ACK frame of a version without ECN counts", func() {
+				b := bytes.NewReader([]byte{0x40,
+					0x3,       // largest acked
+					0x0, 0x8e, // delay time
+					0x3,        // block length
+					0, 0, 0, 0, // num receive timestamps
+					0,
+				})
+				frame, err := ParseAckFrame(b, protocol.VersionMP)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frame.LargestAcked).To(Equal(protocol.PacketNumber(3)))
+				Expect(frame.LowestAcked).To(Equal(protocol.PacketNumber(1)))
+				Expect(frame.ECN).To(BeNil())
+				Expect(b.Len()).To(BeZero())
+			})
+		})
 	})
 
 	Context("when writing", func() {
@@ -661,6 +698,43 @@ var _ = Describe("AckFrame", func() {
 						Expect(r.Len()).To(BeZero())
 					})
 
+					It("writes the receive timestamps", func() {
+						rcvTime := time.Now()
+						frameOrig := &AckFrame{
+							LargestAcked: 20,
+							LowestAcked:  10,
+							Timestamps: []PacketTimestamp{
+								{PacketNumber: 12, ReceiveTime: rcvTime},
+								{PacketNumber: 11, ReceiveTime: rcvTime.Add(time.Millisecond)},
+							},
+						}
+						err := frameOrig.Write(b, version)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(frameOrig.MinLength(version)).To(BeEquivalentTo(b.Len()))
+						r := bytes.NewReader(b.Bytes())
+						frame, err := ParseAckFrame(r, version)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(frame.Timestamps).To(HaveLen(2))
+						Expect(frame.Timestamps[0].PacketNumber).To(Equal(protocol.PacketNumber(12)))
+						Expect(frame.Timestamps[0].ReceiveTime).To(BeTemporally("==", rcvTime))
+						Expect(frame.Timestamps[1].PacketNumber).To(Equal(protocol.PacketNumber(11)))
+						Expect(frame.Timestamps[1].ReceiveTime).To(BeTemporally("==", rcvTime.Add(time.Millisecond)))
+						Expect(r.Len()).To(BeZero())
+					})
+
+					It("errors if the timestamps are cut", func() {
+						frameOrig := &AckFrame{
+							LargestAcked: 20,
+							LowestAcked:  10,
+							Timestamps:   []PacketTimestamp{{PacketNumber: 12, ReceiveTime: time.Now()}},
+						}
+						err := frameOrig.Write(b, version)
+						Expect(err).ToNot(HaveOccurred())
+						// cut the ending byte and a part of the timestamp
+						_, err = ParseAckFrame(bytes.NewReader(b.Bytes()[:b.Len()-4]), version)
+						Expect(err).To(MatchError(io.EOF))
+					})
+
 					It("writes the correct block length in a simple ACK frame", func() {
 						frameOrig := &AckFrame{
 							LargestAcked: 20,
@@ -1198,6 +1272,56 @@ var _ = Describe("AckFrame", func() {
 			})
 		})
 
+		Context("ECN counts", func() {
+			It("writes the ECN counts", func() {
+				frameOrig := &AckFrame{
+					LargestAcked: 20,
+					LowestAcked:  10,
+					ECN:          &ECNCounts{ECT0: 10, ECT1: 1, ECNCE: 0xdeadbeef},
+				}
+				err := frameOrig.Write(b, protocol.VersionMPECN)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frameOrig.MinLength(protocol.VersionMPECN)).To(BeEquivalentTo(b.Len()))
+				r := bytes.NewReader(b.Bytes())
+				frame, err := ParseAckFrame(r, protocol.VersionMPECN)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frame.ECN).To(Equal(frameOrig.ECN))
+				Expect(r.Len()).To(BeZero())
+			})
+
+			It("writes an ACK frame without ECN counts", func() {
+				frameOrig := &AckFrame{
+					LargestAcked: 20,
+					LowestAcked:  10,
+				}
+				err := frameOrig.Write(b, protocol.VersionMPECN)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frameOrig.MinLength(protocol.VersionMPECN)).To(BeEquivalentTo(b.Len()))
+				r := bytes.NewReader(b.Bytes())
+				frame, err := ParseAckFrame(r, protocol.VersionMPECN)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frame.ECN).To(BeNil())
+				Expect(r.Len()).To(BeZero())
+			})
+
+			It("doesn't write the ECN counts for versions without them", func() {
+				frameOrig := &AckFrame{
+					LargestAcked: 20,
+					LowestAcked:  10,
+					ECN:          &ECNCounts{ECT0: 10},
+				}
+				err := frameOrig.Write(b, protocol.VersionMP)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frameOrig.MinLength(protocol.VersionMP)).To(BeEquivalentTo(b.Len()))
+				r := bytes.NewReader(b.Bytes())
+				frame, err := ParseAckFrame(r, protocol.VersionMP)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frame.LargestAcked).To(Equal(frameOrig.LargestAcked))
+				Expect(frame.ECN).To(BeNil())
+				Expect(r.Len()).To(BeZero())
+			})
+		})
+
 		Context("min length", func() {
 			It("has proper min length", func() {
 				f := &AckFrame{
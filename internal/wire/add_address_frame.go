@@ -17,16 +17,28 @@ var (
 	errInconsistentAddrIPVersion = errors.New("internal inconsistency: Addr does not match IP version")
 )
 
+// addAddressBackupFlag is set in the IP version byte when the address should only be used as a backup
+// Only the versions using the backup flag define it, see protocol.VersionNumber.UsesBackupFlag.
+const addAddressBackupFlag uint8 = 0x80
+
 // A AddAddressFrame in QUIC
 type AddAddressFrame struct {
 	IPVersion uint8
 	Addr      net.UDPAddr
+	// Backup indicates that the peer would rather not have paths to this address used
+	// as long as any other path works
+	Backup bool
 }
 
 func (f *AddAddressFrame) Write(b *bytes.Buffer, version protocol.VersionNumber) error {
 	typeByte := uint8(0x10)
 	b.WriteByte(typeByte)
-	b.WriteByte(f.IPVersion)
+	ipv := f.IPVersion
+	// The flag is dropped if the peer doesn't know it
+	if f.Backup && version.UsesBackupFlag() {
+		ipv |= addAddressBackupFlag
+	}
+	b.WriteByte(ipv)
 
 	switch f.IPVersion {
 	case 4:
@@ -68,6 +80,10 @@ func ParseAddAddressFrame(r *bytes.Reader, version protocol.VersionNumber) (*Add
 		return nil, err
 	}
 	frame.IPVersion = ipv
+	if version.UsesBackupFlag() {
+		frame.Backup = ipv&addAddressBackupFlag != 0
+		frame.IPVersion = ipv &^ addAddressBackupFlag
+	}
 
 	switch frame.IPVersion {
 	case 4:
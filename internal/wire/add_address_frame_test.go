@@ -0,0 +1,114 @@
+package wire
+
+import (
+	"bytes"
+	"net"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AddAddressFrame", func() {
+	Context("when parsing", func() {
+		It("accepts an IPv4 address", func() {
+			b := bytes.NewReader([]byte{0x10, 0x04, 127, 0, 0, 1, 0x12, 0x34})
+			frame, err := ParseAddAddressFrame(b, versionBigEndian)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame.IPVersion).To(Equal(uint8(4)))
+			Expect(frame.Addr.IP.Equal(net.IPv4(127, 0, 0, 1))).To(BeTrue())
+			Expect(frame.Addr.Port).To(Equal(0x1234))
+			Expect(frame.Backup).To(BeFalse())
+			Expect(b.Len()).To(BeZero())
+		})
+
+		It("reads the backup flag", func() {
+			b := bytes.NewReader([]byte{0x10, 0x84, 10, 0, 0, 2, 0x12, 0x34})
+			frame, err := ParseAddAddressFrame(b, protocol.VersionMPBackup)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame.IPVersion).To(Equal(uint8(4)))
+			Expect(frame.Addr.IP.Equal(net.IPv4(10, 0, 0, 2))).To(BeTrue())
+			Expect(frame.Backup).To(BeTrue())
+			Expect(b.Len()).To(BeZero())
+		})
+
+		It("doesn't read the backup flag for versions without it", func() {
+			b := bytes.NewReader([]byte{0x10, 0x84, 10, 0, 0, 2, 0x12, 0x34})
+			_, err := ParseAddAddressFrame(b, protocol.VersionMP)
+			Expect(err).To(MatchError(ErrUnknownIPVersion))
+		})
+
+		It("errors on unknown IP versions", func() {
+			b := bytes.NewReader([]byte{0x10, 0x05, 127, 0, 0, 1, 0x12, 0x34})
+			_, err := ParseAddAddressFrame(b, versionBigEndian)
+			Expect(err).To(MatchError(ErrUnknownIPVersion))
+		})
+
+		It("errors on EOFs", func() {
+			data := []byte{0x10, 0x04, 127, 0, 0, 1, 0x12, 0x34}
+			_, err := ParseAddAddressFrame(bytes.NewReader(data), versionBigEndian)
+			Expect(err).NotTo(HaveOccurred())
+			for i := range data {
+				_, err := ParseAddAddressFrame(bytes.NewReader(data[0:i]), versionBigEndian)
+				Expect(err).To(HaveOccurred())
+			}
+		})
+	})
+
+	Context("when writing", func() {
+		It("writes an IPv6 backup address", func() {
+			b := &bytes.Buffer{}
+			frameOrig := &AddAddressFrame{
+				IPVersion: 6,
+				Addr:      net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 4433},
+				Backup:    true,
+			}
+			err := frameOrig.Write(b, protocol.VersionMPBackup)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b.Bytes()[1]).To(Equal(uint8(0x86)))
+			frame, err := ParseAddAddressFrame(bytes.NewReader(b.Bytes()), protocol.VersionMPBackup)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame.IPVersion).To(Equal(uint8(6)))
+			Expect(frame.Addr.IP.Equal(frameOrig.Addr.IP)).To(BeTrue())
+			Expect(frame.Addr.Port).To(Equal(4433))
+			Expect(frame.Backup).To(BeTrue())
+		})
+
+		It("writes the frame unchanged for versions without the backup flag", func() {
+			frameOrig := &AddAddressFrame{
+				IPVersion: 4,
+				Addr:      net.UDPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 0x1234},
+				Backup:    true,
+			}
+			b := &bytes.Buffer{}
+			err := frameOrig.Write(b, protocol.VersionMP)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b.Bytes()).To(Equal([]byte{0x10, 0x04, 10, 0, 0, 2, 0x12, 0x34}))
+			frame, err := ParseAddAddressFrame(bytes.NewReader(b.Bytes()), protocol.VersionMP)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame.IPVersion).To(Equal(uint8(4)))
+			Expect(frame.Backup).To(BeFalse())
+		})
+
+		It("has the proper min length", func() {
+			b := &bytes.Buffer{}
+			f := &AddAddressFrame{
+				IPVersion: 4,
+				Addr:      net.UDPAddr{IP: net.IPv4(192, 168, 1, 1), Port: 443},
+				Backup:    true,
+			}
+			err := f.Write(b, protocol.VersionWhatever)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(f.MinLength(protocol.VersionWhatever)).To(Equal(protocol.ByteCount(b.Len())))
+		})
+
+		It("refuses to write an address not matching the IP version", func() {
+			f := &AddAddressFrame{
+				IPVersion: 4,
+				Addr:      net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 443},
+			}
+			err := f.Write(&bytes.Buffer{}, versionBigEndian)
+			Expect(err).To(MatchError(errInconsistentAddrIPVersion))
+		})
+	})
+})
@@ -66,6 +66,18 @@ var _ = Describe("Frame logging", func() {
 		Expect(buf.Bytes()).To(ContainSubstring("\t<- &wire.AckFrame{PathID: 0x0, LargestAcked: 0x1337, LowestAcked: 0x42, AckRanges: []wire.AckRange(nil), DelayTime: 1ms}\n"))
 	})
 
+	It("logs ACK frames with ECN counts", func() {
+		frame := &AckFrame{
+			PathID:       0,
+			LargestAcked: 0x1337,
+			LowestAcked:  0x42,
+			DelayTime:    1 * time.Millisecond,
+			ECN:          &ECNCounts{ECT0: 10, ECT1: 0, ECNCE: 2},
+		}
+		LogFrame(frame, false)
+		Expect(buf.Bytes()).To(ContainSubstring("\t<- &wire.AckFrame{PathID: 0x0, LargestAcked: 0x1337, LowestAcked: 0x42, AckRanges: []wire.AckRange(nil), DelayTime: 1ms, ECT0: 10, ECT1: 0, CE: 2}\n"))
+	})
+
 	It("logs incoming StopWaiting frames", func() {
 		frame := &StopWaitingFrame{
 			LeastUnacked: 0x1337,
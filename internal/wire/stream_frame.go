@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"io"
+	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
@@ -17,6 +18,10 @@ type StreamFrame struct {
 	DataLenPresent bool
 	Offset         protocol.ByteCount
 	Data           []byte
+
+	// DeliveryDeadline is the time by which the data must reach the peer, zero if there is none.
+	// It is not sent on the wire.
+	DeliveryDeadline time.Time
 }
 
 var (
@@ -0,0 +1,74 @@
+// Package logging defines the structured events traced by a session.
+package logging
+
+import (
+	"net"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/congestion"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+)
+
+type (
+	// A ByteCount is used to count bytes.
+	ByteCount = protocol.ByteCount
+	// A ConnectionID is a QUIC connection ID.
+	ConnectionID = protocol.ConnectionID
+	// A PacketNumber is a QUIC packet number.
+	PacketNumber = protocol.PacketNumber
+	// A PathID is the ID of a path.
+	PathID = protocol.PathID
+	// The Perspective determines if we're acting as a server or a client.
+	Perspective = protocol.Perspective
+	// A Frame is a QUIC frame.
+	Frame = wire.Frame
+	// RTTStats holds the RTT estimates of a path.
+	RTTStats = congestion.RTTStats
+)
+
+const (
+	// PerspectiveServer is used for a QUIC server
+	PerspectiveServer Perspective = protocol.PerspectiveServer
+	// PerspectiveClient is used for a QUIC client
+	PerspectiveClient Perspective = protocol.PerspectiveClient
+)
+
+// PacketLossReason is the reason why a packet was declared lost.
+type PacketLossReason uint8
+
+const (
+	// PacketLossTimeThreshold is used when the packet was not acknowledged in time, while a later packet was.
+	PacketLossTimeThreshold PacketLossReason = iota
+	// PacketLossRTO is used when the packet was queued for retransmission by a retransmission timeout.
+	PacketLossRTO
+	// PacketLossPathClosed is used for the packets in flight on a closed path.
+	PacketLossPathClosed
+)
+
+// An OWDSample is the one-way delay of an acknowledged packet, computed from the timestamp of the peer.
+type OWDSample struct {
+	PacketNumber PacketNumber
+	OWD          time.Duration
+}
+
+// A Tracer traces the events of a session.
+// Paths are created and closed outside of the session run loop, so it must be safe for concurrent use.
+type Tracer interface {
+	SentPacket(pathID PathID, packetNumber PacketNumber, size ByteCount, frames []Frame)
+	ReceivedPacket(pathID PathID, packetNumber PacketNumber, size ByteCount, frames []Frame)
+	// ProcessedAck is called when an ACK acknowledged new packets, with the samples of the packets timestamped by the peer
+	ProcessedAck(pathID PathID, largestAcked PacketNumber, samples []OWDSample)
+	LostPacket(pathID PathID, packetNumber PacketNumber, reason PacketLossReason)
+	// UpdatedMetrics is called after the congestion controller and the RTT estimates may have changed
+	UpdatedMetrics(pathID PathID, rttStats *RTTStats, congestionWindow, bytesInFlight ByteCount)
+	// ChosePath is called when the scheduler selected the path of the next packet
+	ChosePath(pathID PathID)
+	CreatedPath(pathID PathID, localAddr, remoteAddr net.Addr)
+	ClosedPath(pathID PathID)
+	// SBDDecision is called when the shared bottleneck detection grouped the paths.
+	// sharedBottleneck contains the paths sharing a bottleneck with pathID, including itself.
+	SBDDecision(pathID PathID, sharedBottleneck []PathID)
+	// Close is called when the session is closed
+	Close()
+}
@@ -0,0 +1,85 @@
+package quic
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Multipath", func() {
+	var (
+		server     Listener
+		received   chan []byte
+		serverSess chan Session
+	)
+
+	BeforeEach(func() {
+		var err error
+		server, err = ListenAddr("127.0.0.1:0", testdata.GetTLSConfig(), &Config{CreatePaths: true})
+		Expect(err).ToNot(HaveOccurred())
+		received = make(chan []byte, 1)
+		serverSess = make(chan Session, 1)
+		go func() {
+			defer GinkgoRecover()
+			sess, err := server.Accept()
+			if err != nil {
+				return
+			}
+			serverSess <- sess
+			str, err := sess.AcceptStream()
+			Expect(err).ToNot(HaveOccurred())
+			data, err := ioutil.ReadAll(str)
+			Expect(err).ToNot(HaveOccurred())
+			received <- data
+		}()
+	})
+
+	AfterEach(func() {
+		if server != nil {
+			Expect(server.Close()).To(Succeed())
+		}
+	})
+
+	It("transfers data over two paths on the loopback", func() {
+		sess, err := DialAddr(
+			server.Addr().String(),
+			&tls.Config{InsecureSkipVerify: true},
+			&Config{CreatePaths: true},
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer sess.Close(nil)
+		// open two more sockets on the loopback, the client creates a path from each of them
+		pconnMgr := sess.(*session).pathManager.pconnMgr
+		for i := 0; i < 2; i++ {
+			locAddr, err := pconnMgr.createPconn(net.IPv4(127, 0, 0, 1))
+			Expect(err).ToNot(HaveOccurred())
+			pconnMgr.mutex.Lock()
+			pconnMgr.localAddrs = append(pconnMgr.localAddrs, *locAddr)
+			pconnMgr.mutex.Unlock()
+		}
+		pconnMgr.changePaths <- struct{}{}
+		// the paths are read from this goroutine while the session goroutines create them
+		Eventually(func() int { return len(sess.(*session).paths()) }, 5).Should(BeNumerically(">=", 3))
+		var srvSess Session
+		Eventually(serverSess).Should(Receive(&srvSess))
+		Eventually(func() int { return len(srvSess.(*session).paths()) }, 5).Should(BeNumerically(">=", 3))
+
+		data := make([]byte, 1<<20)
+		rand.Read(data)
+		str, err := sess.OpenStreamSync()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Write(data)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.Close()).To(Succeed())
+		var rcvd []byte
+		Eventually(received, 10).Should(Receive(&rcvd))
+		Expect(bytes.Equal(rcvd, data)).To(BeTrue())
+	})
+})
@@ -0,0 +1,87 @@
+package quic
+
+import (
+	"net"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// The UDP socket options of the segmentation offload, from linux/udp.h
+const (
+	udpSegment = 103
+	udpGRO     = 104
+)
+
+var (
+	// gsoOOBSize is the size of the control message setting the segment size of a datagram sent with GSO
+	gsoOOBSize = syscall.CmsgSpace(2)
+	// groOOBSize is the size of the control message buffer of a read, it holds the segment size set by GRO
+	groOOBSize = syscall.CmsgSpace(4)
+)
+
+// gsoSupported says if the kernel can split a datagram in segments when it's sent on the socket
+func gsoSupported(pconn net.PacketConn) bool {
+	var supported bool
+	controlUDPSocket(pconn, func(fd int) {
+		_, err := syscall.GetsockoptInt(fd, syscall.IPPROTO_UDP, udpSegment)
+		supported = err == nil
+	})
+	return supported
+}
+
+// enableGRO asks the kernel to coalesce the datagrams received on the socket.
+// It returns false if GRO is not supported.
+func enableGRO(pconn net.PacketConn) bool {
+	var enabled bool
+	controlUDPSocket(pconn, func(fd int) {
+		enabled = syscall.SetsockoptInt(fd, syscall.IPPROTO_UDP, udpGRO, 1) == nil
+	})
+	return enabled
+}
+
+// groEnabled says if the datagrams received on the socket may be coalesced
+func groEnabled(pconn net.PacketConn) bool {
+	var enabled bool
+	controlUDPSocket(pconn, func(fd int) {
+		val, err := syscall.GetsockoptInt(fd, syscall.IPPROTO_UDP, udpGRO)
+		enabled = err == nil && val != 0
+	})
+	return enabled
+}
+
+func controlUDPSocket(pconn net.PacketConn, f func(fd int)) {
+	udpConn, ok := pconn.(*net.UDPConn)
+	if !ok {
+		return
+	}
+	rawConn, err := udpConn.SyscallConn()
+	if err != nil {
+		return
+	}
+	rawConn.Control(func(fd uintptr) { f(int(fd)) })
+}
+
+// appendSegmentSize appends the control message setting the segment size of a datagram sent with GSO
+func appendSegmentSize(b []byte, size uint16) []byte {
+	start := len(b)
+	b = append(b, make([]byte, syscall.CmsgSpace(2))...)
+	h := (*syscall.Cmsghdr)(unsafe.Pointer(&b[start]))
+	h.Level = syscall.IPPROTO_UDP
+	h.Type = udpSegment
+	h.SetLen(syscall.CmsgLen(2))
+	*(*uint16)(unsafe.Pointer(&b[start+syscall.CmsgLen(0)])) = size
+	return b
+}
+
+// isGSOError says if a write failed because the segmentation offload is not available,
+// e.g. if the network interface doesn't compute the checksums
+func isGSOError(err error) bool {
+	if opErr, ok := err.(*net.OpError); ok {
+		err = opErr.Err
+	}
+	if sysErr, ok := err.(*os.SyscallError); ok {
+		err = sysErr.Err
+	}
+	return err == syscall.EIO
+}
@@ -0,0 +1,23 @@
+//go:build !linux
+// +build !linux
+
+package quic
+
+import "net"
+
+// UDP segmentation offload (GSO and GRO) is only available on Linux
+
+var (
+	gsoOOBSize = 0
+	groOOBSize = 0
+)
+
+func gsoSupported(pconn net.PacketConn) bool { return false }
+
+func enableGRO(pconn net.PacketConn) bool { return false }
+
+func groEnabled(pconn net.PacketConn) bool { return false }
+
+func appendSegmentSize(b []byte, size uint16) []byte { return b }
+
+func isGSOError(err error) bool { return false }
@@ -155,6 +155,8 @@ func (p *packetPacker) PackPacket(pth *path) (*packedPacket, error) {
 	} else {
 		maxSize := protocol.MaxPacketSize - protocol.ByteCount(sealer.Overhead()) - publicHeaderLength
 		payloadFrames, err = p.composeNextPacket(maxSize, p.canSendData(encLevel), pth)
+		// The RST_STREAM frames of the expired streams are queued for the next packets
+		p.streamFramer.ResetExpiredStreams()
 		if err != nil {
 			return nil, err
 		}
@@ -256,7 +258,7 @@ func (p *packetPacker) composeNextPacket(
 	// however, for the last StreamFrame in the packet, we can omit the DataLen, thus saving 2 bytes and yielding a packet of exactly the correct size
 	maxFrameSize += 2
 
-	fs := p.streamFramer.PopStreamFrames(maxFrameSize - payloadLength)
+	fs := p.streamFramer.PopStreamFrames(maxFrameSize-payloadLength, pth)
 	if len(fs) != 0 {
 		fs[len(fs)-1].DataLenPresent = false
 	}
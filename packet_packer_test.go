@@ -3,6 +3,7 @@ package quic
 import (
 	"bytes"
 	"math"
+	"time"
 
 	"github.com/lucas-clemente/quic-go/ackhandler"
 	"github.com/lucas-clemente/quic-go/congestion"
@@ -73,7 +74,7 @@ var _ = Describe("Packet packer", func() {
 		streamFramer = newStreamFramer(streamsMap, nil)
 
 		pth = &path{
-			sentPacketHandler:     ackhandler.NewSentPacketHandler(&congestion.RTTStats{}, nil, nil),
+			sentPacketHandler:     ackhandler.NewSentPacketHandler(0, &congestion.RTTStats{}, nil, nil, nil),
 			packetNumberGenerator: newPacketNumberGenerator(protocol.SkipPacketAveragePeriodLength),
 		}
 
@@ -124,6 +125,29 @@ var _ = Describe("Packet packer", func() {
 		Expect(p.encryptionLevel).To(Equal(protocol.EncryptionForwardSecure))
 	})
 
+	It("resets the streams which missed their deadline only once the packet is composed", func() {
+		pth.sess = &session{}
+		pth.rttStats = &congestion.RTTStats{}
+		pth.sess.addPath(pth)
+		str := newStream(7, func() {}, func(id protocol.StreamID, offset protocol.ByteCount, code uint32) {
+			packer.QueueControlFrame(&wire.RstStreamFrame{StreamID: id, ByteOffset: offset, ErrorCode: code}, pth)
+		}, nil)
+		streamFramer.streamsMap.putStream(str)
+		streamFramer.AddFrameForRetransmission(&wire.StreamFrame{
+			StreamID:         7,
+			Data:             []byte("foobar"),
+			DeliveryDeadline: time.Now().Add(-time.Millisecond),
+		})
+		f := &wire.StreamFrame{StreamID: 5, Data: []byte("foobar")}
+		streamFramer.AddFrameForRetransmission(f)
+		p, err := packer.PackPacket(pth)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(p.frames).To(Equal([]wire.Frame{f}))
+		Expect(packer.controlFrames).To(Equal([]wire.Frame{
+			&wire.RstStreamFrame{StreamID: 7, ErrorCode: StreamDeadlineExceeded},
+		}))
+	})
+
 	Context("diversificaton nonces", func() {
 		var nonce []byte
 
@@ -11,11 +11,7 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/wire"
 )
 
-const (
-	minPathTimer = 10 * time.Millisecond
-	// XXX (QDC): To avoid idling...
-	maxPathTimer = 1 * time.Second
-)
+const minPathTimer = 10 * time.Millisecond
 
 type path struct {
 	pathID protocol.PathID
@@ -27,13 +23,17 @@ type path struct {
 	sentPacketHandler     ackhandler.SentPacketHandler
 	receivedPacketHandler ackhandler.ReceivedPacketHandler
 
-	open      utils.AtomicBool
-	closeChan chan *qerr.QuicError
-	runClosed chan struct{}
+	open utils.AtomicBool
 
 	potentiallyFailed utils.AtomicBool
+	// A backup path is only used when all other paths are potentially failed or closed
+	backup utils.AtomicBool
+
+	// Name of the local interface used by the path, if known
+	ifaceName string
 
-	sentPacket          chan struct{}
+	// Cost and byte budget of the path, nil if the path is free
+	budget *pathBudget
 
 	// It is now the responsibility of the path to keep its packet number
 	packetNumberGenerator *packetNumberGenerator
@@ -47,21 +47,18 @@ type path struct {
 
 	lastNetworkActivityTime time.Time
 
-	timer           *utils.Timer
+	// timer is in the timer wheel of the session
+	timer *utils.WheelTimer
 }
 
 // setup initializes values that are independent of the perspective
-func (p *path) setup(oliaSenders map[protocol.PathID]*congestion.OliaSender) {
+// pm may be nil, the path is then not coupled with other paths
+func (p *path) setup(pm *pathManager) {
 	p.rttStats = &congestion.RTTStats{}
 
-	var cong congestion.SendAlgorithm
-
-	if p.sess.version >= protocol.VersionMP && oliaSenders != nil && p.pathID != protocol.InitialPathID {
-		cong = congestion.NewOliaSender(oliaSenders, p.rttStats, protocol.InitialCongestionWindow, protocol.DefaultMaxCongestionWindow)
-		oliaSenders[p.pathID] = cong.(*congestion.OliaSender)
-	}
+	cong := p.newCongestionControl(pm)
 
-	sentPacketHandler := ackhandler.NewSentPacketHandler(p.rttStats, cong, p.onRTO)
+	sentPacketHandler := ackhandler.NewSentPacketHandler(p.pathID, p.rttStats, cong, p.onRTO, p.sess.tracer)
 
 	now := time.Now()
 
@@ -70,61 +67,179 @@ func (p *path) setup(oliaSenders map[protocol.PathID]*congestion.OliaSender) {
 
 	p.packetNumberGenerator = newPacketNumberGenerator(protocol.SkipPacketAveragePeriodLength)
 
-	p.closeChan = make(chan *qerr.QuicError, 1)
-	p.runClosed = make(chan struct{}, 1)
-	p.sentPacket = make(chan struct{}, 1)
-
-	p.timer = utils.NewTimer()
+	p.timer = p.sess.timerWheel.NewTimer(p.onTimer)
 	p.lastNetworkActivityTime = now
 
 	p.open.Set(true)
 	p.potentiallyFailed.Set(false)
 
-	// Once the path is setup, run it
-	go p.run()
+	if p.sess.tracer != nil {
+		p.sess.tracer.CreatedPath(p.pathID, p.conn.LocalAddr(), p.conn.RemoteAddr())
+	}
 }
 
-func (p *path) close() error {
-	p.open.Set(false)
-	return nil
+// newCongestionControl creates the congestion controller selected by the config
+// The coupled controllers register themselves in pm. A nil return value lets the sentPacketHandler use Cubic
+func (p *path) newCongestionControl(pm *pathManager) congestion.SendAlgorithm {
+	config := p.sess.config
+	if config != nil && config.NewCongestionControl != nil {
+		return config.NewCongestionControl(p.pathID, p.rttStats)
+	}
+
+	cong := p.newCongestionControlAlgorithm(pm, config)
+	if config == nil || !config.HyStartPlusPlus {
+		return cong
+	}
+	if cong == nil {
+		// Same as the default controller of the sentPacketHandler
+		cong = congestion.NewCubicSender(
+			congestion.DefaultClock{},
+			p.rttStats,
+			false,
+			protocol.InitialCongestionWindow,
+			protocol.DefaultMaxCongestionWindow,
+		)
+	}
+	if cong, ok := cong.(congestion.SendAlgorithmWithSlowStart); ok {
+		cong.SetSlowStart(congestion.NewHyStartPlusPlus())
+	}
+	return cong
 }
 
-func (p *path) run() {
-	// XXX (QDC): relay everything to the session, maybe not the most efficient
-runLoop:
-	for {
-		// Close immediately if requested
-		select {
-		case <-p.closeChan:
-			break runLoop
-		default:
+// newCongestionControlAlgorithm creates the congestion controller of config.CongestionControl
+func (p *path) newCongestionControlAlgorithm(pm *pathManager, config *Config) congestion.SendAlgorithm {
+	algorithm := CongestionControlDefault
+	if config != nil {
+		algorithm = config.CongestionControl
+	}
+	multipath := p.sess.version >= protocol.VersionMP
+	// The initial path of multipath sessions is not coupled with the others
+	coupled := pm != nil && (!multipath || p.pathID != protocol.InitialPathID)
+
+	switch algorithm {
+	case CongestionControlCubic, CongestionControlReno:
+		return congestion.NewCubicSender(
+			congestion.DefaultClock{},
+			p.rttStats,
+			algorithm == CongestionControlReno,
+			protocol.InitialCongestionWindow,
+			protocol.DefaultMaxCongestionWindow,
+		)
+	case CongestionControlBbr:
+		return congestion.NewBbrSender(
+			congestion.DefaultClock{},
+			p.rttStats,
+			protocol.InitialCongestionWindow,
+			protocol.DefaultMaxCongestionWindow,
+		)
+	case CongestionControlOlia:
+		if coupled {
+			return p.newOliaSender(pm.oliaSenders)
 		}
-
-		p.maybeResetTimer()
-
-		select {
-		case <-p.closeChan:
-			break runLoop
-		case <-p.timer.Chan():
-			p.timer.SetRead()
-			select {
-			case p.sess.pathTimers <- p:
-			// XXX (QDC): don't remain stuck here!
-			case <-p.closeChan:
-				break runLoop
-			case <-p.sentPacket:
-				// Don't remain stuck here!
-			}
-		case <-p.sentPacket:
-			// Used to reset the path timer
+	case CongestionControlLia:
+		if coupled {
+			cong := congestion.NewLiaSender(pm.liaSenders, p.rttStats, protocol.InitialCongestionWindow, protocol.DefaultMaxCongestionWindow)
+			pm.liaSenders.Add(p.pathID, cong.(*congestion.LiaSender))
+			return cong
+		}
+	case CongestionControlBalia:
+		if coupled {
+			cong := congestion.NewBaliaSender(pm.baliaSenders, p.rttStats, protocol.InitialCongestionWindow, protocol.DefaultMaxCongestionWindow)
+			pm.baliaSenders.Add(p.pathID, cong.(*congestion.BaliaSender))
+			return cong
+		}
+	case CongestionControlWVegas:
+		if coupled {
+			cong := congestion.NewWVegasSender(pm.wvegasSenders, congestion.DefaultClock{}, p.rttStats, protocol.InitialCongestionWindow, protocol.DefaultMaxCongestionWindow)
+			pm.wvegasSenders.Add(p.pathID, cong.(*congestion.WVegasSender))
+			return cong
+		}
+	default:
+		if multipath && coupled {
+			return p.newOliaSender(pm.oliaSenders)
 		}
 	}
-	p.close()
-	p.runClosed <- struct{}{}
+	return nil
+}
+
+func (p *path) newOliaSender(oliaSenders *congestion.OliaSenders) congestion.SendAlgorithm {
+	cong := congestion.NewOliaSender(oliaSenders, p.rttStats, protocol.InitialCongestionWindow, protocol.DefaultMaxCongestionWindow)
+	oliaSenders.Add(p.pathID, cong.(*congestion.OliaSender))
+	return cong
+}
+
+// setPotentiallyFailed flags the path as potentially failed or working, and updates the coupling of its sender accordingly
+func (p *path) setPotentiallyFailed(failed bool) {
+	if p.potentiallyFailed.Get() == failed {
+		return
+	}
+	p.potentiallyFailed.Set(failed)
+	if pm := p.sess.pathManager; pm != nil && pm.oliaSenders != nil {
+		pm.oliaSenders.SetPotentiallyFailed(p.pathID, failed)
+		pm.liaSenders.SetPotentiallyFailed(p.pathID, failed)
+		pm.baliaSenders.SetPotentiallyFailed(p.pathID, failed)
+		pm.wvegasSenders.SetPotentiallyFailed(p.pathID, failed)
+	}
+}
+
+// close closes the path, its timer is stopped by the session
+func (p *path) close() error {
+	if !p.open.Swap(false) {
+		return nil
+	}
+	if p.sess.tracer != nil {
+		p.sess.tracer.ClosedPath(p.pathID)
+	}
+	return nil
+}
+
+// onTimer is called by the timer wheel of the session
+func (p *path) onTimer() {
+	if timeout := p.sentPacketHandler.GetAlarmTimeout(); !timeout.IsZero() && !timeout.After(time.Now()) {
+		p.sentPacketHandler.OnAlarm()
+	}
 }
 
 func (p *path) SendingAllowed() bool {
-	return p.open.Get() && p.sentPacketHandler.SendingAllowed()
+	return p.open.Get() && p.sentPacketHandler.SendingAllowed() && p.sentPacketHandler.TimeUntilSend() == 0
+}
+
+// costPerMB returns the cost of sending data on the path
+func (p *path) costPerMB() float64 {
+	if p.budget == nil {
+		return 0
+	}
+	return p.budget.cost.CostPerMB
+}
+
+// budgetExhausted returns true if the path should not carry data anymore
+func (p *path) budgetExhausted(now time.Time) bool {
+	return p.budget != nil && p.budget.exhausted(now)
+}
+
+// estimatedDeliveryDelay estimates the time needed by data sent now on the path to reach the peer,
+// i.e., SRTT/2 plus the time to drain the bytes in flight at a rate of one congestion window per SRTT
+func (p *path) estimatedDeliveryDelay() time.Duration {
+	srtt := p.rttStats.SmoothedRTT()
+	if srtt == 0 {
+		srtt = protocol.DefaultDeliveryRTT
+	}
+	delay := srtt / 2
+	if cwnd := p.sentPacketHandler.GetCongestionWindow(); cwnd > 0 {
+		delay += time.Duration(int64(srtt) * int64(p.sentPacketHandler.GetBytesInFlight()) / int64(cwnd))
+	}
+	return delay
+}
+
+// matchesPreference returns true if the path is one of those requested by pref
+func (p *path) matchesPreference(pref PathPreference) bool {
+	if pref.Pinned {
+		return p.pathID == pref.PathID
+	}
+	if pref.Interface != "" {
+		return p.ifaceName == pref.Interface
+	}
+	return true
 }
 
 func (p *path) GetStopWaitingFrame(force bool) *wire.StopWaitingFrame {
@@ -149,7 +264,11 @@ func (p *path) GetClosePathFrame() *wire.ClosePathFrame {
 	return closePathFrame
 }
 
-func (p *path) maybeResetTimer() {
+func (p *path) maybeResetTimer(now time.Time) {
+	if !p.open.Get() {
+		p.timer.Stop()
+		return
+	}
 	deadline := p.lastNetworkActivityTime.Add(p.idleTimeout())
 
 	if ackAlarm := p.receivedPacketHandler.GetAlarmTimeout(); !ackAlarm.IsZero() {
@@ -159,9 +278,7 @@ func (p *path) maybeResetTimer() {
 		deadline = utils.MinTime(deadline, lossTime)
 	}
 
-	deadline = utils.MinTime(utils.MaxTime(deadline, time.Now().Add(minPathTimer)), time.Now().Add(maxPathTimer))
-
-	p.timer.Reset(deadline)
+	p.timer.Reset(utils.MaxTime(deadline, now.Add(minPathTimer)))
 }
 
 func (p *path) idleTimeout() time.Duration {
@@ -189,7 +306,7 @@ func (p *path) handlePacketImpl(pkt *receivedPacket) error {
 	data := pkt.data
 
 	// We just received a new packet on that path, so it works
-	p.potentiallyFailed.Set(false)
+	p.setPotentiallyFailed(false)
 
 	// Calculate packet number
 	hdr.PacketNumber = protocol.InferPacketNumber(
@@ -198,6 +315,13 @@ func (p *path) handlePacketImpl(pkt *receivedPacket) error {
 		hdr.PacketNumber,
 	)
 
+	// Capture the packet before it's decrypted in place
+	if p.sess.capture != nil {
+		raw := make([]byte, 0, len(hdr.Raw)+len(data))
+		raw = append(append(raw, hdr.Raw...), data...)
+		p.sess.capture.WritePacket(p.pathID, pkt.rcvTime, pkt.remoteAddr, p.conn.LocalAddr(), raw, false)
+	}
+
 	packet, err := p.sess.unpacker.Unpack(hdr.Raw, hdr, data)
 	if utils.Debug() {
 		if err != nil {
@@ -224,8 +348,12 @@ func (p *path) handlePacketImpl(pkt *receivedPacket) error {
 	// Only do this after decrupting, so we are sure the packet is not attacker-controlled
 	p.largestRcvdPacketNumber = utils.MaxPacketNumber(p.largestRcvdPacketNumber, hdr.PacketNumber)
 
+	if p.sess.tracer != nil {
+		p.sess.tracer.ReceivedPacket(p.pathID, hdr.PacketNumber, protocol.ByteCount(len(data)+len(hdr.Raw)), packet.frames)
+	}
+
 	isRetransmittable := ackhandler.HasRetransmittableFrames(packet.frames)
-	if err = p.receivedPacketHandler.ReceivedPacket(hdr.PacketNumber, isRetransmittable); err != nil {
+	if err = p.receivedPacketHandler.ReceivedPacket(hdr.PacketNumber, pkt.ecn, isRetransmittable); err != nil {
 		return err
 	}
 
@@ -239,7 +367,7 @@ func (p *path) handlePacketImpl(pkt *receivedPacket) error {
 func (p *path) onRTO(lastSentTime time.Time) bool {
 	// Was there any activity since last sent packet?
 	if p.lastNetworkActivityTime.Before(lastSentTime) {
-		p.potentiallyFailed.Set(true)
+		p.setPotentiallyFailed(true)
 		p.sess.schedulePathsFrame()
 		return true
 	}
@@ -0,0 +1,58 @@
+package quic
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// A pathBudget tracks the bytes sent on all the paths sharing a PathCost
+type pathBudget struct {
+	cost PathCost
+
+	// All the paths ever using this cost, closed ones included
+	// Only modified by the session goroutine
+	paths []*path
+
+	windowStart        time.Time
+	bytesAtWindowStart protocol.ByteCount
+}
+
+func newPathBudget(cost PathCost) *pathBudget {
+	return &pathBudget{
+		cost:        cost,
+		windowStart: time.Now(),
+	}
+}
+
+// bytesSent returns the number of bytes sent on the paths of the budget
+func (b *pathBudget) bytesSent() protocol.ByteCount {
+	var sent protocol.ByteCount
+	for _, pth := range b.paths {
+		sent += pth.sentPacketHandler.GetBytesSent()
+	}
+	return sent
+}
+
+// roll renews the budget if its window ended, the next window starts a whole number of windows after the current one
+// It's called by the session goroutine before sending.
+func (b *pathBudget) roll(now time.Time) {
+	if b.cost.BudgetWindow == 0 || now.Sub(b.windowStart) < b.cost.BudgetWindow {
+		return
+	}
+	windows := now.Sub(b.windowStart) / b.cost.BudgetWindow
+	b.windowStart = b.windowStart.Add(windows * b.cost.BudgetWindow)
+	b.bytesAtWindowStart = b.bytesSent()
+}
+
+// exhausted returns true if the paths of the budget should stop carrying data
+// A budget whose window ended is renewed, even if roll wasn't called yet.
+func (b *pathBudget) exhausted(now time.Time) bool {
+	if b.cost.Budget == 0 {
+		return false
+	}
+	if b.cost.BudgetWindow != 0 && now.Sub(b.windowStart) >= b.cost.BudgetWindow {
+		return false
+	}
+	return b.bytesSent()-b.bytesAtWindowStart >= protocol.ByteCount(b.cost.Budget)
+}
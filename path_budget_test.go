@@ -0,0 +1,141 @@
+package quic
+
+import (
+	"net"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/ackhandler"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Path budget", func() {
+	var (
+		budget *pathBudget
+		sph1   *mockSentPacketHandler
+		sph2   *mockSentPacketHandler
+	)
+
+	BeforeEach(func() {
+		budget = newPathBudget(PathCost{Interface: "wwan0", CostPerMB: 10, Budget: 1000})
+		sph1 = &mockSentPacketHandler{}
+		sph2 = &mockSentPacketHandler{}
+		budget.paths = []*path{
+			{pathID: 1, sentPacketHandler: sph1, budget: budget},
+			{pathID: 3, sentPacketHandler: sph2, budget: budget},
+		}
+	})
+
+	It("sums the bytes sent on all its paths", func() {
+		sph1.SentPacket(&ackhandler.Packet{Length: 300})
+		sph2.SentPacket(&ackhandler.Packet{Length: 400})
+		Expect(budget.bytesSent()).To(Equal(protocol.ByteCount(700)))
+		Expect(budget.exhausted(time.Now())).To(BeFalse())
+	})
+
+	It("is exhausted once the budget is used up", func() {
+		sph1.SentPacket(&ackhandler.Packet{Length: 600})
+		sph2.SentPacket(&ackhandler.Packet{Length: 400})
+		Expect(budget.exhausted(time.Now())).To(BeTrue())
+		Expect(budget.paths[0].budgetExhausted(time.Now())).To(BeTrue())
+		Expect(budget.paths[1].budgetExhausted(time.Now())).To(BeTrue())
+	})
+
+	It("is never exhausted without a budget", func() {
+		budget.cost.Budget = 0
+		sph1.SentPacket(&ackhandler.Packet{Length: 10000})
+		Expect(budget.exhausted(time.Now())).To(BeFalse())
+	})
+
+	It("renews the budget at the end of the window", func() {
+		budget.cost.BudgetWindow = time.Hour
+		start := budget.windowStart
+		sph1.SentPacket(&ackhandler.Packet{Length: 1500})
+		Expect(budget.exhausted(start.Add(time.Minute))).To(BeTrue())
+		Expect(budget.exhausted(start.Add(time.Hour))).To(BeFalse())
+		budget.roll(start.Add(time.Hour))
+		sph2.SentPacket(&ackhandler.Packet{Length: 999})
+		Expect(budget.exhausted(start.Add(time.Hour + time.Minute))).To(BeFalse())
+		sph2.SentPacket(&ackhandler.Packet{Length: 1})
+		Expect(budget.exhausted(start.Add(time.Hour + time.Minute))).To(BeTrue())
+	})
+
+	It("doesn't move the window when queried", func() {
+		budget.cost.BudgetWindow = time.Hour
+		start := budget.windowStart
+		sph1.SentPacket(&ackhandler.Packet{Length: 1500})
+		Expect(budget.exhausted(start.Add(2 * time.Hour))).To(BeFalse())
+		Expect(budget.windowStart).To(Equal(start))
+		Expect(budget.bytesAtWindowStart).To(BeZero())
+		Expect(budget.exhausted(start.Add(time.Minute))).To(BeTrue())
+	})
+
+	It("advances the window by whole windows", func() {
+		budget.cost.BudgetWindow = time.Hour
+		start := budget.windowStart
+		sph1.SentPacket(&ackhandler.Packet{Length: 1500})
+		budget.roll(start.Add(30 * time.Minute))
+		Expect(budget.windowStart).To(Equal(start))
+		// a late roll doesn't shift the windows
+		budget.roll(start.Add(2*time.Hour + 10*time.Minute))
+		Expect(budget.windowStart).To(Equal(start.Add(2 * time.Hour)))
+		Expect(budget.bytesAtWindowStart).To(Equal(protocol.ByteCount(1500)))
+		Expect(budget.exhausted(start.Add(2*time.Hour + 20*time.Minute))).To(BeFalse())
+	})
+
+	Context("initial path", func() {
+		var (
+			sess *session
+			pm   *pathManager
+			pth  *path
+		)
+
+		BeforeEach(func() {
+			cost := PathCost{Addr: net.IPv4(127, 0, 0, 1), CostPerMB: 5, Budget: 1000}
+			sess = &session{config: &Config{PathCosts: []PathCost{cost}}}
+			pm = &pathManager{sess: sess, budgets: []*pathBudget{newPathBudget(cost)}}
+			// The pconn of the initial path listens on all the interfaces
+			mconn := newMockConnection()
+			mconn.localAddr = &net.UDPAddr{IP: net.IPv4zero, Port: 4242}
+			mconn.remoteAddr = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 4433}
+			pth = &path{pathID: protocol.InitialPathID, sess: sess, conn: mconn}
+		})
+
+		It("gives the initial path the cost and budget of the address it is routed from", func() {
+			pm.setupInitialPathCost(pth)
+			Expect(pth.budget).To(Equal(pm.budgets[0]))
+			Expect(pm.budgets[0].paths).To(Equal([]*path{pth}))
+			Expect(pth.costPerMB()).To(Equal(5.0))
+			Expect(pth.ifaceName).ToNot(BeEmpty())
+			Expect(pth.backup.Get()).To(BeFalse())
+		})
+
+		It("flags the initial path on a backup interface", func() {
+			sess.config.BackupInterfaces = []string{interfaceName(net.IPv4(127, 0, 0, 1))}
+			pm.setupInitialPathCost(pth)
+			Expect(pth.backup.Get()).To(BeTrue())
+		})
+
+		It("doesn't schedule data on the only path once its budget is used up", func() {
+			pm.setupInitialPathCost(pth)
+			sph := &mockSentPacketHandler{}
+			pth.sentPacketHandler = sph
+			pth.open.Set(true)
+			sess.addPath(pth)
+			sch := &scheduler{}
+			Expect(sch.selectPathRoundRobin(sess, false, false, nil)).To(Equal(pth))
+			Expect(sch.selectPathLowLatency(sess, false, false, nil)).To(Equal(pth))
+			sph.SentPacket(&ackhandler.Packet{Length: 1000})
+			Expect(sch.selectPathRoundRobin(sess, false, false, nil)).To(BeNil())
+			Expect(sch.selectPathLowLatency(sess, false, false, nil)).To(BeNil())
+		})
+	})
+
+	It("reports the cost of a path", func() {
+		Expect(budget.paths[0].costPerMB()).To(Equal(10.0))
+		Expect((&path{}).costPerMB()).To(BeZero())
+		Expect((&path{}).budgetExhausted(time.Now())).To(BeFalse())
+	})
+})
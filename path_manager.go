@@ -22,9 +22,17 @@ type pathManager struct {
 	remoteAddrs6 []net.UDPAddr
 
 	advertisedLocAddrs map[string]bool
+	// Remote addresses the peer asked to use only as backup, protected by pconnMgr.mutex
+	remoteBackupAddrs map[string]bool
+
+	// One budget per entry of config.PathCosts
+	budgets []*pathBudget
 
 	// TODO (QDC): find a cleaner way
-	oliaSenders map[protocol.PathID]*congestion.OliaSender
+	oliaSenders   *congestion.OliaSenders
+	liaSenders    *congestion.CoupledSenders
+	baliaSenders  *congestion.CoupledSenders
+	wvegasSenders *congestion.CoupledSenders
 
 	handshakeCompleted chan struct{}
 	runClosed          chan struct{}
@@ -44,22 +52,33 @@ func (pm *pathManager) setup(conn connection) {
 	pm.remoteAddrs4 = make([]net.UDPAddr, 0)
 	pm.remoteAddrs6 = make([]net.UDPAddr, 0)
 	pm.advertisedLocAddrs = make(map[string]bool)
+	pm.remoteBackupAddrs = make(map[string]bool)
+	pm.budgets = make([]*pathBudget, len(pm.sess.config.PathCosts))
+	for i, cost := range pm.sess.config.PathCosts {
+		pm.budgets[i] = newPathBudget(cost)
+	}
 	pm.handshakeCompleted = make(chan struct{}, 1)
 	pm.runClosed = make(chan struct{}, 1)
 	pm.timer = time.NewTimer(0)
 	pm.nbPaths = 0
 
-	pm.oliaSenders = make(map[protocol.PathID]*congestion.OliaSender)
+	pm.oliaSenders = congestion.NewOliaSenders()
+	pm.liaSenders = congestion.NewCoupledSenders()
+	pm.baliaSenders = congestion.NewCoupledSenders()
+	pm.wvegasSenders = congestion.NewCoupledSenders()
 
 	// Setup the first path of the connection
-	pm.sess.paths[protocol.InitialPathID] = &path{
+	pth := &path{
 		pathID: protocol.InitialPathID,
 		sess:   pm.sess,
 		conn:   conn,
 	}
 
+	pm.setupInitialPathCost(pth)
+
 	// Setup this first path
-	pm.sess.paths[protocol.InitialPathID].setup(pm.oliaSenders)
+	pth.setup(pm)
+	pm.sess.addPath(pth)
 
 	// With the initial path, get the remoteAddr to create paths accordingly
 	if conn.RemoteAddr() != nil {
@@ -85,13 +104,7 @@ func (pm *pathManager) run() {
 	case <-pm.runClosed:
 		return
 	case <-pm.handshakeCompleted:
-		if pm.sess.createPaths {
-			err := pm.createPaths()
-			if err != nil {
-				pm.closePaths()
-				return
-			}
-		}
+		// The session created the first paths
 	}
 
 runLoop:
@@ -100,8 +113,10 @@ runLoop:
 		case <-pm.runClosed:
 			break runLoop
 		case <-pm.pconnMgr.changePaths:
-			if pm.sess.createPaths {
-				pm.createPaths()
+			// Only the session goroutine creates paths
+			select {
+			case pm.sess.pathsChanged <- struct{}{}:
+			default:
 			}
 		}
 	}
@@ -116,25 +131,147 @@ func getIPVersion(ip net.IP) int {
 	return 6
 }
 
+// isBackupInterface returns true if the local address belongs to one of the configured backup interfaces
+// pconnMgr.mutex must be held
+func (pm *pathManager) isBackupInterface(locAddr string) bool {
+	ifaceName, ok := pm.pconnMgr.ifaceNames[locAddr]
+	if !ok {
+		return false
+	}
+	return pm.isBackupInterfaceName(ifaceName)
+}
+
+// isBackupInterfaceName returns true if the interface is one of the configured backup interfaces
+func (pm *pathManager) isBackupInterfaceName(ifaceName string) bool {
+	for _, name := range pm.sess.config.BackupInterfaces {
+		if name == ifaceName {
+			return true
+		}
+	}
+	return false
+}
+
+// isBackup returns true if a path between these addresses should only be used as backup
+// pconnMgr.mutex must be held
+func (pm *pathManager) isBackup(locAddr string, remAddr string) bool {
+	return pm.isBackupInterface(locAddr) || pm.remoteBackupAddrs[remAddr]
+}
+
+// getBudget returns the budget applying to paths using the local address, or nil if they are free
+// pconnMgr.mutex must be held
+func (pm *pathManager) getBudget(locAddr string) *pathBudget {
+	udpAddr, err := net.ResolveUDPAddr("udp", locAddr)
+	if err != nil {
+		return nil
+	}
+	return pm.getBudgetFor(udpAddr.IP, pm.pconnMgr.ifaceNames[locAddr])
+}
+
+// getBudgetFor returns the budget applying to paths using the local IP on the interface, or nil if they are free
+func (pm *pathManager) getBudgetFor(ip net.IP, ifaceName string) *pathBudget {
+	for i, cost := range pm.sess.config.PathCosts {
+		if cost.Interface != "" {
+			if cost.Interface == ifaceName {
+				return pm.budgets[i]
+			}
+		} else if cost.Addr != nil && cost.Addr.Equal(ip) {
+			return pm.budgets[i]
+		}
+	}
+	return nil
+}
+
+// setupInitialPathCost gives the initial path the interface, backup flag and budget of its local address
+// Its pconn listens on all the interfaces, the local address is the one the kernel routes the peer's address from.
+func (pm *pathManager) setupInitialPathCost(pth *path) {
+	if len(pm.sess.config.PathCosts) == 0 && len(pm.sess.config.BackupInterfaces) == 0 {
+		return
+	}
+	ip := routedLocalIP(pth.conn.LocalAddr(), pth.conn.RemoteAddr())
+	if ip == nil {
+		return
+	}
+	pth.ifaceName = interfaceName(ip)
+	pth.backup.Set(pm.isBackupInterfaceName(pth.ifaceName))
+	pth.budget = pm.getBudgetFor(ip, pth.ifaceName)
+	if pth.budget != nil {
+		pth.budget.paths = append(pth.budget.paths, pth)
+	}
+}
+
+// rollBudgets renews the budgets whose window ended
+// It must be called by the session goroutine
+func (pm *pathManager) rollBudgets(now time.Time) {
+	for _, budget := range pm.budgets {
+		budget.roll(now)
+	}
+}
+
+// routedLocalIP returns the local IP used to reach remAddr, nil if it is unknown
+// If locAddr is unspecified, the routing table decides. No packet is sent to find out.
+func routedLocalIP(locAddr net.Addr, remAddr net.Addr) net.IP {
+	loc, ok := locAddr.(*net.UDPAddr)
+	if !ok {
+		return nil
+	}
+	if !loc.IP.IsUnspecified() {
+		return loc.IP
+	}
+	rem, ok := remAddr.(*net.UDPAddr)
+	if !ok {
+		return nil
+	}
+	c, err := net.DialUDP("udp", nil, rem)
+	if err != nil {
+		return nil
+	}
+	defer c.Close()
+	return c.LocalAddr().(*net.UDPAddr).IP
+}
+
+// interfaceName returns the name of the interface having the IP, an empty string if none has it
+func interfaceName(ip net.IP) string {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return ""
+	}
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			if ipNet, ok := a.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+				return iface.Name
+			}
+		}
+	}
+	return ""
+}
+
 func (pm *pathManager) advertiseAddresses() {
 	pm.pconnMgr.mutex.Lock()
 	defer pm.pconnMgr.mutex.Unlock()
 	for _, locAddr := range pm.pconnMgr.localAddrs {
 		_, sent := pm.advertisedLocAddrs[locAddr.String()]
 		if !sent {
+			backup := pm.isBackupInterface(locAddr.String())
+			// XXX (QDC): the client only advertises its backup addresses, so that the server avoids them.
+			// That's pointless if the version can't flag them.
+			if pm.sess.perspective == protocol.PerspectiveClient && (!backup || !pm.sess.version.UsesBackupFlag()) {
+				continue
+			}
 			version := getIPVersion(locAddr.IP)
-			pm.sess.streamFramer.AddAddressForTransmission(uint8(version), locAddr)
+			pm.sess.streamFramer.AddAddressForTransmission(uint8(version), locAddr, backup)
 			pm.advertisedLocAddrs[locAddr.String()] = true
 		}
 	}
 }
 
+// createPath must be called by the session goroutine, with pconnMgr.mutex held
 func (pm *pathManager) createPath(locAddr net.UDPAddr, remAddr net.UDPAddr) error {
 	// First check that the path does not exist yet
-	pm.sess.pathsLock.Lock()
-	defer pm.sess.pathsLock.Unlock()
-	paths := pm.sess.paths
-	for _, pth := range paths {
+	for _, pth := range pm.sess.paths() {
 		locAddrPath := pth.conn.LocalAddr().String()
 		remAddrPath := pth.conn.RemoteAddr().String()
 		if locAddr.String() == locAddrPath && remAddr.String() == remAddrPath {
@@ -148,21 +285,27 @@ func (pm *pathManager) createPath(locAddr net.UDPAddr, remAddr net.UDPAddr) erro
 		sess:   pm.sess,
 		conn:   &conn{pconn: pm.pconnMgr.pconns[locAddr.String()], currentAddr: &remAddr},
 	}
-	pth.setup(pm.oliaSenders)
-	pm.sess.paths[pm.nxtPathID] = pth
+	pth.backup.Set(pm.isBackup(locAddr.String(), remAddr.String()))
+	pth.ifaceName = pm.pconnMgr.ifaceNames[locAddr.String()]
+	pth.budget = pm.getBudget(locAddr.String())
+	if pth.budget != nil {
+		pth.budget.paths = append(pth.budget.paths, pth)
+	}
+	pth.setup(pm)
+	pm.sess.addPath(pth)
 	if utils.Debug() {
-		utils.Debugf("Created path %x on %s to %s", pm.nxtPathID, locAddr.String(), remAddr.String())
+		utils.Debugf("Created path %x on %s to %s (backup: %t)", pm.nxtPathID, locAddr.String(), remAddr.String(), pth.backup.Get())
 	}
 	//******
-	utils.Infof("Created path %x on %s to %s", pm.nxtPathID, locAddr.String(), remAddr.String())
+	utils.Infof("Created path %x on %s to %s (backup: %t)", pm.nxtPathID, locAddr.String(), remAddr.String(), pth.backup.Get())
 	//******
 	pm.nxtPathID += 2
 	// Send a PING frame to get latency info about the new path and informing the
 	// peer of its existence
-	// Because we hold pathsLock, it is safe to send packet now
 	return pm.sess.sendPing(pth)
 }
 
+// createPaths must be called by the session goroutine, since it creates paths
 func (pm *pathManager) createPaths() error {
 	if utils.Debug() {
 		utils.Debugf("Path manager tries to create paths")
@@ -173,6 +316,8 @@ func (pm *pathManager) createPaths() error {
 		pm.advertiseAddresses()
 		return nil
 	}
+	// Let the server know which of our addresses are backups
+	pm.advertiseAddresses()
 	// TODO (QDC): clearly not optimali
 	pm.pconnMgr.mutex.Lock()
 	defer pm.pconnMgr.mutex.Unlock()
@@ -198,15 +343,26 @@ func (pm *pathManager) createPaths() error {
 	return nil
 }
 
+// createPathFromRemote must be called by the session goroutine
 func (pm *pathManager) createPathFromRemote(p *receivedPacket) (*path, error) {
-	pm.sess.pathsLock.Lock()
-	defer pm.sess.pathsLock.Unlock()
+	backup := false
+	var ifaceName string
+	var budget *pathBudget
+	// XXX (QDC): for tests
+	if pm.pconnMgr != nil {
+		pm.pconnMgr.mutex.Lock()
+		backup = pm.isBackup(p.rcvPconn.LocalAddr().String(), p.remoteAddr.String())
+		ifaceName = pm.pconnMgr.ifaceNames[p.rcvPconn.LocalAddr().String()]
+		budget = pm.getBudget(p.rcvPconn.LocalAddr().String())
+		pm.pconnMgr.mutex.Unlock()
+	}
+
 	localPconn := p.rcvPconn
 	remoteAddr := p.remoteAddr
 	pathID := p.publicHeader.PathID
 
 	// Sanity check: pathID should not exist yet
-	_, ko := pm.sess.paths[pathID]
+	_, ko := pm.sess.paths()[pathID]
 	if ko {
 		return nil, errors.New("trying to create already existing path")
 	}
@@ -224,9 +380,15 @@ func (pm *pathManager) createPathFromRemote(p *receivedPacket) (*path, error) {
 		sess:   pm.sess,
 		conn:   &conn{pconn: localPconn, currentAddr: remoteAddr},
 	}
+	pth.backup.Set(backup)
+	pth.ifaceName = ifaceName
+	pth.budget = budget
+	if budget != nil {
+		budget.paths = append(budget.paths, pth)
+	}
 
-	pth.setup(pm.oliaSenders)
-	pm.sess.paths[pathID] = pth
+	pth.setup(pm)
+	pm.sess.addPath(pth)
 
 	if utils.Debug() {
 		utils.Debugf("Created remote path %x on %s to %s", pathID, localPconn.LocalAddr().String(), remoteAddr.String())
@@ -244,6 +406,17 @@ func (pm *pathManager) handleAddAddressFrame(f *wire.AddAddressFrame) error {
 	default:
 		return wire.ErrUnknownIPVersion
 	}
+	if f.Backup {
+		pm.pconnMgr.mutex.Lock()
+		pm.remoteBackupAddrs[f.Addr.String()] = true
+		pm.pconnMgr.mutex.Unlock()
+		// The peer may already use a path towards this address
+		for _, pth := range pm.sess.paths() {
+			if pth.pathID != protocol.InitialPathID && pth.conn.RemoteAddr().String() == f.Addr.String() {
+				pth.backup.Set(true)
+			}
+		}
+	}
 	if pm.sess.createPaths {
 		return pm.createPaths()
 	}
@@ -251,33 +424,25 @@ func (pm *pathManager) handleAddAddressFrame(f *wire.AddAddressFrame) error {
 }
 
 func (pm *pathManager) closePath(pthID protocol.PathID) error {
-	pm.sess.pathsLock.RLock()
-	defer pm.sess.pathsLock.RUnlock()
-
-	pth, ok := pm.sess.paths[pthID]
+	pth, ok := pm.sess.paths()[pthID]
 	if !ok {
 		// XXX (QDC) Unknown path, what should we do?
 		return nil
 	}
 
-	if pth.open.Get() {
-		pth.closeChan <- nil
-	}
+	pth.close()
+
+	// Stop coupling the other paths with the closed one
+	pm.oliaSenders.Remove(pthID)
+	pm.liaSenders.Remove(pthID)
+	pm.baliaSenders.Remove(pthID)
+	pm.wvegasSenders.Remove(pthID)
 
 	return nil
 }
 
 func (pm *pathManager) closePaths() {
-	pm.sess.pathsLock.RLock()
-	paths := pm.sess.paths
-	for _, pth := range paths {
-		if pth.open.Get() {
-			select {
-			case pth.closeChan <- nil:
-			default:
-				// Don't remain stuck here!
-			}
-		}
+	for _, pth := range pm.sess.paths() {
+		pth.close()
 	}
-	pm.sess.pathsLock.RUnlock()
 }
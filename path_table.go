@@ -0,0 +1,30 @@
+package quic
+
+import "github.com/lucas-clemente/quic-go/internal/protocol"
+
+// A pathTable is an immutable snapshot of the paths of a session.
+// A new table is published when a path is created (copy-on-write), so the paths can be read from
+// any goroutine without locking. Only the session goroutine creates paths.
+type pathTable map[protocol.PathID]*path
+
+// with returns a copy of the table containing pth
+func (t pathTable) with(pth *path) pathTable {
+	paths := make(pathTable, len(t)+1)
+	for pathID, p := range t {
+		paths[pathID] = p
+	}
+	paths[pth.pathID] = pth
+	return paths
+}
+
+// paths returns the current snapshot of the paths, it must not be modified
+func (s *session) paths() pathTable {
+	paths, _ := s.currentPaths.Load().(pathTable)
+	return paths
+}
+
+// addPath publishes a new snapshot containing the path.
+// It must only be called by the session goroutine, or before the session runs.
+func (s *session) addPath(pth *path) {
+	s.currentPaths.Store(s.paths().with(pth))
+}
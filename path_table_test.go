@@ -0,0 +1,59 @@
+package quic
+
+import (
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Path table", func() {
+	It("has no paths before the first one is added", func() {
+		sess := &session{}
+		Expect(sess.paths()).To(BeEmpty())
+		Expect(sess.paths()[protocol.InitialPathID]).To(BeNil())
+	})
+
+	It("adds paths", func() {
+		sess := &session{}
+		pth1 := &path{pathID: 1}
+		pth3 := &path{pathID: 3}
+		sess.addPath(pth1)
+		sess.addPath(pth3)
+		Expect(sess.paths()).To(HaveLen(2))
+		Expect(sess.paths()[1]).To(Equal(pth1))
+		Expect(sess.paths()[3]).To(Equal(pth3))
+	})
+
+	It("doesn't modify the previous snapshots", func() {
+		sess := &session{}
+		sess.addPath(&path{pathID: 1})
+		paths := sess.paths()
+		sess.addPath(&path{pathID: 3})
+		Expect(paths).To(HaveLen(1))
+		Expect(paths).ToNot(HaveKey(protocol.PathID(3)))
+		Expect(sess.paths()).To(HaveLen(2))
+	})
+
+	It("reads the paths from another goroutine while they are added", func() {
+		sess := &session{}
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			for i := 1; i <= 100; i += 2 {
+				sess.addPath(&path{pathID: protocol.PathID(i)})
+			}
+		}()
+		var numPaths int
+		for numPaths < 50 {
+			paths := sess.paths()
+			Expect(len(paths)).To(BeNumerically(">=", numPaths))
+			for pathID, pth := range paths {
+				Expect(pth.pathID).To(Equal(pathID))
+			}
+			numPaths = len(paths)
+		}
+		Eventually(done).Should(BeClosed())
+	})
+})
@@ -0,0 +1,228 @@
+package quic
+
+import (
+	"github.com/lucas-clemente/quic-go/ackhandler"
+	"github.com/lucas-clemente/quic-go/congestion"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Path", func() {
+	Context("congestion control", func() {
+		var (
+			sess          *session
+			pm            *pathManager
+			oliaSenders   *congestion.OliaSenders
+			liaSenders    *congestion.CoupledSenders
+			baliaSenders  *congestion.CoupledSenders
+			wvegasSenders *congestion.CoupledSenders
+		)
+
+		newPath := func(pathID protocol.PathID) *path {
+			return &path{pathID: pathID, sess: sess, rttStats: &congestion.RTTStats{}}
+		}
+
+		BeforeEach(func() {
+			sess = &session{version: protocol.VersionMP, config: &Config{}}
+			oliaSenders = congestion.NewOliaSenders()
+			liaSenders = congestion.NewCoupledSenders()
+			baliaSenders = congestion.NewCoupledSenders()
+			wvegasSenders = congestion.NewCoupledSenders()
+			pm = &pathManager{
+				oliaSenders:   oliaSenders,
+				liaSenders:    liaSenders,
+				baliaSenders:  baliaSenders,
+				wvegasSenders: wvegasSenders,
+			}
+		})
+
+		It("uses OLIA for the paths of multipath sessions by default", func() {
+			Expect(newPath(protocol.InitialPathID).newCongestionControl(pm)).To(BeNil())
+			cong := newPath(1).newCongestionControl(pm)
+			Expect(cong).To(BeAssignableToTypeOf(&congestion.OliaSender{}))
+			Expect(oliaSenders.All()).To(HaveKey(protocol.PathID(1)))
+		})
+
+		It("uses Cubic for single-path sessions by default", func() {
+			sess.version = protocol.VersionMP - 1
+			Expect(newPath(protocol.InitialPathID).newCongestionControl(pm)).To(BeNil())
+			Expect(oliaSenders.All()).To(BeEmpty())
+		})
+
+		It("uses Cubic or Reno on every path if requested", func() {
+			for _, algorithm := range []CongestionControlAlgorithm{CongestionControlCubic, CongestionControlReno} {
+				sess.config.CongestionControl = algorithm
+				cong := newPath(1).newCongestionControl(pm)
+				Expect(cong).ToNot(BeNil())
+				Expect(cong).ToNot(BeAssignableToTypeOf(&congestion.OliaSender{}))
+			}
+			Expect(oliaSenders.All()).To(BeEmpty())
+		})
+
+		It("uses OLIA for single-path sessions if requested", func() {
+			sess.version = protocol.VersionMP - 1
+			sess.config.CongestionControl = CongestionControlOlia
+			cong := newPath(protocol.InitialPathID).newCongestionControl(pm)
+			Expect(cong).To(BeAssignableToTypeOf(&congestion.OliaSender{}))
+			Expect(oliaSenders.All()).To(HaveLen(1))
+		})
+
+		It("couples the paths with LIA if requested", func() {
+			sess.config.CongestionControl = CongestionControlLia
+			Expect(newPath(protocol.InitialPathID).newCongestionControl(pm)).To(BeNil())
+			Expect(newPath(1).newCongestionControl(pm)).To(BeAssignableToTypeOf(&congestion.LiaSender{}))
+			Expect(newPath(3).newCongestionControl(pm)).To(BeAssignableToTypeOf(&congestion.LiaSender{}))
+			Expect(liaSenders.All()).To(HaveLen(2))
+			Expect(oliaSenders.All()).To(BeEmpty())
+		})
+
+		It("couples the paths with BALIA if requested", func() {
+			sess.config.CongestionControl = CongestionControlBalia
+			Expect(newPath(1).newCongestionControl(pm)).To(BeAssignableToTypeOf(&congestion.BaliaSender{}))
+			Expect(baliaSenders.All()).To(HaveKey(protocol.PathID(1)))
+		})
+
+		It("couples the paths with wVegas if requested", func() {
+			sess.config.CongestionControl = CongestionControlWVegas
+			Expect(newPath(protocol.InitialPathID).newCongestionControl(pm)).To(BeNil())
+			Expect(newPath(1).newCongestionControl(pm)).To(BeAssignableToTypeOf(&congestion.WVegasSender{}))
+			Expect(wvegasSenders.All()).To(HaveKey(protocol.PathID(1)))
+		})
+
+		It("uses BBR on every path if requested", func() {
+			sess.config.CongestionControl = CongestionControlBbr
+			Expect(newPath(protocol.InitialPathID).newCongestionControl(pm)).To(BeAssignableToTypeOf(&congestion.BbrSender{}))
+			Expect(newPath(1).newCongestionControl(nil)).To(BeAssignableToTypeOf(&congestion.BbrSender{}))
+		})
+
+		It("doesn't couple paths without path manager", func() {
+			Expect(newPath(1).newCongestionControl(nil)).To(BeNil())
+		})
+
+		It("uses the congestion controller of the factory", func() {
+			var cong congestion.SendAlgorithm
+			var factoryPathID protocol.PathID
+			sess.config.CongestionControl = CongestionControlOlia
+			sess.config.NewCongestionControl = func(pathID PathID, rttStats *congestion.RTTStats) congestion.SendAlgorithm {
+				factoryPathID = pathID
+				cong = congestion.NewCubicSender(congestion.DefaultClock{}, rttStats, true, protocol.InitialCongestionWindow, protocol.DefaultMaxCongestionWindow)
+				return cong
+			}
+			Expect(newPath(3).newCongestionControl(pm)).To(BeIdenticalTo(cong))
+			Expect(factoryPathID).To(Equal(protocol.PathID(3)))
+			Expect(oliaSenders.All()).To(BeEmpty())
+		})
+
+		It("uses HyStart++ if requested", func() {
+			sess.config.HyStartPlusPlus = true
+			cong := newPath(protocol.InitialPathID).newCongestionControl(pm)
+			Expect(cong).ToNot(BeNil())
+			Expect(cong.(congestion.SendAlgorithmWithDebugInfo).HybridSlowStart()).To(BeNil())
+			cong = newPath(1).newCongestionControl(pm)
+			Expect(cong).To(BeAssignableToTypeOf(&congestion.OliaSender{}))
+			Expect(cong.(congestion.SendAlgorithmWithDebugInfo).HybridSlowStart()).To(BeNil())
+			sess.config.CongestionControl = CongestionControlBbr
+			Expect(newPath(3).newCongestionControl(pm)).To(BeAssignableToTypeOf(&congestion.BbrSender{}))
+		})
+
+		It("excludes potentially failed paths from the LIA coupling until they recover", func() {
+			sess.pathManager = pm
+			sess.config.CongestionControl = CongestionControlLia
+			pth := newPath(1)
+			pth.newCongestionControl(pm)
+			newPath(3).newCongestionControl(pm)
+			pth.setPotentiallyFailed(true)
+			Expect(liaSenders.Coupled()).ToNot(HaveKey(protocol.PathID(1)))
+			Expect(liaSenders.All()).To(HaveKey(protocol.PathID(1)))
+			pth.setPotentiallyFailed(false)
+			Expect(liaSenders.Coupled()).To(HaveKey(protocol.PathID(1)))
+		})
+
+		It("deregisters the LIA sender of closed paths", func() {
+			sess.config.CongestionControl = CongestionControlLia
+			sess.currentPaths.Store(pathTable{1: newPath(1), 3: newPath(3)})
+			pm.sess = sess
+			sess.paths()[1].newCongestionControl(pm)
+			sess.paths()[3].newCongestionControl(pm)
+			Expect(pm.closePath(1)).To(Succeed())
+			Expect(liaSenders.All()).To(HaveLen(1))
+			Expect(liaSenders.All()).To(HaveKey(protocol.PathID(3)))
+		})
+
+		It("deregisters the BALIA sender of closed and potentially failed paths", func() {
+			sess.pathManager = pm
+			sess.config.CongestionControl = CongestionControlBalia
+			sess.currentPaths.Store(pathTable{1: newPath(1), 3: newPath(3)})
+			pm.sess = sess
+			sess.paths()[1].newCongestionControl(pm)
+			sess.paths()[3].newCongestionControl(pm)
+			sess.paths()[3].setPotentiallyFailed(true)
+			Expect(baliaSenders.Coupled()).ToNot(HaveKey(protocol.PathID(3)))
+			sess.paths()[3].setPotentiallyFailed(false)
+			Expect(baliaSenders.Coupled()).To(HaveKey(protocol.PathID(3)))
+			Expect(pm.closePath(1)).To(Succeed())
+			Expect(baliaSenders.All()).To(HaveLen(1))
+			Expect(baliaSenders.All()).To(HaveKey(protocol.PathID(3)))
+		})
+
+		It("deregisters the wVegas sender of closed and potentially failed paths", func() {
+			sess.pathManager = pm
+			sess.config.CongestionControl = CongestionControlWVegas
+			sess.currentPaths.Store(pathTable{1: newPath(1), 3: newPath(3)})
+			pm.sess = sess
+			sess.paths()[1].newCongestionControl(pm)
+			sess.paths()[3].newCongestionControl(pm)
+			sess.paths()[3].setPotentiallyFailed(true)
+			Expect(wvegasSenders.Coupled()).ToNot(HaveKey(protocol.PathID(3)))
+			sess.paths()[3].setPotentiallyFailed(false)
+			Expect(wvegasSenders.Coupled()).To(HaveKey(protocol.PathID(3)))
+			Expect(pm.closePath(1)).To(Succeed())
+			Expect(wvegasSenders.All()).To(HaveLen(1))
+			Expect(wvegasSenders.All()).To(HaveKey(protocol.PathID(3)))
+		})
+
+		It("excludes potentially failed paths from the OLIA coupling until they recover", func() {
+			sess.pathManager = pm
+			pth := newPath(1)
+			pth.newCongestionControl(pm)
+			newPath(3).newCongestionControl(pm)
+			pth.setPotentiallyFailed(true)
+			Expect(pth.potentiallyFailed.Get()).To(BeTrue())
+			Expect(oliaSenders.Coupled()).ToNot(HaveKey(protocol.PathID(1)))
+			Expect(oliaSenders.All()).To(HaveKey(protocol.PathID(1)))
+			pth.setPotentiallyFailed(false)
+			Expect(pth.potentiallyFailed.Get()).To(BeFalse())
+			Expect(oliaSenders.Coupled()).To(HaveKey(protocol.PathID(1)))
+		})
+
+		It("deregisters the OLIA sender of closed paths", func() {
+			sess.currentPaths.Store(pathTable{1: newPath(1), 3: newPath(3)})
+			pm.sess = sess
+			sess.paths()[1].newCongestionControl(pm)
+			sess.paths()[3].newCongestionControl(pm)
+			Expect(pm.closePath(1)).To(Succeed())
+			Expect(oliaSenders.All()).To(HaveLen(1))
+			Expect(oliaSenders.All()).To(HaveKey(protocol.PathID(3)))
+		})
+	})
+
+	Context("pacing", func() {
+		It("doesn't allow sending while the path is paced", func() {
+			rttStats := congestion.NewRTTStats()
+			p := &path{rttStats: rttStats, sentPacketHandler: ackhandler.NewSentPacketHandler(0, rttStats, nil, nil, nil)}
+			p.open.Set(true)
+			var pn protocol.PacketNumber
+			for p.SendingAllowed() {
+				Expect(pn).To(BeNumerically("<", protocol.InitialCongestionWindow))
+				pn++
+				err := p.sentPacketHandler.SentPacket(&ackhandler.Packet{PacketNumber: pn, Length: protocol.DefaultTCPMSS, Frames: []wire.Frame{&wire.PingFrame{}}})
+				Expect(err).ToNot(HaveOccurred())
+			}
+			Expect(p.sentPacketHandler.SendingAllowed()).To(BeTrue())
+			Expect(p.sentPacketHandler.TimeUntilSend()).ToNot(BeZero())
+		})
+	})
+})
@@ -16,6 +16,8 @@ type receivedRawPacket struct {
 	remoteAddr net.Addr
 	data       []byte
 	rcvTime    time.Time
+	// ecn is the ECN codepoint of the IP header, Not-ECT if it's unknown
+	ecn protocol.ECN
 }
 
 type pconnManager struct {
@@ -26,10 +28,15 @@ type pconnManager struct {
 	pconnAny net.PacketConn
 
 	localAddrs []net.UDPAddr
+	// Name of the interface of each local address
+	ifaceNames map[string]string
 
 	perspective protocol.Perspective
 
 	rcvRawPackets chan *receivedRawPacket
+	// If set, the listening goroutines pass the received packets to dispatch instead of rcvRawPackets.
+	// It must be set before the setup.
+	dispatch func(*receivedRawPacket)
 
 	changePaths chan struct{}
 	closeConns  chan struct{}
@@ -42,6 +49,7 @@ type pconnManager struct {
 func (pcm *pconnManager) setup(pconnArg net.PacketConn, listenAddr net.Addr) error {
 	pcm.pconns = make(map[string]net.PacketConn)
 	pcm.localAddrs = make([]net.UDPAddr, 0)
+	pcm.ifaceNames = make(map[string]string)
 	pcm.rcvRawPackets = make(chan *receivedRawPacket)
 	pcm.changePaths = make(chan struct{}, 1)
 	pcm.closeConns = make(chan struct{}, 1)
@@ -70,6 +78,7 @@ func (pcm *pconnManager) setup(pconnArg net.PacketConn, listenAddr net.Addr) err
 		// FIXME Update localAddrs
 		pcm.pconnAny = pconnArg
 	}
+	pcm.maybeEnableECN(pcm.pconnAny)
 
 	if utils.Debug() {
 		utils.Debugf("Created pconn_manager, any on %s", pcm.pconnAny.LocalAddr().String())
@@ -82,6 +91,11 @@ func (pcm *pconnManager) setup(pconnArg net.PacketConn, listenAddr net.Addr) err
 }
 
 func (pcm *pconnManager) listen(pconn net.PacketConn) {
+	if batch := newBatchConn(pconn); batch != nil {
+		pcm.listenBatch(pconn, batch)
+		return
+	}
+
 	var err error
 
 listenLoop:
@@ -120,7 +134,119 @@ listenLoop:
 			rcvTime:    time.Now(),
 		}
 
-		pcm.rcvRawPackets <- rcvRawPacket
+		pcm.deliver(rcvRawPacket)
+	}
+}
+
+// listenBatch is like listen, but reads up to protocol.MaxBatchSize packets with a single syscall
+func (pcm *pconnManager) listenBatch(pconn net.PacketConn, batch batchConn) {
+	if groEnabled(pconn) {
+		pcm.listenGRO(pconn, batch)
+		return
+	}
+
+	ms := newBatchMessages(protocol.MaxBatchSize)
+	for i := range ms {
+		ms[i].Buffers[0] = getPacketBuffer()[:protocol.MaxReceivePacketSize]
+		ms[i].OOB = make([]byte, ecnOOBSize)
+	}
+
+	for {
+		// The packet size should not exceed protocol.MaxReceivePacketSize bytes
+		// If it does, we only read a truncate packet, which will then end up undecryptable
+		n, err := batch.ReadBatch(ms, 0)
+		if err != nil {
+			// XXX (QDC): as soon as a path failed, kill the connection.
+			select {
+			case pcm.errorConn <- err:
+			default:
+				// Don't block
+			}
+			return
+		}
+		rcvTime := time.Now()
+
+		for i := range ms[:n] {
+			ecn, _ := parseControlMessages(ms[i].OOB[:ms[i].NN])
+			pcm.deliver(&receivedRawPacket{
+				rcvPconn:   pconn,
+				remoteAddr: ms[i].Addr,
+				data:       ms[i].Buffers[0][:ms[i].N],
+				rcvTime:    rcvTime,
+				ecn:        ecn,
+			})
+			// The buffer is now owned by the session
+			ms[i].Buffers[0] = getPacketBuffer()[:protocol.MaxReceivePacketSize]
+		}
+	}
+}
+
+// listenGRO is like listenBatch, but for a socket on which the kernel coalesces the received packets.
+// The packets are read into large buffers, and copied to packet buffers when they are split.
+func (pcm *pconnManager) listenGRO(pconn net.PacketConn, batch batchConn) {
+	ms := newBatchMessages(groBatchSize)
+	for i := range ms {
+		ms[i].Buffers[0] = make([]byte, groBufferSize)
+		ms[i].OOB = make([]byte, groOOBSize+ecnOOBSize)
+	}
+
+	for {
+		n, err := batch.ReadBatch(ms, 0)
+		if err != nil {
+			// XXX (QDC): as soon as a path failed, kill the connection.
+			select {
+			case pcm.errorConn <- err:
+			default:
+				// Don't block
+			}
+			return
+		}
+		rcvTime := time.Now()
+
+		for i := range ms[:n] {
+			ecn, segmentSize := parseControlMessages(ms[i].OOB[:ms[i].NN])
+			pcm.splitCoalescedPacket(pconn, ms[i].Addr, ms[i].Buffers[0][:ms[i].N], segmentSize, ecn, rcvTime)
+		}
+	}
+}
+
+// splitCoalescedPacket passes the packets coalesced by GRO to the sessions.
+// If the segment size is 0, the data is a single packet. GRO only coalesces packets with the same ECN codepoint.
+func (pcm *pconnManager) splitCoalescedPacket(pconn net.PacketConn, addr net.Addr, data []byte, segmentSize int, ecn protocol.ECN, rcvTime time.Time) {
+	if segmentSize <= 0 {
+		segmentSize = len(data)
+	}
+	for len(data) > 0 {
+		l := utils.Min(segmentSize, len(data))
+		// The packet size should not exceed protocol.MaxReceivePacketSize bytes
+		// If it does, we only copy a truncate packet, which will then end up undecryptable
+		packet := getPacketBuffer()[:protocol.MaxReceivePacketSize]
+		packet = packet[:copy(packet, data[:l])]
+		pcm.deliver(&receivedRawPacket{
+			rcvPconn:   pconn,
+			remoteAddr: addr,
+			data:       packet,
+			rcvTime:    rcvTime,
+			ecn:        ecn,
+		})
+		data = data[l:]
+	}
+}
+
+// deliver passes a received packet to the dispatcher, or to the goroutine reading rcvRawPackets if there is none
+func (pcm *pconnManager) deliver(p *receivedRawPacket) {
+	if pcm.dispatch != nil {
+		pcm.dispatch(p)
+		return
+	}
+	pcm.rcvRawPackets <- p
+}
+
+// maybeEnableECN reads the ECN codepoints of the packets received on the pconn.
+// They are only read by the batched listener.
+func (pcm *pconnManager) maybeEnableECN(pconn net.PacketConn) {
+	if batchIOSupported && enableECN(pconn) && utils.Debug() {
+		utils.Debugf("Enabled ECN on %s", pconn.LocalAddr().String())
 	}
 }
 
@@ -173,6 +299,11 @@ func (pcm *pconnManager) createPconn(ip net.IP) (*net.UDPAddr, error) {
 	if err != nil {
 		return nil, err
 	}
+	// The received packets are coalesced if the kernel supports it, the batched listener splits them
+	if batchIOSupported && enableGRO(pconn) && utils.Debug() {
+		utils.Debugf("Enabled GRO on %s", pconn.LocalAddr().String())
+	}
+	pcm.maybeEnableECN(pconn)
 	locAddr, err := net.ResolveUDPAddr("udp", pconn.LocalAddr().String())
 	if err != nil {
 		return nil, err
@@ -230,7 +361,11 @@ func (pcm *pconnManager) createPconns() error {
 				if err != nil {
 					return err
 				}
+				// The path manager reads them from the session goroutine
+				pcm.mutex.Lock()
 				pcm.localAddrs = append(pcm.localAddrs, *locAddr)
+				pcm.ifaceNames[locAddr.String()] = i.Name
+				pcm.mutex.Unlock()
 			}
 		}
 	}
@@ -238,9 +373,11 @@ func (pcm *pconnManager) createPconns() error {
 }
 
 func (pcm *pconnManager) closePconns() {
+	pcm.mutex.Lock()
 	for _, pconn := range pcm.pconns {
 		pconn.Close()
 	}
+	pcm.mutex.Unlock()
 	pcm.pconnAny.Close()
 	close(pcm.closed)
 }
@@ -0,0 +1,132 @@
+package qlog
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/logging"
+)
+
+// gQUIC packets have no type, and multipath packets are not in the qlog schema yet
+const packetTypeUnknown = "unknown"
+
+type header struct {
+	QlogVersion string `json:"qlog_version"`
+	QlogFormat  string `json:"qlog_format"`
+	Title       string `json:"title"`
+	Trace       trace  `json:"trace"`
+}
+
+type trace struct {
+	VantagePoint vantagePoint `json:"vantage_point"`
+	CommonFields commonFields `json:"common_fields"`
+}
+
+type vantagePoint struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type commonFields struct {
+	ODCID         string  `json:"ODCID"`
+	GroupID       string  `json:"group_id"`
+	ReferenceTime float64 `json:"reference_time"`
+	TimeFormat    string  `json:"time_format"`
+}
+
+type event struct {
+	// Time is relative to the reference time, in milliseconds
+	Time float64     `json:"time"`
+	Name string      `json:"name"`
+	Data interface{} `json:"data"`
+}
+
+type packetHeader struct {
+	PacketType   string               `json:"packet_type"`
+	PacketNumber logging.PacketNumber `json:"packet_number"`
+}
+
+type rawInfo struct {
+	Length logging.ByteCount `json:"length"`
+}
+
+type packetEvent struct {
+	PathID logging.PathID `json:"path_id"`
+	Header packetHeader   `json:"header"`
+	Raw    rawInfo        `json:"raw"`
+	Frames []interface{}  `json:"frames"`
+}
+
+func newPacketEvent(pathID logging.PathID, packetNumber logging.PacketNumber, size logging.ByteCount, frames []logging.Frame) *packetEvent {
+	ev := &packetEvent{
+		PathID: pathID,
+		Header: packetHeader{PacketType: packetTypeUnknown, PacketNumber: packetNumber},
+		Raw:    rawInfo{Length: size},
+		Frames: make([]interface{}, len(frames)),
+	}
+	for i, f := range frames {
+		ev.Frames[i] = toFrame(f)
+	}
+	return ev
+}
+
+type owdSample struct {
+	PacketNumber logging.PacketNumber `json:"packet_number"`
+	// OWD is in milliseconds
+	OWD float64 `json:"owd"`
+}
+
+type ackProcessedEvent struct {
+	PathID       logging.PathID       `json:"path_id"`
+	LargestAcked logging.PacketNumber `json:"largest_acked"`
+	OWDSamples   []owdSample          `json:"owd_samples"`
+}
+
+type packetLostEvent struct {
+	PathID  logging.PathID `json:"path_id"`
+	Header  packetHeader   `json:"header"`
+	Trigger string         `json:"trigger"`
+}
+
+type metrics struct {
+	minRTT           time.Duration
+	smoothedRTT      time.Duration
+	latestRTT        time.Duration
+	rttVariance      time.Duration
+	congestionWindow logging.ByteCount
+	bytesInFlight    logging.ByteCount
+}
+
+// metricsUpdatedEvent only contains the metrics that changed, RTTs are in milliseconds
+type metricsUpdatedEvent struct {
+	PathID           logging.PathID     `json:"path_id"`
+	MinRTT           *float64           `json:"min_rtt,omitempty"`
+	SmoothedRTT      *float64           `json:"smoothed_rtt,omitempty"`
+	LatestRTT        *float64           `json:"latest_rtt,omitempty"`
+	RTTVariance      *float64           `json:"rtt_variance,omitempty"`
+	CongestionWindow *logging.ByteCount `json:"congestion_window,omitempty"`
+	BytesInFlight    *logging.ByteCount `json:"bytes_in_flight,omitempty"`
+}
+
+type pathEvent struct {
+	PathID logging.PathID `json:"path_id"`
+}
+
+type pathCreatedEvent struct {
+	PathID        logging.PathID `json:"path_id"`
+	LocalAddress  string         `json:"local_address,omitempty"`
+	RemoteAddress string         `json:"remote_address,omitempty"`
+}
+
+type sbdDecisionEvent struct {
+	PathID           logging.PathID `json:"path_id"`
+	SharedBottleneck []int          `json:"shared_bottleneck"`
+}
+
+// pathIDList converts path IDs to ints, since encoding/json writes byte slices as base64 strings
+func pathIDList(pathIDs []logging.PathID) []int {
+	list := make([]int, len(pathIDs))
+	for i, pathID := range pathIDs {
+		list[i] = int(pathID)
+	}
+	return list
+}
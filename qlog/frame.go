@@ -0,0 +1,180 @@
+package qlog
+
+import (
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/logging"
+)
+
+type streamFrame struct {
+	FrameType string             `json:"frame_type"`
+	StreamID  protocol.StreamID  `json:"stream_id"`
+	Offset    protocol.ByteCount `json:"offset"`
+	Length    protocol.ByteCount `json:"length"`
+	Fin       bool               `json:"fin,omitempty"`
+}
+
+type ackFrame struct {
+	FrameType string `json:"frame_type"`
+	// PathID is only set for CLOSE_PATH frames
+	PathID *protocol.PathID `json:"path_id,omitempty"`
+	// AckDelay is in milliseconds
+	AckDelay    float64                    `json:"ack_delay,omitempty"`
+	AckedRanges [][2]protocol.PacketNumber `json:"acked_ranges"`
+	// The ECN counts are only set if the ACK frame carries them
+	ECT0 *uint64 `json:"ect0,omitempty"`
+	ECT1 *uint64 `json:"ect1,omitempty"`
+	CE   *uint64 `json:"ce,omitempty"`
+}
+
+type resetStreamFrame struct {
+	FrameType string             `json:"frame_type"`
+	StreamID  protocol.StreamID  `json:"stream_id"`
+	ErrorCode uint32             `json:"error_code"`
+	FinalSize protocol.ByteCount `json:"final_size"`
+}
+
+type maxDataFrame struct {
+	FrameType string             `json:"frame_type"`
+	Maximum   protocol.ByteCount `json:"maximum"`
+}
+
+type maxStreamDataFrame struct {
+	FrameType string             `json:"frame_type"`
+	StreamID  protocol.StreamID  `json:"stream_id"`
+	Maximum   protocol.ByteCount `json:"maximum"`
+}
+
+type streamDataBlockedFrame struct {
+	FrameType string            `json:"frame_type"`
+	StreamID  protocol.StreamID `json:"stream_id"`
+}
+
+type stopWaitingFrame struct {
+	FrameType    string                `json:"frame_type"`
+	LeastUnacked protocol.PacketNumber `json:"least_unacked"`
+}
+
+type connectionCloseFrame struct {
+	FrameType string `json:"frame_type"`
+	ErrorCode uint32 `json:"error_code"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+type goawayFrame struct {
+	FrameType      string            `json:"frame_type"`
+	ErrorCode      uint32            `json:"error_code"`
+	LastGoodStream protocol.StreamID `json:"last_good_stream"`
+	Reason         string            `json:"reason,omitempty"`
+}
+
+type addAddressFrame struct {
+	FrameType string `json:"frame_type"`
+	Address   string `json:"address"`
+	Backup    bool   `json:"backup,omitempty"`
+}
+
+type pathsFrame struct {
+	FrameType string `json:"frame_type"`
+	PathIDs   []int  `json:"path_ids"`
+	// RemoteRTTs are in milliseconds
+	RemoteRTTs []float64 `json:"remote_rtts"`
+}
+
+type simpleFrame struct {
+	FrameType string `json:"frame_type"`
+}
+
+// toFrame converts a frame to its qlog representation.
+// gQUIC frames are mapped to their IETF QUIC equivalent, the others keep their gQUIC name.
+func toFrame(f logging.Frame) interface{} {
+	switch f := f.(type) {
+	case *wire.StreamFrame:
+		return &streamFrame{
+			FrameType: "stream",
+			StreamID:  f.StreamID,
+			Offset:    f.Offset,
+			Length:    f.DataLen(),
+			Fin:       f.FinBit,
+		}
+	case *wire.AckFrame:
+		frame := &ackFrame{
+			FrameType:   "ack",
+			AckDelay:    milliseconds(f.DelayTime),
+			AckedRanges: ackedRanges(f.LowestAcked, f.LargestAcked, f.AckRanges),
+		}
+		if f.ECN != nil {
+			frame.ECT0, frame.ECT1, frame.CE = &f.ECN.ECT0, &f.ECN.ECT1, &f.ECN.ECNCE
+		}
+		return frame
+	case *wire.ClosePathFrame:
+		pathID := f.PathID
+		return &ackFrame{
+			FrameType:   "close_path",
+			PathID:      &pathID,
+			AckedRanges: ackedRanges(f.LowestAcked, f.LargestAcked, f.AckRanges),
+		}
+	case *wire.PingFrame:
+		return &simpleFrame{FrameType: "ping"}
+	case *wire.RstStreamFrame:
+		return &resetStreamFrame{
+			FrameType: "reset_stream",
+			StreamID:  f.StreamID,
+			ErrorCode: f.ErrorCode,
+			FinalSize: f.ByteOffset,
+		}
+	case *wire.WindowUpdateFrame:
+		// Stream 0 is the connection-level flow control window
+		if f.StreamID == 0 {
+			return &maxDataFrame{FrameType: "max_data", Maximum: f.ByteOffset}
+		}
+		return &maxStreamDataFrame{FrameType: "max_stream_data", StreamID: f.StreamID, Maximum: f.ByteOffset}
+	case *wire.BlockedFrame:
+		if f.StreamID == 0 {
+			return &simpleFrame{FrameType: "data_blocked"}
+		}
+		return &streamDataBlockedFrame{FrameType: "stream_data_blocked", StreamID: f.StreamID}
+	case *wire.StopWaitingFrame:
+		return &stopWaitingFrame{FrameType: "stop_waiting", LeastUnacked: f.LeastUnacked}
+	case *wire.ConnectionCloseFrame:
+		return &connectionCloseFrame{
+			FrameType: "connection_close",
+			ErrorCode: uint32(f.ErrorCode),
+			Reason:    f.ReasonPhrase,
+		}
+	case *wire.GoawayFrame:
+		return &goawayFrame{
+			FrameType:      "goaway",
+			ErrorCode:      uint32(f.ErrorCode),
+			LastGoodStream: f.LastGoodStream,
+			Reason:         f.ReasonPhrase,
+		}
+	case *wire.AddAddressFrame:
+		return &addAddressFrame{FrameType: "add_address", Address: f.Addr.String(), Backup: f.Backup}
+	case *wire.PathsFrame:
+		pf := &pathsFrame{
+			FrameType:  "paths",
+			PathIDs:    pathIDList(f.PathIDs),
+			RemoteRTTs: make([]float64, len(f.RemoteRTTs)),
+		}
+		for i, rtt := range f.RemoteRTTs {
+			pf.RemoteRTTs[i] = milliseconds(rtt)
+		}
+		return pf
+	default:
+		return &simpleFrame{FrameType: "unknown"}
+	}
+}
+
+// ackedRanges returns the acknowledged ranges in ascending order
+func ackedRanges(lowest, largest protocol.PacketNumber, ranges []wire.AckRange) [][2]protocol.PacketNumber {
+	if len(ranges) == 0 {
+		return [][2]protocol.PacketNumber{{lowest, largest}}
+	}
+	acked := make([][2]protocol.PacketNumber, len(ranges))
+	// The AckRanges start with the highest range
+	for i, r := range ranges {
+		acked[len(ranges)-1-i] = [2]protocol.PacketNumber{r.First, r.Last}
+	}
+	return acked
+}
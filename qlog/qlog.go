@@ -0,0 +1,227 @@
+// Package qlog writes the events of a session in the qlog format, as JSON text sequences.
+// The traces can be displayed by qlog visualizers, like qvis.
+package qlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/logging"
+)
+
+// recordSeparator starts every record of a JSON text sequence (RFC 7464)
+const recordSeparator = 0x1e
+
+type tracer struct {
+	mutex sync.Mutex
+
+	w      io.WriteCloser
+	buf    *bufio.Writer
+	enc    *json.Encoder
+	err    error
+	closed bool
+
+	referenceTime time.Time
+	// The metrics written last for every path, only the changed ones are written again
+	lastMetrics map[logging.PathID]metrics
+}
+
+var _ logging.Tracer = &tracer{}
+
+// NewTracer makes a tracer writing the events of a session to w.
+// w is closed when the session is closed.
+func NewTracer(w io.WriteCloser, p logging.Perspective, connectionID logging.ConnectionID) logging.Tracer {
+	buf := bufio.NewWriter(w)
+	t := &tracer{
+		w:             w,
+		buf:           buf,
+		enc:           json.NewEncoder(buf),
+		referenceTime: time.Now(),
+		lastMetrics:   make(map[logging.PathID]metrics),
+	}
+	vantagePointType := "server"
+	if p == logging.PerspectiveClient {
+		vantagePointType = "client"
+	}
+	t.writeRecord(&header{
+		QlogVersion: "0.3",
+		QlogFormat:  "JSON-SEQ",
+		Title:       "quic-go qlog",
+		Trace: trace{
+			VantagePoint: vantagePoint{Name: "quic-go", Type: vantagePointType},
+			CommonFields: commonFields{
+				ODCID:         fmt.Sprintf("%016x", connectionID),
+				GroupID:       fmt.Sprintf("%016x", connectionID),
+				ReferenceTime: float64(t.referenceTime.UnixNano()) / 1e6,
+				TimeFormat:    "relative",
+			},
+		},
+	})
+	return t
+}
+
+// writeRecord writes a record, the first error is kept and stops the tracing
+func (t *tracer) writeRecord(record interface{}) {
+	if t.err != nil {
+		return
+	}
+	if t.err = t.buf.WriteByte(recordSeparator); t.err != nil {
+		return
+	}
+	// Encode terminates the record with a newline
+	t.err = t.enc.Encode(record)
+}
+
+func (t *tracer) writeEvent(name string, data interface{}) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.writeRecord(&event{
+		Time: milliseconds(time.Since(t.referenceTime)),
+		Name: name,
+		Data: data,
+	})
+}
+
+func (t *tracer) SentPacket(pathID logging.PathID, packetNumber logging.PacketNumber, size logging.ByteCount, frames []logging.Frame) {
+	t.writeEvent("transport:packet_sent", newPacketEvent(pathID, packetNumber, size, frames))
+}
+
+func (t *tracer) ReceivedPacket(pathID logging.PathID, packetNumber logging.PacketNumber, size logging.ByteCount, frames []logging.Frame) {
+	t.writeEvent("transport:packet_received", newPacketEvent(pathID, packetNumber, size, frames))
+}
+
+func (t *tracer) ProcessedAck(pathID logging.PathID, largestAcked logging.PacketNumber, samples []logging.OWDSample) {
+	ev := &ackProcessedEvent{
+		PathID:       pathID,
+		LargestAcked: largestAcked,
+		OWDSamples:   make([]owdSample, len(samples)),
+	}
+	for i, s := range samples {
+		ev.OWDSamples[i] = owdSample{PacketNumber: s.PacketNumber, OWD: milliseconds(s.OWD)}
+	}
+	t.writeEvent("multipath:ack_processed", ev)
+}
+
+func (t *tracer) LostPacket(pathID logging.PathID, packetNumber logging.PacketNumber, reason logging.PacketLossReason) {
+	var trigger string
+	switch reason {
+	case logging.PacketLossTimeThreshold:
+		trigger = "time_threshold"
+	case logging.PacketLossRTO:
+		trigger = "pto_expired"
+	case logging.PacketLossPathClosed:
+		trigger = "path_closed"
+	}
+	t.writeEvent("recovery:packet_lost", &packetLostEvent{
+		PathID:  pathID,
+		Header:  packetHeader{PacketType: packetTypeUnknown, PacketNumber: packetNumber},
+		Trigger: trigger,
+	})
+}
+
+func (t *tracer) UpdatedMetrics(pathID logging.PathID, rttStats *logging.RTTStats, congestionWindow, bytesInFlight logging.ByteCount) {
+	m := metrics{
+		minRTT:           rttStats.MinRTT(),
+		smoothedRTT:      rttStats.SmoothedRTT(),
+		latestRTT:        rttStats.LatestRTT(),
+		rttVariance:      rttStats.MeanDeviation(),
+		congestionWindow: congestionWindow,
+		bytesInFlight:    bytesInFlight,
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	last, ok := t.lastMetrics[pathID]
+	if ok && last == m {
+		return
+	}
+	t.lastMetrics[pathID] = m
+	ev := &metricsUpdatedEvent{PathID: pathID}
+	if !ok || m.minRTT != last.minRTT {
+		ev.MinRTT = millisecondsPtr(m.minRTT)
+	}
+	if !ok || m.smoothedRTT != last.smoothedRTT {
+		ev.SmoothedRTT = millisecondsPtr(m.smoothedRTT)
+	}
+	if !ok || m.latestRTT != last.latestRTT {
+		ev.LatestRTT = millisecondsPtr(m.latestRTT)
+	}
+	if !ok || m.rttVariance != last.rttVariance {
+		ev.RTTVariance = millisecondsPtr(m.rttVariance)
+	}
+	if !ok || m.congestionWindow != last.congestionWindow {
+		ev.CongestionWindow = &m.congestionWindow
+	}
+	if !ok || m.bytesInFlight != last.bytesInFlight {
+		ev.BytesInFlight = &m.bytesInFlight
+	}
+	t.writeRecord(&event{
+		Time: milliseconds(time.Since(t.referenceTime)),
+		Name: "recovery:metrics_updated",
+		Data: ev,
+	})
+}
+
+func (t *tracer) ChosePath(pathID logging.PathID) {
+	t.writeEvent("multipath:path_chosen", &pathEvent{PathID: pathID})
+}
+
+func (t *tracer) CreatedPath(pathID logging.PathID, localAddr, remoteAddr net.Addr) {
+	t.writeEvent("multipath:path_created", &pathCreatedEvent{
+		PathID:        pathID,
+		LocalAddress:  addressString(localAddr),
+		RemoteAddress: addressString(remoteAddr),
+	})
+}
+
+func (t *tracer) ClosedPath(pathID logging.PathID) {
+	t.writeEvent("multipath:path_closed", &pathEvent{PathID: pathID})
+}
+
+func (t *tracer) SBDDecision(pathID logging.PathID, sharedBottleneck []logging.PathID) {
+	paths := pathIDList(sharedBottleneck)
+	sort.Ints(paths)
+	t.writeEvent("multipath:sbd_decision", &sbdDecisionEvent{PathID: pathID, SharedBottleneck: paths})
+}
+
+func (t *tracer) Close() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.closed {
+		return
+	}
+	t.closed = true
+	if t.err == nil {
+		t.err = t.buf.Flush()
+	}
+	t.w.Close()
+	if t.err == nil {
+		// Don't write anything after closing
+		t.err = io.ErrClosedPipe
+	}
+}
+
+func addressString(addr net.Addr) string {
+	if addr == nil {
+		return ""
+	}
+	return addr.String()
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func millisecondsPtr(d time.Duration) *float64 {
+	ms := milliseconds(d)
+	return &ms
+}
@@ -0,0 +1,234 @@
+package qlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/congestion"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/logging"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type bufferWriteCloser struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *bufferWriteCloser) Close() error {
+	b.closed = true
+	return nil
+}
+
+var _ = Describe("qlog tracer", func() {
+	var (
+		buf    *bufferWriteCloser
+		tracer logging.Tracer
+	)
+
+	BeforeEach(func() {
+		buf = &bufferWriteCloser{}
+		tracer = NewTracer(buf, logging.PerspectiveClient, 0xdecafbad)
+	})
+
+	// records closes the tracer and returns the records that were written
+	records := func() []map[string]interface{} {
+		tracer.Close()
+		Expect(buf.Bytes()[0]).To(BeEquivalentTo(recordSeparator))
+		var recs []map[string]interface{}
+		for _, b := range bytes.Split(buf.Bytes()[1:], []byte{recordSeparator}) {
+			Expect(b[len(b)-1]).To(Equal(byte('\n')))
+			var rec map[string]interface{}
+			Expect(json.Unmarshal(b, &rec)).To(Succeed())
+			recs = append(recs, rec)
+		}
+		return recs
+	}
+
+	// events returns the data of the events that were written, keyed by their name
+	events := func(name string) []map[string]interface{} {
+		var data []map[string]interface{}
+		for _, rec := range records()[1:] {
+			Expect(rec).To(HaveKey("time"))
+			if rec["name"] == name {
+				data = append(data, rec["data"].(map[string]interface{}))
+			}
+		}
+		return data
+	}
+
+	It("writes the header", func() {
+		header := records()[0]
+		Expect(header).To(HaveKeyWithValue("qlog_version", "0.3"))
+		Expect(header).To(HaveKeyWithValue("qlog_format", "JSON-SEQ"))
+		trace := header["trace"].(map[string]interface{})
+		Expect(trace["vantage_point"]).To(HaveKeyWithValue("type", "client"))
+		Expect(trace["common_fields"]).To(HaveKeyWithValue("ODCID", "00000000decafbad"))
+		Expect(trace["common_fields"]).To(HaveKeyWithValue("time_format", "relative"))
+	})
+
+	It("uses the server vantage point", func() {
+		tracer = NewTracer(buf, logging.PerspectiveServer, 1)
+		trace := records()[0]["trace"].(map[string]interface{})
+		Expect(trace["vantage_point"]).To(HaveKeyWithValue("type", "server"))
+	})
+
+	It("traces sent packets with their frames", func() {
+		tracer.SentPacket(3, 42, 1234, []logging.Frame{
+			&wire.StreamFrame{StreamID: 5, Offset: 100, Data: []byte("foobar"), FinBit: true},
+			&wire.AckFrame{
+				LargestAcked: 10,
+				LowestAcked:  1,
+				AckRanges:    []wire.AckRange{{First: 8, Last: 10}, {First: 1, Last: 5}},
+				DelayTime:    2 * time.Millisecond,
+			},
+			&wire.WindowUpdateFrame{StreamID: 0, ByteOffset: 1000},
+			&wire.PingFrame{},
+		})
+		ev := events("transport:packet_sent")
+		Expect(ev).To(HaveLen(1))
+		Expect(ev[0]).To(HaveKeyWithValue("path_id", BeEquivalentTo(3)))
+		Expect(ev[0]["header"]).To(HaveKeyWithValue("packet_number", BeEquivalentTo(42)))
+		Expect(ev[0]["raw"]).To(HaveKeyWithValue("length", BeEquivalentTo(1234)))
+		frames := ev[0]["frames"].([]interface{})
+		Expect(frames).To(HaveLen(4))
+		Expect(frames[0]).To(Equal(map[string]interface{}{
+			"frame_type": "stream",
+			"stream_id":  5.0,
+			"offset":     100.0,
+			"length":     6.0,
+			"fin":        true,
+		}))
+		Expect(frames[1]).To(Equal(map[string]interface{}{
+			"frame_type":   "ack",
+			"ack_delay":    2.0,
+			"acked_ranges": []interface{}{[]interface{}{1.0, 5.0}, []interface{}{8.0, 10.0}},
+		}))
+		Expect(frames[2]).To(Equal(map[string]interface{}{"frame_type": "max_data", "maximum": 1000.0}))
+		Expect(frames[3]).To(Equal(map[string]interface{}{"frame_type": "ping"}))
+	})
+
+	It("traces the ECN counts of ACK frames", func() {
+		tracer.ReceivedPacket(1, 7, 100, []logging.Frame{
+			&wire.AckFrame{LargestAcked: 3, LowestAcked: 1, ECN: &wire.ECNCounts{ECT0: 2, ECNCE: 1}},
+		})
+		ev := events("transport:packet_received")
+		Expect(ev).To(HaveLen(1))
+		Expect(ev[0]["frames"]).To(ConsistOf(map[string]interface{}{
+			"frame_type":   "ack",
+			"acked_ranges": []interface{}{[]interface{}{1.0, 3.0}},
+			"ect0":         2.0,
+			"ect1":         0.0,
+			"ce":           1.0,
+		}))
+	})
+
+	It("traces received packets", func() {
+		tracer.ReceivedPacket(1, 7, 100, []logging.Frame{
+			&wire.ClosePathFrame{PathID: 1, LowestAcked: 2, LargestAcked: 4},
+			&wire.PathsFrame{PathIDs: []protocol.PathID{1, 3}, RemoteRTTs: []time.Duration{10 * time.Millisecond, 0}},
+		})
+		ev := events("transport:packet_received")
+		Expect(ev).To(HaveLen(1))
+		Expect(ev[0]["frames"]).To(ConsistOf(
+			map[string]interface{}{
+				"frame_type":   "close_path",
+				"path_id":      1.0,
+				"acked_ranges": []interface{}{[]interface{}{2.0, 4.0}},
+			},
+			map[string]interface{}{
+				"frame_type":  "paths",
+				"path_ids":    []interface{}{1.0, 3.0},
+				"remote_rtts": []interface{}{10.0, 0.0},
+			},
+		))
+	})
+
+	It("traces the OWD samples of processed ACKs", func() {
+		tracer.ProcessedAck(1, 10, []logging.OWDSample{{PacketNumber: 9, OWD: 15 * time.Millisecond}})
+		ev := events("multipath:ack_processed")
+		Expect(ev).To(HaveLen(1))
+		Expect(ev[0]).To(HaveKeyWithValue("largest_acked", 10.0))
+		Expect(ev[0]["owd_samples"]).To(ConsistOf(map[string]interface{}{"packet_number": 9.0, "owd": 15.0}))
+	})
+
+	It("traces lost packets", func() {
+		tracer.LostPacket(1, 3, logging.PacketLossTimeThreshold)
+		tracer.LostPacket(1, 4, logging.PacketLossRTO)
+		tracer.LostPacket(1, 5, logging.PacketLossPathClosed)
+		ev := events("recovery:packet_lost")
+		Expect(ev).To(HaveLen(3))
+		Expect(ev[0]).To(HaveKeyWithValue("trigger", "time_threshold"))
+		Expect(ev[1]).To(HaveKeyWithValue("trigger", "pto_expired"))
+		Expect(ev[2]).To(HaveKeyWithValue("trigger", "path_closed"))
+		Expect(ev[2]["header"]).To(HaveKeyWithValue("packet_number", 5.0))
+	})
+
+	It("only traces the metrics that changed", func() {
+		rttStats := congestion.NewRTTStats()
+		rttStats.UpdateRTT(100*time.Millisecond, 0, time.Now())
+		tracer.UpdatedMetrics(1, rttStats, 10*protocol.DefaultTCPMSS, 1000)
+		tracer.UpdatedMetrics(1, rttStats, 10*protocol.DefaultTCPMSS, 1000)
+		tracer.UpdatedMetrics(1, rttStats, 12*protocol.DefaultTCPMSS, 1000)
+		tracer.UpdatedMetrics(3, rttStats, 12*protocol.DefaultTCPMSS, 1000)
+		ev := events("recovery:metrics_updated")
+		Expect(ev).To(HaveLen(3))
+		Expect(ev[0]).To(Equal(map[string]interface{}{
+			"path_id":           1.0,
+			"min_rtt":           100.0,
+			"smoothed_rtt":      100.0,
+			"latest_rtt":        100.0,
+			"rtt_variance":      50.0,
+			"congestion_window": float64(10 * protocol.DefaultTCPMSS),
+			"bytes_in_flight":   1000.0,
+		}))
+		Expect(ev[1]).To(Equal(map[string]interface{}{
+			"path_id":           1.0,
+			"congestion_window": float64(12 * protocol.DefaultTCPMSS),
+		}))
+		// every path has its own metrics
+		Expect(ev[2]).To(HaveLen(7))
+	})
+
+	It("traces the paths", func() {
+		local := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234}
+		remote := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 4321}
+		tracer.CreatedPath(1, local, remote)
+		tracer.ChosePath(1)
+		tracer.ClosedPath(1)
+		Expect(records()).To(HaveLen(4))
+		Expect(events("multipath:path_created")).To(ConsistOf(map[string]interface{}{
+			"path_id":        1.0,
+			"local_address":  "10.0.0.1:1234",
+			"remote_address": "10.0.0.2:4321",
+		}))
+		Expect(events("multipath:path_chosen")).To(ConsistOf(map[string]interface{}{"path_id": 1.0}))
+		Expect(events("multipath:path_closed")).To(ConsistOf(map[string]interface{}{"path_id": 1.0}))
+	})
+
+	It("traces the SBD decisions", func() {
+		tracer.SBDDecision(3, []logging.PathID{5, 1, 3})
+		Expect(events("multipath:sbd_decision")).To(ConsistOf(map[string]interface{}{
+			"path_id":           3.0,
+			"shared_bottleneck": []interface{}{1.0, 3.0, 5.0},
+		}))
+	})
+
+	It("flushes and closes the writer when closed", func() {
+		tracer.ChosePath(1)
+		Expect(buf.Len()).To(BeZero())
+		tracer.Close()
+		Expect(buf.closed).To(BeTrue())
+		n := buf.Len()
+		Expect(n).ToNot(BeZero())
+		// nothing is written after closing
+		tracer.ChosePath(1)
+		tracer.Close()
+		Expect(buf.Len()).To(Equal(n))
+	})
+})
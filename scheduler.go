@@ -12,10 +12,16 @@ import (
 type scheduler struct {
 	// XXX Currently round-robin based, inspired from MPTCP scheduler
 	quotas map[protocol.PathID]uint
+	// Paths that had nothing to send during the current sendPacket call,
+	// e.g. because the streams with pending data prefer other paths
+	emptyPaths map[protocol.PathID]bool
+	// Reused by candidatePaths to avoid an allocation per packet
+	candidates []*path
 }
 
 func (sch *scheduler) setup() {
 	sch.quotas = make(map[protocol.PathID]uint)
+	sch.emptyPaths = make(map[protocol.PathID]bool)
 }
 
 func (sch *scheduler) getRetransmission(s *session) (hasRetransmission bool, retransmitPacket *ackhandler.Packet, pth *path) {
@@ -23,16 +29,14 @@ func (sch *scheduler) getRetransmission(s *session) (hasRetransmission bool, ret
 	for {
 		// TODO add ability to reinject on another path
 		// XXX We need to check on ALL paths if any packet should be first retransmitted
-		s.pathsLock.RLock()
 	retransmitLoop:
-		for _, pthTmp := range s.paths {
+		for _, pthTmp := range s.paths() {
 			retransmitPacket = pthTmp.sentPacketHandler.DequeuePacketForRetransmission()
 			if retransmitPacket != nil {
 				pth = pthTmp
 				break retransmitLoop
 			}
 		}
-		s.pathsLock.RUnlock()
 		if retransmitPacket == nil {
 			break
 		}
@@ -70,47 +74,108 @@ func (sch *scheduler) getRetransmission(s *session) (hasRetransmission bool, ret
 	return
 }
 
-func (sch *scheduler) selectPathRoundRobin(s *session, hasRetransmission bool, hasStreamRetransmission bool, fromPth *path) *path {
-	if sch.quotas == nil {
-		sch.setup()
-	}
-
-	// XXX Avoid using PathID 0 if there is more than 1 path
-	if len(s.paths) <= 1 {
-		if !hasRetransmission && !s.paths[protocol.InitialPathID].SendingAllowed() {
-			return nil
+// hasUsableNonBackupPath returns true if a non-backup path other than the initial one can still be used,
+// in which case backup paths should be left aside.
+func (sch *scheduler) hasUsableNonBackupPath(s *session) bool {
+	now := time.Now()
+	for pathID, pth := range s.paths() {
+		if pathID == protocol.InitialPathID {
+			continue
+		}
+		if !pth.backup.Get() && pth.open.Get() && !pth.potentiallyFailed.Get() && !pth.budgetExhausted(now) {
+			return true
 		}
-		return s.paths[protocol.InitialPathID]
 	}
+	return false
+}
 
-	// TODO cope with decreasing number of paths (needed?)
-	var selectedPath *path
-	var lowerQuota, currentQuota uint
-	var ok bool
+// candidatePaths returns the paths that can carry the next packet, in sch.candidates.
+// Only the cheapest of them are kept: metered paths are left aside as long as a cheaper path can be used.
+func (sch *scheduler) candidatePaths(s *session, paths pathTable, hasRetransmission bool) []*path {
+	skipBackup := sch.hasUsableNonBackupPath(s)
+	now := time.Now()
 
-	// Max possible value for lowerQuota at the beginning
-	lowerQuota = ^uint(0)
+	sch.candidates = sch.candidates[:0]
+	var lowestCost float64
 
 pathLoop:
-	for pathID, pth := range s.paths {
+	for pathID, pth := range paths {
 		// Don't block path usage if we retransmit, even on another path
 		if !hasRetransmission && !pth.SendingAllowed() {
 			continue pathLoop
 		}
 
-		// If this path is potentially failed, do no consider it for sending
+		// If this path is potentially failed, do not consider it for sending
 		if pth.potentiallyFailed.Get() {
 			continue pathLoop
 		}
 
+		// Backup paths are only used when no other path remains
+		if skipBackup && pth.backup.Get() {
+			continue pathLoop
+		}
+
+		// Nothing more to send on this path
+		if sch.emptyPaths[pathID] {
+			continue pathLoop
+		}
+
+		// Paths having used up their byte budget don't carry data anymore
+		if pth.budgetExhausted(now) {
+			continue pathLoop
+		}
+
 		// XXX Prevent using initial pathID if multiple paths
 		if pathID == protocol.InitialPathID {
 			continue pathLoop
 		}
 
-		currentQuota, ok = sch.quotas[pathID]
+		// Cheaper paths win over the other ones
+		cost := pth.costPerMB()
+		if len(sch.candidates) > 0 {
+			if cost > lowestCost {
+				continue pathLoop
+			}
+			if cost < lowestCost {
+				sch.candidates = sch.candidates[:0]
+			}
+		}
+		lowestCost = cost
+		sch.candidates = append(sch.candidates, pth)
+	}
+
+	return sch.candidates
+}
+
+func (sch *scheduler) selectPathRoundRobin(s *session, hasRetransmission bool, hasStreamRetransmission bool, fromPth *path) *path {
+	if sch.quotas == nil {
+		sch.setup()
+	}
+
+	// XXX Avoid using PathID 0 if there is more than 1 path
+	paths := s.paths()
+	if len(paths) <= 1 {
+		if !hasRetransmission && !paths[protocol.InitialPathID].SendingAllowed() {
+			return nil
+		}
+		if sch.emptyPaths[protocol.InitialPathID] || paths[protocol.InitialPathID].budgetExhausted(time.Now()) {
+			return nil
+		}
+		return paths[protocol.InitialPathID]
+	}
+
+	// TODO cope with decreasing number of paths (needed?)
+	var selectedPath *path
+	var lowerQuota, currentQuota uint
+	var ok bool
+
+	// Max possible value for lowerQuota at the beginning
+	lowerQuota = ^uint(0)
+
+	for _, pth := range sch.candidatePaths(s, paths, hasRetransmission) {
+		currentQuota, ok = sch.quotas[pth.pathID]
 		if !ok {
-			sch.quotas[pathID] = 0
+			sch.quotas[pth.pathID] = 0
 			currentQuota = 0
 		}
 
@@ -126,21 +191,33 @@ pathLoop:
 
 func (sch *scheduler) selectPathLowLatency(s *session, hasRetransmission bool, hasStreamRetransmission bool, fromPth *path) *path {
 	// XXX Avoid using PathID 0 if there is more than 1 path
-	if len(s.paths) <= 1 {
-		if !hasRetransmission && !s.paths[protocol.InitialPathID].SendingAllowed() {
+	paths := s.paths()
+	if len(paths) <= 1 {
+		if !hasRetransmission && !paths[protocol.InitialPathID].SendingAllowed() {
+			return nil
+		}
+		if sch.emptyPaths[protocol.InitialPathID] || paths[protocol.InitialPathID].budgetExhausted(time.Now()) {
 			return nil
 		}
-		return s.paths[protocol.InitialPathID]
+		return paths[protocol.InitialPathID]
 	}
 
 	// FIXME Only works at the beginning... Cope with new paths during the connection
 	if hasRetransmission && hasStreamRetransmission && fromPth.rttStats.SmoothedRTT() == 0 {
+		skipBackup := sch.hasUsableNonBackupPath(s)
+		now := time.Now()
 		// Is there any other path with a lower number of packet sent?
 		currentQuota := sch.quotas[fromPth.pathID]
-		for pathID, pth := range s.paths {
+		for pathID, pth := range paths {
 			if pathID == protocol.InitialPathID || pathID == fromPth.pathID {
 				continue
 			}
+			if skipBackup && pth.backup.Get() {
+				continue
+			}
+			if sch.emptyPaths[pathID] || pth.budgetExhausted(now) {
+				continue
+			}
 			// The congestion window was checked when duplicating the packet
 			if sch.quotas[pathID] < currentQuota {
 				return pth
@@ -154,22 +231,8 @@ func (sch *scheduler) selectPathLowLatency(s *session, hasRetransmission bool, h
 	selectedPathID := protocol.PathID(255)
 
 pathLoop:
-	for pathID, pth := range s.paths {
-		// Don't block path usage if we retransmit, even on another path
-		if !hasRetransmission && !pth.SendingAllowed() {
-			continue pathLoop
-		}
-
-		// If this path is potentially failed, do not consider it for sending
-		if pth.potentiallyFailed.Get() {
-			continue pathLoop
-		}
-
-		// XXX Prevent using initial pathID if multiple paths
-		if pathID == protocol.InitialPathID {
-			continue pathLoop
-		}
-
+	for _, pth := range sch.candidatePaths(s, paths, hasRetransmission) {
+		pathID := pth.pathID
 		currentRTT = pth.rttStats.SmoothedRTT()
 
 		// Prefer staying single-path if not blocked by current path
@@ -204,7 +267,6 @@ pathLoop:
 	return selectedPath
 }
 
-// Lock of s.paths must be held
 func (sch *scheduler) selectPath(s *session, hasRetransmission bool, hasStreamRetransmission bool, fromPth *path) *path {
 	// XXX Currently round-robin
 	// TODO select the right scheduler dynamically
@@ -212,7 +274,6 @@ func (sch *scheduler) selectPath(s *session, hasRetransmission bool, hasStreamRe
 	// return sch.selectPathRoundRobin(s, hasRetransmission, hasStreamRetransmission, fromPth)
 }
 
-// Lock of s.paths must be free (in case of log print)
 func (sch *scheduler) performPacketSending(s *session, windowUpdateFrames []*wire.WindowUpdateFrame, pth *path) (*ackhandler.Packet, bool, error) {
 	// add a retransmittable frame
 	if pth.sentPacketHandler.ShouldSendRetransmittablePacket() {
@@ -222,7 +283,7 @@ func (sch *scheduler) performPacketSending(s *session, windowUpdateFrames []*wir
 	if err != nil || packet == nil {
 		return nil, false, err
 	}
-	if err = s.sendPackedPacket(packet, pth); err != nil {
+	if err = s.queuePackedPacket(packet, pth); err != nil {
 		return nil, false, err
 	}
 
@@ -240,14 +301,12 @@ func (sch *scheduler) performPacketSending(s *session, windowUpdateFrames []*wir
 		case *wire.StreamFrame:
 			if frame.FinBit {
 				// Last packet to send on the stream, print stats
-				s.pathsLock.RLock()
 				utils.Infof("Info for stream %x of %x", frame.StreamID, s.connectionID)
-				for pathID, pth := range s.paths {
+				for pathID, pth := range s.paths() {
 					sntPkts, sntRetrans, sntLost := pth.sentPacketHandler.GetStatistics()
 					rcvPkts := pth.receivedPacketHandler.GetStatistics()
 					utils.Infof("Path %x: sent %d retrans %d lost %d; rcv %d rtt %v", pathID, sntPkts, sntRetrans, sntLost, rcvPkts, pth.rttStats.SmoothedRTT())
 				}
-				s.pathsLock.RUnlock()
 			}
 		default:
 		}
@@ -263,21 +322,18 @@ func (sch *scheduler) performPacketSending(s *session, windowUpdateFrames []*wir
 	return pkt, true, nil
 }
 
-// Lock of s.paths must be free
 func (sch *scheduler) ackRemainingPaths(s *session, totalWindowUpdateFrames []*wire.WindowUpdateFrame) error {
 	// Either we run out of data, or CWIN of usable paths are full
 	// Send ACKs on paths not yet used, if needed. Either we have no data to send and
 	// it will be a pure ACK, or we will have data in it, but the CWIN should then
 	// not be an issue.
-	s.pathsLock.RLock()
-	defer s.pathsLock.RUnlock()
 	// get WindowUpdate frames
 	// this call triggers the flow controller to increase the flow control windows, if necessary
 	windowUpdateFrames := totalWindowUpdateFrames
 	if len(windowUpdateFrames) == 0 {
 		windowUpdateFrames = s.getWindowUpdateFrames(s.peerBlocked)
 	}
-	for _, pthTmp := range s.paths {
+	for _, pthTmp := range s.paths() {
 		ackTmp := pthTmp.GetAckFrame()
 		for _, wuf := range windowUpdateFrames {
 			s.packer.QueueControlFrame(wuf, pthTmp)
@@ -303,7 +359,7 @@ func (sch *scheduler) ackRemainingPaths(s *session, totalWindowUpdateFrames []*w
 			if err != nil {
 				return err
 			}
-			err = s.sendPackedPacket(packet, pthTmp)
+			err = s.queuePackedPacket(packet, pthTmp)
 			if err != nil {
 				return err
 			}
@@ -313,15 +369,37 @@ func (sch *scheduler) ackRemainingPaths(s *session, totalWindowUpdateFrames []*w
 	return nil
 }
 
+// sendPacket sends a burst of packets, which are queued and written once the burst is over
 func (sch *scheduler) sendPacket(s *session) error {
+	err := sch.queuePackets(s)
+	if flushErr := sch.flushPaths(s); err == nil {
+		err = flushErr
+	}
+	return err
+}
+
+func (sch *scheduler) flushPaths(s *session) error {
+	var err error
+	for _, pth := range s.paths() {
+		if flushErr := pth.conn.Flush(); err == nil {
+			err = flushErr
+		}
+	}
+	return err
+}
+
+func (sch *scheduler) queuePackets(s *session) error {
 	var pth *path
 
+	// Renew the byte budgets whose window ended
+	if s.pathManager != nil {
+		s.pathManager.rollBudgets(time.Now())
+	}
+
 	// Update leastUnacked value of paths
-	s.pathsLock.RLock()
-	for _, pthTmp := range s.paths {
+	for _, pthTmp := range s.paths() {
 		pthTmp.SetLeastUnacked(pthTmp.sentPacketHandler.GetLeastUnacked())
 	}
-	s.pathsLock.RUnlock()
 
 	// get WindowUpdate frames
 	// this call triggers the flow controller to increase the flow control windows, if necessary
@@ -330,6 +408,10 @@ func (sch *scheduler) sendPacket(s *session) error {
 		s.packer.QueueControlFrame(wuf, pth)
 	}
 
+	for pathID := range sch.emptyPaths {
+		delete(sch.emptyPaths, pathID)
+	}
+
 	// Repeatedly try sending until we don't have any more data, or run out of the congestion window
 	for {
 		// We first check for retransmissions
@@ -338,15 +420,20 @@ func (sch *scheduler) sendPacket(s *session) error {
 		hasStreamRetransmission := s.streamFramer.HasFramesForRetransmission()
 
 		// Select the path here
-		s.pathsLock.RLock()
 		pth = sch.selectPath(s, hasRetransmission, hasStreamRetransmission, fromPth)
-		s.pathsLock.RUnlock()
 
 		// XXX No more path available, should we have a new QUIC error message?
 		if pth == nil {
+			if len(sch.emptyPaths) > 0 {
+				// We ran out of data to send
+				return sch.ackRemainingPaths(s, nil)
+			}
 			windowUpdateFrames := s.getWindowUpdateFrames(false)
 			return sch.ackRemainingPaths(s, windowUpdateFrames)
 		}
+		if s.tracer != nil {
+			s.tracer.ChosePath(pth.pathID)
+		}
 
 		// If we have an handshake packet retransmission, do it directly
 		if hasRetransmission && retransmitHandshakePacket != nil {
@@ -355,7 +442,7 @@ func (sch *scheduler) sendPacket(s *session) error {
 			if err != nil {
 				return err
 			}
-			if err = s.sendPackedPacket(packet, pth); err != nil {
+			if err = s.queuePackedPacket(packet, pth); err != nil {
 				return err
 			}
 			continue
@@ -396,20 +483,29 @@ func (sch *scheduler) sendPacket(s *session) error {
 		}
 		windowUpdateFrames = nil
 		if !sent {
-			// Prevent sending empty packets
-			return sch.ackRemainingPaths(s, windowUpdateFrames)
+			// Prevent sending empty packets, but streams preferring other paths may still have data
+			sch.emptyPaths[pth.pathID] = true
+			pth.sentPacketHandler.SetAppLimited()
+			continue
 		}
 
 		// Duplicate traffic when it was sent on an unknown performing path
 		// FIXME adapt for new paths coming during the connection
 		if pth.rttStats.SmoothedRTT() == 0 {
 			currentQuota := sch.quotas[pth.pathID]
+			skipBackup := sch.hasUsableNonBackupPath(s)
 			// Was the packet duplicated on all potential paths?
 		duplicateLoop:
-			for pathID, tmpPth := range s.paths {
+			for pathID, tmpPth := range s.paths() {
 				if pathID == protocol.InitialPathID || pathID == pth.pathID {
 					continue
 				}
+				if skipBackup && tmpPth.backup.Get() {
+					continue
+				}
+				if tmpPth.budgetExhausted(time.Now()) {
+					continue
+				}
 				if sch.quotas[pathID] < currentQuota && tmpPth.sentPacketHandler.SendingAllowed() {
 					// Duplicate it
 					pth.sentPacketHandler.DuplicatePacket(pkt)
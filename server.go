@@ -5,7 +5,6 @@ import (
 	"crypto/tls"
 	"errors"
 	"net"
-	"sync"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/crypto"
@@ -35,8 +34,7 @@ type server struct {
 	certChain crypto.CertChain
 	scfg      *handshake.ServerConfig
 
-	sessions                  map[protocol.ConnectionID]packetHandler
-	sessionsMutex             sync.RWMutex
+	sessions                  *sessionTable
 	deleteClosedSessionsAfter time.Duration
 
 	serverError  error
@@ -65,40 +63,26 @@ func ListenAddrImpl(addr string, tlsConf *tls.Config, config *Config, pconnMgrAr
 		return nil, err
 	}
 
-	var pconnMgr *pconnManager
-
-	if pconnMgrArg == nil {
-		// Create the pconnManager here. It will be used to start udp connections
-		pconnMgr = &pconnManager{perspective: protocol.PerspectiveServer}
-		// XXX (QDC): make this cleaner
-		pconn, err := net.ListenUDP("udp", udpAddr)
-		if err != nil {
-			utils.Errorf("pconn_manager: %v", err)
-			// Format for expected consistency
-			operr := &net.OpError{Op: "listen", Net: "udp", Source: udpAddr, Addr: udpAddr, Err: err}
-			return nil, operr
-		}
-		err = pconnMgr.setup(pconn, udpAddr)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		pconnMgr = pconnMgrArg
+	if pconnMgrArg != nil {
+		return ListenImpl(pconnMgrArg.pconnAny, tlsConf, config, pconnMgrArg)
+	}
+	// XXX (QDC): make this cleaner
+	pconn, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		utils.Errorf("pconn_manager: %v", err)
+		// Format for expected consistency
+		operr := &net.OpError{Op: "listen", Net: "udp", Source: udpAddr, Addr: udpAddr, Err: err}
+		return nil, operr
 	}
-	return ListenImpl(pconnMgr.pconnAny, tlsConf, config, pconnMgr)
+	// The pconnManager is created by ListenImpl, so that the packets are dispatched to the session shards
+	return ListenImpl(pconn, tlsConf, config, nil)
 }
 
 // Listen listens for QUIC connections on a given net.PacketConn.
 // The listener is not active until Serve() is called.
 // The tls.Config must not be nil, the quic.Config may be nil.
 func Listen(pconn net.PacketConn, tlsConf *tls.Config, config *Config) (Listener, error) {
-	// Create the pconnManager here. It will be used to start udp connections
-	pconnMgr := &pconnManager{perspective: protocol.PerspectiveServer}
-	err := pconnMgr.setup(pconn, nil)
-	if err != nil {
-		return nil, err
-	}
-	return ListenImpl(pconn, tlsConf, config, pconnMgr)
+	return ListenImpl(pconn, tlsConf, config, nil)
 }
 
 // ListenImpl listens for QUIC connections on a given net.PacketConn.
@@ -115,31 +99,33 @@ func ListenImpl(pconn net.PacketConn, tlsConf *tls.Config, config *Config, pconn
 	if err != nil {
 		return nil, err
 	}
-
-	var pconnMgr *pconnManager
-
-	if pconnMgrArg == nil {
-		pconnMgr = &pconnManager{perspective: protocol.PerspectiveServer}
-		err := pconnMgr.setup(pconn, nil)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		pconnMgr = pconnMgrArg
+	if tlsConf != nil {
+		scfg.KeyLogWriter = tlsConf.KeyLogWriter
 	}
 
 	s := &server{
-		pconnMgr:                  pconnMgr,
 		tlsConf:                   tlsConf,
 		config:                    populateServerConfig(config),
 		certChain:                 certChain,
 		scfg:                      scfg,
-		sessions:                  map[protocol.ConnectionID]packetHandler{},
+		sessions:                  newSessionTable(protocol.NumSessionShards),
 		newSession:                newSession,
 		deleteClosedSessionsAfter: protocol.ClosedSessionDeleteTimeout,
 		sessionQueue:              make(chan Session, 5),
 		errorChan:                 make(chan struct{}),
 	}
+
+	if pconnMgrArg == nil {
+		// Create the pconnManager here. It will be used to start udp connections
+		// The goroutines reading from the sockets dispatch the packets to the session shards themselves
+		s.pconnMgr = &pconnManager{perspective: protocol.PerspectiveServer, dispatch: s.dispatchPacket}
+		err := s.pconnMgr.setup(pconn, nil)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		s.pconnMgr = pconnMgrArg
+	}
 	go s.serve()
 	utils.Debugf("Listening for %s connections on %s", pconn.LocalAddr().Network(), pconn.LocalAddr().String())
 	return s, nil
@@ -205,11 +191,21 @@ func populateServerConfig(config *Config) *Config {
 		KeepAlive:                             config.KeepAlive,
 		MaxReceiveStreamFlowControlWindow:     maxReceiveStreamFlowControlWindow,
 		MaxReceiveConnectionFlowControlWindow: maxReceiveConnectionFlowControlWindow,
+		BackupInterfaces:                      config.BackupInterfaces,
+		PathCosts:                             config.PathCosts,
+		CongestionControl:                     config.CongestionControl,
+		NewCongestionControl:                  config.NewCongestionControl,
+		HyStartPlusPlus:                       config.HyStartPlusPlus,
+		NewTracer:                             config.NewTracer,
+		NewPacketCapture:                      config.NewPacketCapture,
 	}
 }
 
 // serve listens on an existing PacketConn
 func (s *server) serve() {
+	for i := range s.sessions.shards {
+		go s.runShard(&s.sessions.shards[i])
+	}
 	for {
 		select {
 		case err := <-s.pconnMgr.errorConn:
@@ -218,9 +214,39 @@ func (s *server) serve() {
 			_ = s.Close()
 			return
 		case rcvRawPacket := <-s.pconnMgr.rcvRawPackets:
-			if err := s.handlePacket(rcvRawPacket); err != nil {
+			// Only if the pconnManager was given to the server, without our dispatcher
+			s.dispatchPacket(rcvRawPacket)
+		}
+	}
+}
+
+// dispatchPacket queues a received packet in the shard of its connection ID.
+// It's called by the goroutines reading from the sockets, so that the packets of a session are queued in order.
+// When the shard is full, the packet is dropped: the reading goroutines must not wait for a slow shard,
+// that would stall the sessions of all the other shards.
+func (s *server) dispatchPacket(p *receivedRawPacket) {
+	// A packet without connection ID ends up in any shard, where handlePacket rejects it
+	connID, _ := wire.PeekConnectionID(bytes.NewReader(p.data), protocol.PerspectiveClient)
+	select {
+	case s.sessions.shard(connID).packets <- p:
+	case <-s.errorChan:
+	default:
+		if utils.Debug() {
+			utils.Debugf("Dropping packet for connection %x, its shard is full", connID)
+		}
+	}
+}
+
+// runShard handles the packets queued in a shard, one after the other
+func (s *server) runShard(shard *sessionShard) {
+	for {
+		select {
+		case p := <-shard.packets:
+			if err := s.handlePacket(p); err != nil {
 				utils.Errorf("error handling packet: %s", err.Error())
 			}
+		case <-s.errorChan:
+			return
 		}
 	}
 }
@@ -238,15 +264,9 @@ func (s *server) Accept() (Session, error) {
 
 // Close the server
 func (s *server) Close() error {
-	s.sessionsMutex.Lock()
-	for _, session := range s.sessions {
-		if session != nil {
-			s.sessionsMutex.Unlock()
-			_ = session.Close(nil)
-			s.sessionsMutex.Lock()
-		}
+	for _, session := range s.sessions.open() {
+		_ = session.Close(nil)
 	}
-	s.sessionsMutex.Unlock()
 
 	s.pconnMgr.closeConns <- struct{}{}
 	if s.pconnMgr != nil && s.pconnMgr.closed != nil {
@@ -286,9 +306,7 @@ func (s *server) handlePacket(rcvRawPacket *receivedRawPacket) error {
 		return qerr.Error(qerr.InvalidPacketHeader, err.Error())
 	}
 
-	s.sessionsMutex.RLock()
-	session, ok := s.sessions[connID]
-	s.sessionsMutex.RUnlock()
+	session, ok := s.sessions.get(connID)
 
 	if ok && session == nil {
 		// Late packet for closed session
@@ -367,9 +385,8 @@ func (s *server) handlePacket(rcvRawPacket *receivedRawPacket) error {
 		if err != nil {
 			return err
 		}
-		s.sessionsMutex.Lock()
-		s.sessions[connID] = session
-		s.sessionsMutex.Unlock()
+		// Only the goroutine of the shard creates its sessions, so no other session was created meanwhile
+		s.sessions.set(connID, session)
 
 		go func() {
 			// session.run() returns as soon as the session is closed
@@ -396,18 +413,15 @@ func (s *server) handlePacket(rcvRawPacket *receivedRawPacket) error {
 		data:         packet[len(packet)-r.Len():],
 		rcvTime:      rcvTime,
 		rcvPconn:     pconn,
+		ecn:          rcvRawPacket.ecn,
 	})
 	return nil
 }
 
 func (s *server) removeConnection(id protocol.ConnectionID) {
-	s.sessionsMutex.Lock()
-	s.sessions[id] = nil
-	s.sessionsMutex.Unlock()
+	s.sessions.set(id, nil)
 
 	time.AfterFunc(s.deleteClosedSessionsAfter, func() {
-		s.sessionsMutex.Lock()
-		delete(s.sessions, id)
-		s.sessionsMutex.Unlock()
+		s.sessions.delete(id)
 	})
 }
@@ -0,0 +1,221 @@
+package quic
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/handshake"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+)
+
+// These benchmarks compare the dispatch of the received packets to protocol.NumSessionShards shards with the dispatch
+// before the sessions were sharded: the goroutines reading from the sockets handed every packet over to the serve
+// goroutine, which handled them one after the other, with the sessions in a single map protected by an RWMutex.
+// The packets of many clients are dispatched by a few goroutines, as if they were reading from different sockets, and
+// every client always sends on the same socket. Like a real session, every session decrypts its packets on its own
+// goroutine, and drops them when it's too slow. Since packets are dropped, an iteration is a decrypted packet.
+
+// benchmarkPackets is the number of packets sent in a loop by every client, the first one carrying the version
+const benchmarkPackets = 16
+
+// benchmarkPayloadSize is the size of the encrypted payload of the packets
+const benchmarkPayloadSize = 1200
+
+var benchmarkKey = bytes.Repeat([]byte{0x42}, 16)
+
+func newBenchmarkAEAD() cipher.AEAD {
+	block, err := aes.NewCipher(benchmarkKey)
+	if err != nil {
+		panic(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+	return aead
+}
+
+func benchmarkNonce(pn protocol.PacketNumber) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce[4:], uint64(pn))
+	return nonce
+}
+
+// benchmarkCounters count the packets decrypted by the sessions
+type benchmarkCounters struct {
+	decrypted int64
+	failed    int64
+}
+
+type benchmarkSession struct {
+	mockSession
+	counters *benchmarkCounters
+	packets  chan *receivedPacket
+	aead     cipher.AEAD
+	buf      []byte
+}
+
+func (s *benchmarkSession) handlePacket(p *receivedPacket) {
+	select {
+	case s.packets <- p:
+	default:
+	}
+}
+
+func (s *benchmarkSession) run() error {
+	for {
+		select {
+		case p := <-s.packets:
+			var err error
+			s.buf, err = s.aead.Open(s.buf[:0], benchmarkNonce(p.publicHeader.PacketNumber), p.data, p.publicHeader.Raw)
+			if err != nil {
+				atomic.AddInt64(&s.counters.failed, 1)
+			}
+			atomic.AddInt64(&s.counters.decrypted, 1)
+		case <-s.stopRunLoop:
+			return nil
+		}
+	}
+}
+
+func composeBenchmarkPacket(connID protocol.ConnectionID, pn protocol.PacketNumber, aead cipher.AEAD) []byte {
+	hdr := wire.PublicHeader{
+		ConnectionID:    connID,
+		PacketNumber:    pn,
+		PacketNumberLen: protocol.PacketNumberLen2,
+	}
+	version := protocol.VersionWhatever
+	if pn == 1 {
+		hdr.VersionFlag = true
+		hdr.VersionNumber = protocol.SupportedVersions[0]
+		version = hdr.VersionNumber
+	}
+	b := &bytes.Buffer{}
+	hdr.Write(b, version, protocol.PerspectiveClient)
+	raw := b.Bytes()
+	return aead.Seal(raw, benchmarkNonce(pn), make([]byte, benchmarkPayloadSize), raw)
+}
+
+func benchmarkServerDispatch(b *testing.B, sharded bool, numClients, numReaders int) {
+	counters := &benchmarkCounters{}
+	var sessionsMutex sync.Mutex
+	var sessions []packetHandler
+	numShards := 1
+	if sharded {
+		numShards = protocol.NumSessionShards
+	}
+	serv := &server{
+		sessions: newSessionTable(numShards),
+		newSession: func(_ connection, _ *pconnManager, _ bool, _ protocol.VersionNumber, connectionID protocol.ConnectionID, _ *handshake.ServerConfig, _ *tls.Config, _ *Config) (packetHandler, <-chan handshakeEvent, error) {
+			// never accept the session
+			handshakeChan := make(chan handshakeEvent, 1)
+			handshakeChan <- handshakeEvent{err: errors.New("handshake failed")}
+			s := &benchmarkSession{
+				mockSession: mockSession{connectionID: connectionID, stopRunLoop: make(chan struct{})},
+				counters:    counters,
+				packets:     make(chan *receivedPacket, protocol.MaxSessionUnprocessedPackets),
+				aead:        newBenchmarkAEAD(),
+			}
+			sessionsMutex.Lock()
+			sessions = append(sessions, s)
+			sessionsMutex.Unlock()
+			return s, handshakeChan, nil
+		},
+		config:                    populateServerConfig(&Config{}),
+		deleteClosedSessionsAfter: time.Millisecond,
+		sessionQueue:              make(chan Session),
+		errorChan:                 make(chan struct{}),
+	}
+	dispatch := serv.dispatchPacket
+	if !sharded {
+		// The readers wait for the serve goroutine to take the packet, as they did on the rcvRawPackets channel
+		shard := &serv.sessions.shards[0]
+		shard.packets = make(chan *receivedRawPacket)
+		dispatch = func(p *receivedRawPacket) { shard.packets <- p }
+	}
+	var shardsDone sync.WaitGroup
+	for i := range serv.sessions.shards {
+		shardsDone.Add(1)
+		go func(shard *sessionShard) {
+			defer shardsDone.Done()
+			serv.runShard(shard)
+		}(&serv.sessions.shards[i])
+	}
+
+	aead := newBenchmarkAEAD()
+	clients := make([][][]byte, numClients)
+	for i := range clients {
+		clients[i] = make([][]byte, benchmarkPackets)
+		for j := range clients[i] {
+			clients[i][j] = composeBenchmarkPacket(protocol.ConnectionID(0x1000+i), protocol.PacketNumber(j+1), aead)
+		}
+		// Open the session, so that the packets of the benchmark are the packets of established sessions
+		if err := serv.handlePacket(&receivedRawPacket{data: clients[i][0], rcvTime: time.Now()}); err != nil {
+			b.Fatal(err)
+		}
+	}
+	for atomic.LoadInt64(&counters.decrypted) < int64(numClients) {
+		time.Sleep(time.Millisecond)
+	}
+	atomic.StoreInt64(&counters.decrypted, 0)
+
+	b.SetBytes(benchmarkPayloadSize)
+	b.ReportAllocs()
+	b.ResetTimer()
+	var dispatched int64
+	var wg sync.WaitGroup
+	for r := 0; r < numReaders; r++ {
+		wg.Add(1)
+		go func(r int) {
+			defer wg.Done()
+			// Every reader sends the packets of the clients c with c % numReaders == r
+			readerClients := (numClients - r + numReaders - 1) / numReaders
+			for i := 0; atomic.LoadInt64(&counters.decrypted) < int64(b.N); i++ {
+				c := r + i%readerClients*numReaders
+				packet := clients[c][i/readerClients%benchmarkPackets]
+				dispatch(&receivedRawPacket{data: packet, rcvTime: time.Now()})
+				atomic.AddInt64(&dispatched, 1)
+				// A reader blocks in a system call for every batch of packets, giving up the processor
+				if i%protocol.MaxBatchSize == protocol.MaxBatchSize-1 {
+					runtime.Gosched()
+				}
+			}
+		}(r)
+	}
+	wg.Wait()
+	b.StopTimer()
+
+	// Don't close the sessions before the shards stopped handling the packets, they'd be removed
+	close(serv.errorChan)
+	shardsDone.Wait()
+	for _, s := range sessions {
+		s.Close(nil)
+	}
+	if failed := atomic.LoadInt64(&counters.failed); failed > 0 {
+		b.Fatalf("%d packets failed to decrypt", failed)
+	}
+	b.Logf("dispatched %d packets to decrypt %d", dispatched, b.N)
+}
+
+// BenchmarkServerDispatch dispatches the packets of 1000 clients
+func BenchmarkServerDispatch(b *testing.B) {
+	for _, numReaders := range []int{1, 4} {
+		b.Run(fmt.Sprintf("readers=%d/serve-goroutine", numReaders), func(b *testing.B) {
+			benchmarkServerDispatch(b, false, 1000, numReaders)
+		})
+		b.Run(fmt.Sprintf("readers=%d/shards=%d", numReaders, protocol.NumSessionShards), func(b *testing.B) {
+			benchmarkServerDispatch(b, true, 1000, numReaders)
+		})
+	}
+}
@@ -23,6 +23,7 @@ import (
 type mockSession struct {
 	connectionID      protocol.ConnectionID
 	packetCount       int
+	packetNumbers     []protocol.PacketNumber
 	closed            bool
 	closeReason       error
 	closedRemote      bool
@@ -32,8 +33,9 @@ type mockSession struct {
 	remoteAddr        net.Addr
 }
 
-func (s *mockSession) handlePacket(*receivedPacket) {
+func (s *mockSession) handlePacket(p *receivedPacket) {
 	s.packetCount++
+	s.packetNumbers = append(s.packetNumbers, p.publicHeader.PacketNumber)
 }
 
 func (s *mockSession) run() error {
@@ -67,6 +69,7 @@ func (s *mockSession) LocalAddr() net.Addr              { panic("not implemented
 func (s *mockSession) RemoteAddr() net.Addr             { return s.remoteAddr }
 func (*mockSession) Context() context.Context           { panic("not implemented") }
 func (*mockSession) GetVersion() protocol.VersionNumber { return protocol.VersionWhatever }
+func (*mockSession) PathStats() []PathStats             { panic("not implemented") }
 
 var _ Session = &mockSession{}
 var _ NonFWSession = &mockSession{}
@@ -108,19 +111,24 @@ var _ = Describe("Server", func() {
 	Context("with mock session", func() {
 		var (
 			serv        *server
+			getSession  func(protocol.ConnectionID) packetHandler
 			firstPacket []byte // a valid first packet for a new connection with connectionID 0x4cfa9f9b668619f6 (= connID)
 			connID      = protocol.ConnectionID(0x4cfa9f9b668619f6)
 		)
 
 		BeforeEach(func() {
 			serv = &server{
-				sessions:     make(map[protocol.ConnectionID]packetHandler),
+				sessions:     newSessionTable(4),
 				newSession:   newMockSession,
 				pconnMgr:     pconnMgr,
 				config:       config,
 				sessionQueue: make(chan Session, 5),
 				errorChan:    make(chan struct{}),
 			}
+			getSession = func(id protocol.ConnectionID) packetHandler {
+				session, _ := serv.sessions.get(id)
+				return session
+			}
 			b := &bytes.Buffer{}
 			utils.LittleEndian.WriteUint32(b, protocol.VersionNumberToTag(protocol.SupportedVersions[0]))
 			firstPacket = []byte{0x09, 0xf6, 0x19, 0x86, 0x66, 0x9b, 0x9f, 0xfa, 0x4c}
@@ -138,8 +146,8 @@ var _ = Describe("Server", func() {
 		It("creates new sessions", func() {
 			err := serv.handlePacket(&receivedRawPacket{rcvPconn: nil, remoteAddr: nil, data: firstPacket, rcvTime: time.Now()})
 			Expect(err).ToNot(HaveOccurred())
-			Expect(serv.sessions).To(HaveLen(1))
-			sess := serv.sessions[connID].(*mockSession)
+			Expect(serv.sessions.len()).To(Equal(1))
+			sess := getSession(connID).(*mockSession)
 			Expect(sess.connectionID).To(Equal(connID))
 			Expect(sess.packetCount).To(Equal(1))
 		})
@@ -154,8 +162,8 @@ var _ = Describe("Server", func() {
 			}()
 			err := serv.handlePacket(&receivedRawPacket{rcvPconn: nil, remoteAddr: nil, data: firstPacket, rcvTime: time.Now()})
 			Expect(err).ToNot(HaveOccurred())
-			Expect(serv.sessions).To(HaveLen(1))
-			sess := serv.sessions[connID].(*mockSession)
+			Expect(serv.sessions.len()).To(Equal(1))
+			sess := getSession(connID).(*mockSession)
 			sess.handshakeChan <- handshakeEvent{encLevel: protocol.EncryptionSecure}
 			Consistently(func() Session { return acceptedSess }).Should(BeNil())
 			sess.handshakeChan <- handshakeEvent{encLevel: protocol.EncryptionForwardSecure}
@@ -172,8 +180,8 @@ var _ = Describe("Server", func() {
 			}()
 			err := serv.handlePacket(&receivedRawPacket{rcvPconn: nil, remoteAddr: nil, data: firstPacket, rcvTime: time.Now()})
 			Expect(err).ToNot(HaveOccurred())
-			Expect(serv.sessions).To(HaveLen(1))
-			sess := serv.sessions[connID].(*mockSession)
+			Expect(serv.sessions.len()).To(Equal(1))
+			sess := getSession(connID).(*mockSession)
 			sess.handshakeChan <- handshakeEvent{err: errors.New("handshake failed")}
 			Consistently(func() bool { return accepted }).Should(BeFalse())
 			close(done)
@@ -184,9 +192,9 @@ var _ = Describe("Server", func() {
 			Expect(err).ToNot(HaveOccurred())
 			err = serv.handlePacket(&receivedRawPacket{rcvPconn: nil, remoteAddr: nil, data: []byte{0x08, 0xf6, 0x19, 0x86, 0x66, 0x9b, 0x9f, 0xfa, 0x4c, 0x01}, rcvTime: time.Now()})
 			Expect(err).ToNot(HaveOccurred())
-			Expect(serv.sessions).To(HaveLen(1))
-			Expect(serv.sessions[connID].(*mockSession).connectionID).To(Equal(connID))
-			Expect(serv.sessions[connID].(*mockSession).packetCount).To(Equal(2))
+			Expect(serv.sessions.len()).To(Equal(1))
+			Expect(getSession(connID).(*mockSession).connectionID).To(Equal(connID))
+			Expect(getSession(connID).(*mockSession).packetCount).To(Equal(2))
 		})
 
 		It("closes and deletes sessions", func() {
@@ -194,13 +202,13 @@ var _ = Describe("Server", func() {
 			nullAEAD := crypto.NewNullAEAD(protocol.PerspectiveServer, protocol.VersionWhatever)
 			err := serv.handlePacket(&receivedRawPacket{rcvPconn: nil, remoteAddr: nil, data: append(firstPacket, nullAEAD.Seal(nil, nil, 0, firstPacket)...), rcvTime: time.Now()})
 			Expect(err).ToNot(HaveOccurred())
-			Expect(serv.sessions).To(HaveLen(1))
-			Expect(serv.sessions[connID]).ToNot(BeNil())
+			Expect(serv.sessions.len()).To(Equal(1))
+			Expect(getSession(connID)).ToNot(BeNil())
 			// make session.run() return
-			serv.sessions[connID].(*mockSession).stopRunLoop <- struct{}{}
+			getSession(connID).(*mockSession).stopRunLoop <- struct{}{}
 			// The server should now have closed the session, leaving a nil value in the sessions map
-			Consistently(func() map[protocol.ConnectionID]packetHandler { return serv.sessions }).Should(HaveLen(1))
-			Expect(serv.sessions[connID]).To(BeNil())
+			Consistently(serv.sessions.len).Should(Equal(1))
+			Expect(getSession(connID)).To(BeNil())
 		})
 
 		It("deletes nil session entries after a wait time", func() {
@@ -208,21 +216,19 @@ var _ = Describe("Server", func() {
 			nullAEAD := crypto.NewNullAEAD(protocol.PerspectiveServer, protocol.VersionWhatever)
 			err := serv.handlePacket(&receivedRawPacket{rcvPconn: nil, remoteAddr: nil, data: append(firstPacket, nullAEAD.Seal(nil, nil, 0, firstPacket)...), rcvTime: time.Now()})
 			Expect(err).ToNot(HaveOccurred())
-			Expect(serv.sessions).To(HaveLen(1))
-			Expect(serv.sessions).To(HaveKey(connID))
+			Expect(serv.sessions.len()).To(Equal(1))
+			Expect(getSession(connID)).ToNot(BeNil())
 			// make session.run() return
-			serv.sessions[connID].(*mockSession).stopRunLoop <- struct{}{}
+			getSession(connID).(*mockSession).stopRunLoop <- struct{}{}
 			Eventually(func() bool {
-				serv.sessionsMutex.Lock()
-				_, ok := serv.sessions[connID]
-				serv.sessionsMutex.Unlock()
+				_, ok := serv.sessions.get(connID)
 				return ok
 			}).Should(BeFalse())
 		})
 
 		It("closes sessions and the connection when Close is called", func() {
 			session, _, _ := newMockSession(nil, pconnMgr, false, 0, 0, nil, nil, nil)
-			serv.sessions[1] = session
+			serv.sessions.set(1, session)
 			err := serv.Close()
 			Expect(err).NotTo(HaveOccurred())
 			Expect(session.(*mockSession).closed).To(BeTrue())
@@ -230,11 +236,11 @@ var _ = Describe("Server", func() {
 		})
 
 		It("ignores packets for closed sessions", func() {
-			serv.sessions[connID] = nil
+			serv.sessions.set(connID, nil)
 			err := serv.handlePacket(&receivedRawPacket{rcvPconn: nil, remoteAddr: nil, data: []byte{0x08, 0xf6, 0x19, 0x86, 0x66, 0x9b, 0x9f, 0xfa, 0x4c, 0x01}, rcvTime: time.Now()})
 			Expect(err).ToNot(HaveOccurred())
-			Expect(serv.sessions).To(HaveLen(1))
-			Expect(serv.sessions[connID]).To(BeNil())
+			Expect(serv.sessions.len()).To(Equal(1))
+			Expect(getSession(connID)).To(BeNil())
 		})
 
 		It("works if no quic.Config is given", func(done Done) {
@@ -272,12 +278,12 @@ var _ = Describe("Server", func() {
 
 		It("closes all sessions when encountering a connection error", func() {
 			session, _, _ := newMockSession(nil, pconnMgr, false, 0, 0, nil, nil, nil)
-			serv.sessions[0x12345] = session
-			Expect(serv.sessions[0x12345].(*mockSession).closed).To(BeFalse())
+			serv.sessions.set(0x12345, session)
+			Expect(getSession(0x12345).(*mockSession).closed).To(BeFalse())
 			testErr := errors.New("connection error")
 			conn.readErr = testErr
 			go serv.serve()
-			Eventually(func() Session { return serv.sessions[connID] }).Should(BeNil())
+			Eventually(func() Session { return getSession(connID) }).Should(BeNil())
 			Eventually(func() bool { return session.(*mockSession).closed }).Should(BeTrue())
 			Expect(serv.Close()).To(Succeed())
 		})
@@ -285,7 +291,7 @@ var _ = Describe("Server", func() {
 		It("ignores delayed packets with mismatching versions", func() {
 			err := serv.handlePacket(&receivedRawPacket{rcvPconn: nil, remoteAddr: nil, data: firstPacket, rcvTime: time.Now()})
 			Expect(err).ToNot(HaveOccurred())
-			Expect(serv.sessions[connID].(*mockSession).packetCount).To(Equal(1))
+			Expect(getSession(connID).(*mockSession).packetCount).To(Equal(1))
 			b := &bytes.Buffer{}
 			// add an unsupported version
 			utils.LittleEndian.WriteUint32(b, protocol.VersionNumberToTag(protocol.SupportedVersions[0]+1))
@@ -296,7 +302,7 @@ var _ = Describe("Server", func() {
 			// if we didn't ignore the packet, the server would try to send a version negotation packet, which would make the test panic because it doesn't have a udpConn
 			Expect(conn.dataWritten.Bytes()).To(BeEmpty())
 			// make sure the packet was *not* passed to session.handlePacket()
-			Expect(serv.sessions[connID].(*mockSession).packetCount).To(Equal(1))
+			Expect(getSession(connID).(*mockSession).packetCount).To(Equal(1))
 		})
 
 		It("errors on invalid public header", func() {
@@ -307,28 +313,101 @@ var _ = Describe("Server", func() {
 		It("ignores public resets for unknown connections", func() {
 			err := serv.handlePacket(&receivedRawPacket{rcvPconn: nil, remoteAddr: nil, data: wire.WritePublicReset(999, 1, 1337), rcvTime: time.Now()})
 			Expect(err).ToNot(HaveOccurred())
-			Expect(serv.sessions).To(BeEmpty())
+			Expect(serv.sessions.len()).To(BeZero())
 		})
 
 		It("ignores public resets for known connections", func() {
 			err := serv.handlePacket(&receivedRawPacket{rcvPconn: nil, remoteAddr: nil, data: firstPacket, rcvTime: time.Now()})
-			Expect(serv.sessions).To(HaveLen(1))
-			Expect(serv.sessions[connID].(*mockSession).packetCount).To(Equal(1))
+			Expect(serv.sessions.len()).To(Equal(1))
+			Expect(getSession(connID).(*mockSession).packetCount).To(Equal(1))
 			err = serv.handlePacket(&receivedRawPacket{rcvPconn: nil, remoteAddr: nil, data: wire.WritePublicReset(connID, 1, 1337), rcvTime: time.Now()})
 			Expect(err).ToNot(HaveOccurred())
-			Expect(serv.sessions).To(HaveLen(1))
-			Expect(serv.sessions[connID].(*mockSession).packetCount).To(Equal(1))
+			Expect(serv.sessions.len()).To(Equal(1))
+			Expect(getSession(connID).(*mockSession).packetCount).To(Equal(1))
 		})
 
 		It("ignores invalid public resets for known connections", func() {
 			err := serv.handlePacket(&receivedRawPacket{rcvPconn: nil, remoteAddr: nil, data: firstPacket, rcvTime: time.Now()})
-			Expect(serv.sessions).To(HaveLen(1))
-			Expect(serv.sessions[connID].(*mockSession).packetCount).To(Equal(1))
+			Expect(serv.sessions.len()).To(Equal(1))
+			Expect(getSession(connID).(*mockSession).packetCount).To(Equal(1))
 			data := wire.WritePublicReset(connID, 1, 1337)
 			err = serv.handlePacket(&receivedRawPacket{rcvPconn: nil, remoteAddr: nil, data: data[:len(data)-2], rcvTime: time.Now()})
 			Expect(err).ToNot(HaveOccurred())
-			Expect(serv.sessions).To(HaveLen(1))
-			Expect(serv.sessions[connID].(*mockSession).packetCount).To(Equal(1))
+			Expect(serv.sessions.len()).To(Equal(1))
+			Expect(getSession(connID).(*mockSession).packetCount).To(Equal(1))
+		})
+
+		Context("dispatching packets", func() {
+			composePacket := func(connID protocol.ConnectionID, pn protocol.PacketNumber) *receivedRawPacket {
+				hdr := wire.PublicHeader{
+					ConnectionID:    connID,
+					PacketNumber:    pn,
+					PacketNumberLen: protocol.PacketNumberLen2,
+				}
+				version := protocol.VersionWhatever
+				if pn == 1 {
+					hdr.VersionFlag = true
+					hdr.VersionNumber = protocol.SupportedVersions[0]
+					version = hdr.VersionNumber
+				}
+				b := &bytes.Buffer{}
+				Expect(hdr.Write(b, version, protocol.PerspectiveClient)).To(Succeed())
+				b.WriteByte(0x01)
+				return &receivedRawPacket{data: b.Bytes(), rcvTime: time.Now()}
+			}
+
+			It("puts the sessions of different connection IDs in different shards", func() {
+				Expect(serv.sessions.shard(connID)).To(Equal(serv.sessions.shard(connID)))
+				Expect(serv.sessions.shard(connID)).ToNot(BeIdenticalTo(serv.sessions.shard(connID + 1)))
+			})
+
+			It("handles the packets of every session in order", func() {
+				go serv.serve()
+				connIDs := []protocol.ConnectionID{connID, connID + 1, connID + 2}
+				for pn := protocol.PacketNumber(1); pn <= 100; pn++ {
+					for _, id := range connIDs {
+						serv.dispatchPacket(composePacket(id, pn))
+					}
+				}
+				for _, id := range connIDs {
+					id := id
+					Eventually(func() int {
+						if sess := getSession(id); sess != nil {
+							return sess.(*mockSession).packetCount
+						}
+						return 0
+					}).Should(Equal(100))
+					sess := getSession(id).(*mockSession)
+					for i, pn := range sess.packetNumbers {
+						Expect(pn).To(Equal(protocol.PacketNumber(i + 1)))
+					}
+				}
+			})
+
+			It("dispatches the packets received on a given pconnManager", func() {
+				go serv.serve()
+				pconnMgr.rcvRawPackets <- composePacket(connID, 1)
+				Eventually(func() packetHandler { return getSession(connID) }).ShouldNot(BeNil())
+				Eventually(func() int { return getSession(connID).(*mockSession).packetCount }).Should(Equal(1))
+			})
+
+			It("drops the packets of a full shard, without blocking the other shards", func() {
+				// the shards are not handled, so their queues fill up
+				for i := 0; i < protocol.MaxShardUnprocessedPackets+10; i++ {
+					serv.dispatchPacket(composePacket(connID, protocol.PacketNumber(i+1)))
+				}
+				Expect(serv.sessions.shard(connID).packets).To(HaveLen(protocol.MaxShardUnprocessedPackets))
+				serv.dispatchPacket(composePacket(connID+1, 1))
+				Expect(serv.sessions.shard(connID + 1).packets).To(HaveLen(1))
+			})
+
+			It("stops dispatching when encountering a connection error", func() {
+				close(serv.errorChan)
+				for i := 0; i < protocol.MaxShardUnprocessedPackets+1; i++ {
+					serv.dispatchPacket(composePacket(connID, 1))
+				}
+				Expect(serv.sessions.len()).To(BeZero())
+			})
 		})
 
 		It("doesn't respond with a version negotiation packet if the first packet is too small", func() {
@@ -361,7 +440,8 @@ var _ = Describe("Server", func() {
 		Expect(err).ToNot(HaveOccurred())
 		server := ln.(*server)
 		Expect(server.deleteClosedSessionsAfter).To(Equal(protocol.ClosedSessionDeleteTimeout))
-		Expect(server.sessions).ToNot(BeNil())
+		Expect(server.sessions.shards).To(HaveLen(protocol.NumSessionShards))
+		Expect(server.pconnMgr.dispatch).ToNot(BeNil())
 		Expect(server.scfg).ToNot(BeNil())
 		Expect(server.config.Versions).To(Equal(supportedVersions))
 		Expect(server.config.HandshakeTimeout).To(Equal(1337 * time.Hour))
@@ -449,7 +529,7 @@ var _ = Describe("Server", func() {
 		Eventually(func() int { return conn.dataWritten.Len() }).ShouldNot(BeZero())
 		Expect(conn.dataWrittenTo).To(Equal(udpAddr))
 		Expect(conn.dataWritten.Bytes()[0] & 0x02).ToNot(BeZero()) // check that the ResetFlag is set
-		Expect(ln.(*server).sessions).To(BeEmpty())
+		Expect(ln.(*server).sessions.len()).To(BeZero())
 	})
 })
 
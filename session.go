@@ -7,15 +7,18 @@ import (
 	"fmt"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/ackhandler"
 	"github.com/lucas-clemente/quic-go/congestion"
 	"github.com/lucas-clemente/quic-go/internal/flowcontrol"
 	"github.com/lucas-clemente/quic-go/internal/handshake"
+	"github.com/lucas-clemente/quic-go/internal/pcapng"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/logging"
 	"github.com/lucas-clemente/quic-go/qerr"
 )
 
@@ -29,6 +32,7 @@ type receivedPacket struct {
 	data         []byte
 	rcvTime      time.Time
 	rcvPconn     net.PacketConn
+	ecn          protocol.ECN
 }
 
 var (
@@ -41,6 +45,15 @@ var (
 	newCryptoSetupClient = handshake.NewCryptoSetupClient
 )
 
+const (
+	// timerWheelTick is the resolution of the timers of a session and of its paths
+	timerWheelTick = time.Millisecond
+	// sbdInterval is the interval at which the shared bottleneck detection samples the paths
+	sbdInterval = 350 * time.Millisecond
+	// pathsFrameInterval is the interval at which PATHS frames are sent
+	pathsFrameInterval = 200 * time.Millisecond
+)
+
 type handshakeEvent struct {
 	encLevel protocol.EncryptionLevel
 	err      error
@@ -58,9 +71,11 @@ type session struct {
 	version      protocol.VersionNumber
 	config       *Config
 
-	paths        map[protocol.PathID]*path
+	// currentPaths holds the pathTable, read it with paths()
+	currentPaths atomic.Value
 	closedPaths  map[protocol.PathID]bool
-	pathsLock    sync.RWMutex
+	// pathsChanged is notified by the path manager when paths may be created
+	pathsChanged chan struct{}
 
 	createPaths bool
 
@@ -117,16 +132,29 @@ type session struct {
 	sbdcount                int
 	//*****************
 	timer           *utils.Timer
+	// timerWheel holds the timers of the session and of its paths, the timer is set to its next deadline
+	timerWheel       *utils.TimerWheel
+	idleTimer        *utils.WheelTimer
+	handshakeTimer   *utils.WheelTimer
+	publicResetTimer *utils.WheelTimer
+	pathsFrameTimer  *utils.WheelTimer
+	sbdTimer         *utils.WheelTimer
 	// keepAlivePingSent stores whether a Ping frame was sent to the peer or not
 	// it is reset as soon as we receive a packet from the peer
 	keepAlivePingSent bool
 
-	pathTimers chan *path
+	// Requests for PathStats, answered by the run loop
+	pathStatsRequests chan chan []PathStats
 
 	pathManager         *pathManager
 	pathManagerLaunched bool
 
 	scheduler           *scheduler
+
+	// tracer is nil if the session is not traced
+	tracer logging.Tracer
+	// capture is nil if the packets are not captured
+	capture *pcapng.Writer
 }
 
 var _ Session = &session{}
@@ -143,7 +171,6 @@ func newSession(
 	config *Config,
 ) (packetHandler, <-chan handshakeEvent, error) {
 	s := &session{
-		paths:        make(map[protocol.PathID]*path),
 		closedPaths:  make(map[protocol.PathID]bool),
 		createPaths:  createPaths,
 		remoteRTTs:   make(map[protocol.PathID]time.Duration),
@@ -168,7 +195,6 @@ var newClientSession = func(
 	negotiatedVersions []protocol.VersionNumber,
 ) (packetHandler, <-chan handshakeEvent, error) {
 	s := &session{
-		paths:        make(map[protocol.PathID]*path),
 		closedPaths:  make(map[protocol.PathID]bool),
 		createPaths:  createPaths,
 		remoteRTTs:   make(map[protocol.PathID]time.Duration),
@@ -196,11 +222,19 @@ func (s *session) setup(
 	s.receivedPackets = make(chan *receivedPacket, protocol.MaxSessionUnprocessedPackets)
 	s.closeChan = make(chan closeError, 1)
 	s.sendingScheduled = make(chan struct{}, 1)
+	s.pathStatsRequests = make(chan chan []PathStats)
 	s.undecryptablePackets = make([]*receivedPacket, 0, protocol.MaxUndecryptablePackets)
 	s.ctx, s.ctxCancel = context.WithCancel(context.Background())
 
 	s.timer = utils.NewTimer()
 	now := time.Now()
+	// The paths add their timers to the wheel
+	s.timerWheel = utils.NewTimerWheel(now, timerWheelTick)
+	s.idleTimer = s.timerWheel.NewTimer(nil)
+	s.handshakeTimer = s.timerWheel.NewTimer(nil)
+	s.publicResetTimer = s.timerWheel.NewTimer(nil)
+	s.pathsFrameTimer = s.timerWheel.NewTimer(nil)
+	s.sbdTimer = s.timerWheel.NewTimer(nil)
 	s.lastNetworkActivityTime = now
 	s.sessionCreationTime = now
 	//********
@@ -217,14 +251,26 @@ func (s *session) setup(
 	s.scheduler = &scheduler{}
 	s.scheduler.setup()
 
+	// The tracer must exist before the paths are created
+	if s.config.NewTracer != nil {
+		s.tracer = s.config.NewTracer(s.perspective, s.connectionID)
+	}
+	if s.config.NewPacketCapture != nil {
+		if w := s.config.NewPacketCapture(s.perspective, s.connectionID); w != nil {
+			s.capture = pcapng.NewWriter(w, fmt.Sprintf("QUIC connection %x", s.connectionID))
+		}
+	}
+
+	s.pathsChanged = make(chan struct{}, 1)
 	if pconnMgr == nil && conn != nil {
 		// XXX ONLY VALID FOR BENCHMARK!
-		s.paths[protocol.InitialPathID] = &path{
+		pth := &path{
 			pathID: protocol.InitialPathID,
 			sess:   s,
 			conn:   conn,
 		}
-		s.paths[protocol.InitialPathID].setup(nil)
+		pth.setup(nil)
+		s.addPath(pth)
 	} else if pconnMgr != nil && conn != nil {
 		s.pathManager = &pathManager{pconnMgr: pconnMgr, sess: s}
 		s.pathManager.setup(conn)
@@ -232,11 +278,10 @@ func (s *session) setup(
 		panic("session without conn")
 	}
 	// XXX (QDC): use the PathID 0 as the session RTT path
-	s.rttStats = s.paths[protocol.InitialPathID].rttStats
+	s.rttStats = s.paths()[protocol.InitialPathID].rttStats
 	s.flowControlManager = flowcontrol.NewFlowControlManager(s.connectionParameters, s.rttStats, s.remoteRTTs)
 	s.streamsMap = newStreamsMap(s.newStream, s.perspective, s.connectionParameters)
 	s.streamFramer = newStreamFramer(s.streamsMap, s.flowControlManager)
-	s.pathTimers = make(chan *path)
 
 	var err error
 	if s.perspective == protocol.PerspectiveServer {
@@ -257,7 +302,7 @@ func (s *session) setup(
 		} else {
 			s.cryptoSetup, err = newCryptoSetup(
 				s.connectionID,
-				s.paths[protocol.InitialPathID].conn.RemoteAddr(),
+				s.paths()[protocol.InitialPathID].conn.RemoteAddr(),
 				s.version,
 				scfg,
 				cryptoStream,
@@ -320,21 +365,11 @@ func (s *session) run() error {
 	var closeErr closeError
 	aeadChanged := s.aeadChanged
 
-	var timerPth *path
-
 runLoop:
 	for {
 		// Close immediately if requested
 		select {
 		case closeErr = <-s.closeChan:
-			s.pathsLock.RLock()
-			for _, pth := range s.paths {
-				select {
-				case pth.closeChan <- nil:
-				default:
-				}
-			}
-			s.pathsLock.RUnlock()
 			break runLoop
 		default:
 		}
@@ -357,10 +392,13 @@ runLoop:
 		case <-s.sendingScheduled:
 			// We do all the interesting stuff after the switch statement, so
 			// nothing to see here.
-		case tmpPth := <-s.pathTimers:
-			timerPth = tmpPth
-			// We do all the interesting stuff after the switch statement, so
-			// nothing to see here.
+		case resp := <-s.pathStatsRequests:
+			resp <- s.getPathStats()
+			continue
+		case <-s.pathsChanged:
+			if s.createPaths {
+				s.pathManager.createPaths()
+			}
 		case p := <-s.receivedPackets:
 			err := s.handlePacketImpl(p)
 			if err != nil {
@@ -387,36 +425,41 @@ runLoop:
 		}
 
 		now := time.Now()
-		if timerPth != nil {
-			if timeout := timerPth.sentPacketHandler.GetAlarmTimeout(); !timeout.IsZero() && timeout.Before(now) {
-				// This could cause packets to be retransmitted, so check it before trying
-				// to send packets.
-				timerPth.sentPacketHandler.OnAlarm()
-			}
-			timerPth = nil
-		}
+		// The path timers could cause packets to be retransmitted, so fire them before trying to send packets.
+		s.timerWheel.Advance(now)
+
 		//******
 		sntPkts1 :=make(map[protocol.PathID]uint64)
-		if now.Sub(s.sbdBeginTime) >= 350*time.Millisecond{
+		if now.Sub(s.sbdBeginTime) >= sbdInterval{
 			s.sbdBeginTime = now
 			s.sbdcount++
 			//if s.sbdcount == 50&&s.createPaths{
-			if s.sbdcount == 50&&len(s.pathManager.oliaSenders)>=1{
+			var oliaSenders map[protocol.PathID]*congestion.OliaSender
+			if s.pathManager != nil {
+				oliaSenders = s.pathManager.oliaSenders.All()
+			}
+			// OLIA and BALIA both couple the paths sharing a bottleneck
+			sbdEstimates := s.sbdEstimates()
+			if s.sbdcount == 50&&len(sbdEstimates)>=1{
 
 				s.sbdcount = 0
 				//count从0开始，count=10说明已经观察了10个周期
 				sntPkts :=make(map[protocol.PathID]uint64)
 				sntLost :=make(map[protocol.PathID]uint64)
 				sntre :=make(map[protocol.PathID]uint64)
-				for pathID, pth := range s.paths {
-					if(pathID == 0){
+				for pathID, pth := range s.paths() {
+					// Closed paths and paths without OLIA or BALIA have no estimates
+					sbd, ok := sbdEstimates[pathID]
+					if !ok {
 						continue
 					}
 					sntPkts[pathID], sntre[pathID], sntLost[pathID] = pth.sentPacketHandler.GetStatistics()
-					s.pathManager.oliaSenders[pathID].Olia.SBD.Pac_ack[0]=s.pathManager.oliaSenders[pathID].Olia.SBD.Pac_ack[1]
-					s.pathManager.oliaSenders[pathID].Olia.SBD.Pac_ack[1]=sntPkts[pathID]
-					s.pathManager.oliaSenders[pathID].Olia.SBD.Pac_loss1[0]=s.pathManager.oliaSenders[pathID].Olia.SBD.Pac_loss1[1]
-					s.pathManager.oliaSenders[pathID].Olia.SBD.Pac_loss1[1]=sntLost[pathID]
+					sbd.Pac_ack[0]=sbd.Pac_ack[1]
+					sbd.Pac_ack[1]=sntPkts[pathID]
+					sbd.Pac_loss1[0]=sbd.Pac_loss1[1]
+					sbd.Pac_loss1[1]=sntLost[pathID]
+					sbd.Pac_ce[0]=sbd.Pac_ce[1]
+					sbd.Pac_ce[1]=pth.sentPacketHandler.GetCongestionState().ECNCEMarks
 					utils.Infof("Path %x: sent %d  lost %d;", pathID, sntPkts[pathID],sntLost[pathID])
 					//fmt.Println(sntPkts[pathID])
 				}
@@ -427,7 +470,7 @@ runLoop:
 				//	os.Olia.SBD.Pac_loss1[1] = sntLost[pathID]
 				//	//utils.Infof("Path %x: sent %d lost %d;", pathID, os.Olia.SBD.Pac_ack, os.Olia.SBD.Pac_loss1)
 				//}
-				for _,os :=range s.pathManager.oliaSenders{
+				for _,os :=range oliaSenders{
 					os.Clearset()
 					//此函数负责将之前的set集合清空
 					os.CalculateParameter()
@@ -436,18 +479,27 @@ runLoop:
 					//此函数进行决策
 					break
 				}
+				if s.tracer != nil {
+					s.traceSBDDecisions(oliaSBDGroups(oliaSenders))
+				}
+				if s.pathManager != nil && len(s.pathManager.baliaSenders.All()) > 0 {
+					groups := congestion.BaliaSbdDecision(s.pathManager.baliaSenders)
+					if s.tracer != nil {
+						s.traceSBDDecisions(groups)
+					}
+				}
 			}
-			if s.sbdcount == 50&&len(s.paths)==1{
+			if s.sbdcount == 50&&len(s.paths())==1{
 			{
 				  s.sbdcount = 0
-					a, _, _ := s.paths[0].sentPacketHandler.GetStatistics()
+					a, _, _ := s.paths()[0].sentPacketHandler.GetStatistics()
 					utils.Infof("Path %x: sent %d;", 0, a-sntPkts1[0])
 					sntPkts1[0]=a
 					//fmt.Println(sntPkts[pathID])
 				}
 						}
-			for _,os :=range s.pathManager.oliaSenders{
-				os.Olia.SBD.Sbdcount=s.sbdcount
+			for _, sbd := range sbdEstimates {
+				sbd.Sbdcount = s.sbdcount
 			}
 		}
 
@@ -455,19 +507,17 @@ runLoop:
 		if !s.pathManagerLaunched && s.handshakeComplete {
 			// XXX (QDC): for benchmark tests
 			if s.pathManager != nil {
-				s.pathManager.handshakeCompleted <- struct{}{}
 				s.pathManagerLaunched = true
+				s.launchPathManager()
 			}
 		}
 
 		if s.config.KeepAlive && s.handshakeComplete && time.Since(s.lastNetworkActivityTime) >= s.idleTimeout()/2 {
 			// send the PING frame since there is no activity in the session
-			s.pathsLock.RLock()
 			// XXX (QDC): send PING over all paths, but is it really needed/useful?
-			for _, tmpPth := range s.paths {
+			for _, tmpPth := range s.paths() {
 				s.packer.QueueControlFrame(&wire.PingFrame{}, tmpPth)
 			}
-			s.pathsLock.RUnlock()
 			s.keepAlivePingSent = true
 		}
 
@@ -484,8 +534,8 @@ runLoop:
 			s.closeLocal(qerr.Error(qerr.NetworkIdleTimeout, "No recent network activity."))
 		}
 
-		// Check if we should send a PATHS frame (currently hardcoded at 200 ms) only when at least one stream is open (not counting streams 1 and 3 never closed...)
-		if s.handshakeComplete && s.version >= protocol.VersionMP && now.Sub(s.lastPathsFrameSent) >= 200 * time.Millisecond && len(s.streamsMap.openStreams) > 2 {
+		// Check if we should send a PATHS frame
+		if s.shouldSendPathsFrame() && now.Sub(s.lastPathsFrameSent) >= pathsFrameInterval {
 			s.schedulePathsFrame()
 		}
 
@@ -499,34 +549,83 @@ runLoop:
 		s.handshakeChan <- handshakeEvent{err: closeErr.err}
 	}
 	s.handleCloseError(closeErr)
+	if s.tracer != nil {
+		s.tracer.Close()
+	}
+	if s.capture != nil {
+		if err := s.capture.Close(); err != nil {
+			utils.Errorf("Closing the packet capture failed: %s", err)
+		}
+	}
 	defer s.ctxCancel()
 	return closeErr.err
 }
 
 
+// launchPathManager creates the paths once the handshake is complete,
+// and lets the path manager notify the session when they change
+func (s *session) launchPathManager() {
+	if s.createPaths {
+		if err := s.pathManager.createPaths(); err != nil {
+			s.pathManager.closePaths()
+			return
+		}
+	}
+	s.pathManager.handshakeCompleted <- struct{}{}
+}
+
 func (s *session) Context() context.Context {
 	return s.ctx
 }
 
+// maybeResetTimer sets the timers of the session and of its paths, and the timer of the run loop to the first deadline
 func (s *session) maybeResetTimer() {
-	var deadline time.Time
 	if s.config.KeepAlive && s.handshakeComplete && !s.keepAlivePingSent {
-		deadline = s.lastNetworkActivityTime.Add(s.idleTimeout() / 2)
+		s.idleTimer.Reset(s.lastNetworkActivityTime.Add(s.idleTimeout() / 2))
 	} else {
-		deadline = s.lastNetworkActivityTime.Add(s.idleTimeout())
+		s.idleTimer.Reset(s.lastNetworkActivityTime.Add(s.idleTimeout()))
 	}
 
 	if !s.handshakeComplete {
-		handshakeDeadline := s.sessionCreationTime.Add(s.config.HandshakeTimeout)
-		deadline = utils.MinTime(deadline, handshakeDeadline)
+		s.handshakeTimer.Reset(s.sessionCreationTime.Add(s.config.HandshakeTimeout))
+	} else {
+		s.handshakeTimer.Stop()
 	}
 	if !s.receivedTooManyUndecrytablePacketsTime.IsZero() {
-		deadline = utils.MinTime(deadline, s.receivedTooManyUndecrytablePacketsTime.Add(protocol.PublicResetTimeout))
+		s.publicResetTimer.Reset(s.receivedTooManyUndecrytablePacketsTime.Add(protocol.PublicResetTimeout))
+	} else {
+		s.publicResetTimer.Stop()
+	}
+	if s.shouldSendPathsFrame() {
+		s.pathsFrameTimer.Reset(s.lastPathsFrameSent.Add(pathsFrameInterval))
+	} else {
+		s.pathsFrameTimer.Stop()
+	}
+	s.sbdTimer.Reset(s.sbdBeginTime.Add(sbdInterval))
+
+	now := time.Now()
+	for _, pth := range s.paths() {
+		pth.maybeResetTimer(now)
+	}
+
+	// The idle timer is always set
+	deadline := s.timerWheel.NextDeadline()
+	// Wake up when paced paths can send again, more precisely than the ticks of the wheel
+	for _, pth := range s.paths() {
+		if delay := pth.sentPacketHandler.TimeUntilSend(); delay > 0 {
+			deadline = utils.MinTime(deadline, now.Add(delay))
+		}
 	}
 
 	s.timer.Reset(deadline)
 }
 
+// shouldSendPathsFrame returns true if PATHS frames are sent periodically, only when at least one stream is open
+// (not counting streams 1 and 3 never closed...)
+func (s *session) shouldSendPathsFrame() bool {
+	return s.handshakeComplete && s.version >= protocol.VersionMP && s.streamsMap.NumOpenStreams() > 2
+}
+
 func (s *session) idleTimeout() time.Duration {
 	return s.connectionParameters.GetIdleConnectionStateLifetime()
 }
@@ -552,7 +651,7 @@ func (s *session) handlePacketImpl(p *receivedPacket) error {
 	var ok  bool
 	var err error
 
-	pth, ok = s.paths[p.publicHeader.PathID]
+	pth, ok = s.paths()[p.publicHeader.PathID]
 	if !ok {
 		// It's a new path initiated from remote host
 		pth, err = s.pathManager.createPathFromRemote(p)
@@ -596,15 +695,13 @@ func (s *session) handleFrames(fs []wire.Frame, p *path) error {
 			s.handleClosePathFrame(frame)
 		case *wire.PathsFrame:
 			// So far, do nothing
-			s.pathsLock.RLock()
 			for i := 0; i < int(frame.NumPaths); i++ {
 				s.remoteRTTs[frame.PathIDs[i]] = frame.RemoteRTTs[i]
 				if frame.RemoteRTTs[i] >= 30 * time.Minute {
 					// Path is potentially failed
-					s.paths[frame.PathIDs[i]].potentiallyFailed.Set(true)
+					s.paths()[frame.PathIDs[i]].setPotentiallyFailed(true)
 				}
 			}
-			s.pathsLock.RUnlock()
 		default:
 			return errors.New("Session BUG: unexpected frame type")
 		}
@@ -652,14 +749,12 @@ func (s *session) handleStreamFrame(frame *wire.StreamFrame) error {
 	if frame.FinBit {
 		// Receiving end of stream, print stats about it
 		// Print client statistics about its paths
-		s.pathsLock.RLock()
 		utils.Infof("Info for stream %x of %x", frame.StreamID, s.connectionID)
-		for pathID, pth := range s.paths {
+		for pathID, pth := range s.paths() {
 			sntPkts, sntRetrans, sntLost := pth.sentPacketHandler.GetStatistics()
 			rcvPkts := pth.receivedPacketHandler.GetStatistics()
 			utils.Infof("Path %x: sent %d retrans %d lost %d; rcv %d", pathID, sntPkts, sntRetrans, sntLost, rcvPkts)
 		}
-		s.pathsLock.RUnlock()
 	}
 	return str.AddStreamFrame(frame)
 }
@@ -692,7 +787,7 @@ func (s *session) handleRstStreamFrame(frame *wire.RstStreamFrame) error {
 }
 
 func (s *session) handleAckFrame(frame *wire.AckFrame) error {
-	pth := s.paths[frame.PathID]
+	pth := s.paths()[frame.PathID]
 
 	err := pth.sentPacketHandler.ReceivedAck(frame, pth.lastRcvdPacketNumber, pth.lastNetworkActivityTime,s.sbdcount)
 
@@ -708,16 +803,13 @@ func (s *session) handleClosePathFrame(frame *wire.ClosePathFrame) error {
 		return err
 	}
 	// This is safe because closePath checks this
-	pth := s.paths[frame.PathID]
+	pth := s.paths()[frame.PathID]
 	// This allows the host to retransmit packets sent on this path that were not acked by the ClosePath frame
 	return pth.sentPacketHandler.ReceivedClosePath(frame, pth.lastRcvdPacketNumber, pth.lastNetworkActivityTime)
 }
 
 func (s *session) closePath(pthID protocol.PathID, sendClosePathFrame bool) error {
-	s.pathsLock.RLock()
-	defer s.pathsLock.RUnlock()
-
-	pth, ok := s.paths[pthID]
+	pth, ok := s.paths()[pthID]
 	if !ok {
 		return errors.New("Unknown path ID to close")
 	}
@@ -756,23 +848,12 @@ func (s *session) closePaths() {
 		s.pathManager.closePaths()
 		if s.pathManager.pconnMgr == nil {
 			// XXX For tests
-			s.paths[0].conn.Close()
+			s.paths()[0].conn.Close()
 		}
 	} else {
-		s.pathsLock.RLock()
-		for _, pth := range s.paths {
-			select {
-			case pth.closeChan<-nil:
-			default:
-				// Don't block
-			}
+		for _, pth := range s.paths() {
+			pth.close()
 		}
-		s.pathsLock.RUnlock()
-	}
-
-	// wait for the run loops of path to finish
-	for _, pth := range s.paths {
-		<-pth.runClosed
 	}
 }
 
@@ -830,7 +911,7 @@ func (s *session) handleCloseError(closeErr closeError) error {
 		quicErr == handshake.ErrHOLExperiment ||
 		quicErr == handshake.ErrNSTPExperiment {
 		// XXX seems reasonable to send public reset on path ID 0, but this can change
-		return s.sendPublicReset(s.paths[0].lastRcvdPacketNumber)
+		return s.sendPublicReset(s.paths()[0].lastRcvdPacketNumber)
 	}
 	return s.sendConnectionClose(quicErr)
 }
@@ -840,34 +921,55 @@ func (s *session) sendPacket() error {
 }
 
 func (s *session) sendPackedPacket(packet *packedPacket, pth *path) error {
-	defer putPacketBuffer(packet.raw)
-	err := pth.sentPacketHandler.SentPacket(&ackhandler.Packet{
+	if err := s.queuePackedPacket(packet, pth); err != nil {
+		return err
+	}
+	return pth.conn.Flush()
+}
+
+// queuePackedPacket is like sendPackedPacket, but the packet is only written when the connection of the path is flushed.
+// It's used for bursts of packets, which are then written with a single syscall.
+func (s *session) queuePackedPacket(packet *packedPacket, pth *path) error {
+	ecn, err := s.onPackedPacketSent(packet, pth)
+	if err != nil {
+		putPacketBuffer(packet.raw)
+		return err
+	}
+	return pth.conn.Queue(packet.raw, ecn)
+}
+
+// onPackedPacketSent registers a packet in the sent packet handler of its path, just before it's written.
+// It returns the ECN codepoint the packet must be sent with.
+func (s *session) onPackedPacketSent(packet *packedPacket, pth *path) (protocol.ECN, error) {
+	p := &ackhandler.Packet{
 		PacketNumber:    packet.number,
 		Frames:          packet.frames,
 		Length:          protocol.ByteCount(len(packet.raw)),
 		EncryptionLevel: packet.encryptionLevel,
-	})
-	if err != nil {
-		return err
 	}
-	pth.sentPacket<-struct{}{}
+	if err := pth.sentPacketHandler.SentPacket(p); err != nil {
+		return protocol.ECNNon, err
+	}
 
 	s.logPacket(packet, pth.pathID)
-	return pth.conn.Write(packet.raw)
+	s.captureSentPacket(pth, packet.raw)
+	return p.ECN, nil
 }
 
 func (s *session) sendConnectionClose(quicErr *qerr.QuicError) error {
-	s.paths[0].SetLeastUnacked(s.paths[0].sentPacketHandler.GetLeastUnacked())
+	// XXX (QDC): seems reasonable to send on pathID 0, but this can change
+	pth := s.paths()[protocol.InitialPathID]
+	pth.SetLeastUnacked(pth.sentPacketHandler.GetLeastUnacked())
 	packet, err := s.packer.PackConnectionClose(&wire.ConnectionCloseFrame{
 		ErrorCode:    quicErr.ErrorCode,
 		ReasonPhrase: quicErr.ErrorMessage,
-	}, s.paths[0])
+	}, pth)
 	if err != nil {
 		return err
 	}
 	s.logPacket(packet, protocol.InitialPathID)
-	// XXX (QDC): seems reasonable to send on pathID 0, but this can change
-	return s.paths[protocol.InitialPathID].conn.Write(packet.raw)
+	s.captureSentPacket(pth, packet.raw)
+	return pth.conn.Write(packet.raw)
 }
 
 func (s *session) sendPing(pth *path) error {
@@ -882,6 +984,9 @@ func (s *session) sendPing(pth *path) error {
 }
 
 func (s *session) logPacket(packet *packedPacket, pathID protocol.PathID) {
+	if s.tracer != nil {
+		s.tracer.SentPacket(pathID, packet.number, protocol.ByteCount(len(packet.raw)), packet.frames)
+	}
 	if !utils.Debug() {
 		// We don't need to allocate the slices for calling the format functions
 		return
@@ -903,6 +1008,15 @@ func (s *session) GetOrOpenStream(id protocol.StreamID) (Stream, error) {
 	return nil, err
 }
 
+// GetStream returns an open stream, or nil if no stream with the provided ID is open. It never opens a stream.
+func (s *session) GetStream(id protocol.StreamID) Stream {
+	if str := s.streamsMap.GetStream(id); str != nil {
+		return str
+	}
+	// make sure to return an actual nil value here, not an Stream with value nil
+	return nil
+}
+
 // AcceptStream returns the next stream openend by the peer
 func (s *session) AcceptStream() (Stream, error) {
 	return s.streamsMap.AcceptStream()
@@ -921,11 +1035,12 @@ func (s *session) WaitUntilHandshakeComplete() error {
 	return <-s.handshakeCompleteChan
 }
 
-func (s *session) queueResetStreamFrame(id protocol.StreamID, offset protocol.ByteCount) {
+func (s *session) queueResetStreamFrame(id protocol.StreamID, offset protocol.ByteCount, errorCode uint32) {
 	s.packer.QueueControlFrame(&wire.RstStreamFrame{
 		StreamID:   id,
+		ErrorCode:  errorCode,
 		ByteOffset: offset,
-	}, s.paths[protocol.InitialPathID])
+	}, s.paths()[protocol.InitialPathID])
 	s.scheduleSending()
 }
 
@@ -958,7 +1073,17 @@ func (s *session) garbageCollectStreams() {
 func (s *session) sendPublicReset(rejectedPacketNumber protocol.PacketNumber) error {
 	utils.Infof("Sending public reset for connection %x, packet number %d", s.connectionID, rejectedPacketNumber)
 	// XXX: seems reasonable to send on the pathID 0, but this can change
-	return s.paths[protocol.InitialPathID].conn.Write(wire.WritePublicReset(s.connectionID, rejectedPacketNumber, 0))
+	raw := wire.WritePublicReset(s.connectionID, rejectedPacketNumber, 0)
+	pth := s.paths()[protocol.InitialPathID]
+	s.captureSentPacket(pth, raw)
+	return pth.conn.Write(raw)
+}
+
+// captureSentPacket writes a datagram sent on a path to the packet capture
+func (s *session) captureSentPacket(pth *path, raw []byte) {
+	if s.capture != nil {
+		s.capture.WritePacket(pth.pathID, time.Now(), pth.conn.LocalAddr(), pth.conn.RemoteAddr(), raw, true)
+	}
 }
 
 // scheduleSending signals that we have data for sending
@@ -1005,13 +1130,105 @@ func (s *session) getWindowUpdateFrames(force bool) []*wire.WindowUpdateFrame {
 
 func (s *session) LocalAddr() net.Addr {
 	// XXX (QDC): do it like with MPTCP (master initial path), what if it is closed?
-	return s.paths[0].conn.LocalAddr()
+	return s.paths()[0].conn.LocalAddr()
 }
 
 // RemoteAddr returns the net.Addr of the client
 func (s *session) RemoteAddr() net.Addr {
 	// XXX (QDC): do it like with MPTCP (master initial path), what if it is closed?
-	return s.paths[0].conn.RemoteAddr()
+	return s.paths()[0].conn.RemoteAddr()
+}
+
+// PathStats returns statistics about the paths of the session
+func (s *session) PathStats() []PathStats {
+	// Path statistics are only consistent when read by the run loop
+	resp := make(chan []PathStats, 1)
+	select {
+	case s.pathStatsRequests <- resp:
+	case <-s.ctx.Done():
+		return nil
+	}
+	return <-resp
+}
+
+func (s *session) getPathStats() []PathStats {
+	now := time.Now()
+	paths := s.paths()
+	stats := make([]PathStats, 0, len(paths))
+	for pathID, pth := range paths {
+		sntPkts, sntRetrans, sntLost := pth.sentPacketHandler.GetStatistics()
+		cong := pth.sentPacketHandler.GetCongestionState()
+		st := PathStats{
+			PathID:            pathID,
+			LocalAddr:         pth.conn.LocalAddr(),
+			RemoteAddr:        pth.conn.RemoteAddr(),
+			Interface:         pth.ifaceName,
+			Open:              pth.open.Get(),
+			PotentiallyFailed: pth.potentiallyFailed.Get(),
+			Backup:            pth.backup.Get(),
+			PacketsSent:       sntPkts,
+			Retransmissions:   sntRetrans,
+			Losses:            sntLost,
+			BytesSent:         pth.sentPacketHandler.GetBytesSent(),
+
+			CongestionWindow:   cong.CongestionWindow,
+			SlowStartThreshold: cong.SlowStartThreshold,
+			BytesInFlight:      cong.BytesInFlight,
+			InRecovery:         cong.InRecovery,
+			InSlowStart:        cong.InSlowStart,
+			BandwidthEstimate:  cong.BandwidthEstimate,
+			MinRTT:             pth.rttStats.MinRTT(),
+			LatestRTT:          pth.rttStats.LatestRTT(),
+			SmoothedRTT:        pth.rttStats.SmoothedRTT(),
+			RTTVar:             pth.rttStats.MeanDeviation(),
+			TLPCount:           cong.TLPCount,
+			RTOCount:           cong.RTOCount,
+			Olia:               cong.Olia,
+			ECN:                cong.ECN,
+			ECNCEMarks:         cong.ECNCEMarks,
+		}
+		if pth.budget != nil {
+			st.CostPerMB = pth.budget.cost.CostPerMB
+			st.BudgetExhausted = pth.budget.exhausted(now)
+		}
+		stats = append(stats, st)
+	}
+	return stats
+}
+
+// sbdEstimates returns the SBD estimates of the paths whose sender couples the paths sharing a bottleneck
+func (s *session) sbdEstimates() map[protocol.PathID]*congestion.Sbd {
+	estimates := make(map[protocol.PathID]*congestion.Sbd)
+	if s.pathManager == nil {
+		return estimates
+	}
+	for pathID, os := range s.pathManager.oliaSenders.All() {
+		estimates[pathID] = &os.Olia.SBD
+	}
+	for pathID, bs := range s.pathManager.baliaSenders.All() {
+		estimates[pathID] = &bs.(*congestion.BaliaSender).SBD
+	}
+	return estimates
+}
+
+// oliaSBDGroups returns the paths sharing the bottleneck of every path, as decided by the SBD of OLIA
+func oliaSBDGroups(oliaSenders map[protocol.PathID]*congestion.OliaSender) map[protocol.PathID][]protocol.PathID {
+	groups := make(map[protocol.PathID][]protocol.PathID, len(oliaSenders))
+	for pathID, os := range oliaSenders {
+		sharedBottleneck := make([]protocol.PathID, 0, len(os.Sbd_set.Set))
+		for pathID1 := range os.Sbd_set.Set {
+			sharedBottleneck = append(sharedBottleneck, pathID1)
+		}
+		groups[pathID] = sharedBottleneck
+	}
+	return groups
+}
+
+// traceSBDDecisions traces the shared bottleneck of every path, as decided by the SBD
+func (s *session) traceSBDDecisions(groups map[protocol.PathID][]protocol.PathID) {
+	for pathID, sharedBottleneck := range groups {
+		s.tracer.SBDDecision(pathID, sharedBottleneck)
+	}
 }
 
 func (s *session) GetVersion() protocol.VersionNumber {
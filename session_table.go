@@ -0,0 +1,86 @@
+package quic
+
+import (
+	"sync"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// sessionTable holds the sessions of a server, sharded by connection ID.
+// Every shard has its own lock and its own queue of received packets, which is handled by a single goroutine.
+// The packets of different shards are handled in parallel, while the packets of a session stay in order.
+type sessionTable struct {
+	shards []sessionShard
+}
+
+type sessionShard struct {
+	mutex sync.RWMutex
+	// A nil session is a session that was closed recently, its late packets are ignored
+	sessions map[protocol.ConnectionID]packetHandler
+
+	packets chan *receivedRawPacket
+}
+
+func newSessionTable(numShards int) *sessionTable {
+	t := &sessionTable{shards: make([]sessionShard, numShards)}
+	for i := range t.shards {
+		t.shards[i].sessions = make(map[protocol.ConnectionID]packetHandler)
+		t.shards[i].packets = make(chan *receivedRawPacket, protocol.MaxShardUnprocessedPackets)
+	}
+	return t
+}
+
+// shard returns the shard holding the session of a connection ID
+func (t *sessionTable) shard(id protocol.ConnectionID) *sessionShard {
+	return &t.shards[uint64(id)%uint64(len(t.shards))]
+}
+
+func (t *sessionTable) get(id protocol.ConnectionID) (packetHandler, bool) {
+	shard := t.shard(id)
+	shard.mutex.RLock()
+	session, ok := shard.sessions[id]
+	shard.mutex.RUnlock()
+	return session, ok
+}
+
+func (t *sessionTable) set(id protocol.ConnectionID, session packetHandler) {
+	shard := t.shard(id)
+	shard.mutex.Lock()
+	shard.sessions[id] = session
+	shard.mutex.Unlock()
+}
+
+func (t *sessionTable) delete(id protocol.ConnectionID) {
+	shard := t.shard(id)
+	shard.mutex.Lock()
+	delete(shard.sessions, id)
+	shard.mutex.Unlock()
+}
+
+// len returns the number of sessions, including the closed ones that were not deleted yet
+func (t *sessionTable) len() int {
+	var n int
+	for i := range t.shards {
+		shard := &t.shards[i]
+		shard.mutex.RLock()
+		n += len(shard.sessions)
+		shard.mutex.RUnlock()
+	}
+	return n
+}
+
+// open returns the sessions that are not closed
+func (t *sessionTable) open() []packetHandler {
+	var sessions []packetHandler
+	for i := range t.shards {
+		shard := &t.shards[i]
+		shard.mutex.RLock()
+		for _, session := range shard.sessions {
+			if session != nil {
+				sessions = append(sessions, session)
+			}
+		}
+		shard.mutex.RUnlock()
+	}
+	return sessions
+}
@@ -0,0 +1,58 @@
+package quic
+
+import (
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Session table", func() {
+	var table *sessionTable
+
+	BeforeEach(func() {
+		table = newSessionTable(4)
+	})
+
+	It("is empty", func() {
+		Expect(table.len()).To(BeZero())
+		Expect(table.open()).To(BeEmpty())
+		_, ok := table.get(1)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("sets and deletes sessions", func() {
+		session := &mockSession{connectionID: 1}
+		table.set(1, session)
+		s, ok := table.get(1)
+		Expect(ok).To(BeTrue())
+		Expect(s).To(Equal(session))
+		table.delete(1)
+		_, ok = table.get(1)
+		Expect(ok).To(BeFalse())
+		Expect(table.len()).To(BeZero())
+	})
+
+	It("keeps the closed sessions, without returning them as open", func() {
+		session := &mockSession{connectionID: 2}
+		table.set(1, nil)
+		table.set(2, session)
+		s, ok := table.get(1)
+		Expect(ok).To(BeTrue())
+		Expect(s).To(BeNil())
+		Expect(table.len()).To(Equal(2))
+		Expect(table.open()).To(Equal([]packetHandler{session}))
+	})
+
+	It("spreads the sessions over the shards", func() {
+		for id := protocol.ConnectionID(100); id < 108; id++ {
+			table.set(id, &mockSession{connectionID: id})
+		}
+		Expect(table.len()).To(Equal(8))
+		Expect(table.open()).To(HaveLen(8))
+		for i := range table.shards {
+			Expect(table.shards[i].sessions).To(HaveLen(2))
+		}
+		Expect(table.shard(100)).To(BeIdenticalTo(table.shard(104)))
+	})
+})
@@ -19,9 +19,11 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/handshake"
 	"github.com/lucas-clemente/quic-go/internal/mocks"
 	"github.com/lucas-clemente/quic-go/internal/mocks/mocks_fc"
+	"github.com/lucas-clemente/quic-go/internal/pcapng"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/testdata"
 	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/logging"
 	"github.com/lucas-clemente/quic-go/qerr"
 )
 
@@ -29,6 +31,9 @@ type mockConnection struct {
 	remoteAddr net.Addr
 	localAddr  net.Addr
 	written    chan []byte
+	queued     [][]byte
+	// the ECN codepoints of the packets written
+	ecn []protocol.ECN
 }
 
 func newMockConnection() *mockConnection {
@@ -48,6 +53,22 @@ func (m *mockConnection) Write(p []byte) error {
 	}
 	return nil
 }
+func (m *mockConnection) Queue(p []byte, ecn protocol.ECN) error {
+	b := make([]byte, len(p))
+	copy(b, p)
+	m.queued = append(m.queued, b)
+	m.ecn = append(m.ecn, ecn)
+	return nil
+}
+func (m *mockConnection) Flush() error {
+	for _, p := range m.queued {
+		if err := m.Write(p); err != nil {
+			return err
+		}
+	}
+	m.queued = nil
+	return nil
+}
 func (m *mockConnection) Read([]byte) (int, net.Addr, error) { panic("not implemented") }
 
 func (m *mockConnection) SetCurrentRemoteAddr(addr net.Addr) {
@@ -76,6 +97,7 @@ type mockSentPacketHandler struct {
 	congestionLimited               bool
 	requestedStopWaiting            bool
 	shouldSendRetransmittablePacket bool
+	bytesInFlight                   protocol.ByteCount
 }
 
 func (h *mockSentPacketHandler) SentPacket(packet *ackhandler.Packet) error {
@@ -83,7 +105,7 @@ func (h *mockSentPacketHandler) SentPacket(packet *ackhandler.Packet) error {
 	return nil
 }
 
-func (h *mockSentPacketHandler) ReceivedAck(ackFrame *wire.AckFrame, withPacketNumber protocol.PacketNumber, recvTime time.Time) error {
+func (h *mockSentPacketHandler) ReceivedAck(ackFrame *wire.AckFrame, withPacketNumber protocol.PacketNumber, recvTime time.Time, count int) error {
 	return nil
 }
 
@@ -102,6 +124,23 @@ func (h *mockSentPacketHandler) ShouldSendRetransmittablePacket() bool {
 	return b
 }
 func (h *mockSentPacketHandler) GetStatistics() (uint64, uint64, uint64) { panic("not implemented") }
+func (h *mockSentPacketHandler) GetBytesSent() protocol.ByteCount {
+	var sent protocol.ByteCount
+	for _, p := range h.sentPackets {
+		sent += p.Length
+	}
+	return sent
+}
+
+func (h *mockSentPacketHandler) GetBytesInFlight() protocol.ByteCount { return h.bytesInFlight }
+func (h *mockSentPacketHandler) GetCongestionWindow() protocol.ByteCount {
+	return protocol.InitialCongestionWindow * protocol.DefaultTCPMSS
+}
+func (h *mockSentPacketHandler) GetCongestionState() ackhandler.CongestionState {
+	return ackhandler.CongestionState{CongestionWindow: h.GetCongestionWindow(), BytesInFlight: h.bytesInFlight}
+}
+func (h *mockSentPacketHandler) SetAppLimited()               {}
+func (h *mockSentPacketHandler) TimeUntilSend() time.Duration { return 0 }
 
 func (h *mockSentPacketHandler) GetStopWaitingFrame(force bool) *wire.StopWaitingFrame {
 	h.requestedStopWaiting = true
@@ -138,7 +177,7 @@ func (m *mockReceivedPacketHandler) GetAckFrame() *wire.AckFrame {
 	m.nextAckFrame = nil
 	return f
 }
-func (m *mockReceivedPacketHandler) ReceivedPacket(packetNumber protocol.PacketNumber, shouldInstigateAck bool) error {
+func (m *mockReceivedPacketHandler) ReceivedPacket(packetNumber protocol.PacketNumber, ecn protocol.ECN, shouldInstigateAck bool) error {
 	panic("not implemented")
 }
 func (m *mockReceivedPacketHandler) SetLowerLimit(protocol.PacketNumber) {
@@ -155,6 +194,33 @@ func (m *mockReceivedPacketHandler) GetClosePathFrame() *wire.ClosePathFrame {
 
 var _ ackhandler.ReceivedPacketHandler = &mockReceivedPacketHandler{}
 
+type mockTracer struct {
+	logging.Tracer
+	createdPaths []protocol.PathID
+	chosenPaths  []protocol.PathID
+	sentPackets  []protocol.PacketNumber
+	sentFrames   []wire.Frame
+}
+
+func (t *mockTracer) CreatedPath(pathID protocol.PathID, _, _ net.Addr) {
+	t.createdPaths = append(t.createdPaths, pathID)
+}
+func (t *mockTracer) ChosePath(pathID protocol.PathID) { t.chosenPaths = append(t.chosenPaths, pathID) }
+func (t *mockTracer) SentPacket(pathID protocol.PathID, pn protocol.PacketNumber, _ protocol.ByteCount, frames []wire.Frame) {
+	t.sentPackets = append(t.sentPackets, pn)
+	t.sentFrames = append(t.sentFrames, frames...)
+}
+
+type mockPacketCapture struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (c *mockPacketCapture) Close() error {
+	c.closed = true
+	return nil
+}
+
 func areSessionsRunning() bool {
 	var b bytes.Buffer
 	pprof.Lookup("goroutine").WriteTo(&b, 1)
@@ -328,6 +394,14 @@ var _ = Describe("Session", func() {
 			Expect(p).To(Equal([]byte{0xde, 0xca, 0xfb, 0xad}))
 		})
 
+		It("gets open streams without opening new ones", func() {
+			Expect(sess.GetStream(5)).To(BeNil())
+			Expect(sess.streamsMap.openStreams).ToNot(ContainElement(protocol.StreamID(5)))
+			str, err := sess.GetOrOpenStream(5)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sess.GetStream(5)).To(Equal(str))
+		})
+
 		It("does not delete streams with Close()", func() {
 			str, err := sess.GetOrOpenStream(5)
 			Expect(err).ToNot(HaveOccurred())
@@ -573,7 +647,7 @@ var _ = Describe("Session", func() {
 			err := sess.handleFrames([]wire.Frame{&wire.RstStreamFrame{
 				StreamID:  5,
 				ErrorCode: 42,
-			}}, sess.paths[0])
+			}}, sess.paths()[0])
 			Expect(err).NotTo(HaveOccurred())
 		})
 
@@ -658,32 +732,32 @@ var _ = Describe("Session", func() {
 			err = sess.handleFrames([]wire.Frame{&wire.WindowUpdateFrame{
 				StreamID:   5,
 				ByteOffset: 1337,
-			}}, sess.paths[0])
+			}}, sess.paths()[0])
 			Expect(err).NotTo(HaveOccurred())
 		})
 	})
 
 	It("handles PING frames", func() {
 		// XXX (QDC): adapted to multiple paths
-		err := sess.handleFrames([]wire.Frame{&wire.PingFrame{}}, sess.paths[0])
+		err := sess.handleFrames([]wire.Frame{&wire.PingFrame{}}, sess.paths()[0])
 		Expect(err).NotTo(HaveOccurred())
 	})
 
 	It("handles BLOCKED frames", func() {
 		// XXX (QDC): adapted to multiple paths
-		err := sess.handleFrames([]wire.Frame{&wire.BlockedFrame{}}, sess.paths[0])
+		err := sess.handleFrames([]wire.Frame{&wire.BlockedFrame{}}, sess.paths()[0])
 		Expect(err).NotTo(HaveOccurred())
 	})
 
 	It("errors on GOAWAY frames", func() {
 		// XXX (QDC): adapted to multiple paths
-		err := sess.handleFrames([]wire.Frame{&wire.GoawayFrame{}}, sess.paths[0])
+		err := sess.handleFrames([]wire.Frame{&wire.GoawayFrame{}}, sess.paths()[0])
 		Expect(err).To(MatchError("unimplemented: handling GOAWAY frames"))
 	})
 
 	It("handles STOP_WAITING frames", func() {
 		// XXX (QDC): adapted to multiple paths
-		err := sess.handleFrames([]wire.Frame{&wire.StopWaitingFrame{LeastUnacked: 10}}, sess.paths[0])
+		err := sess.handleFrames([]wire.Frame{&wire.StopWaitingFrame{LeastUnacked: 10}}, sess.paths()[0])
 		Expect(err).NotTo(HaveOccurred())
 	})
 
@@ -691,7 +765,7 @@ var _ = Describe("Session", func() {
 		go sess.run()
 		str, _ := sess.GetOrOpenStream(5)
 		// XXX (QDC): adapted to multiple paths
-		err := sess.handleFrames([]wire.Frame{&wire.ConnectionCloseFrame{ErrorCode: 42, ReasonPhrase: "foobar"}}, sess.paths[0])
+		err := sess.handleFrames([]wire.Frame{&wire.ConnectionCloseFrame{ErrorCode: 42, ReasonPhrase: "foobar"}}, sess.paths()[0])
 		Expect(err).NotTo(HaveOccurred())
 		Eventually(sess.Context().Done()).Should(BeClosed())
 		_, err = str.Read([]byte{0})
@@ -888,8 +962,19 @@ var _ = Describe("Session", func() {
 			err := sess.handlePacketImpl(&receivedPacket{publicHeader: hdr})
 			Expect(err).ToNot(HaveOccurred())
 			// XXX (QDC): adapted to multiple paths
-			Expect(sess.paths[0].lastRcvdPacketNumber).To(Equal(protocol.PacketNumber(5)))
-			Expect(sess.paths[0].largestRcvdPacketNumber).To(Equal(protocol.PacketNumber(5)))
+			Expect(sess.paths()[0].lastRcvdPacketNumber).To(Equal(protocol.PacketNumber(5)))
+			Expect(sess.paths()[0].largestRcvdPacketNumber).To(Equal(protocol.PacketNumber(5)))
+		})
+
+		It("captures the received packets before decrypting them", func() {
+			capture := &mockPacketCapture{}
+			sess.capture = pcapng.NewWriter(capture, "")
+			hdr.PacketNumber = 5
+			hdr.Raw = []byte("raw header")
+			err := sess.handlePacketImpl(&receivedPacket{publicHeader: hdr, data: []byte("encrypted")})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sess.capture.Close()).To(Succeed())
+			Expect(capture.String()).To(ContainSubstring("raw headerencrypted"))
 		})
 
 		It("closes when handling a packet fails", func(done Done) {
@@ -911,14 +996,14 @@ var _ = Describe("Session", func() {
 			err := sess.handlePacketImpl(&receivedPacket{publicHeader: hdr})
 			Expect(err).ToNot(HaveOccurred())
 			// XXX (QDC): adapted to multiple paths
-			Expect(sess.paths[0].lastRcvdPacketNumber).To(Equal(protocol.PacketNumber(5)))
-			Expect(sess.paths[0].largestRcvdPacketNumber).To(Equal(protocol.PacketNumber(5)))
+			Expect(sess.paths()[0].lastRcvdPacketNumber).To(Equal(protocol.PacketNumber(5)))
+			Expect(sess.paths()[0].largestRcvdPacketNumber).To(Equal(protocol.PacketNumber(5)))
 			hdr.PacketNumber = 3
 			err = sess.handlePacketImpl(&receivedPacket{publicHeader: hdr})
 			Expect(err).ToNot(HaveOccurred())
 			// XXX (QDC): adapted to multiple paths
-			Expect(sess.paths[0].lastRcvdPacketNumber).To(Equal(protocol.PacketNumber(3)))
-			Expect(sess.paths[0].largestRcvdPacketNumber).To(Equal(protocol.PacketNumber(5)))
+			Expect(sess.paths()[0].lastRcvdPacketNumber).To(Equal(protocol.PacketNumber(3)))
+			Expect(sess.paths()[0].largestRcvdPacketNumber).To(Equal(protocol.PacketNumber(5)))
 		})
 
 		It("handles duplicate packets", func() {
@@ -933,7 +1018,7 @@ var _ = Describe("Session", func() {
 			It("sets the remote address", func() {
 				remoteIP := &net.IPAddr{IP: net.IPv4(192, 168, 0, 100)}
 				// XXX (QDC): ugly...
-				Expect(sess.paths[0].conn.(*mockConnection).remoteAddr).ToNot(Equal(remoteIP))
+				Expect(sess.paths()[0].conn.(*mockConnection).remoteAddr).ToNot(Equal(remoteIP))
 				p := receivedPacket{
 					remoteAddr:   remoteIP,
 					publicHeader: &wire.PublicHeader{PacketNumber: 1337},
@@ -941,14 +1026,14 @@ var _ = Describe("Session", func() {
 				err := sess.handlePacketImpl(&p)
 				Expect(err).ToNot(HaveOccurred())
 				// XXX (QDC): ugly...
-				Expect(sess.paths[0].conn.(*mockConnection).remoteAddr).To(Equal(remoteIP))
+				Expect(sess.paths()[0].conn.(*mockConnection).remoteAddr).To(Equal(remoteIP))
 			})
 
 			It("doesn't change the remote address if authenticating the packet fails", func() {
 				remoteIP := &net.IPAddr{IP: net.IPv4(192, 168, 0, 100)}
 				attackerIP := &net.IPAddr{IP: net.IPv4(192, 168, 0, 102)}
 				// XXX (QDC): ugly...
-				sess.paths[0].conn.(*mockConnection).remoteAddr = remoteIP
+				sess.paths()[0].conn.(*mockConnection).remoteAddr = remoteIP
 				// use the real packetUnpacker here, to make sure this test fails if the error code for failed decryption changes
 				sess.unpacker = &packetUnpacker{}
 				sess.unpacker.(*packetUnpacker).aead = &mockAEAD{}
@@ -960,14 +1045,14 @@ var _ = Describe("Session", func() {
 				quicErr := err.(*qerr.QuicError)
 				Expect(quicErr.ErrorCode).To(Equal(qerr.DecryptionFailure))
 				// XXX (QDC): ugly...
-				Expect(sess.paths[0].conn.(*mockConnection).remoteAddr).To(Equal(remoteIP))
+				Expect(sess.paths()[0].conn.(*mockConnection).remoteAddr).To(Equal(remoteIP))
 			})
 
 			It("sets the remote address, if the packet is authenticated, but unpacking fails for another reason", func() {
 				testErr := errors.New("testErr")
 				remoteIP := &net.IPAddr{IP: net.IPv4(192, 168, 0, 100)}
 				// XXX (QDC): ugly...
-				Expect(sess.paths[0].conn.(*mockConnection).remoteAddr).ToNot(Equal(remoteIP))
+				Expect(sess.paths()[0].conn.(*mockConnection).remoteAddr).ToNot(Equal(remoteIP))
 				p := receivedPacket{
 					remoteAddr:   remoteIP,
 					publicHeader: &wire.PublicHeader{PacketNumber: 1337},
@@ -976,7 +1061,7 @@ var _ = Describe("Session", func() {
 				err := sess.handlePacketImpl(&p)
 				Expect(err).To(MatchError(testErr))
 				// XXX (QDC): ugly...
-				Expect(sess.paths[0].conn.(*mockConnection).remoteAddr).To(Equal(remoteIP))
+				Expect(sess.paths()[0].conn.(*mockConnection).remoteAddr).To(Equal(remoteIP))
 			})
 		})
 	})
@@ -985,7 +1070,7 @@ var _ = Describe("Session", func() {
 		It("sends ack frames", func() {
 			packetNumber := protocol.PacketNumber(0x035E)
 			// XXX (QDC): adapted to multiple paths
-			sess.paths[0].receivedPacketHandler.ReceivedPacket(packetNumber, true)
+			sess.paths()[0].receivedPacketHandler.ReceivedPacket(packetNumber, protocol.ECNNon, true)
 			err := sess.sendPacket()
 			Expect(err).NotTo(HaveOccurred())
 			Expect(mconn.written).To(HaveLen(1))
@@ -993,10 +1078,10 @@ var _ = Describe("Session", func() {
 		})
 
 		It("sends ACK frames when congestion limited", func() {
-			sess.paths[0].sentPacketHandler = &mockSentPacketHandler{congestionLimited: true}
-			sess.paths[0].packetNumberGenerator.next = 0x1338
+			sess.paths()[0].sentPacketHandler = &mockSentPacketHandler{congestionLimited: true}
+			sess.paths()[0].packetNumberGenerator.next = 0x1338
 			packetNumber := protocol.PacketNumber(0x035E)
-			sess.paths[0].receivedPacketHandler.ReceivedPacket(packetNumber, true)
+			sess.paths()[0].receivedPacketHandler.ReceivedPacket(packetNumber, protocol.ECNNon, true)
 			err := sess.sendPacket()
 			Expect(err).NotTo(HaveOccurred())
 			Expect(mconn.written).To(HaveLen(1))
@@ -1004,11 +1089,18 @@ var _ = Describe("Session", func() {
 		})
 
 		It("sends a retransmittable packet when required by the SentPacketHandler", func() {
-			sess.paths[0].sentPacketHandler = &mockSentPacketHandler{shouldSendRetransmittablePacket: true}
+			sess.paths()[0].sentPacketHandler = &mockSentPacketHandler{shouldSendRetransmittablePacket: true}
 			err := sess.sendPacket()
 			Expect(err).ToNot(HaveOccurred())
 			Expect(mconn.written).To(HaveLen(1))
-			Expect(sess.paths[0].sentPacketHandler.(*mockSentPacketHandler).sentPackets[0].Frames).To(ContainElement(&wire.PingFrame{}))
+			Expect(sess.paths()[0].sentPacketHandler.(*mockSentPacketHandler).sentPackets[0].Frames).To(ContainElement(&wire.PingFrame{}))
+		})
+
+		It("marks the packets with the ECN codepoint chosen by the SentPacketHandler", func() {
+			err := sess.sendPing(sess.paths()[0])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(mconn.written).To(HaveLen(1))
+			Expect(mconn.ecn).To(Equal([]protocol.ECN{protocol.ECT0}))
 		})
 
 		It("sends two WindowUpdate frames", func() {
@@ -1026,6 +1118,21 @@ var _ = Describe("Session", func() {
 			Expect(mconn.written).To(Receive(ContainSubstring(string([]byte{0x04, 0x05, 0, 0, 0}))))
 		})
 
+		It("traces the chosen path and the sent packets", func() {
+			tracer := &mockTracer{}
+			sess.tracer = tracer
+			sess.paths()[0].packetNumberGenerator.next = 0x1337
+			sess.paths()[0].receivedPacketHandler.ReceivedPacket(0x035E, protocol.ECNNon, true)
+			err := sess.sendPacket()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mconn.written).To(HaveLen(1))
+			// the path is chosen again after the ACK was sent, before noticing there is nothing left to send
+			Expect(tracer.chosenPaths).ToNot(BeEmpty())
+			Expect(tracer.chosenPaths[0]).To(Equal(protocol.PathID(0)))
+			Expect(tracer.sentPackets).To(Equal([]protocol.PacketNumber{0x1337}))
+			Expect(tracer.sentFrames).To(ContainElement(BeAssignableToTypeOf(&wire.AckFrame{})))
+		})
+
 		It("sends public reset", func() {
 			err := sess.sendPublicReset(1)
 			Expect(err).NotTo(HaveOccurred())
@@ -1033,10 +1140,19 @@ var _ = Describe("Session", func() {
 			Expect(mconn.written).To(Receive(ContainSubstring("PRST")))
 		})
 
+		It("captures the sent packets", func() {
+			capture := &mockPacketCapture{}
+			sess.capture = pcapng.NewWriter(capture, "")
+			err := sess.sendPublicReset(1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sess.capture.Close()).To(Succeed())
+			Expect(capture.String()).To(ContainSubstring("PRST"))
+		})
+
 		It("informs the SentPacketHandler about sent packets", func() {
 			// XXX (QDC): adapted to multiple paths
-			sess.paths[0].sentPacketHandler = newMockSentPacketHandler()
-			sess.paths[0].packetNumberGenerator.next = 0x1337 + 9
+			sess.paths()[0].sentPacketHandler = newMockSentPacketHandler()
+			sess.paths()[0].packetNumberGenerator.next = 0x1337 + 9
 			sess.packer.cryptoSetup = &mockCryptoSetup{encLevelSeal: protocol.EncryptionForwardSecure}
 
 			f := &wire.StreamFrame{
@@ -1050,7 +1166,7 @@ var _ = Describe("Session", func() {
 			Expect(err).NotTo(HaveOccurred())
 			Expect(mconn.written).To(HaveLen(1))
 			// XXX (QDC): adapted to multiple paths
-			sentPackets := sess.paths[0].sentPacketHandler.(*mockSentPacketHandler).sentPackets
+			sentPackets := sess.paths()[0].sentPacketHandler.(*mockSentPacketHandler).sentPackets
 			Expect(sentPackets).To(HaveLen(1))
 			Expect(sentPackets[0].Frames).To(ContainElement(f))
 			Expect(sentPackets[0].EncryptionLevel).To(Equal(protocol.EncryptionForwardSecure))
@@ -1063,10 +1179,10 @@ var _ = Describe("Session", func() {
 		var sph *mockSentPacketHandler
 		BeforeEach(func() {
 			// a StopWaitingFrame is added, so make sure the packet number of the new package is higher than the packet number of the retransmitted packet
-			sess.paths[0].packetNumberGenerator.next = 0x1337 + 10
+			sess.paths()[0].packetNumberGenerator.next = 0x1337 + 10
 			sph = newMockSentPacketHandler().(*mockSentPacketHandler)
 			// XXX (QDC): adapted to multiple paths
-			sess.paths[0].sentPacketHandler = sph
+			sess.paths()[0].sentPacketHandler = sph
 			sess.packer.cryptoSetup = &mockCryptoSetup{encLevelSeal: protocol.EncryptionForwardSecure}
 		})
 
@@ -1258,10 +1374,10 @@ var _ = Describe("Session", func() {
 		rtt := time.Millisecond
 		sess.rttStats.UpdateRTT(rtt, 0, time.Now())
 		Expect(sess.rttStats.SmoothedRTT()).To(Equal(rtt)) // make sure it worked
-		sess.paths[0].packetNumberGenerator.next = n + 1
+		sess.paths()[0].packetNumberGenerator.next = n + 1
 		// Now, we send a single packet, and expect that it was retransmitted later
 		// XXX (QDC): adapted to multiple paths
-		err := sess.paths[0].sentPacketHandler.SentPacket(&ackhandler.Packet{
+		err := sess.paths()[0].sentPacketHandler.SentPacket(&ackhandler.Packet{
 			PacketNumber: n,
 			Length:       1,
 			Frames: []wire.Frame{&wire.StreamFrame{
@@ -1272,8 +1388,6 @@ var _ = Describe("Session", func() {
 		Expect(err).NotTo(HaveOccurred())
 		go sess.run()
 		defer sess.Close(nil)
-		// XXX (QDC) actually this test is ill suited with multipath...
-		sess.paths[0].maybeResetTimer()
 		sess.scheduleSending()
 		Eventually(func() int { return len(mconn.written) }).ShouldNot(BeZero())
 		Expect(mconn.written).To(Receive(ContainSubstring("foobar")))
@@ -1300,7 +1414,7 @@ var _ = Describe("Session", func() {
 			rph := &mockReceivedPacketHandler{ackAlarm: time.Now().Add(10 * time.Millisecond)}
 			rph.nextAckFrame = &wire.AckFrame{LargestAcked: 0x1337}
 			// XXX (QDC): adapted to multiple paths
-			sess.paths[0].receivedPacketHandler = rph
+			sess.paths()[0].receivedPacketHandler = rph
 			go sess.run()
 			defer sess.Close(nil)
 			time.Sleep(10 * time.Millisecond)
@@ -1361,7 +1475,7 @@ var _ = Describe("Session", func() {
 			It("sends a queued ACK frame only once", func() {
 				packetNumber := protocol.PacketNumber(0x1337)
 				// XXX (QDC): adapted to multiple paths
-				sess.paths[0].receivedPacketHandler.ReceivedPacket(packetNumber, true)
+				sess.paths()[0].receivedPacketHandler.ReceivedPacket(packetNumber, protocol.ECNNon, true)
 
 				s, err := sess.GetOrOpenStream(5)
 				Expect(err).NotTo(HaveOccurred())
@@ -1674,17 +1788,17 @@ var _ = Describe("Session", func() {
 	Context("ignoring errors", func() {
 		It("ignores duplicate acks", func() {
 			// XXX (QDC): adapted to multiple paths
-			sess.paths[0].sentPacketHandler.SentPacket(&ackhandler.Packet{
+			sess.paths()[0].sentPacketHandler.SentPacket(&ackhandler.Packet{
 				PacketNumber: 1,
 				Length:       1,
 			})
 			err := sess.handleFrames([]wire.Frame{&wire.AckFrame{
 				LargestAcked: 1,
-			}}, sess.paths[0])
+			}}, sess.paths()[0])
 			Expect(err).NotTo(HaveOccurred())
 			err = sess.handleFrames([]wire.Frame{&wire.AckFrame{
 				LargestAcked: 1,
-			}}, sess.paths[0])
+			}}, sess.paths()[0])
 			Expect(err).NotTo(HaveOccurred())
 		})
 	})
@@ -1722,6 +1836,61 @@ var _ = Describe("Session", func() {
 		mconn.remoteAddr = addr
 		Expect(sess.RemoteAddr()).To(Equal(addr))
 	})
+
+	It("creates the tracer before the paths", func() {
+		tracer := &mockTracer{}
+		var perspective protocol.Perspective
+		var connID protocol.ConnectionID
+		config := populateServerConfig(&Config{})
+		config.NewTracer = func(p logging.Perspective, c logging.ConnectionID) logging.Tracer {
+			perspective = p
+			connID = c
+			return tracer
+		}
+		pSess, _, err := newSession(mconn, nil, true, protocol.Version37, 0x1337, scfg, nil, config)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pSess.(*session).tracer).To(BeIdenticalTo(tracer))
+		Expect(perspective).To(Equal(protocol.PerspectiveServer))
+		Expect(connID).To(Equal(protocol.ConnectionID(0x1337)))
+		Expect(tracer.createdPaths).To(Equal([]protocol.PathID{0}))
+	})
+
+	It("creates the packet capture and closes it with the session", func() {
+		capture := &mockPacketCapture{}
+		config := populateServerConfig(&Config{})
+		config.NewPacketCapture = func(logging.Perspective, logging.ConnectionID) io.WriteCloser {
+			return capture
+		}
+		pSess, _, err := newSession(mconn, nil, true, protocol.Version37, 0x1337, scfg, nil, config)
+		Expect(err).NotTo(HaveOccurred())
+		s := pSess.(*session)
+		Expect(s.capture).ToNot(BeNil())
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			s.run()
+			close(done)
+		}()
+		Expect(s.Close(nil)).To(Succeed())
+		Eventually(done).Should(BeClosed())
+		Expect(capture.closed).To(BeTrue())
+		Expect(capture.String()).To(ContainSubstring("QUIC connection 1337"))
+	})
+
+	It("returns the congestion state of the paths", func() {
+		sess.paths()[0].rttStats.UpdateRTT(100*time.Millisecond, 0, time.Now())
+		stats := sess.getPathStats()
+		Expect(stats).To(HaveLen(1))
+		Expect(stats[0].CongestionWindow).To(Equal(protocol.InitialCongestionWindow * protocol.DefaultTCPMSS))
+		Expect(stats[0].InSlowStart).To(BeTrue())
+		Expect(stats[0].MinRTT).To(Equal(100 * time.Millisecond))
+		Expect(stats[0].LatestRTT).To(Equal(100 * time.Millisecond))
+		Expect(stats[0].SmoothedRTT).To(Equal(100 * time.Millisecond))
+		Expect(stats[0].RTTVar).To(Equal(50 * time.Millisecond))
+		Expect(stats[0].Olia).To(BeNil())
+		Expect(stats[0].ECN).To(Equal(ackhandler.ECNStateTesting))
+		Expect(stats[0].ECNCEMarks).To(BeZero())
+	})
 })
 
 var _ = Describe("Client Session", func() {
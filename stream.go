@@ -2,6 +2,7 @@ package quic
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -26,7 +27,7 @@ type stream struct {
 	streamID protocol.StreamID
 	onData   func()
 	// onReset is a callback that should send a RST_STREAM
-	onReset func(protocol.StreamID, protocol.ByteCount)
+	onReset func(protocol.StreamID, protocol.ByteCount, uint32)
 
 	readPosInFrame int
 	writeOffset    protocol.ByteCount
@@ -56,6 +57,16 @@ type stream struct {
 	writeChan      chan struct{}
 	writeDeadline  time.Time
 
+	deliveryDeadline time.Duration
+	// Time by which dataForWriting must be delivered, zero if there is no deadline
+	dataDeadline time.Time
+
+	pathPreference PathPreference
+	priority       StreamPriority
+	// Weighted amount of data sent, used by the streamsMap to share the capacity between streams
+	// Only accessed while holding the lock of the streamsMap
+	schedPass uint64
+
 	flowControlManager flowcontrol.FlowControlManager
 }
 
@@ -69,10 +80,12 @@ func (deadlineError) Timeout() bool   { return true }
 
 var errDeadline net.Error = &deadlineError{}
 
+var errDeliveryDeadline = errors.New("delivery deadline exceeded")
+
 // newStream creates a new Stream
 func newStream(StreamID protocol.StreamID,
 	onData func(),
-	onReset func(protocol.StreamID, protocol.ByteCount),
+	onReset func(protocol.StreamID, protocol.ByteCount, uint32),
 	flowControlManager flowcontrol.FlowControlManager) *stream {
 	s := &stream{
 		onData:             onData,
@@ -197,6 +210,11 @@ func (s *stream) Write(p []byte) (int, error) {
 
 	s.dataForWriting = make([]byte, len(p))
 	copy(s.dataForWriting, p)
+	if s.deliveryDeadline != 0 {
+		s.dataDeadline = time.Now().Add(s.deliveryDeadline)
+	} else {
+		s.dataDeadline = time.Time{}
+	}
 	s.onData()
 
 	var err error
@@ -373,6 +391,15 @@ func (s *stream) Cancel(err error) {
 
 // resets the stream locally
 func (s *stream) Reset(err error) {
+	s.reset(err, 0)
+}
+
+// resetDeadlineExceeded resets the stream locally because its data missed the delivery deadline
+func (s *stream) resetDeadlineExceeded() {
+	s.reset(errDeliveryDeadline, StreamDeadlineExceeded)
+}
+
+func (s *stream) reset(err error, errorCode uint32) {
 	if s.resetLocally.Get() {
 		return
 	}
@@ -386,7 +413,7 @@ func (s *stream) Reset(err error) {
 		s.signalWrite()
 	}
 	if s.shouldSendReset() {
-		s.onReset(s.streamID, s.writeOffset)
+		s.onReset(s.streamID, s.writeOffset, errorCode)
 		s.rstSent.Set(true)
 	}
 	s.mutex.Unlock()
@@ -406,7 +433,7 @@ func (s *stream) RegisterRemoteError(err error) {
 		s.signalWrite()
 	}
 	if s.shouldSendReset() {
-		s.onReset(s.streamID, s.writeOffset)
+		s.onReset(s.streamID, s.writeOffset, 0)
 		s.rstSent.Set(true)
 	}
 	s.mutex.Unlock()
@@ -439,3 +466,66 @@ func (s *stream) GetBytesSent() (protocol.ByteCount, error) {
 func (s *stream) GetBytesRetrans() (protocol.ByteCount, error) {
 	return s.flowControlManager.GetBytesRetrans(s.streamID)
 }
+
+func (s *stream) SetPathPreference(pref PathPreference) {
+	s.mutex.Lock()
+	s.pathPreference = pref
+	s.mutex.Unlock()
+	// The stream may now be sent on other paths
+	s.onData()
+}
+
+func (s *stream) PathPreference() PathPreference {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.pathPreference
+}
+
+func (s *stream) SetPriority(prio StreamPriority) {
+	if prio.Weight > protocol.MaxStreamWeight {
+		prio.Weight = protocol.MaxStreamWeight
+	}
+	s.mutex.Lock()
+	s.priority = prio
+	s.mutex.Unlock()
+}
+
+func (s *stream) Priority() StreamPriority {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.priority
+}
+
+// weight returns the weight used for scheduling
+func (s *stream) weight() uint64 {
+	w := s.Priority().Weight
+	if w == 0 {
+		w = protocol.DefaultStreamWeight
+	}
+	return uint64(w)
+}
+
+// onDataSent accounts the data sent for the weighted scheduling
+// The lock of the streamsMap must be held
+func (s *stream) onDataSent(n protocol.ByteCount) {
+	s.schedPass += uint64(n) * protocol.MaxStreamWeight / s.weight()
+}
+
+func (s *stream) SetDeliveryDeadline(d time.Duration) {
+	s.mutex.Lock()
+	s.deliveryDeadline = d
+	s.mutex.Unlock()
+}
+
+func (s *stream) DeliveryDeadline() time.Duration {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.deliveryDeadline
+}
+
+// getDataDeadline returns the time by which the data to write must be delivered, or zero if there is none
+func (s *stream) getDataDeadline() time.Time {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.dataDeadline
+}
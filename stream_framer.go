@@ -20,6 +20,10 @@ type streamFramer struct {
 	addAddressFrameQueue []*wire.AddAddressFrame
 	closePathFrameQueue  []*wire.ClosePathFrame
 	pathsFrame           *wire.PathsFrame
+
+	// Streams which missed their delivery deadline while frames were popped.
+	// They are reset by ResetExpiredStreams, since resetting a stream queues a RST_STREAM frame in the packer.
+	expiredStreams []*stream
 }
 
 func newStreamFramer(streamsMap *streamsMap, flowControlManager flowcontrol.FlowControlManager) *streamFramer {
@@ -33,9 +37,68 @@ func (f *streamFramer) AddFrameForRetransmission(frame *wire.StreamFrame) {
 	f.retransmissionQueue = append(f.retransmissionQueue, frame)
 }
 
-func (f *streamFramer) PopStreamFrames(maxLen protocol.ByteCount) []*wire.StreamFrame {
-	fs, currentLen := f.maybePopFramesForRetransmission(maxLen)
-	return append(fs, f.maybePopNormalFrames(maxLen-currentLen)...)
+// PopStreamFrames returns the stream frames to send on pth, respecting the path preferences of the streams
+// If pth is nil, the path preferences are ignored
+func (f *streamFramer) PopStreamFrames(maxLen protocol.ByteCount, pth *path) []*wire.StreamFrame {
+	fs, currentLen := f.maybePopFramesForRetransmission(maxLen, pth)
+	return append(fs, f.maybePopNormalFrames(maxLen-currentLen, pth)...)
+}
+
+// ResetExpiredStreams resets the streams which missed their delivery deadline during the last PopStreamFrames calls.
+// It must not be called while a packet is being composed.
+func (f *streamFramer) ResetExpiredStreams() {
+	for _, str := range f.expiredStreams {
+		str.resetDeadlineExceeded()
+	}
+	f.expiredStreams = nil
+}
+
+// canSendOnPath returns true if the data of the stream may be sent on pth
+func (f *streamFramer) canSendOnPath(str *stream, pth *path) bool {
+	if str == nil || pth == nil {
+		return true
+	}
+	pref := str.PathPreference()
+	if pth.matchesPreference(pref) {
+		return true
+	}
+	// Don't let the stream starve if none of its preferred paths is usable anymore
+	for _, pthTmp := range pth.sess.paths() {
+		if pthTmp.matchesPreference(pref) && pthTmp.open.Get() && !pthTmp.potentiallyFailed.Get() {
+			return false
+		}
+	}
+	return true
+}
+
+// checkDeadline tells if data that must reach the peer by deadline should be sent on pth,
+// and if it can still make it in time on any path
+func (f *streamFramer) checkDeadline(deadline time.Time, pth *path) (onPath bool, inTime bool) {
+	if deadline.IsZero() {
+		return true, true
+	}
+	now := time.Now()
+	if pth == nil {
+		inTime = !now.After(deadline)
+		return inTime, inTime
+	}
+	if !now.Add(pth.estimatedDeliveryDelay()).After(deadline) {
+		return true, true
+	}
+	// Maybe another path is fast enough
+	paths := pth.sess.paths()
+	for pathID, pthTmp := range paths {
+		if pthTmp == pth || (pathID == protocol.InitialPathID && len(paths) > 1) {
+			continue
+		}
+		if !pthTmp.open.Get() || pthTmp.potentiallyFailed.Get() || pthTmp.budgetExhausted(now) {
+			continue
+		}
+		if !now.Add(pthTmp.estimatedDeliveryDelay()).After(deadline) {
+			return false, true
+		}
+	}
+	return false, false
 }
 
 func (f *streamFramer) PopBlockedFrame() *wire.BlockedFrame {
@@ -47,8 +110,8 @@ func (f *streamFramer) PopBlockedFrame() *wire.BlockedFrame {
 	return frame
 }
 
-func (f *streamFramer) AddAddressForTransmission(ipVersion uint8, addr net.UDPAddr) {
-	f.addAddressFrameQueue = append(f.addAddressFrameQueue, &wire.AddAddressFrame{IPVersion: ipVersion, Addr: addr})
+func (f *streamFramer) AddAddressForTransmission(ipVersion uint8, addr net.UDPAddr, backup bool) {
+	f.addAddressFrameQueue = append(f.addAddressFrameQueue, &wire.AddAddressFrame{IPVersion: ipVersion, Addr: addr, Backup: backup})
 }
 
 func (f *streamFramer) PopAddAddressFrame() *wire.AddAddressFrame {
@@ -61,17 +124,16 @@ func (f *streamFramer) PopAddAddressFrame() *wire.AddAddressFrame {
 }
 
 func (f *streamFramer) AddPathsFrameForTransmission(s *session) {
-	s.pathsLock.RLock()
-	defer s.pathsLock.RUnlock()
-	paths := make([]protocol.PathID, len(s.paths))
-	remoteRTTs := make([]time.Duration, len(s.paths))
+	pths := s.paths()
+	paths := make([]protocol.PathID, len(pths))
+	remoteRTTs := make([]time.Duration, len(pths))
 	i := 0
-	for pathID := range s.paths {
+	for pathID, pth := range pths {
 		paths[i] = pathID
-		if s.paths[pathID].potentiallyFailed.Get() {
+		if pth.potentiallyFailed.Get() {
 			remoteRTTs[i] = time.Hour
 		} else {
-			remoteRTTs[i] = s.paths[pathID].rttStats.SmoothedRTT()
+			remoteRTTs[i] = pth.rttStats.SmoothedRTT()
 		}
 		i++
 	}
@@ -126,9 +188,29 @@ func (f *streamFramer) PopCryptoStreamFrame(maxLen protocol.ByteCount) *wire.Str
 	return frame
 }
 
-func (f *streamFramer) maybePopFramesForRetransmission(maxLen protocol.ByteCount) (res []*wire.StreamFrame, currentLen protocol.ByteCount) {
+func (f *streamFramer) maybePopFramesForRetransmission(maxLen protocol.ByteCount, pth *path) (res []*wire.StreamFrame, currentLen protocol.ByteCount) {
+	// Frames that should rather be retransmitted on another path
+	var otherPathFrames []*wire.StreamFrame
+	defer func() {
+		f.retransmissionQueue = append(otherPathFrames, f.retransmissionQueue...)
+	}()
+
 	for len(f.retransmissionQueue) > 0 {
 		frame := f.retransmissionQueue[0]
+		onPath, inTime := f.checkDeadline(frame.DeliveryDeadline, pth)
+		if !inTime {
+			// Too late, drop the frame instead of retransmitting it
+			f.retransmissionQueue = f.retransmissionQueue[1:]
+			if str := f.streamsMap.GetStream(frame.StreamID); str != nil {
+				f.expiredStreams = append(f.expiredStreams, str)
+			}
+			continue
+		}
+		if pth != nil && (!onPath || !f.canSendOnPath(f.streamsMap.GetStream(frame.StreamID), pth)) {
+			otherPathFrames = append(otherPathFrames, frame)
+			f.retransmissionQueue = f.retransmissionQueue[1:]
+			continue
+		}
 		frame.DataLenPresent = true
 
 		frameHeaderLen, _ := frame.MinLength(protocol.VersionWhatever) // can never error
@@ -162,7 +244,7 @@ func (f *streamFramer) maybePopFramesForRetransmission(maxLen protocol.ByteCount
 	return
 }
 
-func (f *streamFramer) maybePopNormalFrames(maxBytes protocol.ByteCount) (res []*wire.StreamFrame) {
+func (f *streamFramer) maybePopNormalFrames(maxBytes protocol.ByteCount, pth *path) (res []*wire.StreamFrame) {
 	frame := &wire.StreamFrame{DataLenPresent: true}
 	var currentLen protocol.ByteCount
 
@@ -171,6 +253,23 @@ func (f *streamFramer) maybePopNormalFrames(maxBytes protocol.ByteCount) (res []
 			return true, nil
 		}
 
+		if !f.canSendOnPath(s, pth) {
+			return true, nil
+		}
+
+		deadline := s.getDataDeadline()
+		if s.lenOfDataForWriting() != 0 {
+			onPath, inTime := f.checkDeadline(deadline, pth)
+			if !inTime {
+				// The stream fell behind, its data is useless now
+				f.expiredStreams = append(f.expiredStreams, s)
+				return true, nil
+			}
+			if !onPath {
+				return true, nil
+			}
+		}
+
 		frame.StreamID = s.streamID
 		// not perfect, but thread-safe since writeOffset is only written when getting data
 		frame.Offset = s.writeOffset
@@ -210,6 +309,9 @@ func (f *streamFramer) maybePopNormalFrames(maxBytes protocol.ByteCount) (res []
 		}
 
 		frame.Data = data
+		if data != nil {
+			frame.DeliveryDeadline = deadline
+		}
 		f.flowControlManager.AddBytesSent(s.streamID, protocol.ByteCount(len(data)))
 
 		// Finally, check if we are now FC blocked and should queue a BLOCKED frame
@@ -223,6 +325,7 @@ func (f *streamFramer) maybePopNormalFrames(maxBytes protocol.ByteCount) (res []
 
 		res = append(res, frame)
 		currentLen += frameHeaderBytes + frame.DataLen()
+		s.onDataSent(frame.DataLen())
 
 		if currentLen == maxBytes {
 			return false, nil
@@ -232,7 +335,7 @@ func (f *streamFramer) maybePopNormalFrames(maxBytes protocol.ByteCount) (res []
 		return true, nil
 	}
 
-	f.streamsMap.RoundRobinIterate(fn)
+	f.streamsMap.PriorityIterate(fn)
 
 	return
 }
@@ -249,10 +352,11 @@ func maybeSplitOffFrame(frame *wire.StreamFrame, n protocol.ByteCount) *wire.Str
 	}()
 
 	return &wire.StreamFrame{
-		FinBit:         false,
-		StreamID:       frame.StreamID,
-		Offset:         frame.Offset,
-		Data:           frame.Data[:n],
-		DataLenPresent: frame.DataLenPresent,
+		FinBit:           false,
+		StreamID:         frame.StreamID,
+		Offset:           frame.Offset,
+		Data:             frame.Data[:n],
+		DataLenPresent:   frame.DataLenPresent,
+		DeliveryDeadline: frame.DeliveryDeadline,
 	}
 }
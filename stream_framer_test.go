@@ -2,7 +2,9 @@ package quic
 
 import (
 	"bytes"
+	"time"
 
+	"github.com/lucas-clemente/quic-go/congestion"
 	"github.com/lucas-clemente/quic-go/internal/mocks/mocks_fc"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/wire"
@@ -54,7 +56,7 @@ var _ = Describe("Stream Framer", func() {
 	It("sets the DataLenPresent for dequeued retransmitted frames", func() {
 		mockFcm.EXPECT().AddBytesRetrans(retransmittedFrame1.StreamID, retransmittedFrame1.DataLen())
 		framer.AddFrameForRetransmission(retransmittedFrame1)
-		fs := framer.PopStreamFrames(protocol.MaxByteCount)
+		fs := framer.PopStreamFrames(protocol.MaxByteCount, nil)
 		Expect(fs).To(HaveLen(1))
 		Expect(fs[0].DataLenPresent).To(BeTrue())
 	})
@@ -64,14 +66,14 @@ var _ = Describe("Stream Framer", func() {
 		mockFcm.EXPECT().AddBytesSent(id1, protocol.ByteCount(6))
 		mockFcm.EXPECT().RemainingConnectionWindowSize().Return(protocol.MaxByteCount)
 		stream1.dataForWriting = []byte("foobar")
-		fs := framer.PopStreamFrames(protocol.MaxByteCount)
+		fs := framer.PopStreamFrames(protocol.MaxByteCount, nil)
 		Expect(fs).To(HaveLen(1))
 		Expect(fs[0].DataLenPresent).To(BeTrue())
 	})
 
 	Context("Popping", func() {
 		It("returns nil when popping an empty framer", func() {
-			Expect(framer.PopStreamFrames(1000)).To(BeEmpty())
+			Expect(framer.PopStreamFrames(1000, nil)).To(BeEmpty())
 		})
 
 		It("pops frames for retransmission", func() {
@@ -79,11 +81,11 @@ var _ = Describe("Stream Framer", func() {
 			mockFcm.EXPECT().AddBytesRetrans(retransmittedFrame2.StreamID, retransmittedFrame2.DataLen())
 			framer.AddFrameForRetransmission(retransmittedFrame1)
 			framer.AddFrameForRetransmission(retransmittedFrame2)
-			fs := framer.PopStreamFrames(1000)
+			fs := framer.PopStreamFrames(1000, nil)
 			Expect(fs).To(HaveLen(2))
 			Expect(fs[0]).To(Equal(retransmittedFrame1))
 			Expect(fs[1]).To(Equal(retransmittedFrame2))
-			Expect(framer.PopStreamFrames(1000)).To(BeEmpty())
+			Expect(framer.PopStreamFrames(1000, nil)).To(BeEmpty())
 		})
 
 		It("returns normal frames", func() {
@@ -91,11 +93,11 @@ var _ = Describe("Stream Framer", func() {
 			mockFcm.EXPECT().AddBytesSent(id1, protocol.ByteCount(6))
 			mockFcm.EXPECT().RemainingConnectionWindowSize().Return(protocol.MaxByteCount)
 			stream1.dataForWriting = []byte("foobar")
-			fs := framer.PopStreamFrames(1000)
+			fs := framer.PopStreamFrames(1000, nil)
 			Expect(fs).To(HaveLen(1))
 			Expect(fs[0].StreamID).To(Equal(stream1.streamID))
 			Expect(fs[0].Data).To(Equal([]byte("foobar")))
-			Expect(framer.PopStreamFrames(1000)).To(BeEmpty())
+			Expect(framer.PopStreamFrames(1000, nil)).To(BeEmpty())
 		})
 
 		It("returns multiple normal frames", func() {
@@ -107,7 +109,7 @@ var _ = Describe("Stream Framer", func() {
 			mockFcm.EXPECT().RemainingConnectionWindowSize().Return(protocol.MaxByteCount)
 			stream1.dataForWriting = []byte("foobar")
 			stream2.dataForWriting = []byte("foobaz")
-			fs := framer.PopStreamFrames(1000)
+			fs := framer.PopStreamFrames(1000, nil)
 			Expect(fs).To(HaveLen(2))
 			// Swap if we dequeued in other order
 			if fs[0].StreamID != stream1.streamID {
@@ -117,7 +119,7 @@ var _ = Describe("Stream Framer", func() {
 			Expect(fs[0].Data).To(Equal([]byte("foobar")))
 			Expect(fs[1].StreamID).To(Equal(stream2.streamID))
 			Expect(fs[1].Data).To(Equal([]byte("foobaz")))
-			Expect(framer.PopStreamFrames(1000)).To(BeEmpty())
+			Expect(framer.PopStreamFrames(1000, nil)).To(BeEmpty())
 		})
 
 		It("returns retransmission frames before normal frames", func() {
@@ -127,22 +129,22 @@ var _ = Describe("Stream Framer", func() {
 			mockFcm.EXPECT().AddBytesRetrans(retransmittedFrame1.StreamID, retransmittedFrame1.DataLen())
 			framer.AddFrameForRetransmission(retransmittedFrame1)
 			stream1.dataForWriting = []byte("foobar")
-			fs := framer.PopStreamFrames(1000)
+			fs := framer.PopStreamFrames(1000, nil)
 			Expect(fs).To(HaveLen(2))
 			Expect(fs[0]).To(Equal(retransmittedFrame1))
 			Expect(fs[1].StreamID).To(Equal(stream1.streamID))
-			Expect(framer.PopStreamFrames(1000)).To(BeEmpty())
+			Expect(framer.PopStreamFrames(1000, nil)).To(BeEmpty())
 		})
 
 		It("does not pop empty frames", func() {
 			mockFcm.EXPECT().SendWindowSize(id1).Return(protocol.MaxByteCount, nil)
 			stream1.dataForWriting = []byte("foobar")
-			fs := framer.PopStreamFrames(4)
+			fs := framer.PopStreamFrames(4, nil)
 			Expect(fs).To(HaveLen(0))
 			mockFcm.EXPECT().SendWindowSize(id1).Return(protocol.MaxByteCount, nil)
 			mockFcm.EXPECT().AddBytesSent(id1, protocol.ByteCount(1))
 			mockFcm.EXPECT().RemainingConnectionWindowSize().Return(protocol.MaxByteCount)
-			fs = framer.PopStreamFrames(5)
+			fs = framer.PopStreamFrames(5, nil)
 			Expect(fs).To(HaveLen(1))
 			Expect(fs[0].Data).ToNot(BeEmpty())
 			Expect(fs[0].FinBit).To(BeFalse())
@@ -157,11 +159,11 @@ var _ = Describe("Stream Framer", func() {
 			mockFcm.EXPECT().RemainingConnectionWindowSize().Return(protocol.MaxByteCount)
 			stream1.dataForWriting = bytes.Repeat([]byte("f"), 100)
 			stream2.dataForWriting = bytes.Repeat([]byte("e"), 100)
-			fs := framer.PopStreamFrames(10)
+			fs := framer.PopStreamFrames(10, nil)
 			Expect(fs).To(HaveLen(1))
 			// it doesn't matter here if this data is from stream1 or from stream2...
 			firstStreamID := fs[0].StreamID
-			fs = framer.PopStreamFrames(10)
+			fs = framer.PopStreamFrames(10, nil)
 			Expect(fs).To(HaveLen(1))
 			// ... but the data popped this time has to be from the other stream
 			Expect(fs[0].StreamID).ToNot(Equal(firstStreamID))
@@ -205,7 +207,7 @@ var _ = Describe("Stream Framer", func() {
 				mockFcm.EXPECT().AddBytesRetrans(retransmittedFrame2.StreamID, protocol.ByteCount(2))
 				framer.AddFrameForRetransmission(retransmittedFrame2)
 				origlen := retransmittedFrame2.DataLen()
-				fs := framer.PopStreamFrames(6)
+				fs := framer.PopStreamFrames(6, nil)
 				Expect(fs).To(HaveLen(1))
 				minLength, _ := fs[0].MinLength(0)
 				Expect(minLength + fs[0].DataLen()).To(Equal(protocol.ByteCount(6)))
@@ -233,7 +235,7 @@ var _ = Describe("Stream Framer", func() {
 					if i - int(frameHeaderLen) > 0 {
 						mockFcm.EXPECT().AddBytesRetrans(origFrame.StreamID, protocol.ByteCount(i) - frameHeaderLen)
 					}
-					frames, currentLen := framer.maybePopFramesForRetransmission(protocol.ByteCount(i))
+					frames, currentLen := framer.maybePopFramesForRetransmission(protocol.ByteCount(i), nil)
 					if len(frames) == 0 {
 						Expect(currentLen).To(BeZero())
 					} else {
@@ -253,11 +255,11 @@ var _ = Describe("Stream Framer", func() {
 			It("only removes a frame from the framer after returning all split parts", func() {
 				framer.AddFrameForRetransmission(retransmittedFrame2)
 				mockFcm.EXPECT().AddBytesRetrans(retransmittedFrame2.StreamID, protocol.ByteCount(2))
-				fs := framer.PopStreamFrames(6)
+				fs := framer.PopStreamFrames(6, nil)
 				Expect(fs).To(HaveLen(1))
 				Expect(framer.retransmissionQueue).ToNot(BeEmpty())
 				mockFcm.EXPECT().AddBytesRetrans(retransmittedFrame2.StreamID, protocol.ByteCount(2))
-				fs = framer.PopStreamFrames(1000)
+				fs = framer.PopStreamFrames(1000, nil)
 				Expect(fs).To(HaveLen(1))
 				Expect(framer.retransmissionQueue).To(BeEmpty())
 			})
@@ -271,13 +273,13 @@ var _ = Describe("Stream Framer", func() {
 				mockFcm.EXPECT().RemainingConnectionWindowSize().Return(protocol.MaxByteCount)
 				origdata := []byte("foobar")
 				stream1.dataForWriting = origdata
-				fs := framer.PopStreamFrames(7)
+				fs := framer.PopStreamFrames(7, nil)
 				Expect(fs).To(HaveLen(1))
 				Expect(fs[0].Data).To(Equal([]byte("foo")))
 				var b bytes.Buffer
 				fs[0].Write(&b, 0)
 				Expect(b.Len()).To(Equal(7))
-				fs = framer.PopStreamFrames(1000)
+				fs = framer.PopStreamFrames(1000, nil)
 				Expect(fs).To(HaveLen(1))
 				Expect(fs[0].Data).To(Equal([]byte("bar")))
 			})
@@ -289,7 +291,7 @@ var _ = Describe("Stream Framer", func() {
 				mockFcm.EXPECT().RemainingConnectionWindowSize().Return(protocol.MaxByteCount)
 				stream1.writeOffset = 42
 				stream1.finishedWriting.Set(true)
-				fs := framer.PopStreamFrames(1000)
+				fs := framer.PopStreamFrames(1000, nil)
 				Expect(fs).To(HaveLen(1))
 				Expect(fs[0].StreamID).To(Equal(stream1.streamID))
 				Expect(fs[0].Offset).To(Equal(stream1.writeOffset))
@@ -302,7 +304,7 @@ var _ = Describe("Stream Framer", func() {
 				mockFcm.EXPECT().RemainingConnectionWindowSize().Return(protocol.MaxByteCount)
 				stream1.writeOffset = 42
 				stream1.finishedWriting.Set(true)
-				fs := framer.PopStreamFrames(1000)
+				fs := framer.PopStreamFrames(1000, nil)
 				Expect(fs).To(HaveLen(1))
 				Expect(fs[0].StreamID).To(Equal(stream1.streamID))
 				Expect(fs[0].Offset).To(Equal(stream1.writeOffset))
@@ -316,7 +318,7 @@ var _ = Describe("Stream Framer", func() {
 				mockFcm.EXPECT().RemainingConnectionWindowSize().Return(protocol.MaxByteCount)
 				stream1.dataForWriting = []byte("foobar")
 				stream1.finishedWriting.Set(true)
-				fs := framer.PopStreamFrames(1000)
+				fs := framer.PopStreamFrames(1000, nil)
 				Expect(fs).To(HaveLen(1))
 				Expect(fs[0].StreamID).To(Equal(stream1.streamID))
 				Expect(fs[0].Data).To(Equal([]byte("foobar")))
@@ -331,13 +333,13 @@ var _ = Describe("Stream Framer", func() {
 			mockFcm.EXPECT().AddBytesSent(id1, protocol.ByteCount(6))
 			mockFcm.EXPECT().RemainingConnectionWindowSize().Return(protocol.MaxByteCount)
 			stream1.dataForWriting = []byte("foobar")
-			framer.PopStreamFrames(1000)
+			framer.PopStreamFrames(1000, nil)
 		})
 
 		It("does not count retransmitted frames as sent bytes", func() {
 			framer.AddFrameForRetransmission(retransmittedFrame1)
 			mockFcm.EXPECT().AddBytesRetrans(retransmittedFrame1.StreamID, retransmittedFrame1.DataLen())
-			framer.PopStreamFrames(1000)
+			framer.PopStreamFrames(1000, nil)
 		})
 
 		It("returns the whole frame if it fits", func() {
@@ -346,7 +348,7 @@ var _ = Describe("Stream Framer", func() {
 			mockFcm.EXPECT().RemainingConnectionWindowSize().Return(protocol.MaxByteCount)
 			stream1.writeOffset = 10
 			stream1.dataForWriting = []byte("foobar")
-			fs := framer.PopStreamFrames(1000)
+			fs := framer.PopStreamFrames(1000, nil)
 			Expect(fs).To(HaveLen(1))
 			Expect(fs[0].DataLen()).To(Equal(protocol.ByteCount(6)))
 		})
@@ -356,7 +358,7 @@ var _ = Describe("Stream Framer", func() {
 			mockFcm.EXPECT().AddBytesSent(id1, protocol.ByteCount(3))
 			mockFcm.EXPECT().RemainingConnectionWindowSize().Return(protocol.MaxByteCount)
 			stream1.dataForWriting = []byte("foobar")
-			fs := framer.PopStreamFrames(1000)
+			fs := framer.PopStreamFrames(1000, nil)
 			Expect(fs).To(HaveLen(1))
 			Expect(fs[0].Data).To(Equal([]byte("foo")))
 		})
@@ -367,7 +369,7 @@ var _ = Describe("Stream Framer", func() {
 			mockFcm.EXPECT().RemainingConnectionWindowSize().Return(protocol.MaxByteCount)
 			stream1.writeOffset = 1
 			stream1.dataForWriting = []byte("foobar")
-			fs := framer.PopStreamFrames(1000)
+			fs := framer.PopStreamFrames(1000, nil)
 			Expect(fs).To(HaveLen(1))
 			Expect(fs[0].Data).To(Equal([]byte("foo")))
 		})
@@ -379,7 +381,7 @@ var _ = Describe("Stream Framer", func() {
 			mockFcm.EXPECT().RemainingConnectionWindowSize().Return(protocol.MaxByteCount)
 			stream1.dataForWriting = []byte("foobar")
 			stream2.dataForWriting = []byte("foobaz")
-			fs := framer.PopStreamFrames(1000)
+			fs := framer.PopStreamFrames(1000, nil)
 			Expect(fs).To(HaveLen(1))
 			Expect(fs[0].StreamID).To(Equal(stream2.StreamID()))
 			Expect(fs[0].Data).To(Equal([]byte("foobaz")))
@@ -390,11 +392,184 @@ var _ = Describe("Stream Framer", func() {
 			mockFcm.EXPECT().SendWindowSize(id2).Return(protocol.ByteCount(0), nil)
 			stream1.dataForWriting = []byte("foobar")
 			stream2.dataForWriting = []byte("foobaz")
-			fs := framer.PopStreamFrames(1000)
+			fs := framer.PopStreamFrames(1000, nil)
 			Expect(fs).To(BeEmpty())
 		})
 	})
 
+	Context("path preferences", func() {
+		var (
+			sess       *session
+			pth1, pth2 *path
+		)
+
+		BeforeEach(func() {
+			sess = &session{}
+			pth1 = &path{pathID: 1, sess: sess, ifaceName: "wlan0"}
+			pth2 = &path{pathID: 3, sess: sess, ifaceName: "rmnet0"}
+			pth1.open.Set(true)
+			pth2.open.Set(true)
+			sess.addPath(pth1)
+			sess.addPath(pth2)
+		})
+
+		It("only sends a pinned stream on its path", func() {
+			stream1.pathPreference = PathPreference{Pinned: true, PathID: 3}
+			stream1.dataForWriting = []byte("foobar")
+			Expect(framer.PopStreamFrames(1000, pth1)).To(BeEmpty())
+			mockFcm.EXPECT().SendWindowSize(id1).Return(protocol.MaxByteCount, nil)
+			mockFcm.EXPECT().AddBytesSent(id1, protocol.ByteCount(6))
+			mockFcm.EXPECT().RemainingConnectionWindowSize().Return(protocol.MaxByteCount)
+			fs := framer.PopStreamFrames(1000, pth2)
+			Expect(fs).To(HaveLen(1))
+			Expect(fs[0].StreamID).To(Equal(id1))
+		})
+
+		It("sends a stream on the paths of its preferred interface", func() {
+			stream1.pathPreference = PathPreference{Interface: "wlan0"}
+			stream1.dataForWriting = []byte("foobar")
+			Expect(framer.PopStreamFrames(1000, pth2)).To(BeEmpty())
+			mockFcm.EXPECT().SendWindowSize(id1).Return(protocol.MaxByteCount, nil)
+			mockFcm.EXPECT().AddBytesSent(id1, protocol.ByteCount(6))
+			mockFcm.EXPECT().RemainingConnectionWindowSize().Return(protocol.MaxByteCount)
+			Expect(framer.PopStreamFrames(1000, pth1)).To(HaveLen(1))
+		})
+
+		It("falls back to any path if the preferred path is potentially failed", func() {
+			stream1.pathPreference = PathPreference{Pinned: true, PathID: 3}
+			stream1.dataForWriting = []byte("foobar")
+			pth2.potentiallyFailed.Set(true)
+			mockFcm.EXPECT().SendWindowSize(id1).Return(protocol.MaxByteCount, nil)
+			mockFcm.EXPECT().AddBytesSent(id1, protocol.ByteCount(6))
+			mockFcm.EXPECT().RemainingConnectionWindowSize().Return(protocol.MaxByteCount)
+			Expect(framer.PopStreamFrames(1000, pth1)).To(HaveLen(1))
+		})
+
+		It("keeps retransmissions for another path in the queue", func() {
+			stream1.pathPreference = PathPreference{Pinned: true, PathID: 3}
+			frame := &wire.StreamFrame{StreamID: id1, Data: []byte("foobar")}
+			framer.AddFrameForRetransmission(frame)
+			framer.AddFrameForRetransmission(retransmittedFrame1)
+			mockFcm.EXPECT().AddBytesRetrans(retransmittedFrame1.StreamID, retransmittedFrame1.DataLen())
+			fs := framer.PopStreamFrames(1000, pth1)
+			Expect(fs).To(Equal([]*wire.StreamFrame{retransmittedFrame1}))
+			Expect(framer.HasFramesForRetransmission()).To(BeTrue())
+			mockFcm.EXPECT().AddBytesRetrans(id1, frame.DataLen())
+			fs = framer.PopStreamFrames(1000, pth2)
+			Expect(fs).To(Equal([]*wire.StreamFrame{frame}))
+			Expect(framer.HasFramesForRetransmission()).To(BeFalse())
+		})
+	})
+
+	Context("delivery deadlines", func() {
+		const id3 = protocol.StreamID(12)
+
+		var (
+			sess       *session
+			pth1, pth2 *path
+			stream3    *stream
+			resetCode  uint32
+			resetCalls int
+		)
+
+		newPath := func(pathID protocol.PathID, srtt time.Duration) *path {
+			pth := &path{
+				pathID:            pathID,
+				sess:              sess,
+				rttStats:          &congestion.RTTStats{},
+				sentPacketHandler: &mockSentPacketHandler{},
+			}
+			pth.rttStats.UpdateRTT(srtt, 0, time.Now())
+			pth.open.Set(true)
+			sess.addPath(pth)
+			return pth
+		}
+
+		BeforeEach(func() {
+			resetCalls = 0
+			sess = &session{}
+			pth1 = newPath(1, 400*time.Millisecond)
+			pth2 = newPath(3, 20*time.Millisecond)
+			stream3 = newStream(id3, func() {}, func(_ protocol.StreamID, _ protocol.ByteCount, code uint32) {
+				resetCalls++
+				resetCode = code
+			}, mockFcm)
+			streamsMap.putStream(stream3)
+		})
+
+		It("estimates the delivery delay of a path", func() {
+			Expect(pth2.estimatedDeliveryDelay()).To(Equal(10 * time.Millisecond))
+			pth2.sentPacketHandler.(*mockSentPacketHandler).bytesInFlight = protocol.InitialCongestionWindow * protocol.DefaultTCPMSS / 2
+			Expect(pth2.estimatedDeliveryDelay()).To(Equal(20 * time.Millisecond))
+		})
+
+		It("sends data on a path meeting its deadline", func() {
+			deadline := time.Now().Add(time.Second)
+			stream3.dataForWriting = []byte("foobar")
+			stream3.dataDeadline = deadline
+			mockFcm.EXPECT().SendWindowSize(id3).Return(protocol.MaxByteCount, nil)
+			mockFcm.EXPECT().AddBytesSent(id3, protocol.ByteCount(6))
+			mockFcm.EXPECT().RemainingConnectionWindowSize().Return(protocol.MaxByteCount)
+			fs := framer.PopStreamFrames(1000, pth1)
+			Expect(fs).To(HaveLen(1))
+			Expect(fs[0].DeliveryDeadline).To(Equal(deadline))
+		})
+
+		It("leaves data to a path fast enough to meet its deadline", func() {
+			stream3.dataForWriting = []byte("foobar")
+			stream3.dataDeadline = time.Now().Add(100 * time.Millisecond)
+			Expect(framer.PopStreamFrames(1000, pth1)).To(BeEmpty())
+			mockFcm.EXPECT().SendWindowSize(id3).Return(protocol.MaxByteCount, nil)
+			mockFcm.EXPECT().AddBytesSent(id3, protocol.ByteCount(6))
+			mockFcm.EXPECT().RemainingConnectionWindowSize().Return(protocol.MaxByteCount)
+			Expect(framer.PopStreamFrames(1000, pth2)).To(HaveLen(1))
+			Expect(resetCalls).To(BeZero())
+		})
+
+		It("resets a stream which fell behind", func() {
+			stream3.dataForWriting = []byte("foobar")
+			stream3.dataDeadline = time.Now().Add(5 * time.Millisecond)
+			Expect(framer.PopStreamFrames(1000, pth1)).To(BeEmpty())
+			// the stream is only reset once the packet is composed
+			Expect(resetCalls).To(BeZero())
+			framer.ResetExpiredStreams()
+			Expect(resetCalls).To(Equal(1))
+			Expect(resetCode).To(Equal(StreamDeadlineExceeded))
+			Expect(stream3.err).To(MatchError(errDeliveryDeadline))
+			Expect(stream3.lenOfDataForWriting()).To(BeZero())
+		})
+
+		It("drops retransmissions which can't make it anymore", func() {
+			frame := &wire.StreamFrame{StreamID: id3, Data: []byte("foobar"), DeliveryDeadline: time.Now().Add(-time.Millisecond)}
+			framer.AddFrameForRetransmission(frame)
+			framer.AddFrameForRetransmission(retransmittedFrame1)
+			mockFcm.EXPECT().AddBytesRetrans(retransmittedFrame1.StreamID, retransmittedFrame1.DataLen())
+			fs := framer.PopStreamFrames(1000, pth2)
+			Expect(fs).To(Equal([]*wire.StreamFrame{retransmittedFrame1}))
+			Expect(framer.HasFramesForRetransmission()).To(BeFalse())
+			Expect(resetCalls).To(BeZero())
+			framer.ResetExpiredStreams()
+			Expect(resetCalls).To(Equal(1))
+			Expect(resetCode).To(Equal(StreamDeadlineExceeded))
+		})
+
+		It("keeps retransmissions for a faster path in the queue", func() {
+			frame := &wire.StreamFrame{StreamID: id3, Data: []byte("foobar"), DeliveryDeadline: time.Now().Add(100 * time.Millisecond)}
+			framer.AddFrameForRetransmission(frame)
+			Expect(framer.PopStreamFrames(1000, pth1)).To(BeEmpty())
+			Expect(framer.HasFramesForRetransmission()).To(BeTrue())
+			mockFcm.EXPECT().AddBytesRetrans(id3, frame.DataLen())
+			Expect(framer.PopStreamFrames(1000, pth2)).To(Equal([]*wire.StreamFrame{frame}))
+			Expect(resetCalls).To(BeZero())
+		})
+
+		It("keeps the deadline when splitting a frame", func() {
+			deadline := time.Now()
+			frame := &wire.StreamFrame{Data: []byte("foobar"), DeliveryDeadline: deadline}
+			Expect(maybeSplitOffFrame(frame, 3).DeliveryDeadline).To(Equal(deadline))
+		})
+	})
+
 	Context("BLOCKED frames", func() {
 		It("Pop returns nil if no frame is queued", func() {
 			Expect(framer.PopBlockedFrame()).To(BeNil())
@@ -405,7 +580,7 @@ var _ = Describe("Stream Framer", func() {
 			mockFcm.EXPECT().AddBytesSent(id1, protocol.ByteCount(3))
 			mockFcm.EXPECT().RemainingConnectionWindowSize().Return(protocol.MaxByteCount)
 			stream1.dataForWriting = []byte("foo")
-			frames := framer.PopStreamFrames(1000)
+			frames := framer.PopStreamFrames(1000, nil)
 			Expect(frames).To(HaveLen(1))
 			blockedFrame := framer.PopBlockedFrame()
 			Expect(blockedFrame).ToNot(BeNil())
@@ -420,11 +595,11 @@ var _ = Describe("Stream Framer", func() {
 			mockFcm.EXPECT().AddBytesSent(id1, protocol.ByteCount(0))
 			mockFcm.EXPECT().RemainingConnectionWindowSize().Return(protocol.MaxByteCount)
 			stream1.dataForWriting = []byte("foo")
-			frames := framer.PopStreamFrames(1000)
+			frames := framer.PopStreamFrames(1000, nil)
 			Expect(frames).To(HaveLen(1))
 			Expect(frames[0].FinBit).To(BeFalse())
 			stream1.finishedWriting.Set(true)
-			frames = framer.PopStreamFrames(1000)
+			frames = framer.PopStreamFrames(1000, nil)
 			Expect(frames).To(HaveLen(1))
 			Expect(frames[0].FinBit).To(BeTrue())
 			Expect(frames[0].DataLen()).To(BeZero())
@@ -438,7 +613,7 @@ var _ = Describe("Stream Framer", func() {
 			mockFcm.EXPECT().AddBytesSent(id1, protocol.ByteCount(3))
 			mockFcm.EXPECT().RemainingConnectionWindowSize().Return(protocol.ByteCount(0))
 			stream1.dataForWriting = []byte("foo")
-			framer.PopStreamFrames(1000)
+			framer.PopStreamFrames(1000, nil)
 			blockedFrame := framer.PopBlockedFrame()
 			Expect(blockedFrame).ToNot(BeNil())
 			Expect(blockedFrame.StreamID).To(BeZero())
@@ -450,7 +625,7 @@ var _ = Describe("Stream Framer", func() {
 			mockFcm.EXPECT().AddBytesSent(id1, protocol.ByteCount(3))
 			mockFcm.EXPECT().RemainingConnectionWindowSize().Return(protocol.MaxByteCount)
 			stream1.dataForWriting = []byte("foo")
-			framer.PopStreamFrames(1000)
+			framer.PopStreamFrames(1000, nil)
 			Expect(framer.PopBlockedFrame()).To(BeNil())
 		})
 
@@ -459,7 +634,7 @@ var _ = Describe("Stream Framer", func() {
 			mockFcm.EXPECT().AddBytesSent(id1, protocol.ByteCount(3))
 			mockFcm.EXPECT().RemainingConnectionWindowSize().Return(protocol.MaxByteCount)
 			stream1.dataForWriting = []byte("foobar")
-			framer.PopStreamFrames(1000)
+			framer.PopStreamFrames(1000, nil)
 			blockedFrame := framer.PopBlockedFrame()
 			Expect(blockedFrame).ToNot(BeNil())
 			Expect(blockedFrame.StreamID).To(Equal(stream1.StreamID()))
@@ -29,6 +29,7 @@ var _ = Describe("Stream", func() {
 		resetCalled          bool
 		resetCalledForStream protocol.StreamID
 		resetCalledAtOffset  protocol.ByteCount
+		resetCalledWithCode  uint32
 
 		mockFcm *mocks_fc.MockFlowControlManager
 	)
@@ -49,10 +50,11 @@ var _ = Describe("Stream", func() {
 		onDataCalled = true
 	}
 
-	onReset := func(id protocol.StreamID, offset protocol.ByteCount) {
+	onReset := func(id protocol.StreamID, offset protocol.ByteCount, errorCode uint32) {
 		resetCalled = true
 		resetCalledForStream = id
 		resetCalledAtOffset = offset
+		resetCalledWithCode = errorCode
 	}
 
 	BeforeEach(func() {
@@ -732,6 +734,16 @@ var _ = Describe("Stream", func() {
 				str.Reset(testErr)
 				Expect(str.Context().Done()).To(BeClosed())
 			})
+
+			It("calls onReset with the error code for missed delivery deadlines", func() {
+				str.Reset(testErr)
+				Expect(resetCalledWithCode).To(BeZero())
+				str = newStream(streamID, onData, onReset, mockFcm)
+				str.resetDeadlineExceeded()
+				Expect(resetCalled).To(BeTrue())
+				Expect(resetCalledWithCode).To(Equal(StreamDeadlineExceeded))
+				Expect(str.err).To(MatchError(errDeliveryDeadline))
+			})
 		})
 	})
 
@@ -878,6 +890,46 @@ var _ = Describe("Stream", func() {
 			})
 		})
 
+		Context("delivery deadlines", func() {
+			It("has no delivery deadline by default", func() {
+				Expect(str.DeliveryDeadline()).To(BeZero())
+				go func() {
+					defer GinkgoRecover()
+					strWithTimeout.Write([]byte("foobar"))
+				}()
+				Eventually(func() protocol.ByteCount { return str.lenOfDataForWriting() }).ShouldNot(BeZero())
+				Expect(str.getDataDeadline()).To(BeZero())
+				str.getDataForWriting(6)
+			})
+
+			It("sets the deadline of the written data", func() {
+				str.SetDeliveryDeadline(time.Second)
+				Expect(str.DeliveryDeadline()).To(Equal(time.Second))
+				go func() {
+					defer GinkgoRecover()
+					strWithTimeout.Write([]byte("foobar"))
+				}()
+				Eventually(func() protocol.ByteCount { return str.lenOfDataForWriting() }).ShouldNot(BeZero())
+				Expect(str.getDataDeadline()).To(BeTemporally("~", time.Now().Add(time.Second), scaleDuration(20*time.Millisecond)))
+				str.getDataForWriting(6)
+			})
+
+			It("unblocks Write when the stream fell behind", func() {
+				str.SetDeliveryDeadline(time.Millisecond)
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					n, err := strWithTimeout.Write([]byte("foobar"))
+					Expect(err).To(MatchError(errDeliveryDeadline))
+					Expect(n).To(Equal(3))
+					close(done)
+				}()
+				Eventually(func() []byte { return str.getDataForWriting(3) }).ShouldNot(BeEmpty())
+				str.resetDeadlineExceeded()
+				Eventually(done).Should(BeClosed())
+			})
+		})
+
 		Context("closing", func() {
 			It("sets finishedWriting when calling Close", func() {
 				str.Close()
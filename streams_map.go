@@ -3,6 +3,7 @@ package quic
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/lucas-clemente/quic-go/internal/handshake"
@@ -20,6 +21,8 @@ type streamsMap struct {
 	// needed for round-robin scheduling
 	openStreams     []protocol.StreamID
 	roundRobinIndex uint32
+	// needed for weighted scheduling, the smallest schedPass of the streams with data to send
+	virtualTime uint64
 
 	nextStream                protocol.StreamID // StreamID of the next Stream that will be returned by OpenStream()
 	highestStreamOpenedByPeer protocol.StreamID
@@ -124,6 +127,20 @@ func (m *streamsMap) GetOrOpenStream(id protocol.StreamID) (*stream, error) {
 	return m.streams[id], nil
 }
 
+// GetStream returns the stream with the provided ID, or nil if it is not open
+func (m *streamsMap) GetStream(id protocol.StreamID) *stream {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.streams[id]
+}
+
+// NumOpenStreams returns the number of open streams, including the crypto and header streams
+func (m *streamsMap) NumOpenStreams() int {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return len(m.openStreams)
+}
+
 func (m *streamsMap) openRemoteStream(id protocol.StreamID) (*stream, error) {
 	if m.numIncomingStreams >= m.connectionParameters.GetMaxIncomingStreams() {
 		return nil, qerr.TooManyOpenStreams
@@ -272,6 +289,96 @@ func (m *streamsMap) RoundRobinIterate(fn streamLambda) error {
 	return nil
 }
 
+// PriorityIterate executes the streamLambda for every open stream with data to send, until the streamLambda returns false
+// It prioritizes the crypto- and the header-stream (StreamIDs 1 and 3), then the streams that depend on no other stream
+// with data to send. Among them, the streams having sent the least data relative to their weight come first.
+// The streamLambda should call onDataSent on the streams it took data from.
+func (m *streamsMap) PriorityIterate(fn streamLambda) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, i := range []protocol.StreamID{1, 3} {
+		cont, err := m.iterateFunc(i, fn)
+		if err != nil && err != errMapAccess {
+			return err
+		}
+		if !cont {
+			return nil
+		}
+	}
+
+	active := make([]*stream, 0, len(m.openStreams))
+	for _, streamID := range m.openStreams {
+		if streamID == 1 || streamID == 3 {
+			continue
+		}
+		str := m.streams[streamID]
+		if str == nil || (str.lenOfDataForWriting() == 0 && !str.shouldSendFin()) {
+			continue
+		}
+		// Streams that were idle don't get credit for it
+		if str.schedPass < m.virtualTime {
+			str.schedPass = m.virtualTime
+		}
+		active = append(active, str)
+	}
+	if len(active) == 0 {
+		return nil
+	}
+
+	depths := make(map[protocol.StreamID]int, len(active))
+	for _, str := range active {
+		depths[str.streamID] = m.dependencyDepth(str)
+	}
+	sort.SliceStable(active, func(i, j int) bool {
+		di, dj := depths[active[i].streamID], depths[active[j].streamID]
+		if di != dj {
+			return di < dj
+		}
+		return active[i].schedPass < active[j].schedPass
+	})
+
+	m.virtualTime = active[0].schedPass
+	for _, str := range active[1:] {
+		if str.schedPass < m.virtualTime {
+			m.virtualTime = str.schedPass
+		}
+	}
+
+	for _, str := range active {
+		cont, err := fn(str)
+		if err != nil {
+			return err
+		}
+		if !cont {
+			break
+		}
+	}
+	return nil
+}
+
+// dependencyDepth returns the number of open streams with data to send the stream depends on
+// The lock of the streamsMap must be held
+func (m *streamsMap) dependencyDepth(str *stream) int {
+	depth := 0
+	for i := 0; i < protocol.MaxStreamDependencyDepth; i++ {
+		parentID := str.Priority().DependsOn
+		if parentID == 0 || parentID == str.streamID {
+			break
+		}
+		parent := m.streams[parentID]
+		if parent == nil {
+			break
+		}
+		// A parent with nothing to send does not hold its children back
+		if parent.lenOfDataForWriting() > 0 || parent.shouldSendFin() {
+			depth++
+		}
+		str = parent
+	}
+	return depth
+}
+
 func (m *streamsMap) iterateFunc(streamID protocol.StreamID, fn streamLambda) (bool, error) {
 	str, ok := m.streams[streamID]
 	if !ok {
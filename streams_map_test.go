@@ -506,6 +506,13 @@ var _ = Describe("Streams Map", func() {
 				}
 				Expect(m.openStreams).To(BeEmpty())
 			})
+
+			It("counts the open streams", func() {
+				Expect(m.NumOpenStreams()).To(Equal(5))
+				err := m.RemoveStream(3)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(m.NumOpenStreams()).To(Equal(4))
+			})
 		})
 
 		Context("Iterate", func() {
@@ -673,5 +680,94 @@ var _ = Describe("Streams Map", func() {
 				})
 			})
 		})
+
+		Context("PriorityIterate", func() {
+			var lambdaCalledForStream []protocol.StreamID
+
+			BeforeEach(func() {
+				lambdaCalledForStream = lambdaCalledForStream[:0]
+				for i := 4; i <= 6; i++ {
+					err := m.putStream(&stream{streamID: protocol.StreamID(i), dataForWriting: []byte("foobar")})
+					Expect(err).NotTo(HaveOccurred())
+				}
+			})
+
+			fn := func(str *stream) (bool, error) {
+				lambdaCalledForStream = append(lambdaCalledForStream, str.StreamID())
+				return true, nil
+			}
+
+			It("only considers streams with data to send", func() {
+				m.streams[5].dataForWriting = nil
+				err := m.PriorityIterate(fn)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(lambdaCalledForStream).To(Equal([]protocol.StreamID{4, 6}))
+			})
+
+			It("considers a stream having sent less data than the others first", func() {
+				m.streams[4].onDataSent(1000)
+				m.streams[6].onDataSent(500)
+				err := m.PriorityIterate(fn)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(lambdaCalledForStream).To(Equal([]protocol.StreamID{5, 6, 4}))
+			})
+
+			It("shares the data between streams according to their weight", func() {
+				m.streams[4].SetPriority(StreamPriority{Weight: 64})
+				m.streams[5].SetPriority(StreamPriority{Weight: 32})
+				m.streams[6].SetPriority(StreamPriority{Weight: 32})
+				sent := make(map[protocol.StreamID]int)
+				for i := 0; i < 400; i++ {
+					err := m.PriorityIterate(func(str *stream) (bool, error) {
+						sent[str.StreamID()] += 100
+						str.onDataSent(100)
+						return false, nil
+					})
+					Expect(err).ToNot(HaveOccurred())
+				}
+				Expect(sent[4]).To(BeNumerically("~", 20000, 200))
+				Expect(sent[5]).To(BeNumerically("~", 10000, 200))
+				Expect(sent[6]).To(BeNumerically("~", 10000, 200))
+			})
+
+			It("doesn't give credit to streams that were idle", func() {
+				m.streams[6].dataForWriting = nil
+				m.streams[4].onDataSent(10000)
+				m.streams[5].onDataSent(10000)
+				err := m.PriorityIterate(fn)
+				Expect(err).ToNot(HaveOccurred())
+				m.streams[6].dataForWriting = []byte("foobar")
+				err = m.PriorityIterate(fn)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(m.streams[6].schedPass).To(Equal(m.streams[4].schedPass))
+			})
+
+			It("considers a stream after the stream it depends on", func() {
+				m.streams[4].SetPriority(StreamPriority{DependsOn: 6})
+				m.streams[5].SetPriority(StreamPriority{DependsOn: 4})
+				err := m.PriorityIterate(fn)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(lambdaCalledForStream).To(Equal([]protocol.StreamID{6, 4, 5}))
+			})
+
+			It("doesn't hold a stream back if the stream it depends on has nothing to send", func() {
+				m.streams[4].SetPriority(StreamPriority{DependsOn: 6})
+				m.streams[6].dataForWriting = nil
+				m.streams[5].onDataSent(1000)
+				err := m.PriorityIterate(fn)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(lambdaCalledForStream).To(Equal([]protocol.StreamID{4, 5}))
+			})
+
+			It("gets crypto- and header stream first", func() {
+				err := m.putStream(&stream{streamID: 1})
+				Expect(err).NotTo(HaveOccurred())
+				err = m.putStream(&stream{streamID: 3})
+				Expect(err).NotTo(HaveOccurred())
+				err = m.PriorityIterate(fn)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(lambdaCalledForStream).To(Equal([]protocol.StreamID{1, 3, 4, 5, 6}))
+			})
+		})
 	})
 })